@@ -2,15 +2,24 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
+	"bookmarks-go/internal/ai"
 	"bookmarks-go/internal/api"
+	"bookmarks-go/internal/archiver"
+	"bookmarks-go/internal/blobstore"
+	"bookmarks-go/internal/imageproxy"
+	"bookmarks-go/internal/jobs"
+	"bookmarks-go/internal/scraper"
 	"bookmarks-go/internal/storage"
+	"bookmarks-go/internal/wayback"
 
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
@@ -22,10 +31,103 @@ const (
 )
 
 func main() {
+	// Debugging subcommand that needs no database
+	if len(os.Args) > 1 && os.Args[1] == "scrape" {
+		os.Exit(runScrape(os.Args[2:], os.Stdout))
+	}
+
 	// Get configuration from environment
 	port := getEnv("PORT", defaultPort)
 	dsn := getEnv("DATABASE_URL", defaultDSN)
 
+	// Scraper client, limits and caches
+	fetcher := newScraper()
+
+	// Background jobs
+	jobWorkers, err := strconv.Atoi(getEnv("JOB_WORKERS", strconv.Itoa(jobs.DefaultWorkers)))
+	if err != nil {
+		log.Fatalf("Invalid JOB_WORKERS: %v", err)
+	}
+	jobMaxAttempts, err := strconv.Atoi(getEnv("JOB_MAX_ATTEMPTS", strconv.Itoa(jobs.DefaultMaxAttempts)))
+	if err != nil {
+		log.Fatalf("Invalid JOB_MAX_ATTEMPTS: %v", err)
+	}
+
+	// Refreshing the metadata of stale bookmarks
+	refreshMaxAge, err := time.ParseDuration(getEnv("REFRESH_MAX_AGE", "720h"))
+	if err != nil {
+		log.Fatalf("Invalid REFRESH_MAX_AGE: %v", err)
+	}
+	refreshInterval, err := time.ParseDuration(getEnv("REFRESH_INTERVAL", jobs.DefaultRefreshInterval.String()))
+	if err != nil {
+		log.Fatalf("Invalid REFRESH_INTERVAL: %v", err)
+	}
+	refreshConcurrency, err := strconv.Atoi(getEnv("REFRESH_CONCURRENCY", strconv.Itoa(jobs.DefaultRefreshConcurrency)))
+	if err != nil {
+		log.Fatalf("Invalid REFRESH_CONCURRENCY: %v", err)
+	}
+
+	// Link health checks
+	checkInterval, err := time.ParseDuration(getEnv("LINK_CHECK_INTERVAL", jobs.DefaultCheckInterval.String()))
+	if err != nil {
+		log.Fatalf("Invalid LINK_CHECK_INTERVAL: %v", err)
+	}
+	brokenAfter, err := strconv.Atoi(getEnv("LINK_CHECK_BROKEN_AFTER", strconv.Itoa(jobs.DefaultBrokenAfter)))
+	if err != nil {
+		log.Fatalf("Invalid LINK_CHECK_BROKEN_AFTER: %v", err)
+	}
+	checkConcurrency, err := strconv.Atoi(getEnv("LINK_CHECK_CONCURRENCY", strconv.Itoa(jobs.DefaultCheckConcurrency)))
+	if err != nil {
+		log.Fatalf("Invalid LINK_CHECK_CONCURRENCY: %v", err)
+	}
+
+	// Change monitoring of watched pages
+	watchInterval, err := time.ParseDuration(getEnv("WATCH_INTERVAL", jobs.DefaultWatchInterval.String()))
+	if err != nil {
+		log.Fatalf("Invalid WATCH_INTERVAL: %v", err)
+	}
+	watchConcurrency, err := strconv.Atoi(getEnv("WATCH_CONCURRENCY", strconv.Itoa(jobs.DefaultWatchConcurrency)))
+	if err != nil {
+		log.Fatalf("Invalid WATCH_CONCURRENCY: %v", err)
+	}
+
+	// Web archive lookups of dead links
+	waybackURL := getEnv("WAYBACK_URL", "")
+	scrapeSnapshots, err := strconv.ParseBool(getEnv("WAYBACK_SCRAPE_SNAPSHOTS", "true"))
+	if err != nil {
+		log.Fatalf("Invalid WAYBACK_SCRAPE_SNAPSHOTS: %v", err)
+	}
+
+	// Page archives
+	archivePages, err := strconv.ParseBool(getEnv("ARCHIVE_PAGES", "true"))
+	if err != nil {
+		log.Fatalf("Invalid ARCHIVE_PAGES: %v", err)
+	}
+	archiveMaxResources, err := strconv.Atoi(getEnv("ARCHIVE_MAX_RESOURCES", strconv.Itoa(archiver.DefaultMaxResources)))
+	if err != nil {
+		log.Fatalf("Invalid ARCHIVE_MAX_RESOURCES: %v", err)
+	}
+
+	// Summaries and tag suggestions by a language model
+	model, err := newModel()
+	if err != nil {
+		log.Fatalf("Failed to create language model client: %v", err)
+	}
+	suggestTags, err := strconv.ParseBool(getEnv("AI_SUGGEST_TAGS", "true"))
+	if err != nil {
+		log.Fatalf("Invalid AI_SUGGEST_TAGS: %v", err)
+	}
+	autoApplyTags, err := strconv.ParseBool(getEnv("AI_AUTO_APPLY_TAGS", "false"))
+	if err != nil {
+		log.Fatalf("Invalid AI_AUTO_APPLY_TAGS: %v", err)
+	}
+
+	// Blob store for cached images and page archives
+	blobs, err := newBlobStore()
+	if err != nil {
+		log.Fatalf("Failed to create blob store: %v", err)
+	}
+
 	// Connect to database
 	db, err := sqlx.Connect("postgres", dsn)
 	if err != nil {
@@ -41,8 +143,42 @@ func main() {
 	// Create repository
 	repo := storage.NewPostgresRepository(db)
 
+	// Create job queue
+	queue, err := newJobQueue(db)
+	if err != nil {
+		log.Fatalf("Failed to create job queue: %v", err)
+	}
+	pool := jobs.NewPool(queue, jobs.WithWorkers(jobWorkers), jobs.WithMaxAttempts(jobMaxAttempts))
+
+	// Pages, images, link checks and page archives share one client, so that
+	// politeness limits apply to all requests to a host
+	images := imageproxy.New(blobs, fetcher)
+
+	// Look up archived snapshots of links that cannot be fetched
+	if waybackURL != "" {
+		var snapshots *scraper.Scraper
+		if scrapeSnapshots {
+			snapshots = fetcher
+		}
+		jobs.NewArchiveLookup(pool, repo, wayback.New(waybackURL, 10*time.Second), snapshots)
+	}
+
+	// Archives stay readable when archiving new pages is disabled
+	archives := archiver.New(blobs, fetcher, archiver.WithMaxResources(archiveMaxResources))
+	if archivePages {
+		jobs.NewPageArchiver(pool, repo, archives)
+	}
+
+	// Summarize extracted content and suggest tags in the background
+	if model != nil {
+		jobs.NewBookmarkSummarizer(pool, repo, model)
+		if suggestTags {
+			jobs.NewTagSuggester(pool, repo, model, autoApplyTags)
+		}
+	}
+
 	// Create router
-	router := api.SetupRoutes(repo)
+	router := api.SetupRoutes(repo, pool, images, archives, fetcher)
 
 	// Configure server
 	srv := &http.Server{
@@ -53,6 +189,49 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Start job workers
+	pool.Start()
+	log.Printf("Started %d job workers", jobWorkers)
+
+	// Start refreshing stale bookmarks
+	var scheduler *jobs.Scheduler
+	if refreshMaxAge > 0 {
+		scheduler = jobs.NewScheduler(
+			pool,
+			repo,
+			refreshMaxAge,
+			jobs.WithRefreshInterval(refreshInterval),
+			jobs.WithRefreshConcurrency(refreshConcurrency),
+		)
+		scheduler.Start()
+	}
+
+	// Start checking links
+	var checker *jobs.LinkChecker
+	if checkInterval > 0 {
+		checker = jobs.NewLinkChecker(
+			pool,
+			repo,
+			fetcher,
+			jobs.WithCheckInterval(checkInterval),
+			jobs.WithBrokenAfter(brokenAfter),
+			jobs.WithCheckConcurrency(checkConcurrency),
+		)
+		checker.Start()
+	}
+
+	// Start checking watched pages for changes
+	var watcher *jobs.Watcher
+	if watchInterval > 0 {
+		watcher = jobs.NewWatcher(
+			repo,
+			fetcher,
+			jobs.WithWatchInterval(watchInterval),
+			jobs.WithWatchConcurrency(watchConcurrency),
+		)
+		watcher.Start()
+	}
+
 	// Start server in a goroutine
 	go func() {
 		log.Printf("Server starting on port %s", port)
@@ -75,9 +254,175 @@ func main() {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
 
+	// Interrupted jobs are queued again and resume after a restart
+	if scheduler != nil {
+		scheduler.Stop()
+	}
+	if checker != nil {
+		checker.Stop()
+	}
+	if watcher != nil {
+		watcher.Stop()
+	}
+	pool.Stop()
+
 	log.Println("Server exited properly")
 }
 
+// newScraper creates the scraper configured by the SCRAPER_ environment
+// variables, which the server and the scrape subcommand share
+func newScraper() *scraper.Scraper {
+	// Private networks the scraper may fetch from, e.g. for intranet deployments
+	allowedNetworks, err := scraper.ParseNetworks(getEnv("SCRAPER_ALLOWED_NETWORKS", ""))
+	if err != nil {
+		log.Fatalf("Invalid SCRAPER_ALLOWED_NETWORKS: %v", err)
+	}
+
+	// HTTP client of the scraper
+	scraperTimeout, err := time.ParseDuration(getEnv("SCRAPER_TIMEOUT", scraper.DefaultTimeout.String()))
+	if err != nil {
+		log.Fatalf("Invalid SCRAPER_TIMEOUT: %v", err)
+	}
+	userAgent := getEnv("SCRAPER_USER_AGENT", scraper.DefaultUserAgent)
+	proxyURL, err := scraper.ParseProxyURL(getEnv("SCRAPER_PROXY", ""))
+	if err != nil {
+		log.Fatalf("Invalid SCRAPER_PROXY: %v", err)
+	}
+
+	// Politeness towards scraped sites
+	robotsPolicy, err := scraper.ParseRobotsPolicy(getEnv("SCRAPER_ROBOTS_POLICY", string(scraper.RobotsBackground)))
+	if err != nil {
+		log.Fatalf("Invalid SCRAPER_ROBOTS_POLICY: %v", err)
+	}
+	hostRate, err := strconv.ParseFloat(getEnv("SCRAPER_HOST_RATE", "1"), 64)
+	if err != nil {
+		log.Fatalf("Invalid SCRAPER_HOST_RATE: %v", err)
+	}
+	hostBurst, err := strconv.Atoi(getEnv("SCRAPER_HOST_BURST", "5"))
+	if err != nil {
+		log.Fatalf("Invalid SCRAPER_HOST_BURST: %v", err)
+	}
+
+	// Retries of transient fetch failures
+	retryPolicy := scraper.DefaultRetryPolicy
+	retryPolicy.MaxAttempts, err = strconv.Atoi(getEnv("SCRAPER_RETRY_ATTEMPTS", strconv.Itoa(retryPolicy.MaxAttempts)))
+	if err != nil {
+		log.Fatalf("Invalid SCRAPER_RETRY_ATTEMPTS: %v", err)
+	}
+
+	// Recent scrape results, reused for repeated saves and revalidated once stale
+	cacheSize, err := strconv.Atoi(getEnv("SCRAPER_CACHE_SIZE", "1000"))
+	if err != nil {
+		log.Fatalf("Invalid SCRAPER_CACHE_SIZE: %v", err)
+	}
+	cacheTTL, err := time.ParseDuration(getEnv("SCRAPER_CACHE_TTL", "10m"))
+	if err != nil {
+		log.Fatalf("Invalid SCRAPER_CACHE_TTL: %v", err)
+	}
+
+	// Size in pixels the favicon is chosen for
+	iconSize, err := strconv.Atoi(getEnv("SCRAPER_ICON_SIZE", strconv.Itoa(scraper.DefaultIconSize)))
+	if err != nil {
+		log.Fatalf("Invalid SCRAPER_ICON_SIZE: %v", err)
+	}
+
+	scraperOpts := []scraper.Option{
+		scraper.WithContentExtraction(),
+		scraper.WithUserAgent(userAgent),
+		scraper.WithAllowedNetworks(allowedNetworks...),
+		scraper.WithRobotsPolicy(robotsPolicy),
+		scraper.WithHostRateLimit(hostRate, hostBurst),
+		scraper.WithRetryPolicy(retryPolicy),
+		scraper.WithResultCache(cacheSize, cacheTTL),
+		scraper.WithIconSize(iconSize),
+	}
+	if proxyURL != nil {
+		scraperOpts = append(scraperOpts, scraper.WithProxy(proxyURL))
+	}
+
+	// Private certificate authorities, e.g. of intranet sites
+	if path := getEnv("SCRAPER_CA_BUNDLE", ""); path != "" {
+		roots, err := scraper.LoadCABundle(path)
+		if err != nil {
+			log.Fatalf("Invalid SCRAPER_CA_BUNDLE: %v", err)
+		}
+		scraperOpts = append(scraperOpts, scraper.WithRootCAs(roots))
+	}
+
+	// Headers and cookies for sites behind single sign-on
+	if path := getEnv("SCRAPER_DOMAIN_RULES", ""); path != "" {
+		rules, err := scraper.LoadDomainRules(path)
+		if err != nil {
+			log.Fatalf("Invalid SCRAPER_DOMAIN_RULES: %v", err)
+		}
+		scraperOpts = append(scraperOpts, scraper.WithDomainRules(rules...))
+	}
+
+	return scraper.NewScraper(scraperTimeout, scraperOpts...)
+}
+
+// newBlobStore creates the blob store selected by BLOB_STORE: the local
+// filesystem (default) or an S3-compatible service
+func newBlobStore() (blobstore.Store, error) {
+	switch kind := getEnv("BLOB_STORE", "file"); kind {
+	case "file":
+		return blobstore.NewFileStore(getEnv("BLOB_DIR", "./data/blobs"))
+	case "s3":
+		return blobstore.NewS3Store(blobstore.S3Config{
+			Endpoint:        getEnv("S3_ENDPOINT", "https://s3.amazonaws.com"),
+			Region:          getEnv("S3_REGION", "us-east-1"),
+			Bucket:          getEnv("S3_BUCKET", ""),
+			AccessKeyID:     getEnv("S3_ACCESS_KEY_ID", ""),
+			SecretAccessKey: getEnv("S3_SECRET_ACCESS_KEY", ""),
+			Prefix:          getEnv("S3_PREFIX", ""),
+		})
+	default:
+		return nil, fmt.Errorf("unknown blob store: %q", kind)
+	}
+}
+
+// newModel creates the language model client selected by AI_PROVIDER: none
+// (default), an OpenAI-compatible service or Ollama. AI_BASE_URL points it at
+// another server, such as a local stub.
+func newModel() (ai.Model, error) {
+	timeout, err := time.ParseDuration(getEnv("AI_TIMEOUT", ai.DefaultTimeout.String()))
+	if err != nil {
+		return nil, fmt.Errorf("invalid AI_TIMEOUT: %w", err)
+	}
+	switch kind := getEnv("AI_PROVIDER", "none"); kind {
+	case "none":
+		return nil, nil
+	case "openai":
+		return ai.NewOpenAI(
+			getEnv("AI_BASE_URL", ai.DefaultOpenAIBaseURL),
+			getEnv("AI_API_KEY", ""),
+			getEnv("AI_MODEL", ai.DefaultOpenAIModel),
+			timeout,
+		), nil
+	case "ollama":
+		return ai.NewOllama(
+			getEnv("AI_BASE_URL", ai.DefaultOllamaBaseURL),
+			getEnv("AI_MODEL", ai.DefaultOllamaModel),
+			timeout,
+		), nil
+	default:
+		return nil, fmt.Errorf("unknown AI provider: %q", kind)
+	}
+}
+
+// newJobQueue creates the job queue selected by JOB_QUEUE: PostgreSQL
+// (default) or memory, which loses queued jobs on restart
+func newJobQueue(db *sqlx.DB) (storage.JobQueue, error) {
+	switch kind := getEnv("JOB_QUEUE", "postgres"); kind {
+	case "postgres":
+		return storage.NewPostgresJobQueue(db), nil
+	case "memory":
+		return storage.NewMemoryJobQueue(), nil
+	default:
+		return nil, fmt.Errorf("unknown job queue: %q", kind)
+	}
+}
+
 // getEnv gets an environment variable or returns the default value
 func getEnv(key, defaultValue string) string {
 	if value, exists := os.LookupEnv(key); exists {
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode"
+
+	"bookmarks-go/internal/models"
+	"bookmarks-go/internal/scraper"
+)
+
+// maxValueWidth is the width values are cut to in table output
+const maxValueWidth = 80
+
+// scrapeReport is the output of the scrape subcommand
+type scrapeReport struct {
+	URL        string              `json:"url"`
+	FinalURL   string              `json:"final_url,omitempty"`
+	MediaType  string              `json:"media_type,omitempty"`
+	Fields     []reportField       `json:"fields"`
+	Candidates []scraper.Candidate `json:"candidates"`
+	Redirects  []reportRedirect    `json:"redirects"`
+	Timings    []reportTiming      `json:"timings"`
+	Error      string              `json:"error,omitempty"`
+}
+
+// reportField is a field of the scraped metadata and where its value was found
+type reportField struct {
+	Field      string                  `json:"field"`
+	Value      string                  `json:"value"`
+	Provenance *models.FieldProvenance `json:"provenance,omitempty"`
+}
+
+// reportRedirect is a hop of the redirect chain
+type reportRedirect struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+	Kind       string `json:"kind"`
+}
+
+// reportTiming is a step of the scrape and how long it took
+type reportTiming struct {
+	Step       string  `json:"step"`
+	DurationMS float64 `json:"duration_ms"`
+}
+
+// runScrape runs the scrape subcommand, which scrapes a URL with the
+// configured scraper and shows how each metadata value was found. It returns
+// the exit code.
+func runScrape(args []string, out io.Writer) int {
+	flags := flag.NewFlagSet("scrape", flag.ContinueOnError)
+	format := flags.String("format", "table", "output format: table or json")
+	background := flags.Bool("background", false, "scrape like a background refresh, under the background robots.txt policy")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: server scrape [flags] <url>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 || (*format != "table" && *format != "json") {
+		flags.Usage()
+		return 2
+	}
+	urlStr := flags.Arg(0)
+
+	ctx := context.Background()
+	if *background {
+		ctx = scraper.BackgroundContext(ctx)
+	}
+	var trace scraper.Trace
+	metadata, err := newScraper().GetMetadata(scraper.WithTrace(ctx, &trace), urlStr)
+
+	report := newScrapeReport(urlStr, metadata, &trace)
+	if err != nil {
+		report.Error = err.Error()
+	}
+	if *format == "json" {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	} else {
+		writeScrapeTable(out, report)
+	}
+	if err != nil {
+		return 1
+	}
+	return 0
+}
+
+// newScrapeReport collects the result and trace of a scrape; metadata is nil
+// if the scrape failed
+func newScrapeReport(urlStr string, metadata *scraper.Metadata, trace *scraper.Trace) *scrapeReport {
+	report := &scrapeReport{
+		URL:        urlStr,
+		Fields:     []reportField{},
+		Candidates: trace.Candidates,
+		Redirects:  []reportRedirect{},
+		Timings:    []reportTiming{},
+	}
+	if report.Candidates == nil {
+		report.Candidates = []scraper.Candidate{}
+	}
+	for _, timing := range trace.Timings {
+		report.Timings = append(report.Timings, reportTiming{
+			Step:       timing.Step,
+			DurationMS: float64(timing.Duration.Microseconds()) / 1000,
+		})
+	}
+	if metadata == nil {
+		return report
+	}
+
+	report.FinalURL = metadata.FinalURL
+	report.MediaType = metadata.MediaType
+	fields := []reportField{
+		{Field: models.FieldTitle, Value: metadata.Title},
+		{Field: models.FieldDescription, Value: metadata.Description},
+		{Field: models.FieldAuthor, Value: metadata.Author},
+		{Field: models.FieldFaviconURL, Value: metadata.FaviconURL},
+		{Field: models.FieldImageURL, Value: metadata.ImageURL},
+		{Field: models.FieldPublished, Value: metadata.Published},
+	}
+	for _, field := range fields {
+		if provenance, ok := metadata.Provenance[field.Field]; ok {
+			field.Provenance = &provenance
+		}
+		report.Fields = append(report.Fields, field)
+	}
+	for _, redirect := range metadata.Redirects {
+		report.Redirects = append(report.Redirects, reportRedirect(redirect))
+	}
+	return report
+}
+
+// writeScrapeTable writes report as aligned tables, marking the candidates
+// that were selected with an asterisk
+func writeScrapeTable(out io.Writer, report *scrapeReport) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "URL\t%s\n", report.URL)
+	if report.FinalURL != "" {
+		fmt.Fprintf(w, "Final URL\t%s\n", report.FinalURL)
+		fmt.Fprintf(w, "Media type\t%s\n", report.MediaType)
+	}
+	if report.Error != "" {
+		fmt.Fprintf(w, "Error\t%s\n", report.Error)
+	}
+
+	if len(report.Fields) > 0 {
+		fmt.Fprintln(w, "\nFIELD\tVALUE\tSOURCE\tRULE\tCONFIDENCE")
+		for _, field := range report.Fields {
+			var provenance models.FieldProvenance
+			if field.Provenance != nil {
+				provenance = *field.Provenance
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", field.Field, cell(field.Value), provenanceCells(provenance))
+		}
+
+		fmt.Fprintln(w, "\n\tCANDIDATE\tVALUE\tSOURCE\tRULE\tCONFIDENCE")
+		for _, candidate := range report.Candidates {
+			mark := ""
+			if candidate.Selected {
+				mark = "*"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", mark, candidate.Field, cell(candidate.Value), provenanceCells(candidate.FieldProvenance))
+		}
+	}
+
+	if len(report.Redirects) > 0 {
+		fmt.Fprintln(w, "\nREDIRECT\tSTATUS\tKIND")
+		for _, redirect := range report.Redirects {
+			fmt.Fprintf(w, "%s\t%d\t%s\n", cell(redirect.URL), redirect.StatusCode, redirect.Kind)
+		}
+	}
+
+	fmt.Fprintln(w, "\nSTEP\tDURATION")
+	for _, timing := range report.Timings {
+		duration := time.Duration(timing.DurationMS * float64(time.Millisecond))
+		fmt.Fprintf(w, "%s\t%s\n", cell(timing.Step), duration.Round(time.Microsecond))
+	}
+	w.Flush()
+}
+
+// provenanceCells formats the source, rule and confidence columns
+func provenanceCells(p models.FieldProvenance) string {
+	if p.Source == "" {
+		return "\t\t"
+	}
+	return p.Source + "\t" + cell(p.Rule) + "\t" + strconv.FormatFloat(p.Confidence, 'f', 2, 64)
+}
+
+// cell prepares a value for a table cell: control characters, which would
+// break the layout, become spaces and long values are cut
+func cell(value string) string {
+	value = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, value)
+	if runes := []rune(value); len(runes) > maxValueWidth {
+		value = string(runes[:maxValueWidth-3]) + "..."
+	}
+	return value
+}
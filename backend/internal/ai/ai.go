@@ -0,0 +1,106 @@
+// Package ai summarizes and tags bookmarked pages with language models
+// behind OpenAI-compatible and Ollama chat endpoints
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout bounds each request to a model unless configured otherwise;
+// local models may take a while to answer
+const DefaultTimeout = 60 * time.Second
+
+// maxInputRunes bounds the page text sent to a model, keeping requests
+// within the context window of small local models
+const maxInputRunes = 12000
+
+// maxResponseSize is the maximum size of a response read from a provider
+const maxResponseSize = 1 << 20
+
+// ErrEmptyResponse is returned when a model replies with no text
+var ErrEmptyResponse = errors.New("empty response from model")
+
+// Summarizer summarizes the text of bookmarked pages
+type Summarizer interface {
+	Summarize(ctx context.Context, title, text string) (string, error)
+}
+
+// message is a chat message sent to a model
+type message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// chatModel sends chat conversations to a model and returns its reply. A
+// non-nil format asks for a JSON reply matching the schema.
+type chatModel interface {
+	complete(ctx context.Context, messages []message, format *Schema) (string, error)
+}
+
+// summaryPrompt instructs models how to summarize a page
+const summaryPrompt = "You summarize web pages saved in a bookmarks app. Reply with a summary of two " +
+	"to three sentences in the language of the page, without any preamble."
+
+// summarize asks m for a summary of a page
+func summarize(ctx context.Context, m chatModel, title, text string) (string, error) {
+	messages := []message{
+		{Role: "system", Content: summaryPrompt},
+		{Role: "user", Content: pageInput(title, text)},
+	}
+	reply, err := m.complete(ctx, messages, nil)
+	if err != nil {
+		return "", err
+	}
+	reply = strings.TrimSpace(reply)
+	if reply == "" {
+		return "", ErrEmptyResponse
+	}
+	return reply, nil
+}
+
+// pageInput formats a page for a prompt, cutting long texts
+func pageInput(title, text string) string {
+	if runes := []rune(text); len(runes) > maxInputRunes {
+		text = string(runes[:maxInputRunes])
+	}
+	return "Title: " + title + "\n\n" + text
+}
+
+// postJSON posts body as JSON to url and decodes the JSON response into dest
+func postJSON(ctx context.Context, client *http.Client, url string, header http.Header, body, dest interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(dest); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultOllamaBaseURL is a local Ollama server
+	DefaultOllamaBaseURL = "http://localhost:11434"
+	// DefaultOllamaModel is the model used unless configured otherwise
+	DefaultOllamaModel = "llama3.2"
+)
+
+// Ollama uses the chat endpoint of an Ollama server
+type Ollama struct {
+	baseURL string
+	model   string
+	client  *http.Client
+}
+
+// NewOllama creates a client for the Ollama server at baseURL, such as
+// DefaultOllamaBaseURL or a local stub
+func NewOllama(baseURL, model string, timeout time.Duration) *Ollama {
+	return &Ollama{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		model:   model,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// Summarize returns a short summary of a page
+func (c *Ollama) Summarize(ctx context.Context, title, text string) (string, error) {
+	return summarize(ctx, c, title, text)
+}
+
+// SuggestTags returns tags for a page, preferring those in vocabulary
+func (c *Ollama) SuggestTags(ctx context.Context, title, text string, vocabulary []string) ([]string, error) {
+	return suggestTags(ctx, c, title, text, vocabulary)
+}
+
+// ollamaChat is the response of the chat endpoint when not streaming
+type ollamaChat struct {
+	Message message `json:"message"`
+}
+
+func (c *Ollama) complete(ctx context.Context, messages []message, format *Schema) (string, error) {
+	body := map[string]interface{}{
+		"model":    c.model,
+		"messages": messages,
+		"stream":   false,
+		"options":  map[string]interface{}{"temperature": 0.2},
+	}
+	if format != nil {
+		body["format"] = format
+	}
+
+	var chat ollamaChat
+	if err := postJSON(ctx, c.client, c.baseURL+"/api/chat", nil, body, &chat); err != nil {
+		return "", err
+	}
+	return chat.Message.Content, nil
+}
@@ -0,0 +1,63 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOllamaSummarize(t *testing.T) {
+	var got struct {
+		Model    string    `json:"model"`
+		Messages []message `json:"messages"`
+		Stream   bool      `json:"stream"`
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/chat", r.URL.Path)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"model": "test-model", "message": {"role": "assistant", "content": "A short summary."}, "done": true}`))
+	}))
+	defer ts.Close()
+
+	summary, err := NewOllama(ts.URL, "test-model", 5*time.Second).Summarize(context.Background(), "Page", "Text")
+	require.NoError(t, err)
+	assert.Equal(t, "A short summary.", summary)
+	assert.Equal(t, "test-model", got.Model)
+	assert.False(t, got.Stream)
+	require.Len(t, got.Messages, 2)
+	assert.Equal(t, "Title: Page\n\nText", got.Messages[1].Content)
+}
+
+func TestOllamaEmptyResponse(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"message": {"role": "assistant", "content": " "}, "done": true}`))
+	}))
+	defer ts.Close()
+
+	_, err := NewOllama(ts.URL, "test-model", 5*time.Second).Summarize(context.Background(), "Page", "Text")
+	assert.Equal(t, ErrEmptyResponse, err)
+}
+
+func TestOllamaSuggestTags(t *testing.T) {
+	var got struct {
+		Format *Schema `json:"format"`
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.Write([]byte(`{"message": {"role": "assistant", "content": "{\"tags\": [\"recipes\"]}"}, "done": true}`))
+	}))
+	defer ts.Close()
+
+	tags, err := NewOllama(ts.URL, "test-model", 5*time.Second).SuggestTags(context.Background(), "Page", "Text", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"recipes"}, tags)
+	require.NotNil(t, got.Format)
+	assert.Equal(t, []string{"tags"}, got.Format.Required)
+}
@@ -0,0 +1,85 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultOpenAIBaseURL is the OpenAI API
+	DefaultOpenAIBaseURL = "https://api.openai.com/v1"
+	// DefaultOpenAIModel is the model used unless configured otherwise
+	DefaultOpenAIModel = "gpt-4o-mini"
+)
+
+// OpenAI uses the chat completions endpoint of the OpenAI API or of a
+// compatible service, such as a local inference server
+type OpenAI struct {
+	baseURL string
+	apiKey  string
+	model   string
+	client  *http.Client
+}
+
+// NewOpenAI creates a client for the chat completions endpoint below
+// baseURL, such as DefaultOpenAIBaseURL or a local stub. apiKey may be empty
+// for services that need none.
+func NewOpenAI(baseURL, apiKey, model string, timeout time.Duration) *OpenAI {
+	return &OpenAI{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// Summarize returns a short summary of a page
+func (c *OpenAI) Summarize(ctx context.Context, title, text string) (string, error) {
+	return summarize(ctx, c, title, text)
+}
+
+// SuggestTags returns tags for a page, preferring those in vocabulary
+func (c *OpenAI) SuggestTags(ctx context.Context, title, text string, vocabulary []string) ([]string, error) {
+	return suggestTags(ctx, c, title, text, vocabulary)
+}
+
+// chatCompletion is the response of the chat completions endpoint
+type chatCompletion struct {
+	Choices []struct {
+		Message message `json:"message"`
+	} `json:"choices"`
+}
+
+func (c *OpenAI) complete(ctx context.Context, messages []message, format *Schema) (string, error) {
+	body := map[string]interface{}{
+		"model":       c.model,
+		"messages":    messages,
+		"temperature": 0.2,
+	}
+	if format != nil {
+		// Strict mode rejects length bounds; replies are validated anyway
+		body["response_format"] = map[string]interface{}{
+			"type": "json_schema",
+			"json_schema": map[string]interface{}{
+				"name":   "reply",
+				"schema": format,
+				"strict": false,
+			},
+		}
+	}
+	header := http.Header{}
+	if c.apiKey != "" {
+		header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	var completion chatCompletion
+	if err := postJSON(ctx, c.client, c.baseURL+"/chat/completions", header, body, &completion); err != nil {
+		return "", err
+	}
+	if len(completion.Choices) == 0 {
+		return "", ErrEmptyResponse
+	}
+	return completion.Choices[0].Message.Content, nil
+}
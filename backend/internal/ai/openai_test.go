@@ -0,0 +1,85 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAISummarize(t *testing.T) {
+	var got struct {
+		Model    string    `json:"model"`
+		Messages []message `json:"messages"`
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "  A short summary.\n"}}]}`))
+	}))
+	defer ts.Close()
+
+	client := NewOpenAI(ts.URL+"/v1/", "secret", "test-model", 5*time.Second)
+	summary, err := client.Summarize(context.Background(), "Page", strings.Repeat("word ", 5000))
+	require.NoError(t, err)
+	assert.Equal(t, "A short summary.", summary)
+
+	assert.Equal(t, "test-model", got.Model)
+	require.Len(t, got.Messages, 2)
+	assert.Equal(t, "system", got.Messages[0].Role)
+	assert.True(t, strings.HasPrefix(got.Messages[1].Content, "Title: Page\n\nword word"))
+	// Long texts are cut
+	assert.Len(t, []rune(got.Messages[1].Content), len("Title: Page\n\n")+maxInputRunes)
+}
+
+func TestOpenAIErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			http.Error(w, `{"error": {"message": "missing key"}}`, http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"choices": []}`))
+	}))
+	defer ts.Close()
+
+	_, err := NewOpenAI(ts.URL, "", "test-model", 5*time.Second).Summarize(context.Background(), "Page", "Text")
+	assert.ErrorContains(t, err, "401")
+
+	_, err = NewOpenAI(ts.URL, "secret", "test-model", 5*time.Second).Summarize(context.Background(), "Page", "Text")
+	assert.Equal(t, ErrEmptyResponse, err)
+}
+
+func TestOpenAISuggestTags(t *testing.T) {
+	var got struct {
+		Messages       []message `json:"messages"`
+		ResponseFormat struct {
+			Type       string `json:"type"`
+			JSONSchema struct {
+				Schema Schema `json:"schema"`
+			} `json:"json_schema"`
+		} `json:"response_format"`
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "{\"tags\": [\"Go\", \"databases\"]}"}}]}`))
+	}))
+	defer ts.Close()
+
+	tags, err := NewOpenAI(ts.URL, "", "test-model", 5*time.Second).SuggestTags(context.Background(), "Page", "Text", []string{"go", "rust"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"go", "databases"}, tags)
+
+	assert.Equal(t, "json_schema", got.ResponseFormat.Type)
+	assert.Equal(t, "object", got.ResponseFormat.JSONSchema.Schema.Type)
+	require.Len(t, got.Messages, 2)
+	assert.Equal(t, "Existing tags: go, rust\n\nTitle: Page\n\nText", got.Messages[1].Content)
+}
@@ -0,0 +1,105 @@
+package ai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// ErrInvalidResponse is returned when a model replies with JSON that does not
+// match the requested schema
+var ErrInvalidResponse = errors.New("invalid response from model")
+
+// Schema is the subset of JSON Schema used to constrain model replies. It is
+// sent to providers that support structured output and checked by Validate,
+// since not every model honors it.
+type Schema struct {
+	Type                 string             `json:"type"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+}
+
+// Validate checks a decoded JSON value against s
+func (s *Schema) Validate(v interface{}) error {
+	return s.validate("$", v)
+}
+
+func (s *Schema) validate(path string, v interface{}) error {
+	switch s.Type {
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%w: %s is not an object", ErrInvalidResponse, path)
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				return fmt.Errorf("%w: %s is missing %s", ErrInvalidResponse, path, name)
+			}
+		}
+		for name, value := range obj {
+			property, ok := s.Properties[name]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					return fmt.Errorf("%w: %s has unexpected property %s", ErrInvalidResponse, path, name)
+				}
+				continue
+			}
+			if err := property.validate(path+"."+name, value); err != nil {
+				return err
+			}
+		}
+	case "array":
+		items, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("%w: %s is not an array", ErrInvalidResponse, path)
+		}
+		if s.MaxItems != nil && len(items) > *s.MaxItems {
+			return fmt.Errorf("%w: %s has more than %d items", ErrInvalidResponse, path, *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range items {
+				if err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+					return err
+				}
+			}
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%w: %s is not a string", ErrInvalidResponse, path)
+		}
+		n := utf8.RuneCountInString(str)
+		if s.MinLength != nil && n < *s.MinLength {
+			return fmt.Errorf("%w: %s is shorter than %d characters", ErrInvalidResponse, path, *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			return fmt.Errorf("%w: %s is longer than %d characters", ErrInvalidResponse, path, *s.MaxLength)
+		}
+	default:
+		return fmt.Errorf("unsupported schema type %q", s.Type)
+	}
+	return nil
+}
+
+// decodeReply decodes a JSON reply into dest after validating it against schema
+func decodeReply(reply string, schema *Schema, dest interface{}) error {
+	var v interface{}
+	if err := json.Unmarshal([]byte(reply), &v); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+	if err := schema.Validate(v); err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(reply), dest)
+}
+
+// intPtr returns a pointer to n, for schema bounds
+func intPtr(n int) *int {
+	return &n
+}
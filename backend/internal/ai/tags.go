@@ -0,0 +1,104 @@
+package ai
+
+import (
+	"context"
+	"strings"
+
+	"bookmarks-go/internal/models"
+)
+
+const (
+	// MaxSuggestedTags is the maximum number of tags proposed for a page
+	MaxSuggestedTags = 5
+	// maxNewTags is the maximum number of proposed tags the user does not have yet
+	maxNewTags = 2
+	// maxVocabulary bounds the existing tags listed in a prompt; callers pass
+	// the most used first
+	maxVocabulary = 200
+)
+
+// Tagger proposes tags for bookmarked pages, preferring the given existing tags
+type Tagger interface {
+	SuggestTags(ctx context.Context, title, text string, vocabulary []string) ([]string, error)
+}
+
+// Model is a language model that both summarizes and tags pages
+type Model interface {
+	Summarizer
+	Tagger
+}
+
+// tagsSchema constrains replies to a list of short tags
+var tagsSchema = &Schema{
+	Type: "object",
+	Properties: map[string]*Schema{
+		"tags": {
+			Type:     "array",
+			Items:    &Schema{Type: "string", MinLength: intPtr(1), MaxLength: intPtr(models.MaxTagLength)},
+			MaxItems: intPtr(MaxSuggestedTags),
+		},
+	},
+	Required:             []string{"tags"},
+	AdditionalProperties: new(bool),
+}
+
+// tagPrompt instructs models how to tag a page
+const tagPrompt = "You tag web pages saved in a bookmarks app. Reply with a JSON object of the form " +
+	`{"tags": ["..."]} holding up to 5 short, lowercase tags for the page. Strongly prefer the ` +
+	"user's existing tags; only propose a new tag when none of them fits, and at most 2 new ones."
+
+// suggestTags asks m for tags of a page and returns them normalized
+func suggestTags(ctx context.Context, m chatModel, title, text string, vocabulary []string) ([]string, error) {
+	if len(vocabulary) > maxVocabulary {
+		vocabulary = vocabulary[:maxVocabulary]
+	}
+	existing := "The user has no tags yet."
+	if len(vocabulary) > 0 {
+		existing = "Existing tags: " + strings.Join(vocabulary, ", ")
+	}
+	messages := []message{
+		{Role: "system", Content: tagPrompt},
+		{Role: "user", Content: existing + "\n\n" + pageInput(title, text)},
+	}
+	reply, err := m.complete(ctx, messages, tagsSchema)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(reply) == "" {
+		return nil, ErrEmptyResponse
+	}
+
+	var result struct {
+		Tags []string `json:"tags"`
+	}
+	if err := decodeReply(reply, tagsSchema, &result); err != nil {
+		return nil, err
+	}
+	return preferVocabulary(result.Tags, vocabulary), nil
+}
+
+// preferVocabulary normalizes proposed tags and drops duplicates, invalid
+// tags and new tags beyond maxNewTags
+func preferVocabulary(proposed, vocabulary []string) []string {
+	known := make(map[string]bool, len(vocabulary))
+	for _, tag := range vocabulary {
+		known[models.NormalizeTag(tag)] = true
+	}
+
+	var tags models.Tags
+	newTags := 0
+	for _, tag := range proposed {
+		tag = models.NormalizeTag(tag)
+		if !models.ValidTag(tag) || tags.Has(tag) {
+			continue
+		}
+		if !known[tag] {
+			if newTags == maxNewTags {
+				continue
+			}
+			newTags++
+		}
+		tags = tags.Add(tag)
+	}
+	return tags
+}
@@ -0,0 +1,61 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubModel replies with a fixed text
+type stubModel struct {
+	reply string
+}
+
+func (m stubModel) complete(ctx context.Context, messages []message, format *Schema) (string, error) {
+	return m.reply, nil
+}
+
+func TestSuggestTagsValidatesReply(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply string
+		valid bool
+	}{
+		{"valid", `{"tags": ["go"]}`, true},
+		{"no tags", `{"tags": []}`, true},
+		{"not JSON", `Tags: go, rust`, false},
+		{"missing tags", `{"labels": ["go"]}`, false},
+		{"extra property", `{"tags": ["go"], "reason": "Go code"}`, false},
+		{"not strings", `{"tags": [1, 2]}`, false},
+		{"too many", `{"tags": ["a", "b", "c", "d", "e", "f"]}`, false},
+		{"too long", `{"tags": ["` + strings.Repeat("x", 41) + `"]}`, false},
+		{"empty tag", `{"tags": [""]}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := suggestTags(context.Background(), stubModel{reply: tt.reply}, "Page", "Text", nil)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, errors.Is(err, ErrInvalidResponse), "got %v", err)
+			}
+		})
+	}
+
+	_, err := suggestTags(context.Background(), stubModel{reply: " "}, "Page", "Text", nil)
+	assert.Equal(t, ErrEmptyResponse, err)
+}
+
+func TestPreferVocabulary(t *testing.T) {
+	vocabulary := []string{"go", "databases", "web development"}
+
+	tags := preferVocabulary([]string{"Go", " Web  Development", "postgres", "go", "sql", "indexes", "databases"}, vocabulary)
+	// Existing tags are always kept, new ones only up to maxNewTags
+	assert.Equal(t, []string{"go", "web development", "postgres", "sql", "databases"}, tags)
+
+	require.Empty(t, preferVocabulary(nil, vocabulary))
+}
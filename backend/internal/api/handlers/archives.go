@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"bookmarks-go/internal/archiver"
+	"bookmarks-go/internal/jobs"
+	"bookmarks-go/internal/models"
+	"bookmarks-go/internal/storage"
+)
+
+// replayCSP lets replayed pages load archived images, stylesheets, fonts and
+// media from this server but no scripts, frames or live resources. Like
+// snapshots they are sandboxed, and only this app may frame them.
+const replayCSP = "default-src 'none'; img-src 'self' data:; style-src 'self' 'unsafe-inline' data:; " +
+	"font-src 'self' data:; media-src 'self' data:; form-action 'none'; base-uri 'none'; sandbox; frame-ancestors 'self'"
+
+// ArchiveHandler serves the WARC archives and single-file snapshots of bookmarked pages
+type ArchiveHandler struct {
+	repo     storage.Repository
+	archives *archiver.Archiver
+	jobs     *jobs.Pool
+}
+
+// NewArchiveHandler creates a new archive handler
+func NewArchiveHandler(repo storage.Repository, archives *archiver.Archiver, pool *jobs.Pool) *ArchiveHandler {
+	return &ArchiveHandler{
+		repo:     repo,
+		archives: archives,
+		jobs:     pool,
+	}
+}
+
+// DownloadArchive handles downloading the WARC file of a bookmark
+func (h *ArchiveHandler) DownloadArchive(w http.ResponseWriter, r *http.Request) {
+	bookmark, ok := loadBookmark(w, r, h.repo)
+	if !ok {
+		return
+	}
+
+	body, info, err := h.archives.Open(r.Context(), bookmark.ID)
+	if err != nil {
+		if err == archiver.ErrNotArchived {
+			http.Error(w, "Archive not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to open archive: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer body.Close()
+
+	w.Header().Set("Content-Type", archiver.ContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+archiver.Filename(bookmark.ID)+`"`)
+	if info.Size > 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+	}
+	if !info.ModTime.IsZero() {
+		w.Header().Set("Last-Modified", info.ModTime.UTC().Format(http.TimeFormat))
+	}
+	io.Copy(w, body)
+}
+
+// GetSnapshot handles serving the single-file HTML snapshot of a bookmark.
+// Snapshots only load inlined resources and run no scripts.
+func (h *ArchiveHandler) GetSnapshot(w http.ResponseWriter, r *http.Request) {
+	bookmark, ok := loadBookmark(w, r, h.repo)
+	if !ok {
+		return
+	}
+
+	body, info, err := h.archives.OpenSnapshot(r.Context(), bookmark.ID)
+	if err != nil {
+		if err == archiver.ErrNotArchived {
+			http.Error(w, "Snapshot not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to open snapshot: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer body.Close()
+
+	w.Header().Set("Content-Security-Policy", archiver.SnapshotCSP+"; sandbox")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Type", info.ContentType)
+	w.Header().Set("Content-Disposition", `inline; filename="`+archiver.SnapshotFilename(bookmark.ID)+`"`)
+	if info.Size > 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+	}
+	if !info.ModTime.IsZero() {
+		w.Header().Set("Last-Modified", info.ModTime.UTC().Format(http.TimeFormat))
+	}
+	io.Copy(w, body)
+}
+
+// ArchivePage handles archiving the page of a bookmark again in the background
+func (h *ArchiveHandler) ArchivePage(w http.ResponseWriter, r *http.Request) {
+	bookmark, ok := loadBookmark(w, r, h.repo)
+	if !ok {
+		return
+	}
+	if !h.jobs.Handles(models.JobArchivePage) {
+		http.Error(w, "Page archiving is disabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	job, err := h.jobs.Enqueue(r.Context(), models.JobArchivePage, bookmark.ID)
+	if err != nil {
+		http.Error(w, "Failed to enqueue job: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/jobs/"+strconv.FormatInt(job.ID, 10))
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(models.JobResponse{Job: job})
+}
+
+// ReplayArchive handles serving the archived page of a bookmark, or the
+// archived resource given by the url query parameter. References to archived
+// resources are rewritten to point back here.
+func (h *ArchiveHandler) ReplayArchive(w http.ResponseWriter, r *http.Request) {
+	bookmark, ok := loadBookmark(w, r, h.repo)
+	if !ok {
+		return
+	}
+
+	// Relative links resolve to this endpoint from the page and from resources
+	link := func(ref string) string {
+		return "replay?url=" + url.QueryEscape(ref)
+	}
+	replayed, err := h.archives.Replay(r.Context(), bookmark.ID, r.URL.Query().Get("url"), link)
+	if err != nil {
+		switch err {
+		case archiver.ErrNotArchived:
+			http.Error(w, "Archive not found", http.StatusNotFound)
+		case archiver.ErrResourceNotFound:
+			http.Error(w, "Resource not found in archive", http.StatusNotFound)
+		default:
+			http.Error(w, "Failed to replay archive: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Security-Policy", replayCSP)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if replayed.ContentType != "" {
+		w.Header().Set("Content-Type", replayed.ContentType)
+	}
+	if !replayed.Date.IsZero() {
+		w.Header().Set("Last-Modified", replayed.Date.UTC().Format(http.TimeFormat))
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(replayed.Body)))
+	w.Write(replayed.Body)
+}
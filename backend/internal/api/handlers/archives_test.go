@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"bookmarks-go/internal/archiver"
+	"bookmarks-go/internal/blobstore"
+	"bookmarks-go/internal/jobs"
+	"bookmarks-go/internal/models"
+	"bookmarks-go/internal/scraper"
+	"bookmarks-go/internal/storage"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveEndpoints(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/page":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(`<html><head><link rel="stylesheet" href="style.css"></head><body>Saved</body></html>`))
+		case "/style.css":
+			w.Header().Set("Content-Type", "text/css")
+			w.Write([]byte(`body { color: red }`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer origin.Close()
+
+	loopback, err := scraper.ParseNetworks("127.0.0.0/8")
+	require.NoError(t, err)
+	store, err := blobstore.NewFileStore(t.TempDir())
+	require.NoError(t, err)
+	archives := archiver.New(store, scraper.NewScraper(5*time.Second, scraper.WithAllowedNetworks(loopback...)))
+	_, err = archives.Archive(context.Background(), 1, origin.URL+"/page")
+	require.NoError(t, err)
+
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetBookmark", mock.Anything, int64(1)).Return(&models.Bookmark{ID: 1, URL: origin.URL + "/page"}, nil)
+	mockRepo.On("GetBookmark", mock.Anything, int64(2)).Return(&models.Bookmark{ID: 2, URL: origin.URL + "/page"}, nil)
+	mockRepo.On("GetBookmark", mock.Anything, int64(999)).Return(nil, storage.ErrNotFound)
+	handler := NewArchiveHandler(mockRepo, archives, newTestPool())
+
+	tests := []struct {
+		name           string
+		handler        http.HandlerFunc
+		bookmarkID     string
+		query          string
+		expectedStatus int
+		expectedType   string
+		expectedBody   string
+	}{
+		{name: "download", handler: handler.DownloadArchive, bookmarkID: "1", expectedStatus: http.StatusOK, expectedType: archiver.ContentType},
+		{name: "download without archive", handler: handler.DownloadArchive, bookmarkID: "2", expectedStatus: http.StatusNotFound, expectedBody: "Archive not found\n"},
+		{name: "download bookmark not found", handler: handler.DownloadArchive, bookmarkID: "999", expectedStatus: http.StatusNotFound, expectedBody: "Bookmark not found\n"},
+		{
+			name:           "replay page",
+			handler:        handler.ReplayArchive,
+			bookmarkID:     "1",
+			expectedStatus: http.StatusOK,
+			expectedType:   "text/html; charset=utf-8",
+			expectedBody:   `<html><head><link rel="stylesheet" href="replay?url=` + url.QueryEscape(origin.URL+"/style.css") + `"/></head><body>Saved</body></html>`,
+		},
+		{
+			name:           "replay resource",
+			handler:        handler.ReplayArchive,
+			bookmarkID:     "1",
+			query:          "?url=" + url.QueryEscape(origin.URL+"/style.css"),
+			expectedStatus: http.StatusOK,
+			expectedType:   "text/css",
+			expectedBody:   `body { color: red }`,
+		},
+		{
+			name:           "replay missing resource",
+			handler:        handler.ReplayArchive,
+			bookmarkID:     "1",
+			query:          "?url=" + url.QueryEscape(origin.URL+"/gone.png"),
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "Resource not found in archive\n",
+		},
+		{name: "replay without archive", handler: handler.ReplayArchive, bookmarkID: "2", expectedStatus: http.StatusNotFound, expectedBody: "Archive not found\n"},
+		{name: "snapshot", handler: handler.GetSnapshot, bookmarkID: "1", expectedStatus: http.StatusOK, expectedType: "text/html; charset=utf-8"},
+		{name: "snapshot not found", handler: handler.GetSnapshot, bookmarkID: "2", expectedStatus: http.StatusNotFound, expectedBody: "Snapshot not found\n"},
+		{name: "archiving disabled", handler: handler.ArchivePage, bookmarkID: "2", expectedStatus: http.StatusServiceUnavailable, expectedBody: "Page archiving is disabled\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/bookmarks/"+tt.bookmarkID+"/archive"+tt.query, nil)
+			req = mux.SetURLVars(req, map[string]string{"id": tt.bookmarkID})
+			w := httptest.NewRecorder()
+
+			tt.handler(w, req)
+
+			resp := w.Result()
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			if tt.expectedType != "" {
+				assert.Equal(t, tt.expectedType, resp.Header.Get("Content-Type"))
+			}
+			if tt.expectedBody != "" {
+				body, _ := io.ReadAll(resp.Body)
+				assert.Equal(t, tt.expectedBody, string(body))
+			}
+			if (strings.HasPrefix(tt.name, "replay") || strings.HasPrefix(tt.name, "snapshot")) && tt.expectedStatus == http.StatusOK {
+				assert.Contains(t, resp.Header.Get("Content-Security-Policy"), "default-src 'none'")
+				assert.Contains(t, resp.Header.Get("Content-Security-Policy"), "; sandbox")
+			}
+			if strings.HasPrefix(tt.name, "replay") && tt.expectedStatus == http.StatusOK {
+				assert.Contains(t, resp.Header.Get("Content-Security-Policy"), "frame-ancestors 'self'")
+			}
+		})
+	}
+}
+
+func TestArchivePage(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetBookmark", mock.Anything, int64(1)).Return(&models.Bookmark{ID: 1, URL: "https://example.com"}, nil)
+
+	pool := jobs.NewPool(storage.NewMemoryJobQueue())
+	pool.Handle(models.JobArchivePage, func(ctx context.Context, job *models.Job) error { return nil })
+	handler := NewArchiveHandler(mockRepo, nil, pool)
+
+	req := httptest.NewRequest("POST", "/bookmarks/1/archive", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "1"})
+	w := httptest.NewRecorder()
+
+	handler.ArchivePage(w, req)
+
+	resp := w.Result()
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	var response models.JobResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	require.NotNil(t, response.Job)
+	assert.Equal(t, models.JobArchivePage, response.Job.Kind)
+	assert.Equal(t, "/api/jobs/"+strconv.FormatInt(response.Job.ID, 10), resp.Header.Get("Location"))
+}
@@ -1,11 +1,17 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
+	"net/url"
 	"strconv"
 	"time"
 
+	"bookmarks-go/internal/archiver"
+	"bookmarks-go/internal/jobs"
 	"bookmarks-go/internal/models"
 	"bookmarks-go/internal/scraper"
 	"bookmarks-go/internal/storage"
@@ -13,21 +19,45 @@ import (
 	"github.com/gorilla/mux"
 )
 
+// Number of link checks returned by default and at most
+const (
+	defaultLinkChecksLimit = 50
+	maxLinkChecksLimit     = 1000
+)
+
+// inlineScrapeTimeout bounds the metadata fetch made while creating a bookmark;
+// slower fetches continue as a background job
+const inlineScrapeTimeout = 5 * time.Second
+
 // BookmarkHandler handles bookmark-related HTTP requests
 type BookmarkHandler struct {
-	repo    storage.Repository
-	scraper *scraper.Scraper
+	repo          storage.Repository
+	jobs          *jobs.Pool
+	archives      *archiver.Archiver
+	scraper       *scraper.Scraper
+	inlineTimeout time.Duration
 }
 
-// NewBookmarkHandler creates a new bookmark handler
-func NewBookmarkHandler(repo storage.Repository) *BookmarkHandler {
-	return &BookmarkHandler{
-		repo:    repo,
-		scraper: scraper.NewScraper(10 * time.Second),
+// NewBookmarkHandler creates a new bookmark handler fetching metadata with s
+// and registers its job handlers with pool. Article content is only stored if s
+// extracts it. Page archives of deleted bookmarks are removed from archives,
+// which may be nil.
+func NewBookmarkHandler(repo storage.Repository, pool *jobs.Pool, archives *archiver.Archiver, s *scraper.Scraper) *BookmarkHandler {
+	h := &BookmarkHandler{
+		repo:          repo,
+		jobs:          pool,
+		archives:      archives,
+		scraper:       s,
+		inlineTimeout: inlineScrapeTimeout,
 	}
+	pool.Handle(models.JobScrapeMetadata, h.ScrapeMetadata)
+	pool.Handle(models.JobRefreshMetadata, h.RefreshMetadata)
+	return h
 }
 
-// CreateBookmark handles the creation of a new bookmark
+// CreateBookmark handles the creation of a new bookmark. The bookmark is saved
+// even when its metadata cannot be fetched right away; fetching then continues
+// in a background job and 202 Accepted is returned.
 func (h *BookmarkHandler) CreateBookmark(w http.ResponseWriter, r *http.Request) {
 	var req models.CreateBookmarkRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -35,29 +65,283 @@ func (h *BookmarkHandler) CreateBookmark(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Fetch metadata
-	metadata, err := h.scraper.GetMetadata(r.Context(), req.URL)
-	if err != nil {
-		http.Error(w, "Failed to fetch metadata: "+err.Error(), http.StatusBadRequest)
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		http.Error(w, "Invalid URL: must be an absolute http or https URL", http.StatusBadRequest)
 		return
 	}
 
-	// Create bookmark
 	bookmark := &models.Bookmark{
-		URL:         req.URL,
-		Title:       metadata.Title,
-		Description: metadata.Description,
-		FaviconURL:  metadata.FaviconURL,
+		URL:   req.URL,
+		Title: req.Title,
+	}
+	if req.Title != "" {
+		bookmark.LockedFields = bookmark.LockedFields.Lock(models.FieldTitle)
+		bookmark.Provenance = bookmark.Provenance.Set(models.FieldTitle, models.UserProvenance)
+	}
+
+	// Fetch metadata
+	ctx, cancel := context.WithTimeout(r.Context(), h.inlineTimeout)
+	metadata, err := h.scraper.GetMetadata(ctx, req.URL)
+	cancel()
+	if err != nil {
+		bookmark.MetadataStatus = models.MetadataPending
+		bookmark.MetadataError = err.Error()
+	} else {
+		applyMetadata(bookmark, metadata)
 	}
 
 	if err := h.repo.CreateBookmark(r.Context(), bookmark); err != nil {
 		http.Error(w, "Failed to create bookmark: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	h.archivePage(r.Context(), bookmark.ID)
+
+	if metadata != nil {
+		h.saveContent(r.Context(), bookmark, metadata.Content)
+		h.suggestTags(r.Context(), bookmark.ID)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(models.BookmarkResponse{Bookmark: bookmark})
+		return
+	}
+
+	job, err := h.jobs.Enqueue(r.Context(), models.JobScrapeMetadata, bookmark.ID)
+	if err != nil {
+		http.Error(w, "Failed to enqueue job: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-	// Return response
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(models.BookmarkResponse{Bookmark: bookmark})
+	w.Header().Set("Location", "/api/jobs/"+strconv.FormatInt(job.ID, 10))
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(models.BookmarkResponse{Bookmark: bookmark, Job: job})
+}
+
+// archivePage enqueues archiving the page of a new bookmark, if enabled. The
+// job retries on its own if the page cannot be fetched yet.
+func (h *BookmarkHandler) archivePage(ctx context.Context, bookmarkID int64) {
+	if !h.jobs.Handles(models.JobArchivePage) {
+		return
+	}
+	if _, err := h.jobs.Enqueue(ctx, models.JobArchivePage, bookmarkID); err != nil {
+		log.Printf("Failed to enqueue page archive of bookmark %d: %v", bookmarkID, err)
+	}
+}
+
+// suggestTags enqueues suggesting tags for a newly scraped bookmark, if enabled
+func (h *BookmarkHandler) suggestTags(ctx context.Context, bookmarkID int64) {
+	if !h.jobs.Handles(models.JobSuggestTags) {
+		return
+	}
+	if _, err := h.jobs.Enqueue(ctx, models.JobSuggestTags, bookmarkID); err != nil {
+		log.Printf("Failed to enqueue tag suggestions for bookmark %d: %v", bookmarkID, err)
+	}
+}
+
+// ScrapeMetadata runs a JobScrapeMetadata job, fetching the metadata of a
+// bookmark saved without it. The bookmark is marked failed after the last attempt.
+func (h *BookmarkHandler) ScrapeMetadata(ctx context.Context, job *models.Job) error {
+	bookmark, err := h.repo.GetBookmark(ctx, job.BookmarkID)
+	if err == storage.ErrNotFound {
+		// Deleted in the meantime
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	metadata, err := h.scraper.GetMetadata(scraper.BackgroundContext(ctx), bookmark.URL)
+	if err != nil {
+		giveUp := job.Attempts >= job.MaxAttempts
+		updateErr := storage.UpdateWithRetry(ctx, h.repo, bookmark, func(b *models.Bookmark) {
+			b.MetadataError = err.Error()
+			if giveUp {
+				b.MetadataStatus = models.MetadataFailed
+				b.RefreshedAt = time.Now().UTC()
+			}
+		})
+		if updateErr != nil {
+			log.Printf("Failed to update bookmark %d: %v", bookmark.ID, updateErr)
+		}
+		if giveUp && !errors.Is(err, scraper.ErrBlockedAddress) && h.jobs.Handles(models.JobArchiveLookup) {
+			// The page may still be found in the web archive
+			if _, err := h.jobs.Enqueue(ctx, models.JobArchiveLookup, bookmark.ID); err != nil {
+				log.Printf("Failed to enqueue archive lookup of bookmark %d: %v", bookmark.ID, err)
+			}
+		}
+		return err
+	}
+
+	err = storage.UpdateWithRetry(ctx, h.repo, bookmark, func(b *models.Bookmark) { applyMetadata(b, metadata) })
+	if err != nil {
+		return err
+	}
+	h.saveContent(ctx, bookmark, metadata.Content)
+	h.suggestTags(ctx, bookmark.ID)
+	return nil
+}
+
+// RefreshMetadata runs a JobRefreshMetadata job, fetching the metadata of a
+// stale bookmark again. Pages are revalidated with the stored validators, and
+// fields locked by the user are kept.
+func (h *BookmarkHandler) RefreshMetadata(ctx context.Context, job *models.Job) error {
+	bookmark, err := h.repo.GetBookmark(ctx, job.BookmarkID)
+	if err == storage.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if bookmark.RefreshedAt.After(job.CreatedAt) {
+		// Refreshed by another job in the meantime
+		return nil
+	}
+
+	ctx = scraper.BackgroundContext(ctx)
+	validators := scraper.Validators{
+		ETag:         bookmark.ETag,
+		LastModified: bookmark.LastModified,
+		BodyHash:     bookmark.ContentHash,
+	}
+	var metadata *scraper.Metadata
+	if bookmark.MetadataStatus == models.MetadataOK && validators != (scraper.Validators{}) {
+		metadata, err = h.scraper.Revalidate(ctx, bookmark.URL, validators)
+	} else {
+		metadata, err = h.scraper.GetMetadata(ctx, bookmark.URL)
+	}
+	if err != nil {
+		// Existing metadata stays valid; give up until the bookmark is stale again
+		if job.Attempts >= job.MaxAttempts {
+			updateErr := storage.UpdateWithRetry(ctx, h.repo, bookmark, func(b *models.Bookmark) {
+				b.MetadataError = err.Error()
+				b.RefreshedAt = time.Now().UTC()
+			})
+			if updateErr != nil {
+				log.Printf("Failed to update bookmark %d: %v", bookmark.ID, updateErr)
+			}
+		}
+		return err
+	}
+
+	if metadata.NotModified {
+		return storage.UpdateWithRetry(ctx, h.repo, bookmark, func(b *models.Bookmark) {
+			if metadata.Validators.ETag != "" {
+				b.ETag = metadata.Validators.ETag
+			}
+			if metadata.Validators.LastModified != "" {
+				b.LastModified = metadata.Validators.LastModified
+			}
+			b.MetadataError = ""
+			b.RefreshedAt = time.Now().UTC()
+		})
+	}
+
+	err = storage.UpdateWithRetry(ctx, h.repo, bookmark, func(b *models.Bookmark) { applyMetadata(b, metadata) })
+	if err != nil {
+		return err
+	}
+	h.saveContent(ctx, bookmark, metadata.Content)
+	return nil
+}
+
+// applyMetadata copies scraped metadata onto bookmark. Locked fields are
+// kept, and so are values that did not come from the page, such as those of a
+// web archive snapshot, unless the page now has a value trusted as much.
+func applyMetadata(bookmark *models.Bookmark, metadata *scraper.Metadata) {
+	fields := []struct {
+		field string
+		dest  *string
+		value string
+	}{
+		{models.FieldTitle, &bookmark.Title, metadata.Title},
+		{models.FieldDescription, &bookmark.Description, metadata.Description},
+		{models.FieldAuthor, &bookmark.Author, metadata.Author},
+		{models.FieldFaviconURL, &bookmark.FaviconURL, metadata.FaviconURL},
+		{models.FieldImageURL, &bookmark.ImageURL, metadata.ImageURL},
+	}
+	for _, f := range fields {
+		if bookmark.LockedFields.Has(f.field) {
+			continue
+		}
+		current := bookmark.Provenance[f.field]
+		scraped, found := metadata.Provenance[f.field]
+		if *f.dest != "" && !current.Scraped() && (!found || scraped.Confidence < current.Confidence) {
+			continue
+		}
+		*f.dest = f.value
+		if found {
+			bookmark.Provenance = bookmark.Provenance.Set(f.field, scraped)
+		} else {
+			delete(bookmark.Provenance, f.field)
+		}
+	}
+	bookmark.FinalURL = metadata.FinalURL
+	bookmark.MediaType = metadata.MediaType
+	bookmark.ContentLength = metadata.ContentLength
+	bookmark.PageCount = metadata.PageCount
+	bookmark.Extra = metadata.Extra
+	bookmark.ETag = metadata.Validators.ETag
+	bookmark.LastModified = metadata.Validators.LastModified
+	bookmark.ContentHash = metadata.Validators.BodyHash
+	bookmark.MetadataStatus = models.MetadataOK
+	bookmark.MetadataError = ""
+	bookmark.RefreshedAt = time.Now().UTC()
+
+	bookmark.Icons = nil
+	for _, icon := range metadata.Icons {
+		bookmark.Icons = append(bookmark.Icons, models.Icon{
+			URL:     icon.URL,
+			Rel:     icon.Rel,
+			Type:    icon.Type,
+			Sizes:   icon.Sizes,
+			Purpose: icon.Purpose,
+		})
+	}
+	bookmark.Redirects = nil
+	for _, hop := range metadata.Redirects {
+		bookmark.Redirects = append(bookmark.Redirects, models.Redirect{
+			URL:        hop.URL,
+			StatusCode: hop.StatusCode,
+			Kind:       hop.Kind,
+		})
+	}
+	if metadata.Content != nil {
+		bookmark.WordCount = metadata.Content.WordCount
+		bookmark.ReadingTime = metadata.Content.ReadingTimeMinutes
+	}
+}
+
+// saveContent stores extracted content and, if summaries are enabled,
+// enqueues summarizing it unless an unchanged text was summarized before.
+// The bookmark remains usable without either.
+func (h *BookmarkHandler) saveContent(ctx context.Context, bookmark *models.Bookmark, extracted *scraper.Content) {
+	if extracted == nil {
+		return
+	}
+	summarize := h.jobs.Handles(models.JobSummarize)
+	if summarize && bookmark.SummarizedAt != nil {
+		previous, err := h.repo.GetContent(ctx, bookmark.ID)
+		summarize = err != nil || previous.Text != extracted.Text
+	}
+
+	content := &models.BookmarkContent{
+		BookmarkID:  bookmark.ID,
+		Text:        extracted.Text,
+		HTML:        extracted.HTML,
+		WordCount:   extracted.WordCount,
+		ReadingTime: extracted.ReadingTimeMinutes,
+	}
+	if err := h.repo.SaveContent(ctx, content); err != nil {
+		log.Printf("Failed to save content for bookmark %d: %v", bookmark.ID, err)
+		return
+	}
+
+	if summarize && extracted.Text != "" {
+		if _, err := h.jobs.Enqueue(ctx, models.JobSummarize, bookmark.ID); err != nil {
+			log.Printf("Failed to enqueue summary of bookmark %d: %v", bookmark.ID, err)
+		}
+	}
 }
 
 // GetBookmark handles retrieving a single bookmark
@@ -83,9 +367,154 @@ func (h *BookmarkHandler) GetBookmark(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(models.BookmarkResponse{Bookmark: bookmark})
 }
 
-// ListBookmarks handles retrieving all bookmarks
+// UpdateBookmark handles editing a bookmark. Edited fields are locked, so
+// that refreshing the metadata does not overwrite them.
+func (h *BookmarkHandler) UpdateBookmark(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid bookmark ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateBookmarkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	for _, field := range req.Unlock {
+		if !isEditableField(field) {
+			http.Error(w, "Invalid field to unlock: "+field, http.StatusBadRequest)
+			return
+		}
+	}
+	var tags models.Tags
+	if req.Tags != nil {
+		for _, tag := range *req.Tags {
+			tag = models.NormalizeTag(tag)
+			if !models.ValidTag(tag) {
+				http.Error(w, "Invalid tag: must be 1 to "+strconv.Itoa(models.MaxTagLength)+" characters", http.StatusBadRequest)
+				return
+			}
+			tags = tags.Add(tag)
+		}
+	}
+
+	bookmark, err := h.repo.GetBookmark(r.Context(), id)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			http.Error(w, "Bookmark not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get bookmark: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// A refresh storing the bookmark in the meantime is retried on top of the edit
+	err = storage.UpdateWithRetry(r.Context(), h.repo, bookmark, func(b *models.Bookmark) { applyEdits(b, &req) })
+	if err != nil {
+		http.Error(w, "Failed to update bookmark: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Watching again starts over from the current page
+	if req.Watched != nil && *req.Watched != bookmark.Watched {
+		if err := h.repo.SetWatched(r.Context(), id, *req.Watched); err != nil {
+			http.Error(w, "Failed to update bookmark: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		bookmark.Watched = *req.Watched
+		bookmark.WatchCheckedAt = nil
+	}
+
+	if req.Tags != nil {
+		if err := h.repo.SetTags(r.Context(), id, tags); err != nil {
+			http.Error(w, "Failed to update bookmark: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		bookmark.Tags = tags
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.BookmarkResponse{Bookmark: bookmark})
+}
+
+// applyEdits sets and locks the fields edited by req, and unlocks those it lists
+func applyEdits(bookmark *models.Bookmark, req *models.UpdateBookmarkRequest) {
+	for _, field := range req.Unlock {
+		bookmark.LockedFields = bookmark.LockedFields.Unlock(field)
+		// The value is replaced by the next refresh
+		delete(bookmark.Provenance, field)
+	}
+	edits := []struct {
+		field string
+		value *string
+		dest  *string
+	}{
+		{models.FieldTitle, req.Title, &bookmark.Title},
+		{models.FieldDescription, req.Description, &bookmark.Description},
+		{models.FieldAuthor, req.Author, &bookmark.Author},
+		{models.FieldFaviconURL, req.FaviconURL, &bookmark.FaviconURL},
+		{models.FieldImageURL, req.ImageURL, &bookmark.ImageURL},
+	}
+	for _, edit := range edits {
+		if edit.value != nil {
+			*edit.dest = *edit.value
+			bookmark.LockedFields = bookmark.LockedFields.Lock(edit.field)
+			bookmark.Provenance = bookmark.Provenance.Set(edit.field, models.UserProvenance)
+		}
+	}
+}
+
+// isEditableField reports whether field names a field that can be edited and locked
+func isEditableField(field string) bool {
+	for _, editable := range models.EditableFields {
+		if field == editable {
+			return true
+		}
+	}
+	return false
+}
+
+// GetContent handles retrieving the extracted article content of a bookmark
+func (h *BookmarkHandler) GetContent(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid bookmark ID", http.StatusBadRequest)
+		return
+	}
+
+	content, err := h.repo.GetContent(r.Context(), id)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			http.Error(w, "Content not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get content: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.ContentResponse{Content: content})
+}
+
+// ListBookmarks handles retrieving all bookmarks, optionally only those with
+// the link health given by the health query parameter or the tag given by the
+// tag query parameter
 func (h *BookmarkHandler) ListBookmarks(w http.ResponseWriter, r *http.Request) {
-	bookmarks, err := h.repo.ListBookmarks(r.Context())
+	filter := models.BookmarkFilter{
+		Health: r.URL.Query().Get("health"),
+		Tag:    models.NormalizeTag(r.URL.Query().Get("tag")),
+	}
+	switch filter.Health {
+	case "", models.HealthUnknown, models.HealthOK, models.HealthBroken:
+	default:
+		http.Error(w, "Invalid health: must be unknown, ok or broken", http.StatusBadRequest)
+		return
+	}
+
+	bookmarks, err := h.repo.ListBookmarks(r.Context(), filter)
 	if err != nil {
 		http.Error(w, "Failed to list bookmarks: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -95,6 +524,39 @@ func (h *BookmarkHandler) ListBookmarks(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(models.BookmarksResponse{Bookmarks: bookmarks})
 }
 
+// GetLinkChecks handles retrieving the link check history of a bookmark, newest first
+func (h *BookmarkHandler) GetLinkChecks(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid bookmark ID", http.StatusBadRequest)
+		return
+	}
+	limit, err := queryInt(r, "limit", defaultLinkChecksLimit)
+	if err != nil || limit < 1 || limit > maxLinkChecksLimit {
+		http.Error(w, "Invalid limit", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.repo.GetBookmark(r.Context(), id); err != nil {
+		if err == storage.ErrNotFound {
+			http.Error(w, "Bookmark not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get bookmark: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	checks, err := h.repo.ListLinkChecks(r.Context(), id, limit)
+	if err != nil {
+		http.Error(w, "Failed to list link checks: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.LinkChecksResponse{Checks: checks})
+}
+
 // DeleteBookmark handles deleting a bookmark
 func (h *BookmarkHandler) DeleteBookmark(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -114,6 +576,19 @@ func (h *BookmarkHandler) DeleteBookmark(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if h.archives != nil {
+		if err := h.archives.Delete(r.Context(), id); err != nil {
+			log.Printf("Failed to delete page archive of bookmark %d: %v", id, err)
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(models.DeleteResponse{Success: true})
 }
+
+// ScraperStatus handles reporting the retry counters and per-host circuit
+// breaker states of the metadata scraper, for monitoring
+func (h *BookmarkHandler) ScraperStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.scraper.Stats())
+}
@@ -7,15 +7,20 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"bookmarks-go/internal/jobs"
 	"bookmarks-go/internal/models"
+	"bookmarks-go/internal/scraper"
 	"bookmarks-go/internal/storage"
 
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // MockRepository is a mock implementation of storage.Repository
@@ -36,19 +41,152 @@ func (m *MockRepository) GetBookmark(ctx context.Context, id int64) (*models.Boo
 	return args.Get(0).(*models.Bookmark), args.Error(1)
 }
 
-func (m *MockRepository) ListBookmarks(ctx context.Context) ([]models.Bookmark, error) {
+func (m *MockRepository) ListBookmarks(ctx context.Context, filter models.BookmarkFilter) ([]models.Bookmark, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]models.Bookmark), args.Error(1)
+}
+
+func (m *MockRepository) ListStaleBookmarks(ctx context.Context, before time.Time, limit int) ([]models.Bookmark, error) {
+	args := m.Called(ctx, before, limit)
+	return args.Get(0).([]models.Bookmark), args.Error(1)
+}
+
+func (m *MockRepository) UpdateBookmark(ctx context.Context, bookmark *models.Bookmark) error {
+	args := m.Called(ctx, bookmark)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ListBookmarksToCheck(ctx context.Context, before time.Time, limit int) ([]models.Bookmark, error) {
+	args := m.Called(ctx, before, limit)
+	return args.Get(0).([]models.Bookmark), args.Error(1)
+}
+
+func (m *MockRepository) RecordLinkCheck(ctx context.Context, check *models.LinkCheck, brokenAfter int) error {
+	args := m.Called(ctx, check, brokenAfter)
+	return args.Error(0)
+}
+
+func (m *MockRepository) SetPageArchived(ctx context.Context, id int64, at time.Time) error {
+	args := m.Called(ctx, id, at)
+	return args.Error(0)
+}
+
+func (m *MockRepository) SetSummary(ctx context.Context, id int64, summary string, at time.Time) error {
+	args := m.Called(ctx, id, summary, at)
+	return args.Error(0)
+}
+
+func (m *MockRepository) SetTags(ctx context.Context, id int64, tags models.Tags) error {
+	args := m.Called(ctx, id, tags)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ListTags(ctx context.Context) ([]models.TagCount, error) {
 	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.TagCount), args.Error(1)
+}
+
+func (m *MockRepository) AddTagSuggestions(ctx context.Context, bookmarkID int64, tags []string, apply bool) error {
+	args := m.Called(ctx, bookmarkID, tags, apply)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ListTagSuggestions(ctx context.Context, bookmarkID int64) ([]models.TagSuggestion, error) {
+	args := m.Called(ctx, bookmarkID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.TagSuggestion), args.Error(1)
+}
+
+func (m *MockRepository) DecideTagSuggestion(ctx context.Context, bookmarkID, suggestionID int64, accept bool) (*models.TagSuggestion, error) {
+	args := m.Called(ctx, bookmarkID, suggestionID, accept)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TagSuggestion), args.Error(1)
+}
+
+func (m *MockRepository) SetWatched(ctx context.Context, id int64, watched bool) error {
+	args := m.Called(ctx, id, watched)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ListWatchedToCheck(ctx context.Context, before time.Time, limit int) ([]models.Bookmark, error) {
+	args := m.Called(ctx, before, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
 	return args.Get(0).([]models.Bookmark), args.Error(1)
 }
 
+func (m *MockRepository) GetPageText(ctx context.Context, bookmarkID int64) (*models.PageText, error) {
+	args := m.Called(ctx, bookmarkID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PageText), args.Error(1)
+}
+
+func (m *MockRepository) RecordPageCheck(ctx context.Context, bookmarkID int64, checkedAt time.Time, text *models.PageText, change *models.PageChange) error {
+	args := m.Called(ctx, bookmarkID, checkedAt, text, change)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ListPageChanges(ctx context.Context, bookmarkID int64, limit int) ([]models.PageChange, error) {
+	args := m.Called(ctx, bookmarkID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.PageChange), args.Error(1)
+}
+
+func (m *MockRepository) ListPageChangesAfter(ctx context.Context, after int64, limit int) ([]models.PageChange, error) {
+	args := m.Called(ctx, after, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.PageChange), args.Error(1)
+}
+
+func (m *MockRepository) ListLinkChecks(ctx context.Context, bookmarkID int64, limit int) ([]models.LinkCheck, error) {
+	args := m.Called(ctx, bookmarkID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.LinkCheck), args.Error(1)
+}
+
 func (m *MockRepository) DeleteBookmark(ctx context.Context, id int64) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
+func (m *MockRepository) SaveContent(ctx context.Context, content *models.BookmarkContent) error {
+	args := m.Called(ctx, content)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetContent(ctx context.Context, bookmarkID int64) (*models.BookmarkContent, error) {
+	args := m.Called(ctx, bookmarkID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.BookmarkContent), args.Error(1)
+}
+
 func TestCreateBookmark(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, `<html><head><title>Example</title></head><body></body></html>`)
+	}))
+	defer ts.Close()
+
 	mockRepo := new(MockRepository)
-	handler := NewBookmarkHandler(mockRepo)
+	handler := NewBookmarkHandler(mockRepo, newTestPool(), nil, newTestScraper(allowLoopback(t)))
 
 	tests := []struct {
 		name           string
@@ -60,12 +198,14 @@ func TestCreateBookmark(t *testing.T) {
 		{
 			name: "successful creation",
 			requestBody: models.CreateBookmarkRequest{
-				URL: "https://example.com",
+				URL: ts.URL,
 			},
 			setupMock: func() {
 				mockRepo.On("CreateBookmark", mock.Anything, mock.MatchedBy(func(b *models.Bookmark) bool {
-					return b.URL == "https://example.com"
+					return b.URL == ts.URL && b.Title == "Example" && b.MetadataStatus == models.MetadataOK && len(b.LockedFields) == 0 &&
+						b.Provenance[models.FieldTitle].Source == models.SourceHTMLTitle
 				})).Return(nil)
+				mockRepo.On("SaveContent", mock.Anything, mock.Anything).Return(nil).Maybe()
 			},
 			expectedStatus: http.StatusOK,
 		},
@@ -76,6 +216,15 @@ func TestCreateBookmark(t *testing.T) {
 			expectedStatus: http.StatusBadRequest,
 			expectedError:  "Invalid request body\n",
 		},
+		{
+			name: "invalid url",
+			requestBody: models.CreateBookmarkRequest{
+				URL: "ftp://example.com",
+			},
+			setupMock:      func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "Invalid URL: must be an absolute http or https URL\n",
+		},
 	}
 
 	for _, tt := range tests {
@@ -98,7 +247,8 @@ func TestCreateBookmark(t *testing.T) {
 				var response models.BookmarkResponse
 				json.NewDecoder(resp.Body).Decode(&response)
 				assert.NotNil(t, response.Bookmark)
-				assert.Equal(t, "https://example.com", response.Bookmark.URL)
+				assert.Equal(t, ts.URL, response.Bookmark.URL)
+				assert.Nil(t, response.Job)
 			}
 
 			mockRepo.AssertExpectations(t)
@@ -106,9 +256,417 @@ func TestCreateBookmark(t *testing.T) {
 	}
 }
 
+func TestCreateBookmarkWithoutMetadata(t *testing.T) {
+	mockRepo := new(MockRepository)
+	pool := jobs.NewPool(
+		storage.NewMemoryJobQueue(),
+		jobs.WithMaxAttempts(2),
+		jobs.WithPollInterval(time.Millisecond),
+		jobs.WithRetryDelays(time.Millisecond),
+	)
+	handler := NewBookmarkHandler(mockRepo, pool, nil, newTestScraper())
+
+	// Loopback addresses are refused by the scraper, so every fetch fails
+	const target = "http://127.0.0.1:1/page"
+
+	mockRepo.On("CreateBookmark", mock.Anything, mock.MatchedBy(func(b *models.Bookmark) bool {
+		return b.URL == target && b.Title == "My title" && b.LockedFields.Has(models.FieldTitle) &&
+			b.MetadataStatus == models.MetadataPending && b.MetadataError != ""
+	})).Run(func(args mock.Arguments) {
+		args.Get(1).(*models.Bookmark).ID = 7
+	}).Return(nil)
+	mockRepo.On("GetBookmark", mock.Anything, int64(7)).Return(
+		&models.Bookmark{ID: 7, URL: target, Title: "My title", MetadataStatus: models.MetadataPending}, nil)
+
+	updated := make(chan models.Bookmark, 2)
+	mockRepo.On("UpdateBookmark", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		updated <- *args.Get(1).(*models.Bookmark)
+	}).Return(nil)
+
+	body, _ := json.Marshal(models.CreateBookmarkRequest{URL: target, Title: "My title"})
+	req := httptest.NewRequest("POST", "/bookmarks", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+
+	handler.CreateBookmark(w, req)
+
+	resp := w.Result()
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	var response models.BookmarkResponse
+	json.NewDecoder(resp.Body).Decode(&response)
+	assert.Equal(t, int64(7), response.Bookmark.ID)
+	assert.Equal(t, "My title", response.Bookmark.Title)
+	assert.Equal(t, models.MetadataPending, response.Bookmark.MetadataStatus)
+	if assert.NotNil(t, response.Job) {
+		assert.Equal(t, models.JobScrapeMetadata, response.Job.Kind)
+		assert.Equal(t, "/api/jobs/"+strconv.FormatInt(response.Job.ID, 10), resp.Header.Get("Location"))
+	}
+
+	// The job retries once, then gives up and marks the bookmark failed
+	pool.Start()
+	defer pool.Stop()
+
+	for _, want := range []string{models.MetadataPending, models.MetadataFailed} {
+		select {
+		case bookmark := <-updated:
+			assert.Equal(t, int64(7), bookmark.ID)
+			assert.Equal(t, "My title", bookmark.Title)
+			assert.Equal(t, want, bookmark.MetadataStatus)
+			assert.NotEmpty(t, bookmark.MetadataError)
+		case <-time.After(5 * time.Second):
+			t.Fatal("bookmark was not updated by the job")
+		}
+	}
+}
+
+func TestRefreshMetadata(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v2"`)
+		if r.Header.Get("If-None-Match") == `"v2"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, `<html><head><title>New title</title><meta name="description" content="New description"></head></html>`)
+	}))
+	defer ts.Close()
+
+	refreshedAt := time.Now().Add(-60 * 24 * time.Hour)
+	job := &models.Job{Kind: models.JobRefreshMetadata, BookmarkID: 1, Attempts: 1, MaxAttempts: 1, CreatedAt: time.Now()}
+
+	tests := []struct {
+		name            string
+		etag            string
+		wantTitle       string
+		wantDescription string
+		wantETag        string
+	}{
+		{
+			name:            "changed page keeps locked fields",
+			etag:            `"v1"`,
+			wantTitle:       "My title",
+			wantDescription: "New description",
+			wantETag:        `"v2"`,
+		},
+		{
+			name:            "unchanged page",
+			etag:            `"v2"`,
+			wantTitle:       "My title",
+			wantDescription: "Old description",
+			wantETag:        `"v2"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			handler := NewBookmarkHandler(mockRepo, newTestPool(), nil, newTestScraper(allowLoopback(t)))
+
+			mockRepo.On("GetBookmark", mock.Anything, int64(1)).Return(&models.Bookmark{
+				ID:             1,
+				URL:            ts.URL,
+				Title:          "My title",
+				Description:    "Old description",
+				ETag:           tt.etag,
+				MetadataStatus: models.MetadataOK,
+				RefreshedAt:    refreshedAt,
+				LockedFields:   models.FieldLocks{models.FieldTitle},
+			}, nil)
+			mockRepo.On("UpdateBookmark", mock.Anything, mock.MatchedBy(func(b *models.Bookmark) bool {
+				return b.Title == tt.wantTitle && b.Description == tt.wantDescription &&
+					b.ETag == tt.wantETag && b.RefreshedAt.After(refreshedAt)
+			})).Return(nil)
+
+			err := handler.RefreshMetadata(context.Background(), job)
+			assert.NoError(t, err)
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRefreshMetadataSummarizes(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, `<html><head><title>Article</title></head><body><article><p>`+
+			strings.Repeat("The body text of the article is long enough to be extracted. ", 10)+`</p></article></body></html>`)
+	}))
+	defer ts.Close()
+
+	metadata, err := newTestScraper(allowLoopback(t)).GetMetadata(context.Background(), ts.URL)
+	require.NoError(t, err)
+	require.NotNil(t, metadata.Content)
+	pageText := metadata.Content.Text
+
+	summarizedAt := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name         string
+		summarizedAt *time.Time
+		previousText string
+		wantJob      bool
+	}{
+		{name: "never summarized", wantJob: true},
+		{name: "changed text", summarizedAt: &summarizedAt, previousText: "Old text", wantJob: true},
+		{name: "unchanged text", summarizedAt: &summarizedAt, previousText: pageText, wantJob: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			pool := newTestPool()
+			pool.Handle(models.JobSummarize, func(ctx context.Context, job *models.Job) error { return nil })
+			handler := NewBookmarkHandler(mockRepo, pool, nil, newTestScraper(allowLoopback(t)))
+
+			mockRepo.On("GetBookmark", mock.Anything, int64(1)).Return(&models.Bookmark{
+				ID:           1,
+				URL:          ts.URL,
+				SummarizedAt: tt.summarizedAt,
+			}, nil)
+			mockRepo.On("UpdateBookmark", mock.Anything, mock.Anything).Return(nil)
+			mockRepo.On("SaveContent", mock.Anything, mock.Anything).Return(nil)
+			if tt.summarizedAt != nil {
+				mockRepo.On("GetContent", mock.Anything, int64(1)).Return(&models.BookmarkContent{BookmarkID: 1, Text: tt.previousText}, nil)
+			}
+
+			job := &models.Job{Kind: models.JobRefreshMetadata, BookmarkID: 1, CreatedAt: time.Now()}
+			require.NoError(t, handler.RefreshMetadata(context.Background(), job))
+
+			summary, err := pool.Job(context.Background(), 1)
+			if tt.wantJob {
+				require.NoError(t, err)
+				assert.Equal(t, models.JobSummarize, summary.Kind)
+			} else {
+				assert.Error(t, err)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestScrapeMetadataSuggestsTags(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, `<html><head><title>Article</title></head><body></body></html>`)
+	}))
+	defer ts.Close()
+
+	mockRepo := new(MockRepository)
+	pool := newTestPool()
+	pool.Handle(models.JobSuggestTags, func(ctx context.Context, job *models.Job) error { return nil })
+	handler := NewBookmarkHandler(mockRepo, pool, nil, newTestScraper(allowLoopback(t)))
+
+	mockRepo.On("GetBookmark", mock.Anything, int64(1)).Return(&models.Bookmark{ID: 1, URL: ts.URL}, nil)
+	mockRepo.On("UpdateBookmark", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("SaveContent", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	job := &models.Job{Kind: models.JobScrapeMetadata, BookmarkID: 1, MaxAttempts: 1}
+	require.NoError(t, handler.ScrapeMetadata(context.Background(), job))
+
+	suggestion, err := pool.Job(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobSuggestTags, suggestion.Kind)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestApplyMetadata(t *testing.T) {
+	archived := models.FieldProvenance{Source: models.SourceWebArchive, Rule: "title", Confidence: 0.4}
+	tests := []struct {
+		name      string
+		metadata  *scraper.Metadata
+		wantTitle string
+		wantSrc   string
+	}{
+		{
+			name: "page value replaces archived one",
+			metadata: &scraper.Metadata{
+				Title:      "Live",
+				Provenance: models.Provenance{models.FieldTitle: {Source: models.SourceHTMLTitle, Confidence: 0.8}},
+			},
+			wantTitle: "Live",
+			wantSrc:   models.SourceHTMLTitle,
+		},
+		{
+			name: "less trusted page value keeps archived one",
+			metadata: &scraper.Metadata{
+				Title:      "file.html",
+				Provenance: models.Provenance{models.FieldTitle: {Source: models.SourceFileName, Confidence: 0.3}},
+			},
+			wantTitle: "Archived",
+			wantSrc:   models.SourceWebArchive,
+		},
+		{
+			name:      "missing page value keeps archived one",
+			metadata:  &scraper.Metadata{},
+			wantTitle: "Archived",
+			wantSrc:   models.SourceWebArchive,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bookmark := &models.Bookmark{
+				Title:       "Archived",
+				Description: "Old description",
+				Provenance: models.Provenance{
+					models.FieldTitle:       archived,
+					models.FieldDescription: {Source: models.SourceMeta, Confidence: 0.8},
+				},
+			}
+			applyMetadata(bookmark, tt.metadata)
+			assert.Equal(t, tt.wantTitle, bookmark.Title)
+			assert.Equal(t, tt.wantSrc, bookmark.Provenance[models.FieldTitle].Source)
+			// Values found on the page follow the page
+			assert.Empty(t, bookmark.Description)
+			assert.NotContains(t, bookmark.Provenance, models.FieldDescription)
+		})
+	}
+}
+
+func TestUpdateBookmark(t *testing.T) {
+	tests := []struct {
+		name           string
+		bookmarkID     string
+		requestBody    string
+		setupMock      func(*MockRepository)
+		expectedStatus int
+		expectedError  string
+		expectedLocks  models.FieldLocks
+	}{
+		{
+			name:        "edit locks fields",
+			bookmarkID:  "1",
+			requestBody: `{"title": "Edited", "unlock": ["description"]}`,
+			setupMock: func(mockRepo *MockRepository) {
+				mockRepo.On("GetBookmark", mock.Anything, int64(1)).Return(&models.Bookmark{
+					ID:           1,
+					Title:        "Scraped",
+					LockedFields: models.FieldLocks{models.FieldDescription},
+					Provenance:   models.Provenance{models.FieldDescription: models.UserProvenance},
+				}, nil)
+				mockRepo.On("UpdateBookmark", mock.Anything, mock.MatchedBy(func(b *models.Bookmark) bool {
+					_, described := b.Provenance[models.FieldDescription]
+					return b.Title == "Edited" && !described
+				})).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedLocks:  models.FieldLocks{models.FieldTitle},
+		},
+		{
+			name:        "watch",
+			bookmarkID:  "1",
+			requestBody: `{"title": "Edited", "watched": true}`,
+			setupMock: func(mockRepo *MockRepository) {
+				mockRepo.On("GetBookmark", mock.Anything, int64(1)).Return(&models.Bookmark{ID: 1, Title: "Scraped"}, nil)
+				mockRepo.On("UpdateBookmark", mock.Anything, mock.Anything).Return(nil)
+				mockRepo.On("SetWatched", mock.Anything, int64(1), true).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedLocks:  models.FieldLocks{models.FieldTitle},
+		},
+		{
+			name:        "watch already watched",
+			bookmarkID:  "1",
+			requestBody: `{"title": "Edited", "watched": true}`,
+			setupMock: func(mockRepo *MockRepository) {
+				mockRepo.On("GetBookmark", mock.Anything, int64(1)).Return(&models.Bookmark{ID: 1, Title: "Scraped", Watched: true}, nil)
+				mockRepo.On("UpdateBookmark", mock.Anything, mock.Anything).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedLocks:  models.FieldLocks{models.FieldTitle},
+		},
+		{
+			name:        "tags",
+			bookmarkID:  "1",
+			requestBody: `{"title": "Edited", "tags": [" Go ", "web  development", "go"]}`,
+			setupMock: func(mockRepo *MockRepository) {
+				mockRepo.On("GetBookmark", mock.Anything, int64(1)).Return(&models.Bookmark{ID: 1, Title: "Scraped"}, nil)
+				mockRepo.On("UpdateBookmark", mock.Anything, mock.Anything).Return(nil)
+				mockRepo.On("SetTags", mock.Anything, int64(1), models.Tags{"go", "web development"}).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedLocks:  models.FieldLocks{models.FieldTitle},
+		},
+		{
+			name:           "empty tag",
+			bookmarkID:     "1",
+			requestBody:    `{"tags": [" "]}`,
+			setupMock:      func(mockRepo *MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "Invalid tag: must be 1 to 40 characters\n",
+		},
+		{
+			name:           "unknown field",
+			bookmarkID:     "1",
+			requestBody:    `{"unlock": ["url"]}`,
+			setupMock:      func(mockRepo *MockRepository) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "Invalid field to unlock: url\n",
+		},
+		{
+			name:        "not found",
+			bookmarkID:  "999",
+			requestBody: `{"title": "Edited"}`,
+			setupMock: func(mockRepo *MockRepository) {
+				mockRepo.On("GetBookmark", mock.Anything, int64(999)).Return(nil, storage.ErrNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedError:  "Bookmark not found\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			handler := NewBookmarkHandler(mockRepo, newTestPool(), nil, newTestScraper())
+			tt.setupMock(mockRepo)
+
+			req := httptest.NewRequest("PATCH", "/bookmarks/"+tt.bookmarkID, bytes.NewBufferString(tt.requestBody))
+			req = mux.SetURLVars(req, map[string]string{"id": tt.bookmarkID})
+			w := httptest.NewRecorder()
+
+			handler.UpdateBookmark(w, req)
+
+			resp := w.Result()
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			if tt.expectedError != "" {
+				bodyBytes, _ := io.ReadAll(resp.Body)
+				assert.Equal(t, tt.expectedError, string(bodyBytes))
+			} else {
+				var response models.BookmarkResponse
+				json.NewDecoder(resp.Body).Decode(&response)
+				assert.Equal(t, "Edited", response.Bookmark.Title)
+				assert.Equal(t, tt.expectedLocks, response.Bookmark.LockedFields)
+				assert.Equal(t, models.UserProvenance, response.Bookmark.Provenance[models.FieldTitle])
+			}
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+// newTestPool returns a job pool on an in-memory queue that runs no jobs
+func newTestPool() *jobs.Pool {
+	return jobs.NewPool(storage.NewMemoryJobQueue())
+}
+
+// newTestScraper returns a scraper extracting content like the server's
+func newTestScraper(opts ...scraper.Option) *scraper.Scraper {
+	return scraper.NewScraper(5*time.Second, append([]scraper.Option{scraper.WithContentExtraction()}, opts...)...)
+}
+
+// allowLoopback returns a scraper option permitting fetches from test servers
+func allowLoopback(t *testing.T) scraper.Option {
+	loopback, err := scraper.ParseNetworks("127.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return scraper.WithAllowedNetworks(loopback...)
+}
+
 func TestGetBookmark(t *testing.T) {
 	mockRepo := new(MockRepository)
-	handler := NewBookmarkHandler(mockRepo)
+	handler := NewBookmarkHandler(mockRepo, newTestPool(), nil, newTestScraper())
 
 	bookmark := &models.Bookmark{
 		ID:        1,
@@ -172,9 +730,75 @@ func TestGetBookmark(t *testing.T) {
 	}
 }
 
+func TestGetContent(t *testing.T) {
+	mockRepo := new(MockRepository)
+	handler := NewBookmarkHandler(mockRepo, newTestPool(), nil, newTestScraper())
+
+	content := &models.BookmarkContent{
+		BookmarkID:  1,
+		Text:        "Article text",
+		HTML:        "<p>Article text</p>",
+		WordCount:   2,
+		ReadingTime: 1,
+	}
+
+	tests := []struct {
+		name           string
+		bookmarkID     string
+		setupMock      func()
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:       "successful retrieval",
+			bookmarkID: "1",
+			setupMock: func() {
+				mockRepo.On("GetContent", mock.Anything, int64(1)).Return(content, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:       "not found",
+			bookmarkID: "999",
+			setupMock: func() {
+				mockRepo.On("GetContent", mock.Anything, int64(999)).Return(nil, storage.ErrNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedError:  "Content not found\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			req := httptest.NewRequest("GET", "/bookmarks/"+tt.bookmarkID+"/content", nil)
+			req = mux.SetURLVars(req, map[string]string{"id": tt.bookmarkID})
+			w := httptest.NewRecorder()
+
+			handler.GetContent(w, req)
+
+			resp := w.Result()
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			if tt.expectedError != "" {
+				bodyBytes, _ := io.ReadAll(resp.Body)
+				assert.Equal(t, tt.expectedError, string(bodyBytes))
+			} else {
+				var response models.ContentResponse
+				json.NewDecoder(resp.Body).Decode(&response)
+				assert.Equal(t, content.Text, response.Content.Text)
+				assert.Equal(t, content.HTML, response.Content.HTML)
+			}
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
 func TestListBookmarks(t *testing.T) {
 	mockRepo := new(MockRepository)
-	handler := NewBookmarkHandler(mockRepo)
+	handler := NewBookmarkHandler(mockRepo, newTestPool(), nil, newTestScraper())
 
 	bookmarks := []models.Bookmark{
 		{
@@ -182,22 +806,43 @@ func TestListBookmarks(t *testing.T) {
 			URL: "https://example1.com",
 		},
 		{
-			ID:  2,
-			URL: "https://example2.com",
+			ID:     2,
+			URL:    "https://example2.com",
+			Health: models.HealthBroken,
 		},
 	}
 
 	tests := []struct {
 		name           string
+		query          string
 		setupMock      func()
 		expectedStatus int
+		expectedCount  int
+		expectedError  string
 	}{
 		{
 			name: "successful listing",
 			setupMock: func() {
-				mockRepo.On("ListBookmarks", mock.Anything).Return(bookmarks, nil)
+				mockRepo.On("ListBookmarks", mock.Anything, models.BookmarkFilter{}).Return(bookmarks, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedCount:  2,
+		},
+		{
+			name:  "broken links",
+			query: "?health=broken",
+			setupMock: func() {
+				mockRepo.On("ListBookmarks", mock.Anything, models.BookmarkFilter{Health: models.HealthBroken}).Return(bookmarks[1:], nil)
 			},
 			expectedStatus: http.StatusOK,
+			expectedCount:  1,
+		},
+		{
+			name:           "invalid health",
+			query:          "?health=dead",
+			setupMock:      func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "Invalid health: must be unknown, ok or broken\n",
 		},
 	}
 
@@ -205,7 +850,7 @@ func TestListBookmarks(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.setupMock()
 
-			req := httptest.NewRequest("GET", "/bookmarks", nil)
+			req := httptest.NewRequest("GET", "/bookmarks"+tt.query, nil)
 			w := httptest.NewRecorder()
 
 			handler.ListBookmarks(w, req)
@@ -213,9 +858,87 @@ func TestListBookmarks(t *testing.T) {
 			resp := w.Result()
 			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
 
-			var response models.BookmarksResponse
-			json.NewDecoder(resp.Body).Decode(&response)
-			assert.Equal(t, len(bookmarks), len(response.Bookmarks))
+			if tt.expectedError != "" {
+				bodyBytes, _ := io.ReadAll(resp.Body)
+				assert.Equal(t, tt.expectedError, string(bodyBytes))
+			} else {
+				var response models.BookmarksResponse
+				json.NewDecoder(resp.Body).Decode(&response)
+				assert.Equal(t, tt.expectedCount, len(response.Bookmarks))
+			}
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetLinkChecks(t *testing.T) {
+	mockRepo := new(MockRepository)
+	handler := NewBookmarkHandler(mockRepo, newTestPool(), nil, newTestScraper())
+
+	checks := []models.LinkCheck{
+		{ID: 2, BookmarkID: 1, OK: false, Error: "connection refused"},
+		{ID: 1, BookmarkID: 1, OK: true, StatusCode: http.StatusOK},
+	}
+
+	tests := []struct {
+		name           string
+		bookmarkID     string
+		query          string
+		setupMock      func()
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:       "successful retrieval",
+			bookmarkID: "1",
+			query:      "?limit=10",
+			setupMock: func() {
+				mockRepo.On("GetBookmark", mock.Anything, int64(1)).Return(&models.Bookmark{ID: 1}, nil)
+				mockRepo.On("ListLinkChecks", mock.Anything, int64(1), 10).Return(checks, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid limit",
+			bookmarkID:     "1",
+			query:          "?limit=0",
+			setupMock:      func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "Invalid limit\n",
+		},
+		{
+			name:       "not found",
+			bookmarkID: "999",
+			setupMock: func() {
+				mockRepo.On("GetBookmark", mock.Anything, int64(999)).Return(nil, storage.ErrNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedError:  "Bookmark not found\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			req := httptest.NewRequest("GET", "/bookmarks/"+tt.bookmarkID+"/checks"+tt.query, nil)
+			req = mux.SetURLVars(req, map[string]string{"id": tt.bookmarkID})
+			w := httptest.NewRecorder()
+
+			handler.GetLinkChecks(w, req)
+
+			resp := w.Result()
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			if tt.expectedError != "" {
+				bodyBytes, _ := io.ReadAll(resp.Body)
+				assert.Equal(t, tt.expectedError, string(bodyBytes))
+			} else {
+				var response models.LinkChecksResponse
+				json.NewDecoder(resp.Body).Decode(&response)
+				assert.Equal(t, checks, response.Checks)
+			}
 
 			mockRepo.AssertExpectations(t)
 		})
@@ -224,7 +947,7 @@ func TestListBookmarks(t *testing.T) {
 
 func TestDeleteBookmark(t *testing.T) {
 	mockRepo := new(MockRepository)
-	handler := NewBookmarkHandler(mockRepo)
+	handler := NewBookmarkHandler(mockRepo, newTestPool(), nil, newTestScraper())
 
 	tests := []struct {
 		name           string
@@ -278,3 +1001,21 @@ func TestDeleteBookmark(t *testing.T) {
 		})
 	}
 }
+
+func TestScraperStatus(t *testing.T) {
+	mockRepo := new(MockRepository)
+	handler := NewBookmarkHandler(mockRepo, newTestPool(), nil, newTestScraper())
+
+	req := httptest.NewRequest("GET", "/scraper/status", nil)
+	w := httptest.NewRecorder()
+
+	handler.ScraperStatus(w, req)
+
+	resp := w.Result()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var stats scraper.Stats
+	json.NewDecoder(resp.Body).Decode(&stats)
+	assert.Equal(t, scraper.DefaultBreakerThreshold, stats.BreakerThreshold)
+	assert.NotNil(t, stats.Breakers)
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"bookmarks-go/internal/models"
+	"bookmarks-go/internal/storage"
+)
+
+// Number of page changes and events returned by default and at most
+const (
+	defaultChangesLimit = 50
+	maxChangesLimit     = 1000
+)
+
+// ChangeHandler serves the changes detected on watched pages
+type ChangeHandler struct {
+	repo storage.Repository
+}
+
+// NewChangeHandler creates a new change handler
+func NewChangeHandler(repo storage.Repository) *ChangeHandler {
+	return &ChangeHandler{repo: repo}
+}
+
+// GetPageChanges handles retrieving the change history of a watched bookmark, newest first
+func (h *ChangeHandler) GetPageChanges(w http.ResponseWriter, r *http.Request) {
+	limit, err := queryInt(r, "limit", defaultChangesLimit)
+	if err != nil || limit > maxChangesLimit {
+		http.Error(w, "Invalid limit", http.StatusBadRequest)
+		return
+	}
+	bookmark, ok := loadBookmark(w, r, h.repo)
+	if !ok {
+		return
+	}
+
+	changes, err := h.repo.ListPageChanges(r.Context(), bookmark.ID, limit)
+	if err != nil {
+		http.Error(w, "Failed to list page changes: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.PageChangesResponse{Changes: changes})
+}
+
+// ListEvents handles polling for events, oldest first. Clients pass the ID of
+// the last event they received as the after query parameter.
+func (h *ChangeHandler) ListEvents(w http.ResponseWriter, r *http.Request) {
+	var after int64
+	if value := r.URL.Query().Get("after"); value != "" {
+		var err error
+		after, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || after < 0 {
+			http.Error(w, "Invalid after", http.StatusBadRequest)
+			return
+		}
+	}
+	limit, err := queryInt(r, "limit", defaultChangesLimit)
+	if err != nil || limit > maxChangesLimit {
+		http.Error(w, "Invalid limit", http.StatusBadRequest)
+		return
+	}
+
+	changes, err := h.repo.ListPageChangesAfter(r.Context(), after, limit)
+	if err != nil {
+		http.Error(w, "Failed to list events: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	events := make([]models.Event, len(changes))
+	for i := range changes {
+		events[i] = models.Event{
+			ID:         changes[i].ID,
+			Type:       models.EventChanged,
+			BookmarkID: changes[i].BookmarkID,
+			CreatedAt:  changes[i].DetectedAt,
+			Change:     &changes[i],
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.EventsResponse{Events: events})
+}
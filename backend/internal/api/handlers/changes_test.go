@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"bookmarks-go/internal/models"
+	"bookmarks-go/internal/storage"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetPageChanges(t *testing.T) {
+	mockRepo := new(MockRepository)
+	handler := NewChangeHandler(mockRepo)
+
+	changes := []models.PageChange{
+		{ID: 2, BookmarkID: 1, Diff: "--- a\n+++ b\n@@ -1 +1 @@\n-two\n+three\n", Added: 1, Removed: 1},
+		{ID: 1, BookmarkID: 1, Diff: "--- a\n+++ b\n@@ -1 +1 @@\n-one\n+two\n", Added: 1, Removed: 1},
+	}
+
+	tests := []struct {
+		name           string
+		bookmarkID     string
+		query          string
+		setupMock      func()
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:       "successful retrieval",
+			bookmarkID: "1",
+			query:      "?limit=10",
+			setupMock: func() {
+				mockRepo.On("GetBookmark", mock.Anything, int64(1)).Return(&models.Bookmark{ID: 1, Watched: true}, nil)
+				mockRepo.On("ListPageChanges", mock.Anything, int64(1), 10).Return(changes, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid limit",
+			bookmarkID:     "1",
+			query:          "?limit=1001",
+			setupMock:      func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "Invalid limit\n",
+		},
+		{
+			name:       "not found",
+			bookmarkID: "999",
+			setupMock: func() {
+				mockRepo.On("GetBookmark", mock.Anything, int64(999)).Return(nil, storage.ErrNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedError:  "Bookmark not found\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			req := httptest.NewRequest("GET", "/bookmarks/"+tt.bookmarkID+"/changes"+tt.query, nil)
+			req = mux.SetURLVars(req, map[string]string{"id": tt.bookmarkID})
+			w := httptest.NewRecorder()
+
+			handler.GetPageChanges(w, req)
+
+			resp := w.Result()
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			if tt.expectedError != "" {
+				bodyBytes, _ := io.ReadAll(resp.Body)
+				assert.Equal(t, tt.expectedError, string(bodyBytes))
+			} else {
+				var response models.PageChangesResponse
+				json.NewDecoder(resp.Body).Decode(&response)
+				assert.Equal(t, changes, response.Changes)
+			}
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestListEvents(t *testing.T) {
+	mockRepo := new(MockRepository)
+	handler := NewChangeHandler(mockRepo)
+
+	detectedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	change := models.PageChange{ID: 8, BookmarkID: 3, DetectedAt: detectedAt, Diff: "--- a\n+++ b\n@@ -1 +1 @@\n-one\n+two\n", Added: 1, Removed: 1}
+	mockRepo.On("ListPageChangesAfter", mock.Anything, int64(7), defaultChangesLimit).Return([]models.PageChange{change}, nil)
+
+	req := httptest.NewRequest("GET", "/events?after=7", nil)
+	w := httptest.NewRecorder()
+	handler.ListEvents(w, req)
+
+	resp := w.Result()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var response models.EventsResponse
+	json.NewDecoder(resp.Body).Decode(&response)
+	assert.Equal(t, []models.Event{{
+		ID:         8,
+		Type:       models.EventChanged,
+		BookmarkID: 3,
+		CreatedAt:  detectedAt,
+		Change:     &change,
+	}}, response.Events)
+
+	req = httptest.NewRequest("GET", "/events?after=-1", nil)
+	w = httptest.NewRecorder()
+	handler.ListEvents(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+
+	mockRepo.AssertExpectations(t)
+}
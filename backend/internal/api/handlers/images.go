@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"bookmarks-go/internal/imageproxy"
+	"bookmarks-go/internal/models"
+	"bookmarks-go/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// versionedImageCacheControl lets browsers and CDNs keep proxied images
+	// requested with the version of their source for 30 days
+	versionedImageCacheControl = "public, max-age=2592000, immutable"
+	// imageCacheControl makes clients revalidate images requested by bookmark
+	// alone, as the bookmark's image may change
+	imageCacheControl = "no-cache"
+	// imageCSP stops scripts in proxied SVG images from running
+	imageCSP = "default-src 'none'; style-src 'unsafe-inline'; sandbox"
+)
+
+// ImageHandler serves cached, resized copies of bookmark favicons and preview images
+type ImageHandler struct {
+	repo  storage.Repository
+	proxy *imageproxy.Proxy
+}
+
+// NewImageHandler creates a new image handler
+func NewImageHandler(repo storage.Repository, proxy *imageproxy.Proxy) *ImageHandler {
+	return &ImageHandler{
+		repo:  repo,
+		proxy: proxy,
+	}
+}
+
+// Favicon handles serving a bookmark's favicon, resized to the size query parameter
+func (h *ImageHandler) Favicon(w http.ResponseWriter, r *http.Request) {
+	bookmark, ok := loadBookmark(w, r, h.repo)
+	if !ok {
+		return
+	}
+	if bookmark.FaviconURL == "" {
+		http.Error(w, "Favicon not found", http.StatusNotFound)
+		return
+	}
+
+	size, err := queryInt(r, "size", 32)
+	if err != nil {
+		http.Error(w, "Invalid size", http.StatusBadRequest)
+		return
+	}
+	img, err := h.proxy.Icon(r.Context(), bookmark.FaviconURL, size)
+	h.serve(w, r, bookmark.FaviconURL, img, err)
+}
+
+// PreviewImage handles serving a bookmark's preview image, scaled to the width query parameter
+func (h *ImageHandler) PreviewImage(w http.ResponseWriter, r *http.Request) {
+	bookmark, ok := loadBookmark(w, r, h.repo)
+	if !ok {
+		return
+	}
+	if bookmark.ImageURL == "" {
+		http.Error(w, "Preview image not found", http.StatusNotFound)
+		return
+	}
+
+	width, err := queryInt(r, "width", 640)
+	if err != nil {
+		http.Error(w, "Invalid width", http.StatusBadRequest)
+		return
+	}
+	img, err := h.proxy.Preview(r.Context(), bookmark.ImageURL, width)
+	h.serve(w, r, bookmark.ImageURL, img, err)
+}
+
+// loadBookmark loads the bookmark named by the id route variable, writing an error response on failure
+func loadBookmark(w http.ResponseWriter, r *http.Request, repo storage.Repository) (*models.Bookmark, bool) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid bookmark ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	bookmark, err := repo.GetBookmark(r.Context(), id)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			http.Error(w, "Bookmark not found", http.StatusNotFound)
+			return nil, false
+		}
+		http.Error(w, "Failed to get bookmark: "+err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	return bookmark, true
+}
+
+// serve writes a proxied image of src. Responses to requests whose v query
+// parameter is the version of src may be cached for long, as a new image
+// gets a new URL.
+func (h *ImageHandler) serve(w http.ResponseWriter, r *http.Request, src string, img *imageproxy.Image, err error) {
+	if err != nil {
+		if errors.Is(err, imageproxy.ErrUnsupported) {
+			http.Error(w, "Unsupported image: "+err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		http.Error(w, "Failed to fetch image: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer img.Body.Close()
+
+	if r.URL.Query().Get("v") == imageproxy.Version(src) {
+		w.Header().Set("Cache-Control", versionedImageCacheControl)
+	} else {
+		w.Header().Set("Cache-Control", imageCacheControl)
+	}
+	w.Header().Set("ETag", img.ETag)
+	w.Header().Set("Content-Security-Policy", imageCSP)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if r.Header.Get("If-None-Match") == img.ETag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", img.ContentType)
+	if img.Size > 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(img.Size, 10))
+	}
+	io.Copy(w, img.Body)
+}
+
+// queryInt parses a positive integer query parameter, returning def if it is absent
+func queryInt(r *http.Request, name string, def int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0, errors.New("invalid " + name)
+	}
+	return n, nil
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"bookmarks-go/internal/blobstore"
+	"bookmarks-go/internal/imageproxy"
+	"bookmarks-go/internal/models"
+	"bookmarks-go/internal/scraper"
+	"bookmarks-go/internal/storage"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFavicon(t *testing.T) {
+	icon := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	icon.Set(0, 0, color.White)
+	var iconData bytes.Buffer
+	require.NoError(t, png.Encode(&iconData, icon))
+
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/icon.png" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(iconData.Bytes())
+	}))
+	defer origin.Close()
+
+	loopback, err := scraper.ParseNetworks("127.0.0.0/8")
+	require.NoError(t, err)
+	store, err := blobstore.NewFileStore(t.TempDir())
+	require.NoError(t, err)
+	proxy := imageproxy.New(store, scraper.NewScraper(5*time.Second, scraper.WithAllowedNetworks(loopback...)))
+
+	mockRepo := new(MockRepository)
+	handler := NewImageHandler(mockRepo, proxy)
+	mockRepo.On("GetBookmark", mock.Anything, int64(1)).Return(&models.Bookmark{ID: 1, FaviconURL: origin.URL + "/icon.png"}, nil)
+	mockRepo.On("GetBookmark", mock.Anything, int64(2)).Return(&models.Bookmark{ID: 2}, nil)
+	mockRepo.On("GetBookmark", mock.Anything, int64(3)).Return(&models.Bookmark{ID: 3, FaviconURL: origin.URL + "/gone.png"}, nil)
+	mockRepo.On("GetBookmark", mock.Anything, int64(999)).Return(nil, storage.ErrNotFound)
+
+	tests := []struct {
+		name           string
+		bookmarkID     string
+		query          string
+		expectedStatus int
+		expectedError  string
+		expectedCache  string
+	}{
+		{name: "resized icon", bookmarkID: "1", query: "?size=16", expectedStatus: http.StatusOK, expectedCache: "no-cache"},
+		{name: "versioned icon", bookmarkID: "1", query: "?size=16&v=" + imageproxy.Version(origin.URL+"/icon.png"), expectedStatus: http.StatusOK, expectedCache: "public, max-age=2592000, immutable"},
+		{name: "icon of another version", bookmarkID: "1", query: "?size=16&v=" + imageproxy.Version(origin.URL+"/old.png"), expectedStatus: http.StatusOK, expectedCache: "no-cache"},
+		{name: "invalid size", bookmarkID: "1", query: "?size=-1", expectedStatus: http.StatusBadRequest, expectedError: "Invalid size\n"},
+		{name: "no favicon", bookmarkID: "2", expectedStatus: http.StatusNotFound, expectedError: "Favicon not found\n"},
+		{name: "unreachable favicon", bookmarkID: "3", expectedStatus: http.StatusBadGateway},
+		{name: "bookmark not found", bookmarkID: "999", expectedStatus: http.StatusNotFound, expectedError: "Bookmark not found\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/bookmarks/"+tt.bookmarkID+"/favicon"+tt.query, nil)
+			req = mux.SetURLVars(req, map[string]string{"id": tt.bookmarkID})
+			w := httptest.NewRecorder()
+
+			handler.Favicon(w, req)
+
+			resp := w.Result()
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			if tt.expectedError != "" {
+				bodyBytes, _ := io.ReadAll(resp.Body)
+				assert.Equal(t, tt.expectedError, string(bodyBytes))
+			}
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, "image/png", resp.Header.Get("Content-Type"))
+				assert.Equal(t, tt.expectedCache, resp.Header.Get("Cache-Control"))
+				decoded, err := png.Decode(resp.Body)
+				require.NoError(t, err)
+				assert.Equal(t, 16, decoded.Bounds().Dx())
+
+				// Revalidation with the ETag is answered without a body
+				req.Header.Set("If-None-Match", resp.Header.Get("ETag"))
+				w = httptest.NewRecorder()
+				handler.Favicon(w, req)
+				assert.Equal(t, http.StatusNotModified, w.Code)
+			}
+		})
+	}
+}
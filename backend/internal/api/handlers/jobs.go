@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"bookmarks-go/internal/jobs"
+	"bookmarks-go/internal/models"
+	"bookmarks-go/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// JobHandler handles background job HTTP requests
+type JobHandler struct {
+	jobs *jobs.Pool
+}
+
+// NewJobHandler creates a new job handler
+func NewJobHandler(pool *jobs.Pool) *JobHandler {
+	return &JobHandler{jobs: pool}
+}
+
+// GetJob handles retrieving the status of a background job
+func (h *JobHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid job ID", http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.jobs.Job(r.Context(), id)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			http.Error(w, "Job not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to get job: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.JobResponse{Job: job})
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"bookmarks-go/internal/jobs"
+	"bookmarks-go/internal/models"
+	"bookmarks-go/internal/storage"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetJob(t *testing.T) {
+	pool := jobs.NewPool(storage.NewMemoryJobQueue())
+	handler := NewJobHandler(pool)
+
+	job, err := pool.Enqueue(context.Background(), models.JobScrapeMetadata, 1)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		jobID          string
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "successful retrieval",
+			jobID:          "1",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "not found",
+			jobID:          "999",
+			expectedStatus: http.StatusNotFound,
+			expectedError:  "Job not found\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/jobs/"+tt.jobID, nil)
+			req = mux.SetURLVars(req, map[string]string{"id": tt.jobID})
+			w := httptest.NewRecorder()
+
+			handler.GetJob(w, req)
+
+			resp := w.Result()
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			if tt.expectedError != "" {
+				bodyBytes, _ := io.ReadAll(resp.Body)
+				assert.Equal(t, tt.expectedError, string(bodyBytes))
+			} else {
+				var response models.JobResponse
+				json.NewDecoder(resp.Body).Decode(&response)
+				assert.Equal(t, job.ID, response.Job.ID)
+				assert.Equal(t, models.JobQueued, response.Job.Status)
+				assert.Equal(t, int64(1), response.Job.BookmarkID)
+			}
+		})
+	}
+}
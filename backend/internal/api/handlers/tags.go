@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"bookmarks-go/internal/models"
+	"bookmarks-go/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// TagHandler serves the tags in use and the tags suggested for bookmarks
+type TagHandler struct {
+	repo storage.Repository
+}
+
+// NewTagHandler creates a new tag handler
+func NewTagHandler(repo storage.Repository) *TagHandler {
+	return &TagHandler{repo: repo}
+}
+
+// ListTags handles retrieving every tag in use with its number of bookmarks, most used first
+func (h *TagHandler) ListTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.repo.ListTags(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to list tags: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.TagsResponse{Tags: tags})
+}
+
+// ListTagSuggestions handles retrieving the tags suggested for a bookmark,
+// optionally only those with the status given by the status query parameter
+func (h *TagHandler) ListTagSuggestions(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", models.TagSuggestionPending, models.TagSuggestionAccepted, models.TagSuggestionRejected:
+	default:
+		http.Error(w, "Invalid status: must be pending, accepted or rejected", http.StatusBadRequest)
+		return
+	}
+	bookmark, ok := loadBookmark(w, r, h.repo)
+	if !ok {
+		return
+	}
+
+	suggestions, err := h.repo.ListTagSuggestions(r.Context(), bookmark.ID)
+	if err != nil {
+		http.Error(w, "Failed to list tag suggestions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if status != "" {
+		filtered := []models.TagSuggestion{}
+		for _, suggestion := range suggestions {
+			if suggestion.Status == status {
+				filtered = append(filtered, suggestion)
+			}
+		}
+		suggestions = filtered
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.TagSuggestionsResponse{Suggestions: suggestions})
+}
+
+// AcceptTagSuggestion handles accepting a pending tag suggestion, adding the tag to the bookmark
+func (h *TagHandler) AcceptTagSuggestion(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, true)
+}
+
+// RejectTagSuggestion handles rejecting a pending tag suggestion; the tag is
+// not suggested for the bookmark again
+func (h *TagHandler) RejectTagSuggestion(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, false)
+}
+
+// decide accepts or rejects the tag suggestion of the request
+func (h *TagHandler) decide(w http.ResponseWriter, r *http.Request, accept bool) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid bookmark ID", http.StatusBadRequest)
+		return
+	}
+	suggestionID, err := strconv.ParseInt(vars["suggestionId"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid suggestion ID", http.StatusBadRequest)
+		return
+	}
+
+	suggestion, err := h.repo.DecideTagSuggestion(r.Context(), id, suggestionID, accept)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			http.Error(w, "Pending suggestion not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to decide tag suggestion: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	bookmark, err := h.repo.GetBookmark(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Failed to get bookmark: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.TagSuggestionResponse{Suggestion: suggestion, Bookmark: bookmark})
+}
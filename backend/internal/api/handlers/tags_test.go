@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"bookmarks-go/internal/models"
+	"bookmarks-go/internal/storage"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestListTagSuggestions(t *testing.T) {
+	mockRepo := new(MockRepository)
+	handler := NewTagHandler(mockRepo)
+
+	suggestions := []models.TagSuggestion{
+		{ID: 1, BookmarkID: 1, Tag: "go", Status: models.TagSuggestionAccepted},
+		{ID: 2, BookmarkID: 1, Tag: "databases", Status: models.TagSuggestionPending},
+	}
+	mockRepo.On("GetBookmark", mock.Anything, int64(1)).Return(&models.Bookmark{ID: 1}, nil)
+	mockRepo.On("ListTagSuggestions", mock.Anything, int64(1)).Return(suggestions, nil)
+
+	req := httptest.NewRequest("GET", "/bookmarks/1/tag-suggestions?status=pending", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "1"})
+	w := httptest.NewRecorder()
+	handler.ListTagSuggestions(w, req)
+
+	resp := w.Result()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var response models.TagSuggestionsResponse
+	json.NewDecoder(resp.Body).Decode(&response)
+	assert.Equal(t, suggestions[1:], response.Suggestions)
+
+	req = httptest.NewRequest("GET", "/bookmarks/1/tag-suggestions?status=maybe", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "1"})
+	w = httptest.NewRecorder()
+	handler.ListTagSuggestions(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestDecideTagSuggestion(t *testing.T) {
+	mockRepo := new(MockRepository)
+	handler := NewTagHandler(mockRepo)
+
+	accepted := &models.TagSuggestion{ID: 2, BookmarkID: 1, Tag: "databases", Status: models.TagSuggestionAccepted}
+	bookmark := &models.Bookmark{ID: 1, Tags: models.Tags{"go", "databases"}}
+
+	tests := []struct {
+		name           string
+		suggestionID   string
+		accept         bool
+		setupMock      func()
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:         "accept",
+			suggestionID: "2",
+			accept:       true,
+			setupMock: func() {
+				mockRepo.On("DecideTagSuggestion", mock.Anything, int64(1), int64(2), true).Return(accepted, nil)
+				mockRepo.On("GetBookmark", mock.Anything, int64(1)).Return(bookmark, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:         "already decided",
+			suggestionID: "3",
+			setupMock: func() {
+				mockRepo.On("DecideTagSuggestion", mock.Anything, int64(1), int64(3), false).Return(nil, storage.ErrNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedError:  "Pending suggestion not found\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock()
+
+			req := httptest.NewRequest("POST", "/bookmarks/1/tag-suggestions/"+tt.suggestionID, nil)
+			req = mux.SetURLVars(req, map[string]string{"id": "1", "suggestionId": tt.suggestionID})
+			w := httptest.NewRecorder()
+
+			if tt.accept {
+				handler.AcceptTagSuggestion(w, req)
+			} else {
+				handler.RejectTagSuggestion(w, req)
+			}
+
+			resp := w.Result()
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			if tt.expectedError != "" {
+				bodyBytes, _ := io.ReadAll(resp.Body)
+				assert.Equal(t, tt.expectedError, string(bodyBytes))
+			} else {
+				var response models.TagSuggestionResponse
+				json.NewDecoder(resp.Body).Decode(&response)
+				assert.Equal(t, accepted, response.Suggestion)
+				assert.Equal(t, models.Tags{"go", "databases"}, response.Bookmark.Tags)
+			}
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
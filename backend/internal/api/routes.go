@@ -4,6 +4,10 @@ import (
 	"net/http"
 
 	"bookmarks-go/internal/api/handlers"
+	"bookmarks-go/internal/archiver"
+	"bookmarks-go/internal/imageproxy"
+	"bookmarks-go/internal/jobs"
+	"bookmarks-go/internal/scraper"
 	"bookmarks-go/internal/storage"
 
 	"github.com/gorilla/mux"
@@ -13,7 +17,7 @@ import (
 func CORSMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PATCH, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 
 		if r.Method == "OPTIONS" {
@@ -26,11 +30,16 @@ func CORSMiddleware(next http.Handler) http.Handler {
 }
 
 // SetupRoutes configures all API routes and middleware
-func SetupRoutes(repo storage.Repository) *mux.Router {
+func SetupRoutes(repo storage.Repository, pool *jobs.Pool, images *imageproxy.Proxy, archives *archiver.Archiver, s *scraper.Scraper) *mux.Router {
 	r := mux.NewRouter()
 
 	// Create handlers
-	bookmarkHandler := handlers.NewBookmarkHandler(repo)
+	bookmarkHandler := handlers.NewBookmarkHandler(repo, pool, archives, s)
+	imageHandler := handlers.NewImageHandler(repo, images)
+	archiveHandler := handlers.NewArchiveHandler(repo, archives, pool)
+	jobHandler := handlers.NewJobHandler(pool)
+	changeHandler := handlers.NewChangeHandler(repo)
+	tagHandler := handlers.NewTagHandler(repo)
 
 	// API routes
 	api := r.PathPrefix("/api").Subrouter()
@@ -41,11 +50,37 @@ func SetupRoutes(repo storage.Repository) *mux.Router {
 	bookmarks.HandleFunc("", bookmarkHandler.CreateBookmark).Methods("POST")
 	bookmarks.HandleFunc("", bookmarkHandler.ListBookmarks).Methods("GET")
 	bookmarks.HandleFunc("/{id:[0-9]+}", bookmarkHandler.GetBookmark).Methods("GET")
+	bookmarks.HandleFunc("/{id:[0-9]+}", bookmarkHandler.UpdateBookmark).Methods("PATCH")
 	bookmarks.HandleFunc("/{id:[0-9]+}", bookmarkHandler.DeleteBookmark).Methods("DELETE")
+	bookmarks.HandleFunc("/{id:[0-9]+}/content", bookmarkHandler.GetContent).Methods("GET")
+	bookmarks.HandleFunc("/{id:[0-9]+}/checks", bookmarkHandler.GetLinkChecks).Methods("GET")
+	bookmarks.HandleFunc("/{id:[0-9]+}/changes", changeHandler.GetPageChanges).Methods("GET")
+	bookmarks.HandleFunc("/{id:[0-9]+}/tag-suggestions", tagHandler.ListTagSuggestions).Methods("GET")
+	bookmarks.HandleFunc("/{id:[0-9]+}/tag-suggestions/{suggestionId:[0-9]+}/accept", tagHandler.AcceptTagSuggestion).Methods("POST")
+	bookmarks.HandleFunc("/{id:[0-9]+}/tag-suggestions/{suggestionId:[0-9]+}/reject", tagHandler.RejectTagSuggestion).Methods("POST")
+	bookmarks.HandleFunc("/{id:[0-9]+}/favicon", imageHandler.Favicon).Methods("GET")
+	bookmarks.HandleFunc("/{id:[0-9]+}/image", imageHandler.PreviewImage).Methods("GET")
+	bookmarks.HandleFunc("/{id:[0-9]+}/archive", archiveHandler.DownloadArchive).Methods("GET")
+	bookmarks.HandleFunc("/{id:[0-9]+}/archive", archiveHandler.ArchivePage).Methods("POST")
+	bookmarks.HandleFunc("/{id:[0-9]+}/archive/replay", archiveHandler.ReplayArchive).Methods("GET")
+	bookmarks.HandleFunc("/{id:[0-9]+}/snapshot", archiveHandler.GetSnapshot).Methods("GET")
 
 	// Add OPTIONS method for CORS preflight requests
 	bookmarks.HandleFunc("", func(w http.ResponseWriter, r *http.Request) {}).Methods("OPTIONS")
 	bookmarks.HandleFunc("/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {}).Methods("OPTIONS")
+	bookmarks.HandleFunc("/{id:[0-9]+}/content", func(w http.ResponseWriter, r *http.Request) {}).Methods("OPTIONS")
+
+	// Tags in use
+	api.HandleFunc("/tags", tagHandler.ListTags).Methods("GET")
+
+	// Background jobs
+	api.HandleFunc("/jobs/{id:[0-9]+}", jobHandler.GetJob).Methods("GET")
+
+	// Changes of watched pages
+	api.HandleFunc("/events", changeHandler.ListEvents).Methods("GET")
+
+	// Scraper monitoring
+	api.HandleFunc("/scraper/status", bookmarkHandler.ScraperStatus).Methods("GET")
 
 	return r
 }
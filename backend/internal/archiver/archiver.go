@@ -0,0 +1,300 @@
+// Package archiver saves bookmarked pages together with their same-origin
+// subresources to WARC files and single-file HTML snapshots in a blob store,
+// and replays them
+package archiver
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"bookmarks-go/internal/blobstore"
+	"bookmarks-go/internal/scraper"
+	"bookmarks-go/internal/warc"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	// DefaultMaxResources is the number of subresources archived with a page unless configured otherwise
+	DefaultMaxResources = 100
+	// DefaultMaxSize is the total size of the responses archived for a page unless configured otherwise
+	DefaultMaxSize = 50 << 20
+	// maxResourceSize is the largest single response archived
+	maxResourceSize = 10 << 20
+	// ContentType is the content type archives are stored with. Records are
+	// gzip-compressed one by one.
+	ContentType = "application/warc"
+)
+
+var (
+	// ErrNotArchived is returned when a bookmark has no archive
+	ErrNotArchived = errors.New("page not archived")
+	// ErrResourceNotFound is returned when an archive holds no response for a URL
+	ErrResourceNotFound = errors.New("resource not in archive")
+)
+
+// Fetcher downloads pages and subresources
+type Fetcher interface {
+	FetchResource(ctx context.Context, rawURL string, maxSize int64) (*scraper.Resource, error)
+}
+
+// Archiver writes and reads page archives
+type Archiver struct {
+	store        blobstore.Store
+	fetcher      Fetcher
+	maxResources int
+	maxSize      int64
+	replays      *replayCache
+}
+
+// Option is a functional option for configuring the archiver
+type Option func(*Archiver)
+
+// WithMaxResources sets the number of subresources archived with a page
+func WithMaxResources(n int) Option {
+	return func(a *Archiver) {
+		a.maxResources = n
+	}
+}
+
+// WithMaxSize sets the total size of the responses archived for a page.
+// Subresources that do not fit are left out.
+func WithMaxSize(n int64) Option {
+	return func(a *Archiver) {
+		a.maxSize = n
+	}
+}
+
+// New creates an archiver storing archives in store and downloading pages with fetcher
+func New(store blobstore.Store, fetcher Fetcher, opts ...Option) *Archiver {
+	a := &Archiver{
+		store:        store,
+		fetcher:      fetcher,
+		maxResources: DefaultMaxResources,
+		maxSize:      DefaultMaxSize,
+		replays:      newReplayCache(DefaultReplayCacheSize),
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Key returns the blob key of the archive of a bookmark
+func Key(bookmarkID int64) string {
+	return "archives/" + strconv.FormatInt(bookmarkID, 10) + ".warc.gz"
+}
+
+// Filename returns the name archives of a bookmark are downloaded as
+func Filename(bookmarkID int64) string {
+	return "bookmark-" + strconv.FormatInt(bookmarkID, 10) + ".warc.gz"
+}
+
+// Result summarizes a written archive
+type Result struct {
+	// Resources is the number of subresources archived with the page
+	Resources int
+	// Size is the total size of the archived response bodies
+	Size int64
+}
+
+// Archive fetches pageURL and the stylesheets, images, scripts and fonts it
+// references from the same origin, and stores them as the archive of a
+// bookmark, replacing any earlier one. Subresources that cannot be fetched
+// are left out. HTML pages are also stored as a single-file snapshot.
+func (a *Archiver) Archive(ctx context.Context, bookmarkID int64, pageURL string) (*Result, error) {
+	now := time.Now().UTC()
+	page, err := a.fetcher.FetchResource(ctx, pageURL, maxResourceSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch page: %w", err)
+	}
+	origin, err := url.Parse(page.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid page URL: %w", err)
+	}
+
+	var buf bytes.Buffer
+	w := warc.NewWriter(&buf)
+	info := warc.NewWarcinfo(now, Filename(bookmarkID), map[string]string{
+		"software": "bookmarks-go",
+		"format":   "WARC File Format 1.1",
+	})
+	if err := w.Write(info); err != nil {
+		return nil, err
+	}
+	if err := w.Write(response(page, now)); err != nil {
+		return nil, err
+	}
+
+	result := &Result{Size: int64(len(page.Data))}
+	seen := map[string]bool{pageURL: true, page.URL: true}
+	// resources maps both referenced and final URLs to what was archived
+	resources := make(map[string]*scraper.Resource)
+	queue := references(page)
+	for len(queue) > 0 && result.Resources < a.maxResources {
+		ref := queue[0]
+		queue = queue[1:]
+		if seen[ref] || !sameOrigin(origin, ref) {
+			continue
+		}
+		seen[ref] = true
+
+		resource, err := a.fetcher.FetchResource(ctx, ref, maxResourceSize)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			log.Printf("Failed to archive %s: %v", ref, err)
+			continue
+		}
+		if result.Size+int64(len(resource.Data)) > a.maxSize {
+			log.Printf("Skipped archiving %s: archive size limit reached", ref)
+			continue
+		}
+		if err := w.Write(response(resource, time.Now().UTC())); err != nil {
+			return nil, err
+		}
+		result.Resources++
+		result.Size += int64(len(resource.Data))
+		seen[resource.URL] = true
+		resources[ref] = resource
+		resources[resource.URL] = resource
+
+		// Stylesheets reference fonts, images and other stylesheets
+		queue = append(queue, references(resource)...)
+	}
+
+	a.replays.invalidate(bookmarkID)
+	if err := a.store.Put(ctx, Key(bookmarkID), &buf, ContentType); err != nil {
+		return nil, fmt.Errorf("failed to store archive: %w", err)
+	}
+
+	if isHTML(page.MediaType) {
+		data, err := snapshot(page, resources, a.snapshotFetcher(ctx, origin, result))
+		if err != nil {
+			return nil, err
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		contentType := page.Header.Get("Content-Type")
+		if contentType == "" {
+			contentType = page.MediaType
+		}
+		if err := a.store.Put(ctx, SnapshotKey(bookmarkID), bytes.NewReader(data), contentType); err != nil {
+			return nil, fmt.Errorf("failed to store snapshot: %w", err)
+		}
+	} else if err := a.store.Delete(ctx, SnapshotKey(bookmarkID)); err != nil {
+		// A snapshot of an earlier HTML version would be stale
+		return nil, err
+	}
+	return result, nil
+}
+
+// snapshotFetcher returns a function downloading the resources of other origins
+// that a snapshot inlines, within what the archive left of the resource and
+// size limits. Same-origin resources were already tried for the archive.
+func (a *Archiver) snapshotFetcher(ctx context.Context, origin *url.URL, archived *Result) func(ref string) *scraper.Resource {
+	resources := a.maxResources - archived.Resources
+	size := a.maxSize - archived.Size
+	return func(ref string) *scraper.Resource {
+		if sameOrigin(origin, ref) || resources <= 0 || size <= 0 || ctx.Err() != nil {
+			return nil
+		}
+		if u, err := url.Parse(ref); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil
+		}
+		resources--
+
+		resource, err := a.fetcher.FetchResource(ctx, ref, min(maxResourceSize, size))
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Failed to fetch %s for snapshot: %v", ref, err)
+			}
+			return nil
+		}
+		size -= int64(len(resource.Data))
+		return resource
+	}
+}
+
+// Open opens the archive of a bookmark; the caller must close it
+func (a *Archiver) Open(ctx context.Context, bookmarkID int64) (io.ReadCloser, *blobstore.Info, error) {
+	body, info, err := a.store.Get(ctx, Key(bookmarkID))
+	if errors.Is(err, blobstore.ErrNotFound) {
+		return nil, nil, ErrNotArchived
+	}
+	return body, info, err
+}
+
+// OpenSnapshot opens the single-file snapshot of a bookmark; the caller must close it
+func (a *Archiver) OpenSnapshot(ctx context.Context, bookmarkID int64) (io.ReadCloser, *blobstore.Info, error) {
+	body, info, err := a.store.Get(ctx, SnapshotKey(bookmarkID))
+	if errors.Is(err, blobstore.ErrNotFound) {
+		return nil, nil, ErrNotArchived
+	}
+	return body, info, err
+}
+
+// Delete removes the archive and snapshot of a bookmark, if there are any
+func (a *Archiver) Delete(ctx context.Context, bookmarkID int64) error {
+	a.replays.invalidate(bookmarkID)
+	if err := a.store.Delete(ctx, SnapshotKey(bookmarkID)); err != nil {
+		return err
+	}
+	return a.store.Delete(ctx, Key(bookmarkID))
+}
+
+// response creates the WARC record of a downloaded resource
+func response(resource *scraper.Resource, date time.Time) *warc.Record {
+	return warc.NewResponse(resource.URL, date, 200, resource.Header, resource.Data)
+}
+
+// references returns the absolute URLs of the subresources an HTML page or
+// stylesheet loads
+func references(resource *scraper.Resource) []string {
+	base, err := url.Parse(resource.URL)
+	if err != nil {
+		return nil
+	}
+
+	var refs []string
+	collect := func(ref string) string {
+		refs = append(refs, ref)
+		return ref
+	}
+	switch {
+	case isHTML(resource.MediaType):
+		// <noscript> content is archived too, since snapshots show it
+		doc, err := html.ParseWithOptions(bytes.NewReader(resource.Data), html.ParseOptionEnableScripting(false))
+		if err != nil {
+			return nil
+		}
+		rewriteHTML(doc, base, collect)
+	case resource.MediaType == "text/css":
+		rewriteCSS(string(resource.Data), base, collect)
+	}
+	return refs
+}
+
+// isHTML reports whether mediaType is an HTML document
+func isHTML(mediaType string) bool {
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
+
+// sameOrigin reports whether ref has the scheme and host of origin
+func sameOrigin(origin *url.URL, ref string) bool {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Scheme, origin.Scheme) && strings.EqualFold(u.Host, origin.Host)
+}
@@ -0,0 +1,318 @@
+package archiver
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"bookmarks-go/internal/blobstore"
+	"bookmarks-go/internal/scraper"
+	"bookmarks-go/internal/warc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeFetcher serves resources from a map and records requested URLs
+type fakeFetcher struct {
+	resources map[string]*scraper.Resource
+	fetched   []string
+}
+
+func (f *fakeFetcher) FetchResource(ctx context.Context, rawURL string, maxSize int64) (*scraper.Resource, error) {
+	f.fetched = append(f.fetched, rawURL)
+	resource, ok := f.resources[rawURL]
+	if !ok {
+		return nil, errors.New("unexpected status code: 404")
+	}
+	return resource, nil
+}
+
+func newResource(rawURL, mediaType, data string) *scraper.Resource {
+	header := http.Header{}
+	header.Set("Content-Type", mediaType)
+	return &scraper.Resource{URL: rawURL, MediaType: mediaType, Data: []byte(data), Header: header}
+}
+
+const testPage = `<html><head>
+<base href="/docs/">
+<link rel="stylesheet" href="style.css">
+<link rel="canonical" href="https://example.com/docs/page">
+<meta http-equiv="refresh" content="0; url=https://example.com/elsewhere">
+<script src="https://cdn.example.net/lib.js"></script>
+</head><body>
+<img src="logo.png#top" srcset="logo.png 1x, logo@2x.png 2x">
+<a href="other">Other</a>
+<div style="background: url('bg.png')"></div>
+</body></html>`
+
+func newTestFetcher() *fakeFetcher {
+	return &fakeFetcher{resources: map[string]*scraper.Resource{
+		"https://example.com/page": newResource("https://example.com/page", "text/html", testPage),
+		"https://example.com/docs/style.css": newResource("https://example.com/docs/style.css", "text/css",
+			`@import "print.css"; body { background: url(fonts/a.woff2) }`),
+		"https://example.com/docs/print.css":     newResource("https://example.com/docs/print.css", "text/css", `p { color: red }`),
+		"https://example.com/docs/fonts/a.woff2": newResource("https://example.com/docs/fonts/a.woff2", "font/woff2", "font"),
+		"https://example.com/docs/logo.png":      newResource("https://example.com/docs/logo.png", "image/png", "png"),
+		"https://example.com/docs/bg.png":        newResource("https://example.com/docs/bg.png", "image/png", "bg"),
+	}}
+}
+
+func newTestArchiver(t *testing.T, fetcher Fetcher, opts ...Option) *Archiver {
+	store, err := blobstore.NewFileStore(t.TempDir())
+	require.NoError(t, err)
+	return New(store, fetcher, opts...)
+}
+
+func TestArchive(t *testing.T) {
+	fetcher := newTestFetcher()
+	a := newTestArchiver(t, fetcher)
+
+	result, err := a.Archive(context.Background(), 7, "https://example.com/page")
+	require.NoError(t, err)
+	assert.Equal(t, 5, result.Resources)
+	assert.NotContains(t, fetcher.fetched, "https://cdn.example.net/lib.js")
+	assert.NotContains(t, fetcher.fetched, "https://example.com/docs/other")
+
+	body, info, err := a.Open(context.Background(), 7)
+	require.NoError(t, err)
+	defer body.Close()
+	assert.Equal(t, ContentType, info.ContentType)
+
+	r, err := warc.NewReader(body)
+	require.NoError(t, err)
+	var types, targets []string
+	for {
+		record, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		types = append(types, record.Type())
+		if record.Type() == warc.TypeResponse {
+			targets = append(targets, record.TargetURI())
+		}
+	}
+	assert.Equal(t, warc.TypeWarcinfo, types[0])
+	assert.Equal(t, "https://example.com/page", targets[0])
+	assert.ElementsMatch(t, []string{
+		"https://example.com/page",
+		"https://example.com/docs/style.css",
+		"https://example.com/docs/logo.png",
+		"https://example.com/docs/bg.png",
+		"https://example.com/docs/print.css",
+		"https://example.com/docs/fonts/a.woff2",
+	}, targets)
+
+	require.NoError(t, a.Delete(context.Background(), 7))
+	_, _, err = a.Open(context.Background(), 7)
+	assert.Equal(t, ErrNotArchived, err)
+}
+
+func TestArchiveLimits(t *testing.T) {
+	a := newTestArchiver(t, newTestFetcher(), WithMaxResources(2))
+	result, err := a.Archive(context.Background(), 1, "https://example.com/page")
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Resources)
+
+	a = newTestArchiver(t, newTestFetcher(), WithMaxSize(int64(len(testPage)+3)))
+	result, err = a.Archive(context.Background(), 1, "https://example.com/page")
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Resources)
+	assert.Equal(t, int64(len(testPage)+3), result.Size)
+
+	_, err = a.Archive(context.Background(), 1, "https://example.com/missing")
+	assert.Error(t, err)
+}
+
+func TestReplay(t *testing.T) {
+	a := newTestArchiver(t, newTestFetcher())
+	_, err := a.Archive(context.Background(), 7, "https://example.com/page")
+	require.NoError(t, err)
+
+	link := func(ref string) string {
+		return "replay?url=" + url.QueryEscape(ref)
+	}
+
+	page, err := a.Replay(context.Background(), 7, "", link)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/page", page.URL)
+	assert.Equal(t, "text/html", page.ContentType)
+	assert.False(t, page.Date.IsZero())
+
+	rendered := string(page.Body)
+	assert.Contains(t, rendered, `href="replay?url=https%3A%2F%2Fexample.com%2Fdocs%2Fstyle.css"`)
+	assert.Contains(t, rendered, `src="replay?url=https%3A%2F%2Fexample.com%2Fdocs%2Flogo.png"`)
+	assert.Contains(t, rendered, `srcset="replay?url=https%3A%2F%2Fexample.com%2Fdocs%2Flogo.png 1x, https://example.com/docs/logo@2x.png 2x"`)
+	assert.Contains(t, rendered, `url(&#34;replay?url=https%3A%2F%2Fexample.com%2Fdocs%2Fbg.png&#34;)`)
+	assert.Contains(t, rendered, `<a href="https://example.com/docs/other">`)
+	assert.Contains(t, rendered, `src="https://cdn.example.net/lib.js"`)
+	assert.Contains(t, rendered, `href="https://example.com/docs/page"`)
+	assert.NotContains(t, rendered, "<base")
+	assert.NotContains(t, rendered, "refresh")
+
+	css, err := a.Replay(context.Background(), 7, "https://example.com/docs/style.css", link)
+	require.NoError(t, err)
+	assert.Equal(t, `@import "replay?url=https%3A%2F%2Fexample.com%2Fdocs%2Fprint.css"; body { background: url("replay?url=https%3A%2F%2Fexample.com%2Fdocs%2Ffonts%2Fa.woff2") }`, string(css.Body))
+
+	font, err := a.Replay(context.Background(), 7, "https://example.com/docs/fonts/a.woff2", link)
+	require.NoError(t, err)
+	assert.Equal(t, "font/woff2", font.ContentType)
+	assert.Equal(t, "font", string(font.Body))
+
+	_, err = a.Replay(context.Background(), 7, "https://example.com/docs/missing.png", link)
+	assert.Equal(t, ErrResourceNotFound, err)
+
+	_, err = a.Replay(context.Background(), 8, "", link)
+	assert.Equal(t, ErrNotArchived, err)
+}
+
+// countingStore counts the archive bytes read from a store
+type countingStore struct {
+	blobstore.Store
+	read int64
+}
+
+func (s *countingStore) Get(ctx context.Context, key string) (io.ReadCloser, *blobstore.Info, error) {
+	body, info, err := s.Store.Get(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &countingReader{ReadCloser: body, read: &s.read}, info, nil
+}
+
+type countingReader struct {
+	io.ReadCloser
+	read *int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	*r.read += int64(n)
+	return n, err
+}
+
+func TestReplayCachesArchives(t *testing.T) {
+	files, err := blobstore.NewFileStore(t.TempDir())
+	require.NoError(t, err)
+	store := &countingStore{Store: files}
+	fetcher := newTestFetcher()
+	a := New(store, fetcher)
+	_, err = a.Archive(context.Background(), 7, "https://example.com/page")
+	require.NoError(t, err)
+
+	link := func(ref string) string { return ref }
+	_, err = a.Replay(context.Background(), 7, "", link)
+	require.NoError(t, err)
+	read := store.read
+	assert.Positive(t, read)
+
+	font, err := a.Replay(context.Background(), 7, "https://example.com/docs/fonts/a.woff2", link)
+	require.NoError(t, err)
+	assert.Equal(t, "font", string(font.Body))
+	assert.Equal(t, read, store.read)
+
+	// Archiving again replaces the cached archive
+	fetcher.resources["https://example.com/docs/fonts/a.woff2"] = newResource("https://example.com/docs/fonts/a.woff2", "font/woff2", "font v2")
+	_, err = a.Archive(context.Background(), 7, "https://example.com/page")
+	require.NoError(t, err)
+	font, err = a.Replay(context.Background(), 7, "https://example.com/docs/fonts/a.woff2", link)
+	require.NoError(t, err)
+	assert.Equal(t, "font v2", string(font.Body))
+
+	require.NoError(t, a.Delete(context.Background(), 7))
+	_, err = a.Replay(context.Background(), 7, "", link)
+	assert.Equal(t, ErrNotArchived, err)
+}
+
+func TestReplayCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newReplayCache(10)
+	info := &blobstore.Info{Size: 1}
+	c.put(&replayArchive{bookmarkID: 1, info: *info, size: 4})
+	c.put(&replayArchive{bookmarkID: 2, info: *info, size: 4})
+
+	// Touch 1 so that 2 is the least recently used
+	assert.NotNil(t, c.get(1, info))
+	c.put(&replayArchive{bookmarkID: 3, info: *info, size: 4})
+	assert.Nil(t, c.get(2, info))
+	assert.NotNil(t, c.get(1, info))
+	assert.NotNil(t, c.get(3, info))
+
+	// Archives larger than the cache and changed archives are not served
+	c.put(&replayArchive{bookmarkID: 4, info: *info, size: 11})
+	assert.Nil(t, c.get(4, info))
+	assert.Nil(t, c.get(1, &blobstore.Info{Size: 2}))
+	assert.Equal(t, int64(4), c.size)
+}
+
+func TestSnapshot(t *testing.T) {
+	fetcher := newTestFetcher()
+	fetcher.resources["https://example.com/page"] = newResource("https://example.com/page", "text/html", `<html><head>
+<meta charset="utf-8">
+<link rel="stylesheet" href="/style.css">
+<script>alert(1)</script>
+<script src="https://cdn.example.net/lib.js"></script>
+</head><body onload="alert(2)">
+<noscript><img src="/logo.png"></noscript>
+<img src="/missing.png">
+<img src="https://cdn.example.net/photo.jpg">
+<a href="javascript:alert(3)">Run</a>
+</body></html>`)
+	fetcher.resources["https://example.com/style.css"] = newResource("https://example.com/style.css", "text/css",
+		`@font-face { src: url(font.woff2) } @font-face { src: url(https://fonts.example.org/b.woff2) }`)
+	fetcher.resources["https://example.com/font.woff2"] = newResource("https://example.com/font.woff2", "font/woff2", "font")
+	fetcher.resources["https://fonts.example.org/b.woff2"] = newResource("https://fonts.example.org/b.woff2", "font/woff2", "cdn font")
+	fetcher.resources["https://cdn.example.net/photo.jpg"] = newResource("https://cdn.example.net/photo.jpg", "image/jpeg", "jpeg")
+	fetcher.resources["https://example.com/logo.png"] = newResource("https://example.com/logo.png", "image/png", "png")
+	a := newTestArchiver(t, fetcher)
+
+	_, err := a.Archive(context.Background(), 7, "https://example.com/page")
+	require.NoError(t, err)
+
+	body, info, err := a.OpenSnapshot(context.Background(), 7)
+	require.NoError(t, err)
+	data, err := io.ReadAll(body)
+	body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "text/html", info.ContentType)
+
+	snapshot := string(data)
+	fontURI := "data:font/woff2;base64," + base64.StdEncoding.EncodeToString([]byte("font"))
+	cdnFontURI := "data:font/woff2;base64," + base64.StdEncoding.EncodeToString([]byte("cdn font"))
+	cssURI := "data:text/css;base64," + base64.StdEncoding.EncodeToString([]byte(
+		`@font-face { src: url("`+fontURI+`") } @font-face { src: url("`+cdnFontURI+`") }`))
+	assert.Contains(t, snapshot, `<html><head><meta http-equiv="Content-Security-Policy" content="`+html.EscapeString(SnapshotCSP)+`"/>`)
+	assert.Contains(t, snapshot, `<link rel="stylesheet" href="`+cssURI+`"/>`)
+	assert.Contains(t, snapshot, `<img src="data:image/png;base64,`+base64.StdEncoding.EncodeToString([]byte("png"))+`"/>`)
+	assert.Contains(t, snapshot, `<img src="https://example.com/missing.png"/>`)
+
+	// Resources of other origins are downloaded for the snapshot only, except scripts
+	assert.Contains(t, snapshot, `<img src="data:image/jpeg;base64,`+base64.StdEncoding.EncodeToString([]byte("jpeg"))+`"/>`)
+	assert.NotContains(t, fetcher.fetched, "https://cdn.example.net/lib.js")
+	assert.Equal(t, 1, countOf(fetcher.fetched, "https://example.com/missing.png"))
+	assert.NotContains(t, snapshot, "alert")
+	assert.NotContains(t, snapshot, "script")
+
+	// Archives of pages that are not HTML have no snapshot
+	fetcher.resources["https://example.com/page"] = newResource("https://example.com/page", "application/pdf", "%PDF-1.4")
+	_, err = a.Archive(context.Background(), 7, "https://example.com/page")
+	require.NoError(t, err)
+	_, _, err = a.OpenSnapshot(context.Background(), 7)
+	assert.Equal(t, ErrNotArchived, err)
+}
+
+// countOf returns how often value occurs in values
+func countOf(values []string, value string) int {
+	n := 0
+	for _, v := range values {
+		if v == value {
+			n++
+		}
+	}
+	return n
+}
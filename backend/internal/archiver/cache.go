@@ -0,0 +1,138 @@
+package archiver
+
+import (
+	"container/list"
+	"fmt"
+	"io"
+	"sync"
+
+	"bookmarks-go/internal/blobstore"
+	"bookmarks-go/internal/warc"
+)
+
+// DefaultReplayCacheSize is the total size of the archives kept for replay unless configured otherwise
+const DefaultReplayCacheSize = 64 << 20
+
+// WithReplayCacheSize sets the total size of the recently replayed archives
+// kept in memory, so that replaying the subresources of a page does not read
+// the whole archive from the store for each. A size of zero disables the cache.
+func WithReplayCacheSize(n int64) Option {
+	return func(a *Archiver) {
+		a.replays = newReplayCache(n)
+	}
+}
+
+// replayArchive is the parsed archive of a bookmark
+type replayArchive struct {
+	bookmarkID int64
+	// info identifies the stored version the archive was read from
+	info blobstore.Info
+	// page is the URI of the first response
+	page      string
+	responses map[string]*warc.Record
+	size      int64
+}
+
+// readReplayArchive reads the response records of an archive
+func readReplayArchive(bookmarkID int64, body io.Reader, info *blobstore.Info) (*replayArchive, error) {
+	r, err := warc.NewReader(body)
+	if err != nil {
+		return nil, err
+	}
+	archive := &replayArchive{bookmarkID: bookmarkID, responses: make(map[string]*warc.Record)}
+	if info != nil {
+		archive.info = *info
+	}
+	for {
+		record, err := r.Next()
+		if err == io.EOF {
+			return archive, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		if record.Type() != warc.TypeResponse {
+			continue
+		}
+		uri := record.TargetURI()
+		if _, ok := archive.responses[uri]; ok {
+			continue
+		}
+		if archive.page == "" {
+			archive.page = uri
+		}
+		archive.responses[uri] = record
+		archive.size += int64(len(record.Content))
+	}
+}
+
+// replayCache is an LRU cache of parsed archives keyed by bookmark ID and
+// bounded by the total size of their records
+type replayCache struct {
+	mu      sync.Mutex
+	maxSize int64
+	size    int64
+	order   *list.List
+	entries map[int64]*list.Element
+}
+
+// newReplayCache creates a cache holding archives up to a total of maxSize bytes
+func newReplayCache(maxSize int64) *replayCache {
+	return &replayCache{
+		maxSize: maxSize,
+		order:   list.New(),
+		entries: make(map[int64]*list.Element),
+	}
+}
+
+// get returns the cached archive of a bookmark if it was read from the
+// stored version described by info
+func (c *replayCache) get(bookmarkID int64, info *blobstore.Info) *replayArchive {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[bookmarkID]
+	if !ok {
+		return nil
+	}
+	archive := el.Value.(*replayArchive)
+	if info == nil || archive.info.Size != info.Size || !archive.info.ModTime.Equal(info.ModTime) {
+		c.remove(el)
+		return nil
+	}
+	c.order.MoveToFront(el)
+	return archive
+}
+
+// put caches an archive, evicting the least recently used ones to make room.
+// Archives larger than the cache are not kept.
+func (c *replayCache) put(archive *replayArchive) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[archive.bookmarkID]; ok {
+		c.remove(el)
+	}
+	if archive.size > c.maxSize {
+		return
+	}
+	for c.size+archive.size > c.maxSize {
+		c.remove(c.order.Back())
+	}
+	c.entries[archive.bookmarkID] = c.order.PushFront(archive)
+	c.size += archive.size
+}
+
+// invalidate drops the cached archive of a bookmark
+func (c *replayCache) invalidate(bookmarkID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[bookmarkID]; ok {
+		c.remove(el)
+	}
+}
+
+// remove drops an entry; the caller must hold the lock
+func (c *replayCache) remove(el *list.Element) {
+	archive := c.order.Remove(el).(*replayArchive)
+	delete(c.entries, archive.bookmarkID)
+	c.size -= archive.size
+}
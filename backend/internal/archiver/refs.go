@@ -0,0 +1,190 @@
+package archiver
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// rewriteFunc maps the absolute URL of a subresource to the reference written
+// in its place
+type rewriteFunc func(ref string) string
+
+// resourceAttrs lists the attributes of each element that load a subresource
+var resourceAttrs = map[atom.Atom][]string{
+	atom.Img:    {"src", "srcset"},
+	atom.Source: {"src", "srcset"},
+	atom.Script: {"src"},
+	atom.Video:  {"src", "poster"},
+	atom.Audio:  {"src"},
+	atom.Track:  {"src"},
+	atom.Embed:  {"src"},
+	atom.Input:  {"src"},
+	atom.Object: {"data"},
+}
+
+// linkRels are the <link> relations whose target is part of the page
+var linkRels = []string{"stylesheet", "icon", "apple-touch-icon", "mask-icon", "preload"}
+
+// navigationAttrs lists the attributes that link to other pages. They are
+// made absolute so that links in an archived copy lead to the live site.
+var navigationAttrs = map[atom.Atom]string{
+	atom.A:    "href",
+	atom.Area: "href",
+	atom.Form: "action",
+}
+
+// rewriteHTML replaces every subresource reference in doc, resolved against
+// base, with the result of fn. A <base> element changes the base.
+func rewriteHTML(doc *html.Node, base *url.URL, fn rewriteFunc) {
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			base = rewriteElement(n, base, fn)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+}
+
+// rewriteElement rewrites the references of a single element and returns the
+// base URL for the rest of the document
+func rewriteElement(n *html.Node, base *url.URL, fn rewriteFunc) *url.URL {
+	switch n.DataAtom {
+	case atom.Base:
+		if href, ok := attr(n, "href"); ok {
+			if u, err := base.Parse(strings.TrimSpace(href)); err == nil {
+				base = u
+			}
+		}
+		return base
+	case atom.Link:
+		if rel, _ := attr(n, "rel"); hasAnyRel(rel, linkRels) {
+			rewriteAttr(n, "href", func(value string) string { return resolveRef(base, value, fn) })
+		}
+	case atom.Style:
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.TextNode {
+				c.Data = rewriteCSS(c.Data, base, fn)
+			}
+		}
+	}
+
+	for _, name := range resourceAttrs[n.DataAtom] {
+		if name == "srcset" {
+			rewriteAttr(n, name, func(value string) string { return rewriteSrcset(value, base, fn) })
+			continue
+		}
+		rewriteAttr(n, name, func(value string) string { return resolveRef(base, value, fn) })
+	}
+	if name, ok := navigationAttrs[n.DataAtom]; ok {
+		rewriteAttr(n, name, func(value string) string {
+			if u, err := base.Parse(strings.TrimSpace(value)); err == nil {
+				return u.String()
+			}
+			return value
+		})
+	}
+	rewriteAttr(n, "style", func(value string) string { return rewriteCSS(value, base, fn) })
+	return base
+}
+
+// resolveRef resolves a single reference and passes it to fn. Data URIs,
+// fragments and references that are not http or https are left alone.
+func resolveRef(base *url.URL, ref string, fn rewriteFunc) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || strings.HasPrefix(ref, "#") || strings.HasPrefix(ref, "data:") {
+		return ref
+	}
+	u, err := base.Parse(ref)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ref
+	}
+	u.Fragment = ""
+	return fn(u.String())
+}
+
+// rewriteSrcset rewrites each candidate URL of a srcset attribute
+func rewriteSrcset(srcset string, base *url.URL, fn rewriteFunc) string {
+	candidates := strings.Split(srcset, ",")
+	for i, candidate := range candidates {
+		fields := strings.Fields(candidate)
+		if len(fields) == 0 {
+			continue
+		}
+		fields[0] = resolveRef(base, fields[0], fn)
+		candidates[i] = strings.Join(fields, " ")
+	}
+	return strings.Join(candidates, ", ")
+}
+
+var (
+	// cssURL matches url() references in stylesheets
+	cssURL = regexp.MustCompile(`(?i)url\(\s*(?:"([^"]*)"|'([^']*)'|([^)'"\s]*))\s*\)`)
+	// cssImport matches @import rules given as a plain string
+	cssImport = regexp.MustCompile(`(?i)@import\s+(?:"([^"]*)"|'([^']*)')`)
+)
+
+// rewriteCSS rewrites the url() and @import references of a stylesheet or
+// style attribute, resolved against base
+func rewriteCSS(css string, base *url.URL, fn rewriteFunc) string {
+	css = cssURL.ReplaceAllStringFunc(css, func(match string) string {
+		ref := firstGroup(cssURL.FindStringSubmatch(match))
+		return `url("` + cssEscape(resolveRef(base, ref, fn)) + `")`
+	})
+	return cssImport.ReplaceAllStringFunc(css, func(match string) string {
+		ref := firstGroup(cssImport.FindStringSubmatch(match))
+		return `@import "` + cssEscape(resolveRef(base, ref, fn)) + `"`
+	})
+}
+
+// firstGroup returns the first non-empty capture group of a match
+func firstGroup(groups []string) string {
+	for _, group := range groups[1:] {
+		if group != "" {
+			return group
+		}
+	}
+	return ""
+}
+
+// cssEscape escapes a string for use between double quotes in CSS
+func cssEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\a `).Replace(s)
+}
+
+// attr returns the value of the named attribute of n
+func attr(n *html.Node, name string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && a.Key == name {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+// rewriteAttr replaces the value of the named attribute of n, if present
+func rewriteAttr(n *html.Node, name string, fn func(string) string) {
+	for i, a := range n.Attr {
+		if a.Namespace == "" && a.Key == name {
+			n.Attr[i].Val = fn(a.Val)
+		}
+	}
+}
+
+// hasAnyRel reports whether the space-separated rel attribute contains one of rels
+func hasAnyRel(rel string, rels []string) bool {
+	for _, value := range strings.Fields(strings.ToLower(rel)) {
+		for _, want := range rels {
+			if value == want {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package archiver
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Replayed is an archived response prepared for serving
+type Replayed struct {
+	// URL is the URL the response was archived from
+	URL         string
+	ContentType string
+	Body        []byte
+	// Date is when the response was archived
+	Date time.Time
+}
+
+// Replay returns the archived page of a bookmark, or the archived response
+// for target if it is not empty. References in HTML and stylesheets to
+// responses in the archive are replaced with link(ref); other references are
+// made absolute, so that they point to the live site.
+func (a *Archiver) Replay(ctx context.Context, bookmarkID int64, target string, link func(ref string) string) (*Replayed, error) {
+	archive, err := a.replayArchive(ctx, bookmarkID)
+	if err != nil {
+		return nil, err
+	}
+	if target == "" {
+		target = archive.page
+	}
+	found, ok := archive.responses[target]
+	if !ok {
+		return nil, ErrResourceNotFound
+	}
+
+	resp, err := found.HTTPResponse()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archived response: %w", err)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archived response: %w", err)
+	}
+
+	replayed := &Replayed{
+		URL:         found.TargetURI(),
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        data,
+		Date:        found.Date(),
+	}
+	base, err := url.Parse(replayed.URL)
+	if err != nil {
+		return replayed, nil
+	}
+	rewrite := func(ref string) string {
+		if _, ok := archive.responses[ref]; ok {
+			return link(ref)
+		}
+		return ref
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(replayed.ContentType)
+	switch mediaType = strings.ToLower(mediaType); {
+	case isHTML(mediaType):
+		doc, err := html.Parse(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse archived page: %w", err)
+		}
+		rewriteHTML(doc, base, rewrite)
+		removeElements(doc, isReplayBreaking)
+
+		var buf bytes.Buffer
+		if err := html.Render(&buf, doc); err != nil {
+			return nil, fmt.Errorf("failed to render archived page: %w", err)
+		}
+		replayed.Body = buf.Bytes()
+	case mediaType == "text/css":
+		replayed.Body = []byte(rewriteCSS(string(data), base, rewrite))
+	}
+	return replayed, nil
+}
+
+// replayArchive returns the parsed archive of a bookmark, from the cache if
+// the stored archive has not changed since it was read
+func (a *Archiver) replayArchive(ctx context.Context, bookmarkID int64) (*replayArchive, error) {
+	body, info, err := a.Open(ctx, bookmarkID)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	if archive := a.replays.get(bookmarkID, info); archive != nil {
+		return archive, nil
+	}
+	archive, err := readReplayArchive(bookmarkID, body, info)
+	if err != nil {
+		return nil, err
+	}
+	a.replays.put(archive)
+	return archive, nil
+}
+
+// isReplayBreaking reports whether n would make a replayed page leave the
+// archive: <base> changes where rewritten references resolve, and refresh
+// redirects navigate to the live site
+func isReplayBreaking(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.Base:
+		return true
+	case atom.Meta:
+		equiv, _ := attr(n, "http-equiv")
+		return strings.EqualFold(strings.TrimSpace(equiv), "refresh")
+	}
+	return false
+}
+
+// removeElements removes the elements of doc for which remove returns true
+func removeElements(doc *html.Node, remove func(n *html.Node) bool) {
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; {
+			next := c.NextSibling
+			if c.Type == html.ElementNode && remove(c) {
+				n.RemoveChild(c)
+			} else {
+				walk(c)
+			}
+			c = next
+		}
+	}
+	walk(doc)
+}
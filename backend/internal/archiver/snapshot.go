@@ -0,0 +1,172 @@
+package archiver
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"bookmarks-go/internal/scraper"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// SnapshotCSP is the policy snapshots are served with and carry in a <meta>
+// element, so that they stay inert when opened from disk. Only inlined
+// resources load.
+const SnapshotCSP = "default-src 'none'; img-src data:; style-src 'unsafe-inline' data:; " +
+	"font-src data:; media-src data:; form-action 'none'; base-uri 'none'"
+
+// maxImportDepth bounds how deeply stylesheet @import chains are inlined
+const maxImportDepth = 5
+
+// SnapshotKey returns the blob key of the single-file snapshot of a bookmark
+func SnapshotKey(bookmarkID int64) string {
+	return "snapshots/" + strconv.FormatInt(bookmarkID, 10) + ".html"
+}
+
+// SnapshotFilename returns the name snapshots of a bookmark are saved as
+func SnapshotFilename(bookmarkID int64) string {
+	return "bookmark-" + strconv.FormatInt(bookmarkID, 10) + ".html"
+}
+
+// snapshot builds a self-contained copy of an HTML page, with the resources it
+// references inlined as data URIs and scripts removed. resources maps URLs to
+// archived responses; fetch, if not nil, downloads the others.
+func snapshot(page *scraper.Resource, resources map[string]*scraper.Resource, fetch func(ref string) *scraper.Resource) ([]byte, error) {
+	base, err := url.Parse(page.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid page URL: %w", err)
+	}
+	// With scripting disabled, <noscript> content is parsed as markup
+	doc, err := html.ParseWithOptions(bytes.NewReader(page.Data), html.ParseOptionEnableScripting(false))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse page: %w", err)
+	}
+
+	// Scripts are removed first so that their sources are not downloaded
+	removeElements(doc, func(n *html.Node) bool { return n.DataAtom == atom.Script })
+	inliner := &inliner{resources: resources, fetch: fetch}
+	rewriteHTML(doc, base, func(ref string) string { return inliner.dataURI(ref, 0) })
+	removeElements(doc, isReplayBreaking)
+	unwrapElements(doc, atom.Noscript)
+	stripScripting(doc)
+	addPolicy(doc)
+
+	var buf bytes.Buffer
+	if err := html.Render(&buf, doc); err != nil {
+		return nil, fmt.Errorf("failed to render snapshot: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// inliner turns archived and downloaded resources into data URIs
+type inliner struct {
+	resources map[string]*scraper.Resource
+	fetch     func(ref string) *scraper.Resource
+}
+
+// dataURI returns the resource at ref as a data URI, or ref if it is neither
+// archived nor could be downloaded. Stylesheets have their own references
+// inlined first.
+func (in *inliner) dataURI(ref string, depth int) string {
+	resource, ok := in.resources[ref]
+	if !ok && in.fetch != nil {
+		// Each resource is downloaded once, whether or not that works
+		resource = in.fetch(ref)
+		in.resources[ref] = resource
+	}
+	if resource == nil {
+		return ref
+	}
+
+	data := resource.Data
+	if resource.MediaType == "text/css" {
+		if depth >= maxImportDepth {
+			return ref
+		}
+		base, err := url.Parse(resource.URL)
+		if err != nil {
+			return ref
+		}
+		data = []byte(rewriteCSS(string(data), base, func(ref string) string {
+			return in.dataURI(ref, depth+1)
+		}))
+	}
+	return "data:" + resource.MediaType + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
+
+// unwrapElements replaces the elements of doc with the given atom by their children
+func unwrapElements(doc *html.Node, a atom.Atom) {
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; {
+			next := c.NextSibling
+			walk(c)
+			if c.Type == html.ElementNode && c.DataAtom == a {
+				for c.FirstChild != nil {
+					child := c.FirstChild
+					c.RemoveChild(child)
+					n.InsertBefore(child, c)
+				}
+				n.RemoveChild(c)
+			}
+			c = next
+		}
+	}
+	walk(doc)
+}
+
+// stripScripting removes event handler attributes and javascript: URLs
+func stripScripting(n *html.Node) {
+	if n.Type == html.ElementNode {
+		attrs := n.Attr[:0]
+		for _, a := range n.Attr {
+			if strings.HasPrefix(strings.ToLower(a.Key), "on") {
+				continue
+			}
+			if strings.HasPrefix(strings.ToLower(strings.TrimSpace(a.Val)), "javascript:") {
+				continue
+			}
+			attrs = append(attrs, a)
+		}
+		n.Attr = attrs
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		stripScripting(c)
+	}
+}
+
+// addPolicy inserts SnapshotCSP as the first element of the document head
+func addPolicy(doc *html.Node) {
+	head := findElement(doc, atom.Head)
+	if head == nil {
+		return
+	}
+	meta := &html.Node{
+		Type:     html.ElementNode,
+		Data:     "meta",
+		DataAtom: atom.Meta,
+		Attr: []html.Attribute{
+			{Key: "http-equiv", Val: "Content-Security-Policy"},
+			{Key: "content", Val: SnapshotCSP},
+		},
+	}
+	head.InsertBefore(meta, head.FirstChild)
+}
+
+// findElement returns the first element of n with the given atom
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package blobstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// metaSuffix names the sidecar file holding a blob's content type
+const metaSuffix = ".meta"
+
+// FileStore stores blobs as files below a root directory
+type FileStore struct {
+	root string
+}
+
+// fileMeta is the sidecar content of a stored blob
+type fileMeta struct {
+	ContentType string `json:"content_type"`
+}
+
+// NewFileStore creates a file store rooted at dir, creating it if needed
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create blob directory: %w", err)
+	}
+	return &FileStore{root: dir}, nil
+}
+
+// Put implements Store. Blobs are written to a temporary file and renamed, so
+// readers never see partial contents.
+func (s *FileStore) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create blob directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create blob: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write blob: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write blob: %w", err)
+	}
+
+	meta, err := json.Marshal(fileMeta{ContentType: contentType})
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path+metaSuffix, meta, 0o644); err != nil {
+		return fmt.Errorf("failed to write blob metadata: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to store blob: %w", err)
+	}
+	return nil
+}
+
+// Get implements Store
+func (s *FileStore) Get(ctx context.Context, key string) (io.ReadCloser, *Info, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to open blob: %w", err)
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("failed to stat blob: %w", err)
+	}
+
+	info := &Info{
+		ContentType: "application/octet-stream",
+		Size:        stat.Size(),
+		ModTime:     stat.ModTime(),
+	}
+	if data, err := os.ReadFile(path + metaSuffix); err == nil {
+		var meta fileMeta
+		if json.Unmarshal(data, &meta) == nil && meta.ContentType != "" {
+			info.ContentType = meta.ContentType
+		}
+	}
+	return f, info, nil
+}
+
+// Delete implements Store
+func (s *FileStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	for _, p := range []string{path, path + metaSuffix} {
+		if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to delete blob: %w", err)
+		}
+	}
+	return nil
+}
+
+// path maps a key to its file below the root
+func (s *FileStore) path(key string) (string, error) {
+	if err := validateKey(key); err != nil {
+		return "", err
+	}
+	if strings.HasSuffix(key, metaSuffix) {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
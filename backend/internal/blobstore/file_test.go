@@ -0,0 +1,52 @@
+package blobstore
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStore(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, _, err = store.Get(ctx, "icons/abc/32.png")
+	assert.Equal(t, ErrNotFound, err)
+
+	require.NoError(t, store.Put(ctx, "icons/abc/32.png", strings.NewReader("png data"), "image/png"))
+	r, info, err := store.Get(ctx, "icons/abc/32.png")
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	r.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "png data", string(data))
+	assert.Equal(t, "image/png", info.ContentType)
+	assert.Equal(t, int64(8), info.Size)
+
+	// Put replaces existing blobs
+	require.NoError(t, store.Put(ctx, "icons/abc/32.png", strings.NewReader("new"), "image/png"))
+	r, info, err = store.Get(ctx, "icons/abc/32.png")
+	require.NoError(t, err)
+	r.Close()
+	assert.Equal(t, int64(3), info.Size)
+
+	require.NoError(t, store.Delete(ctx, "icons/abc/32.png"))
+	_, _, err = store.Get(ctx, "icons/abc/32.png")
+	assert.Equal(t, ErrNotFound, err)
+	assert.NoError(t, store.Delete(ctx, "icons/abc/32.png"))
+}
+
+func TestFileStoreRejectsInvalidKeys(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	require.NoError(t, err)
+
+	for _, key := range []string{"", "/etc/passwd", "../escape", "a/../../b", "a//b", `a\b`, "blob.meta"} {
+		err := store.Put(context.Background(), key, strings.NewReader("x"), "")
+		assert.Equal(t, ErrInvalidKey, err, key)
+	}
+}
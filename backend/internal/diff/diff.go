@@ -0,0 +1,205 @@
+// Package diff compares texts line by line and formats the differences as
+// unified diffs
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// Context is the number of unchanged lines shown around each change
+	Context = 3
+	// maxEdits bounds the work spent on finding a minimal diff; texts that
+	// differ in more lines are shown as entirely replaced
+	maxEdits = 2000
+)
+
+// Result is a unified diff with its line counts
+type Result struct {
+	// Text is the unified diff, empty if the texts are equal
+	Text    string
+	Added   int
+	Removed int
+}
+
+// Lines splits text into lines for comparison. Surrounding whitespace and
+// blank lines are dropped, so that reflowed markup does not count as a change.
+func Lines(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// Unified compares a with b and returns their unified diff, with fromName
+// and toName in the file header lines
+func Unified(a, b []string, fromName, toName string) Result {
+	script, ok := shortestEdit(a, b, maxEdits)
+	if !ok {
+		script = replaceAll(a, b)
+	}
+
+	var result Result
+	for _, e := range script {
+		switch e.op {
+		case '+':
+			result.Added++
+		case '-':
+			result.Removed++
+		}
+	}
+	if result.Added == 0 && result.Removed == 0 {
+		return result
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+	for i := 0; i < len(script); {
+		if script[i].op == ' ' {
+			i++
+			continue
+		}
+		// A hunk runs until more than twice the context separates two changes
+		start := max(0, i-Context)
+		last := i
+		for j := i; j < len(script) && j-last <= 2*Context; j++ {
+			if script[j].op != ' ' {
+				last = j
+			}
+		}
+		end := min(len(script), last+Context+1)
+		writeHunk(&sb, script[start:end])
+		i = end
+	}
+	result.Text = sb.String()
+	return result
+}
+
+// edit is a line of an edit script: ' ' kept, '-' removed from a or '+' added from b.
+// aLine and bLine count the lines of a and b before it.
+type edit struct {
+	op    byte
+	text  string
+	aLine int
+	bLine int
+}
+
+// writeHunk writes a hunk header and its lines
+func writeHunk(sb *strings.Builder, hunk []edit) {
+	var aLen, bLen int
+	for _, e := range hunk {
+		if e.op != '+' {
+			aLen++
+		}
+		if e.op != '-' {
+			bLen++
+		}
+	}
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(hunk[0].aLine, aLen), hunkRange(hunk[0].bLine, bLen))
+	for _, e := range hunk {
+		sb.WriteByte(e.op)
+		sb.WriteString(e.text)
+		sb.WriteByte('\n')
+	}
+}
+
+// hunkRange formats the start and length of a hunk side. Empty ranges name
+// the line before them, and a length of one is left out.
+func hunkRange(before, length int) string {
+	switch length {
+	case 0:
+		return fmt.Sprintf("%d,0", before)
+	case 1:
+		return fmt.Sprintf("%d", before+1)
+	}
+	return fmt.Sprintf("%d,%d", before+1, length)
+}
+
+// shortestEdit finds a minimal edit script turning a into b with Myers'
+// algorithm. It gives up once more than limit lines differ.
+func shortestEdit(a, b []string, limit int) ([]edit, bool) {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	// trace[d] holds v[-d-1..d+1] as it was before step d
+	var trace [][]int
+
+	for d := 0; d <= n+m; d++ {
+		if d > limit {
+			return nil, false
+		}
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// backtrack walks the trace of shortestEdit back from the end of both texts
+func backtrack(a, b []string, trace [][]int) []edit {
+	var script []edit
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d >= 0; d-- {
+		prev := func(k int) int { return trace[d][k+d+1] }
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && prev(k-1) < prev(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := prev(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			script = append(script, edit{op: ' ', text: a[x], aLine: x, bLine: y})
+		}
+		if d > 0 {
+			if x == prevX {
+				y--
+				script = append(script, edit{op: '+', text: b[y], aLine: x, bLine: y})
+			} else {
+				x--
+				script = append(script, edit{op: '-', text: a[x], aLine: x, bLine: y})
+			}
+		}
+	}
+
+	for i, j := 0, len(script)-1; i < j; i, j = i+1, j-1 {
+		script[i], script[j] = script[j], script[i]
+	}
+	return script
+}
+
+// replaceAll returns the edit script removing all of a and adding all of b
+func replaceAll(a, b []string) []edit {
+	script := make([]edit, 0, len(a)+len(b))
+	for i, line := range a {
+		script = append(script, edit{op: '-', text: line, aLine: i})
+	}
+	for i, line := range b {
+		script = append(script, edit{op: '+', text: line, aLine: len(a), bLine: i})
+	}
+	return script
+}
@@ -0,0 +1,85 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     string
+		expected string
+		added    int
+		removed  int
+	}{
+		{name: "equal", a: "one\ntwo", b: "one\n\n  two  "},
+		{
+			name: "changed line",
+			a:    "a\nb\nc\nd\ne\nf\ng\nh",
+			b:    "a\nb\nc\nd\nE\nf\ng\nh",
+			expected: "--- old\n+++ new\n" +
+				"@@ -2,7 +2,7 @@\n b\n c\n d\n-e\n+E\n f\n g\n h\n",
+			added:   1,
+			removed: 1,
+		},
+		{
+			name:     "added to empty",
+			a:        "",
+			b:        "x\ny",
+			expected: "--- old\n+++ new\n@@ -0,0 +1,2 @@\n+x\n+y\n",
+			added:    2,
+		},
+		{
+			name:     "removed at end",
+			a:        "a\nb\nc",
+			b:        "a\nb",
+			expected: "--- old\n+++ new\n@@ -1,3 +1,2 @@\n a\n b\n-c\n",
+			removed:  1,
+		},
+		{
+			name: "separate hunks",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12",
+			b:    "0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11",
+			expected: "--- old\n+++ new\n" +
+				"@@ -1,3 +1,4 @@\n+0\n 1\n 2\n 3\n" +
+				"@@ -9,4 +10,3 @@\n 9\n 10\n 11\n-12\n",
+			added:   1,
+			removed: 1,
+		},
+		{
+			name: "myers example",
+			a:    "A\nB\nC\nA\nB\nB\nA",
+			b:    "C\nB\nA\nB\nA\nC",
+			expected: "--- old\n+++ new\n" +
+				"@@ -1,7 +1,6 @@\n-A\n-B\n C\n+B\n A\n B\n-B\n A\n+C\n",
+			added:   2,
+			removed: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Unified(Lines(tt.a), Lines(tt.b), "old", "new")
+			assert.Equal(t, tt.expected, result.Text)
+			assert.Equal(t, tt.added, result.Added)
+			assert.Equal(t, tt.removed, result.Removed)
+		})
+	}
+}
+
+func TestUnifiedLargeRewrite(t *testing.T) {
+	var a, b []string
+	for i := 0; i < maxEdits; i++ {
+		a = append(a, fmt.Sprintf("old %d", i))
+		b = append(b, fmt.Sprintf("new %d", i))
+	}
+
+	result := Unified(a, b, "old", "new")
+	assert.Equal(t, maxEdits, result.Added)
+	assert.Equal(t, maxEdits, result.Removed)
+	assert.True(t, strings.HasPrefix(result.Text, fmt.Sprintf("--- old\n+++ new\n@@ -1,%d +1,%d @@\n-old 0\n", maxEdits, maxEdits)))
+}
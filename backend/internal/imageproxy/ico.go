@@ -0,0 +1,168 @@
+package imageproxy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// errInvalidICO is returned for malformed or unsupported ICO files
+var errInvalidICO = errors.New("invalid ICO file")
+
+// icoMagic starts every ICO file: reserved zero and type 1
+var icoMagic = []byte{0, 0, 1, 0}
+
+// pngMagic starts PNG images, which modern ICO files may embed
+var pngMagic = []byte("\x89PNG\r\n\x1a\n")
+
+// isICO reports whether data looks like an ICO file
+func isICO(data []byte) bool {
+	return len(data) >= 6 && bytes.Equal(data[:4], icoMagic)
+}
+
+// decodeICO decodes the largest image of an ICO file. Entries may be PNG or
+// uncompressed BMP data with 1, 4, 8, 24 or 32 bits per pixel.
+func decodeICO(data []byte) (image.Image, error) {
+	if !isICO(data) {
+		return nil, errInvalidICO
+	}
+	count := int(binary.LittleEndian.Uint16(data[4:6]))
+	if count == 0 || len(data) < 6+16*count {
+		return nil, errInvalidICO
+	}
+
+	// Pick the largest entry, preferring more colors
+	var best []byte
+	bestWidth, bestBPP := -1, -1
+	for i := 0; i < count; i++ {
+		entry := data[6+16*i : 6+16*(i+1)]
+		width := int(entry[0])
+		if width == 0 {
+			width = 256
+		}
+		bpp := int(binary.LittleEndian.Uint16(entry[6:8]))
+		size := int(binary.LittleEndian.Uint32(entry[8:12]))
+		offset := int(binary.LittleEndian.Uint32(entry[12:16]))
+		if offset < 0 || size <= 0 || offset+size > len(data) || offset+size < offset {
+			continue
+		}
+		if width > bestWidth || (width == bestWidth && bpp > bestBPP) {
+			best, bestWidth, bestBPP = data[offset:offset+size], width, bpp
+		}
+	}
+	if best == nil {
+		return nil, errInvalidICO
+	}
+	if bytes.HasPrefix(best, pngMagic) {
+		return decodeEmbeddedPNG(best)
+	}
+	return decodeDIB(best)
+}
+
+// decodeEmbeddedPNG decodes a PNG entry after checking its dimensions, which
+// may be far larger than the entry's directory claims
+func decodeEmbeddedPNG(data []byte) (image.Image, error) {
+	config, err := png.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxSourcePixels {
+		return nil, errInvalidICO
+	}
+	return png.Decode(bytes.NewReader(data))
+}
+
+// decodeDIB decodes an ICO bitmap: a BITMAPINFOHEADER, an optional palette,
+// the bottom-up color rows and the 1-bit transparency mask
+func decodeDIB(data []byte) (image.Image, error) {
+	if len(data) < 40 || binary.LittleEndian.Uint32(data[0:4]) < 40 {
+		return nil, errInvalidICO
+	}
+	headerSize := int(binary.LittleEndian.Uint32(data[0:4]))
+	width := int(int32(binary.LittleEndian.Uint32(data[4:8])))
+	// The height covers both the color rows and the mask
+	height := int(int32(binary.LittleEndian.Uint32(data[8:12]))) / 2
+	bpp := int(binary.LittleEndian.Uint16(data[14:16]))
+	compression := binary.LittleEndian.Uint32(data[16:20])
+	colorsUsed := int(binary.LittleEndian.Uint32(data[32:36]))
+	if width <= 0 || height <= 0 || width*height > maxSourcePixels || compression != 0 || headerSize > len(data) {
+		return nil, errInvalidICO
+	}
+
+	pos := headerSize
+	var palette []color.RGBA
+	switch bpp {
+	case 1, 4, 8:
+		if colorsUsed == 0 || colorsUsed > 1<<bpp {
+			colorsUsed = 1 << bpp
+		}
+		if pos+4*colorsUsed > len(data) {
+			return nil, errInvalidICO
+		}
+		for i := 0; i < colorsUsed; i++ {
+			p := data[pos+4*i:]
+			palette = append(palette, color.RGBA{R: p[2], G: p[1], B: p[0], A: 0xff})
+		}
+		pos += 4 * colorsUsed
+	case 24, 32:
+	default:
+		return nil, errInvalidICO
+	}
+
+	stride := (width*bpp + 31) / 32 * 4
+	maskStride := (width + 31) / 32 * 4
+	pixels := data[pos:]
+	if len(pixels) < stride*height {
+		return nil, errInvalidICO
+	}
+	var mask []byte
+	if len(pixels) >= stride*height+maskStride*height {
+		mask = pixels[stride*height:]
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	hasAlpha := false
+	for y := 0; y < height; y++ {
+		row := pixels[(height-1-y)*stride:]
+		for x := 0; x < width; x++ {
+			var c color.NRGBA
+			switch bpp {
+			case 32:
+				p := row[x*4:]
+				c = color.NRGBA{R: p[2], G: p[1], B: p[0], A: p[3]}
+				if p[3] != 0 {
+					hasAlpha = true
+				}
+			case 24:
+				p := row[x*3:]
+				c = color.NRGBA{R: p[2], G: p[1], B: p[0], A: 0xff}
+			default:
+				bit := x * bpp
+				index := int(row[bit/8]>>(8-bpp-bit%8)) & (1<<bpp - 1)
+				if index < len(palette) {
+					p := palette[index]
+					c = color.NRGBA{R: p.R, G: p.G, B: p.B, A: 0xff}
+				}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+
+	// Without an alpha channel, the AND mask marks transparent pixels
+	if !hasAlpha {
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				c := img.NRGBAAt(x, y)
+				c.A = 0xff
+				if mask != nil && mask[(height-1-y)*maskStride+x/8]&(0x80>>(x%8)) != 0 {
+					c.A = 0
+				}
+				img.SetNRGBA(x, y, c)
+			}
+		}
+	}
+	return img, nil
+}
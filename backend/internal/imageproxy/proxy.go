@@ -0,0 +1,293 @@
+// Package imageproxy downloads, validates, resizes and caches favicons and
+// preview images so that clients never load them from third-party sites
+package imageproxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	_ "image/gif"
+
+	"bookmarks-go/internal/blobstore"
+	"bookmarks-go/internal/scraper"
+)
+
+const (
+	// maxSourceSize is the largest image downloaded
+	maxSourceSize = 5 << 20
+	// maxSourcePixels guards against decompression bombs
+	maxSourcePixels = 25_000_000
+	// originalVariant names the stored copy of images served without resizing
+	originalVariant = "original"
+)
+
+var (
+	// IconSizes are the square sizes icons are rendered in
+	IconSizes = []int{16, 32, 64, 128}
+	// PreviewWidths are the widths preview images are rendered in
+	PreviewWidths = []int{320, 640, 1280}
+)
+
+// ErrUnsupported is returned when a source is not an image the proxy can serve
+var ErrUnsupported = errors.New("unsupported image")
+
+// Fetcher downloads source images
+type Fetcher interface {
+	FetchResource(ctx context.Context, rawURL string, maxSize int64) (*scraper.Resource, error)
+}
+
+// Proxy serves resized copies of remote images from a blob store
+type Proxy struct {
+	store   blobstore.Store
+	fetcher Fetcher
+}
+
+// Image is a cached image variant; the caller must close Body
+type Image struct {
+	Body        io.ReadCloser
+	ContentType string
+	Size        int64
+	// ETag identifies the variant, which is derived from the source URL and size
+	ETag string
+}
+
+// New creates a proxy caching images in store and downloading them with fetcher
+func New(store blobstore.Store, fetcher Fetcher) *Proxy {
+	return &Proxy{store: store, fetcher: fetcher}
+}
+
+// Icon returns the icon at src as a square PNG of the smallest standard size
+// not below size. SVG icons are served as they are.
+func (p *Proxy) Icon(ctx context.Context, src string, size int) (*Image, error) {
+	return p.variant(ctx, "icons", src, standardSize(IconSizes, size), p.renderIcons)
+}
+
+// Preview returns the preview image at src scaled to the smallest standard
+// width not below width, as JPEG or, for transparent images, PNG
+func (p *Proxy) Preview(ctx context.Context, src string, width int) (*Image, error) {
+	return p.variant(ctx, "previews", src, standardSize(PreviewWidths, width), p.renderPreviews)
+}
+
+// standardSize returns the smallest of sizes at least as large as requested, or the largest
+func standardSize(sizes []int, requested int) int {
+	for _, size := range sizes {
+		if size >= requested {
+			return size
+		}
+	}
+	return sizes[len(sizes)-1]
+}
+
+// renderFunc encodes every standard variant of a decoded image
+type renderFunc func(img *image.RGBA) map[string]encoded
+
+// encoded is an encoded variant ready to store
+type encoded struct {
+	data        []byte
+	contentType string
+}
+
+// Version identifies the image at src: the first 32 hex digits of the SHA-256
+// of the URL. It names the cached variants and changes when the URL does.
+func Version(src string) string {
+	sum := sha256.Sum256([]byte(src))
+	return hex.EncodeToString(sum[:16])
+}
+
+// variant returns the cached variant of src, downloading and rendering all
+// variants on a cache miss
+func (p *Proxy) variant(ctx context.Context, kind, src string, size int, render renderFunc) (*Image, error) {
+	prefix := kind + "/" + Version(src)
+	name := strconv.Itoa(size)
+
+	if img, err := p.cached(ctx, prefix, name); !errors.Is(err, blobstore.ErrNotFound) {
+		return img, err
+	}
+
+	variants, err := p.render(ctx, src, render)
+	if err != nil {
+		return nil, err
+	}
+	for variantName, v := range variants {
+		if err := p.store.Put(ctx, prefix+"/"+variantName, bytes.NewReader(v.data), v.contentType); err != nil {
+			return nil, fmt.Errorf("failed to cache image: %w", err)
+		}
+	}
+	return p.cached(ctx, prefix, name)
+}
+
+// cached opens the stored variant name below prefix, falling back to the
+// original of images that are not resized
+func (p *Proxy) cached(ctx context.Context, prefix, name string) (*Image, error) {
+	for _, candidate := range []string{name, originalVariant} {
+		body, info, err := p.store.Get(ctx, prefix+"/"+candidate)
+		if errors.Is(err, blobstore.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &Image{
+			Body:        body,
+			ContentType: info.ContentType,
+			Size:        info.Size,
+			ETag:        `"` + strings.ReplaceAll(prefix, "/", "-") + "-" + candidate + `"`,
+		}, nil
+	}
+	return nil, blobstore.ErrNotFound
+}
+
+// render downloads and validates src and encodes its variants
+func (p *Proxy) render(ctx context.Context, src string, render renderFunc) (map[string]encoded, error) {
+	data, mediaType, err := p.load(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case isSVG(data, mediaType):
+		return map[string]encoded{originalVariant: {data, "image/svg+xml"}}, nil
+	case isPassthrough(data):
+		// Formats the standard library cannot decode are served unresized
+		return map[string]encoded{originalVariant: {data, http.DetectContentType(data)}}, nil
+	}
+
+	img, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	return render(toRGBA(img)), nil
+}
+
+// load returns the bytes and declared media type of src, which may be a data URI
+func (p *Proxy) load(ctx context.Context, src string) ([]byte, string, error) {
+	if strings.HasPrefix(src, "data:") {
+		return decodeDataURI(src)
+	}
+	resource, err := p.fetcher.FetchResource(ctx, src, maxSourceSize)
+	if err != nil {
+		return nil, "", err
+	}
+	return resource.Data, resource.MediaType, nil
+}
+
+// decode decodes a raster image after checking its dimensions
+func decode(data []byte) (image.Image, error) {
+	if isICO(data) {
+		img, err := decodeICO(data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUnsupported, err)
+		}
+		return img, nil
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupported, err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxSourcePixels {
+		return nil, fmt.Errorf("%w: %dx%d pixels", ErrUnsupported, config.Width, config.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupported, err)
+	}
+	return img, nil
+}
+
+// renderIcons encodes src as a PNG square of every icon size
+func (p *Proxy) renderIcons(src *image.RGBA) map[string]encoded {
+	variants := make(map[string]encoded, len(IconSizes))
+	for _, size := range IconSizes {
+		var buf bytes.Buffer
+		png.Encode(&buf, fitSquare(src, size))
+		variants[strconv.Itoa(size)] = encoded{buf.Bytes(), "image/png"}
+	}
+	return variants
+}
+
+// renderPreviews encodes src at every preview width
+func (p *Proxy) renderPreviews(src *image.RGBA) map[string]encoded {
+	variants := make(map[string]encoded, len(PreviewWidths))
+	opaque := isOpaque(src)
+	for _, width := range PreviewWidths {
+		scaled := scaleToWidth(src, width)
+		var buf bytes.Buffer
+		if opaque {
+			jpeg.Encode(&buf, scaled, &jpeg.Options{Quality: 85})
+			variants[strconv.Itoa(width)] = encoded{buf.Bytes(), "image/jpeg"}
+		} else {
+			png.Encode(&buf, scaled)
+			variants[strconv.Itoa(width)] = encoded{buf.Bytes(), "image/png"}
+		}
+	}
+	return variants
+}
+
+// isSVG reports whether data is an SVG document
+func isSVG(data []byte, mediaType string) bool {
+	if mediaType != "image/svg+xml" && !bytes.Contains(data[:min(len(data), 1024)], []byte("<svg")) {
+		return false
+	}
+	// The root element must be <svg>; HTML error pages are rejected
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return false
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name.Local == "svg"
+		}
+	}
+}
+
+// isPassthrough reports whether data is an image format served without resizing
+func isPassthrough(data []byte) bool {
+	if http.DetectContentType(data) == "image/webp" {
+		return true
+	}
+	// AVIF has no sniffing rule in net/http
+	return len(data) >= 12 && string(data[4:12]) == "ftypavif"
+}
+
+// decodeDataURI decodes a base64 or percent-encoded data: URI
+func decodeDataURI(uri string) ([]byte, string, error) {
+	header, payload, ok := strings.Cut(strings.TrimPrefix(uri, "data:"), ",")
+	if !ok {
+		return nil, "", fmt.Errorf("%w: malformed data URI", ErrUnsupported)
+	}
+	mediaType, _, _ := mime.ParseMediaType(strings.TrimSuffix(header, ";base64"))
+
+	var data []byte
+	var err error
+	if strings.HasSuffix(header, ";base64") {
+		data, err = base64.StdEncoding.DecodeString(payload)
+	} else {
+		var unescaped string
+		unescaped, err = url.PathUnescape(payload)
+		data = []byte(unescaped)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrUnsupported, err)
+	}
+	if len(data) > maxSourceSize {
+		return nil, "", scraper.ErrTooLarge
+	}
+	return data, mediaType, nil
+}
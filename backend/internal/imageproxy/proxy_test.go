@@ -0,0 +1,235 @@
+package imageproxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"testing"
+
+	"bookmarks-go/internal/blobstore"
+	"bookmarks-go/internal/scraper"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeFetcher serves resources from memory and counts downloads
+type fakeFetcher struct {
+	resources map[string]*scraper.Resource
+	fetches   int
+}
+
+func (f *fakeFetcher) FetchResource(ctx context.Context, rawURL string, maxSize int64) (*scraper.Resource, error) {
+	f.fetches++
+	resource, ok := f.resources[rawURL]
+	if !ok {
+		return nil, errors.New("unexpected status code: 404")
+	}
+	return resource, nil
+}
+
+func newTestProxy(t *testing.T, resources map[string]*scraper.Resource) (*Proxy, *fakeFetcher) {
+	t.Helper()
+	store, err := blobstore.NewFileStore(t.TempDir())
+	require.NoError(t, err)
+	fetcher := &fakeFetcher{resources: resources}
+	return New(store, fetcher), fetcher
+}
+
+// solidPNG encodes a w×h image filled with c
+func solidPNG(t *testing.T, w, h int, c color.Color) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+// readImage decodes the body of a proxied image
+func readImage(t *testing.T, img *Image) image.Image {
+	t.Helper()
+	defer img.Body.Close()
+	decoded, _, err := image.Decode(img.Body)
+	require.NoError(t, err)
+	return decoded
+}
+
+func TestIconIsResizedAndCached(t *testing.T) {
+	red := color.NRGBA{R: 255, A: 255}
+	proxy, fetcher := newTestProxy(t, map[string]*scraper.Resource{
+		"https://example.com/icon.png": {Data: solidPNG(t, 48, 24, red), MediaType: "image/png"},
+	})
+
+	icon, err := proxy.Icon(context.Background(), "https://example.com/icon.png", 30)
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", icon.ContentType)
+	assert.NotEmpty(t, icon.ETag)
+	decoded := readImage(t, icon)
+	assert.Equal(t, image.Rect(0, 0, 32, 32), decoded.Bounds())
+
+	// Wide icons are centered on a transparent square
+	_, _, _, a := decoded.At(16, 0).RGBA()
+	assert.Zero(t, a)
+	r, _, _, a := decoded.At(16, 16).RGBA()
+	assert.Equal(t, uint32(0xffff), r)
+	assert.Equal(t, uint32(0xffff), a)
+
+	// Every size was rendered by the first request
+	for _, size := range IconSizes {
+		icon, err := proxy.Icon(context.Background(), "https://example.com/icon.png", size)
+		require.NoError(t, err)
+		assert.Equal(t, size, readImage(t, icon).Bounds().Dx())
+	}
+	assert.Equal(t, 1, fetcher.fetches)
+}
+
+func TestPreviewKeepsAspectRatio(t *testing.T) {
+	proxy, _ := newTestProxy(t, map[string]*scraper.Resource{
+		"https://example.com/og.png": {Data: solidPNG(t, 1200, 630, color.NRGBA{B: 255, A: 255}), MediaType: "image/png"},
+	})
+
+	preview, err := proxy.Preview(context.Background(), "https://example.com/og.png", 600)
+	require.NoError(t, err)
+	assert.Equal(t, "image/jpeg", preview.ContentType)
+	assert.Equal(t, image.Rect(0, 0, 640, 336), readImage(t, preview).Bounds())
+
+	// Images are never enlarged
+	preview, err = proxy.Preview(context.Background(), "https://example.com/og.png", 2000)
+	require.NoError(t, err)
+	assert.Equal(t, 1200, readImage(t, preview).Bounds().Dx())
+}
+
+func TestSVGIsServedUnresized(t *testing.T) {
+	svg := []byte(`<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 1 1"></svg>`)
+	proxy, _ := newTestProxy(t, map[string]*scraper.Resource{
+		"https://example.com/icon.svg": {Data: svg, MediaType: "image/svg+xml"},
+	})
+
+	icon, err := proxy.Icon(context.Background(), "https://example.com/icon.svg", 64)
+	require.NoError(t, err)
+	defer icon.Body.Close()
+	assert.Equal(t, "image/svg+xml", icon.ContentType)
+	data, _ := io.ReadAll(icon.Body)
+	assert.Equal(t, svg, data)
+}
+
+func TestNonImagesAreRejected(t *testing.T) {
+	proxy, _ := newTestProxy(t, map[string]*scraper.Resource{
+		"https://example.com/icon.png": {Data: []byte("<html><body>Not found</body></html>"), MediaType: "image/png"},
+		"https://example.com/fake.svg": {Data: []byte("<html><svg></svg></html>"), MediaType: "image/svg+xml"},
+	})
+
+	_, err := proxy.Icon(context.Background(), "https://example.com/icon.png", 32)
+	assert.ErrorIs(t, err, ErrUnsupported)
+	_, err = proxy.Icon(context.Background(), "https://example.com/fake.svg", 32)
+	assert.ErrorIs(t, err, ErrUnsupported)
+	_, err = proxy.Icon(context.Background(), "https://example.com/missing.png", 32)
+	assert.Error(t, err)
+}
+
+func TestDataURIIcon(t *testing.T) {
+	proxy, fetcher := newTestProxy(t, nil)
+	src := "data:image/png;base64," + base64.StdEncoding.EncodeToString(solidPNG(t, 16, 16, color.White))
+
+	icon, err := proxy.Icon(context.Background(), src, 16)
+	require.NoError(t, err)
+	assert.Equal(t, 16, readImage(t, icon).Bounds().Dx())
+	assert.Zero(t, fetcher.fetches)
+}
+
+func TestDecodeICO(t *testing.T) {
+	// A 2×2 24-bit bitmap: bottom row blue and green, top row red and white,
+	// with the top-right pixel masked out
+	var dib bytes.Buffer
+	header := make([]byte, 40)
+	binary.LittleEndian.PutUint32(header[0:], 40)
+	binary.LittleEndian.PutUint32(header[4:], 2)
+	binary.LittleEndian.PutUint32(header[8:], 4)
+	binary.LittleEndian.PutUint16(header[12:], 1)
+	binary.LittleEndian.PutUint16(header[14:], 24)
+	dib.Write(header)
+	dib.Write([]byte{255, 0, 0, 0, 255, 0, 0, 0})     // bottom row (BGR) padded to 8 bytes
+	dib.Write([]byte{0, 0, 255, 255, 255, 255, 0, 0}) // top row
+	dib.Write([]byte{0, 0, 0, 0})                     // bottom mask row
+	dib.Write([]byte{0x40, 0, 0, 0})                  // top mask row
+
+	ico := []byte{0, 0, 1, 0, 1, 0}
+	entry := make([]byte, 16)
+	entry[0], entry[1] = 2, 2
+	binary.LittleEndian.PutUint16(entry[6:], 24)
+	binary.LittleEndian.PutUint32(entry[8:], uint32(dib.Len()))
+	binary.LittleEndian.PutUint32(entry[12:], 22)
+	ico = append(append(ico, entry...), dib.Bytes()...)
+
+	img, err := decodeICO(ico)
+	require.NoError(t, err)
+	assert.Equal(t, color.NRGBA{R: 255, A: 255}, img.At(0, 0))
+	assert.Equal(t, uint8(0), img.At(1, 0).(color.NRGBA).A)
+	assert.Equal(t, color.NRGBA{B: 255, A: 255}, img.At(0, 1))
+	assert.Equal(t, color.NRGBA{G: 255, A: 255}, img.At(1, 1))
+
+	// PNG entries are decoded as PNG
+	pngData := solidPNG(t, 4, 4, color.White)
+	ico = []byte{0, 0, 1, 0, 1, 0}
+	binary.LittleEndian.PutUint32(entry[8:], uint32(len(pngData)))
+	ico = append(append(ico, entry...), pngData...)
+	img, err = decodeICO(ico)
+	require.NoError(t, err)
+	assert.Equal(t, 4, img.Bounds().Dx())
+
+	_, err = decodeICO([]byte{0, 0, 1, 0, 5, 0})
+	assert.Error(t, err)
+}
+
+func TestDecodeICORejectsHugeEntries(t *testing.T) {
+	entry := make([]byte, 16)
+	icoWith := func(data []byte) []byte {
+		binary.LittleEndian.PutUint32(entry[8:], uint32(len(data)))
+		binary.LittleEndian.PutUint32(entry[12:], 22)
+		return append(append([]byte{0, 0, 1, 0, 1, 0}, entry...), data...)
+	}
+
+	// A small PNG whose header claims 10000×10000 pixels
+	pngData := solidPNG(t, 4, 4, color.White)
+	binary.BigEndian.PutUint32(pngData[16:], 10000)
+	binary.BigEndian.PutUint32(pngData[20:], 10000)
+	binary.BigEndian.PutUint32(pngData[29:], crc32.ChecksumIEEE(pngData[12:29]))
+	_, err := decodeICO(icoWith(pngData))
+	assert.ErrorIs(t, err, errInvalidICO)
+
+	// A bitmap header claiming as many pixels
+	header := make([]byte, 40)
+	binary.LittleEndian.PutUint32(header[0:], 40)
+	binary.LittleEndian.PutUint32(header[4:], 10000)
+	binary.LittleEndian.PutUint32(header[8:], 20000)
+	binary.LittleEndian.PutUint16(header[14:], 32)
+	_, err = decodeICO(icoWith(header))
+	assert.ErrorIs(t, err, errInvalidICO)
+}
+
+func TestResizeAveragesPixels(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	src.Set(0, 0, color.RGBA{R: 255, A: 255})
+	src.Set(1, 0, color.RGBA{R: 255, A: 255})
+	src.Set(0, 1, color.RGBA{A: 255})
+	src.Set(1, 1, color.RGBA{A: 255})
+
+	dst := resize(src, 1, 1)
+	assert.Equal(t, color.RGBA{R: 128, A: 255}, dst.At(0, 0))
+
+	up := resize(src, 4, 4)
+	assert.Equal(t, color.RGBA{R: 255, A: 255}, up.At(3, 0))
+	assert.Equal(t, color.RGBA{A: 255}, up.At(0, 3))
+}
@@ -0,0 +1,97 @@
+package imageproxy
+
+import (
+	"image"
+	"image/draw"
+)
+
+// toRGBA converts img to premultiplied RGBA with its origin at (0, 0)
+func toRGBA(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Src)
+	return dst
+}
+
+// resize scales src to w×h with a box filter: every destination pixel is the
+// average of the source pixels it covers, which keeps downscaled icons crisp
+// and upscales by repeating pixels
+func resize(src *image.RGBA, w, h int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	for y := 0; y < h; y++ {
+		y0, y1 := span(y, h, sh)
+		for x := 0; x < w; x++ {
+			x0, x1 := span(x, w, sw)
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += uint64(p[0])
+					g += uint64(p[1])
+					b += uint64(p[2])
+					a += uint64(p[3])
+					n++
+				}
+			}
+			// Premultiplied channels can be averaged directly
+			d := dst.Pix[y*dst.Stride+x*4:]
+			d[0] = uint8((r + n/2) / n)
+			d[1] = uint8((g + n/2) / n)
+			d[2] = uint8((b + n/2) / n)
+			d[3] = uint8((a + n/2) / n)
+		}
+	}
+	return dst
+}
+
+// span returns the source range [lo, hi) covered by destination index i
+// when scaling a dimension from srcLen to dstLen
+func span(i, dstLen, srcLen int) (int, int) {
+	lo := i * srcLen / dstLen
+	hi := (i + 1) * srcLen / dstLen
+	if hi <= lo {
+		hi = lo + 1
+	}
+	return lo, hi
+}
+
+// fitSquare scales src to fit a size×size square, centered on a transparent background
+func fitSquare(src *image.RGBA, size int) *image.RGBA {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	w, h := size, size
+	if sw > sh {
+		h = max(1, size*sh/sw)
+	} else if sh > sw {
+		w = max(1, size*sw/sh)
+	}
+	scaled := resize(src, w, h)
+	if w == size && h == size {
+		return scaled
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	offset := image.Pt((size-w)/2, (size-h)/2)
+	draw.Draw(dst, scaled.Bounds().Add(offset), scaled, image.Point{}, draw.Src)
+	return dst
+}
+
+// scaleToWidth scales src to width w keeping its aspect ratio; images are never enlarged
+func scaleToWidth(src *image.RGBA, w int) *image.RGBA {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	if w >= sw {
+		return src
+	}
+	return resize(src, w, max(1, sh*w/sw))
+}
+
+// isOpaque reports whether every pixel of img is fully opaque
+func isOpaque(img *image.RGBA) bool {
+	for i := 3; i < len(img.Pix); i += 4 {
+		if img.Pix[i] != 0xff {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,110 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"net/url"
+
+	"bookmarks-go/internal/models"
+	"bookmarks-go/internal/scraper"
+	"bookmarks-go/internal/storage"
+	"bookmarks-go/internal/wayback"
+)
+
+// ArchiveLookup runs JobArchiveLookup jobs, storing the nearest web archive
+// snapshot of a bookmark whose link is dead or unreachable
+type ArchiveLookup struct {
+	repo    storage.Repository
+	archive *wayback.Client
+	scraper *scraper.Scraper
+}
+
+// NewArchiveLookup registers archive lookups with pool. If s is not nil,
+// missing metadata of bookmarks is scraped from their snapshot.
+func NewArchiveLookup(pool *Pool, repo storage.Repository, archive *wayback.Client, s *scraper.Scraper) *ArchiveLookup {
+	a := &ArchiveLookup{
+		repo:    repo,
+		archive: archive,
+		scraper: s,
+	}
+	pool.Handle(models.JobArchiveLookup, a.Run)
+	return a
+}
+
+// Run looks up the snapshot of the job's bookmark
+func (a *ArchiveLookup) Run(ctx context.Context, job *models.Job) error {
+	bookmark, err := a.repo.GetBookmark(ctx, job.BookmarkID)
+	if err == storage.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Ask for the page as it was when it last worked: when metadata was last
+	// fetched, or when it was bookmarked if that never succeeded
+	at := bookmark.CreatedAt
+	if bookmark.MetadataStatus == models.MetadataOK {
+		at = bookmark.RefreshedAt
+	}
+
+	// Addresses of intranet pages are not disclosed to the archive
+	if u, err := url.Parse(bookmark.URL); err != nil || scraper.IsPrivateHost(ctx, u.Hostname()) {
+		log.Printf("Not looking up archived snapshot of private bookmark %d", bookmark.ID)
+		return nil
+	}
+
+	snapshot, err := a.archive.Lookup(ctx, bookmark.URL, at)
+	if err == wayback.ErrNoSnapshot {
+		log.Printf("No archived snapshot of bookmark %d", bookmark.ID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var metadata *scraper.Metadata
+	if a.scraper != nil && bookmark.MetadataStatus != models.MetadataOK {
+		metadata, err = a.scraper.GetMetadata(scraper.BackgroundContext(ctx), snapshot.URL)
+		if err != nil {
+			log.Printf("Failed to scrape snapshot of bookmark %d: %v", bookmark.ID, err)
+			metadata = nil
+		}
+	}
+
+	return storage.UpdateWithRetry(ctx, a.repo, bookmark, func(b *models.Bookmark) {
+		b.ArchiveURL = snapshot.URL
+		b.ArchivedAt = &snapshot.Timestamp
+		if metadata != nil && b.MetadataStatus != models.MetadataOK {
+			fillFromSnapshot(b, metadata)
+		}
+	})
+}
+
+// fillFromSnapshot sets descriptive fields that are empty and unlocked from
+// the metadata of an archived snapshot. Icons are not taken, since the
+// archive's own favicon would be found for pages that declare none. Values
+// from snapshots are trusted half as much as if found on the live page.
+func fillFromSnapshot(bookmark *models.Bookmark, metadata *scraper.Metadata) {
+	fields := []struct {
+		field string
+		dest  *string
+		value string
+	}{
+		{models.FieldTitle, &bookmark.Title, metadata.Title},
+		{models.FieldDescription, &bookmark.Description, metadata.Description},
+		{models.FieldAuthor, &bookmark.Author, metadata.Author},
+		{models.FieldImageURL, &bookmark.ImageURL, metadata.ImageURL},
+	}
+	for _, f := range fields {
+		if *f.dest == "" && !bookmark.LockedFields.Has(f.field) && f.value != "" {
+			*f.dest = f.value
+			scraped := metadata.Provenance[f.field]
+			bookmark.Provenance = bookmark.Provenance.Set(f.field, models.FieldProvenance{
+				Source:     models.SourceWebArchive,
+				Rule:       scraped.Rule,
+				Confidence: scraped.Confidence / 2,
+			})
+		}
+	}
+}
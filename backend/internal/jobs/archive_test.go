@@ -0,0 +1,142 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"bookmarks-go/internal/models"
+	"bookmarks-go/internal/scraper"
+	"bookmarks-go/internal/storage"
+	"bookmarks-go/internal/wayback"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// archiveRepository holds a single bookmark and records updates of it
+type archiveRepository struct {
+	storage.Repository
+	bookmark *models.Bookmark
+	updated  *models.Bookmark
+}
+
+func (r *archiveRepository) GetBookmark(ctx context.Context, id int64) (*models.Bookmark, error) {
+	if r.bookmark == nil || r.bookmark.ID != id {
+		return nil, storage.ErrNotFound
+	}
+	b := *r.bookmark
+	return &b, nil
+}
+
+func (r *archiveRepository) UpdateBookmark(ctx context.Context, bookmark *models.Bookmark) error {
+	r.updated = bookmark
+	return nil
+}
+
+// newArchiveServer serves an availability API with a snapshot of /dead only
+func newArchiveServer(t *testing.T) *httptest.Server {
+	var ts *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/wayback/available", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("url") != "https://example.com/dead" {
+			w.Write([]byte(`{"archived_snapshots": {}}`))
+			return
+		}
+		fmt.Fprintf(w, `{"archived_snapshots": {"closest": {"status": "200", "available": true,
+			"timestamp": "20200102030405", "url": "%s/web/20200102030405/https://example.com/dead"}}}`, ts.URL)
+	})
+	mux.HandleFunc("/web/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Archived title</title>
+			<meta name="description" content="Archived description"></head><body></body></html>`))
+	})
+	ts = httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestArchiveLookup(t *testing.T) {
+	ts := newArchiveServer(t)
+
+	loopback, err := scraper.ParseNetworks("127.0.0.0/8")
+	require.NoError(t, err)
+	s := scraper.NewScraper(5*time.Second, scraper.WithAllowedNetworks(loopback...))
+
+	repo := &archiveRepository{bookmark: &models.Bookmark{
+		ID:             1,
+		URL:            "https://example.com/dead",
+		Title:          "Kept",
+		MetadataStatus: models.MetadataFailed,
+		LockedFields:   models.FieldLocks{models.FieldDescription},
+	}}
+	pool := NewPool(storage.NewMemoryJobQueue())
+	lookup := NewArchiveLookup(pool, repo, wayback.New(ts.URL, 5*time.Second), s)
+	assert.True(t, pool.Handles(models.JobArchiveLookup))
+
+	require.NoError(t, lookup.Run(context.Background(), &models.Job{BookmarkID: 1}))
+	require.NotNil(t, repo.updated)
+	assert.Equal(t, ts.URL+"/web/20200102030405/https://example.com/dead", repo.updated.ArchiveURL)
+	require.NotNil(t, repo.updated.ArchivedAt)
+	assert.Equal(t, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), *repo.updated.ArchivedAt)
+	assert.Equal(t, "Kept", repo.updated.Title)
+	assert.Empty(t, repo.updated.Description)
+}
+
+func TestArchiveLookupFillsMetadata(t *testing.T) {
+	ts := newArchiveServer(t)
+
+	loopback, err := scraper.ParseNetworks("127.0.0.0/8")
+	require.NoError(t, err)
+	s := scraper.NewScraper(5*time.Second, scraper.WithAllowedNetworks(loopback...))
+
+	repo := &archiveRepository{bookmark: &models.Bookmark{
+		ID:             1,
+		URL:            "https://example.com/dead",
+		MetadataStatus: models.MetadataFailed,
+	}}
+	lookup := NewArchiveLookup(NewPool(storage.NewMemoryJobQueue()), repo, wayback.New(ts.URL, 5*time.Second), s)
+
+	require.NoError(t, lookup.Run(context.Background(), &models.Job{BookmarkID: 1}))
+	require.NotNil(t, repo.updated)
+	assert.Equal(t, "Archived title", repo.updated.Title)
+	assert.Equal(t, "Archived description", repo.updated.Description)
+	assert.Equal(t, models.FieldProvenance{Source: models.SourceWebArchive, Rule: "title", Confidence: 0.4}, repo.updated.Provenance[models.FieldTitle])
+	assert.NotContains(t, repo.updated.Provenance, models.FieldAuthor)
+}
+
+func TestArchiveLookupWithoutSnapshot(t *testing.T) {
+	ts := newArchiveServer(t)
+
+	repo := &archiveRepository{bookmark: &models.Bookmark{ID: 1, URL: "https://example.com/never"}}
+	lookup := NewArchiveLookup(NewPool(storage.NewMemoryJobQueue()), repo, wayback.New(ts.URL, 5*time.Second), nil)
+
+	require.NoError(t, lookup.Run(context.Background(), &models.Job{BookmarkID: 1}))
+	assert.Nil(t, repo.updated)
+
+	// Deleted bookmarks are not an error
+	require.NoError(t, lookup.Run(context.Background(), &models.Job{BookmarkID: 2}))
+}
+
+func TestArchiveLookupSkipsPrivateHosts(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"archived_snapshots": {}}`))
+	}))
+	defer ts.Close()
+
+	repo := &archiveRepository{}
+	lookup := NewArchiveLookup(NewPool(storage.NewMemoryJobQueue()), repo, wayback.New(ts.URL, 5*time.Second), nil)
+
+	for _, rawURL := range []string{"http://192.168.1.10/admin", "https://wiki.corp/page", "http://intranet/"} {
+		repo.bookmark = &models.Bookmark{ID: 1, URL: rawURL}
+		require.NoError(t, lookup.Run(context.Background(), &models.Job{BookmarkID: 1}))
+	}
+	assert.Zero(t, requests)
+	assert.Nil(t, repo.updated)
+}
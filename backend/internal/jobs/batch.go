@@ -0,0 +1,98 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"bookmarks-go/internal/models"
+)
+
+// batchMinInterval is how long batch runners wait between full batches
+const batchMinInterval = time.Second
+
+// batchRunner periodically loads a batch of due bookmarks and handles them
+// concurrently, continuing soon while full batches are due
+type batchRunner struct {
+	// what names the due bookmarks in log messages
+	what         string
+	due          func(ctx context.Context, limit int) ([]models.Bookmark, error)
+	handle       func(ctx context.Context, bookmark *models.Bookmark)
+	batchSize    int
+	pollInterval time.Duration
+	// minInterval is the wait after a full batch. Bookmarks whose handling
+	// failed may still be due, and must not make the runner spin.
+	minInterval time.Duration
+	concurrency int
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// start starts running due batches
+func (r *batchRunner) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.wg.Add(1)
+	go r.run(ctx)
+}
+
+// stop stops running batches and waits for running handlers to finish
+func (r *batchRunner) stop() {
+	if r.cancel == nil {
+		return
+	}
+	r.cancel()
+	r.wg.Wait()
+}
+
+// run runs due batches until ctx is cancelled
+func (r *batchRunner) run(ctx context.Context) {
+	defer r.wg.Done()
+
+	for {
+		n := r.runDue(ctx)
+
+		// Continue soon while a full batch was due
+		wait := r.minInterval
+		if n < r.batchSize {
+			wait = r.pollInterval
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// runDue handles one batch of due bookmarks concurrently and returns its size
+func (r *batchRunner) runDue(ctx context.Context) int {
+	bookmarks, err := r.due(ctx, r.batchSize)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Failed to list %s: %v", r.what, err)
+		}
+		return 0
+	}
+
+	sem := make(chan struct{}, r.concurrency)
+	var wg sync.WaitGroup
+	for i := range bookmarks {
+		select {
+		case <-ctx.Done():
+		case sem <- struct{}{}:
+			wg.Add(1)
+			go func(bookmark *models.Bookmark) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				r.handle(ctx, bookmark)
+			}(&bookmarks[i])
+		}
+	}
+	wg.Wait()
+	return len(bookmarks)
+}
@@ -0,0 +1,80 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"bookmarks-go/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchRunnerDrainsFullBatches(t *testing.T) {
+	var mu sync.Mutex
+	pending := []models.Bookmark{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}, {ID: 5}}
+	var handled []int64
+
+	runner := &batchRunner{
+		what: "test bookmarks",
+		due: func(ctx context.Context, limit int) ([]models.Bookmark, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			n := min(limit, len(pending))
+			batch := append([]models.Bookmark(nil), pending[:n]...)
+			pending = pending[n:]
+			return batch, nil
+		},
+		handle: func(ctx context.Context, bookmark *models.Bookmark) {
+			mu.Lock()
+			defer mu.Unlock()
+			handled = append(handled, bookmark.ID)
+		},
+		batchSize:    2,
+		pollInterval: time.Hour,
+		minInterval:  time.Millisecond,
+		concurrency:  2,
+	}
+	runner.start()
+	defer runner.stop()
+
+	// Full batches are followed soon by the next, without waiting for the poll interval
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(handled) == 5
+	}, 2*time.Second, 5*time.Millisecond)
+	assert.ElementsMatch(t, []int64{1, 2, 3, 4, 5}, handled)
+}
+
+func TestBatchRunnerWaitsAfterFullBatches(t *testing.T) {
+	var mu sync.Mutex
+	var rounds []time.Time
+
+	// The same bookmarks stay due, as if handling them kept failing
+	runner := &batchRunner{
+		what: "test bookmarks",
+		due: func(ctx context.Context, limit int) ([]models.Bookmark, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			rounds = append(rounds, time.Now())
+			return []models.Bookmark{{ID: 1}, {ID: 2}}, nil
+		},
+		handle:       func(ctx context.Context, bookmark *models.Bookmark) {},
+		batchSize:    2,
+		pollInterval: time.Hour,
+		minInterval:  50 * time.Millisecond,
+		concurrency:  2,
+	}
+	runner.start()
+	time.Sleep(175 * time.Millisecond)
+	runner.stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.LessOrEqual(t, len(rounds), 4)
+	for i := 1; i < len(rounds); i++ {
+		assert.GreaterOrEqual(t, rounds[i].Sub(rounds[i-1]), 50*time.Millisecond)
+	}
+}
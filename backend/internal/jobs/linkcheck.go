@@ -0,0 +1,147 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"bookmarks-go/internal/models"
+	"bookmarks-go/internal/scraper"
+	"bookmarks-go/internal/storage"
+)
+
+const (
+	// DefaultCheckInterval is how often each bookmark's link is checked
+	DefaultCheckInterval = 24 * time.Hour
+
+	// DefaultBrokenAfter is the number of consecutive failed checks after which a bookmark is broken
+	DefaultBrokenAfter = 3
+
+	// DefaultCheckConcurrency is how many links are checked at once
+	DefaultCheckConcurrency = 4
+
+	// checkBatchSize is how many bookmarks are loaded per round of checks
+	checkBatchSize = 100
+
+	// checkPollInterval is how long the checker waits when no bookmark is due
+	checkPollInterval = 5 * time.Minute
+)
+
+// LinkChecker periodically checks whether bookmarked URLs still work and
+// records the outcome, marking bookmarks broken after repeated failures.
+// Bookmarks that break are looked up in the web archive if the pool handles
+// JobArchiveLookup.
+type LinkChecker struct {
+	pool        *Pool
+	repo        storage.Repository
+	scraper     *scraper.Scraper
+	interval    time.Duration
+	brokenAfter int
+	concurrency int
+
+	runner *batchRunner
+}
+
+// LinkCheckerOption configures a LinkChecker
+type LinkCheckerOption func(*LinkChecker)
+
+// WithCheckInterval sets how often each bookmark's link is checked
+func WithCheckInterval(d time.Duration) LinkCheckerOption {
+	return func(c *LinkChecker) {
+		c.interval = d
+	}
+}
+
+// WithBrokenAfter sets the number of consecutive failed checks after which a bookmark is broken
+func WithBrokenAfter(n int) LinkCheckerOption {
+	return func(c *LinkChecker) {
+		c.brokenAfter = n
+	}
+}
+
+// WithCheckConcurrency sets how many links are checked at once
+func WithCheckConcurrency(n int) LinkCheckerOption {
+	return func(c *LinkChecker) {
+		c.concurrency = n
+	}
+}
+
+// NewLinkChecker creates a link checker fetching with s
+func NewLinkChecker(pool *Pool, repo storage.Repository, s *scraper.Scraper, opts ...LinkCheckerOption) *LinkChecker {
+	c := &LinkChecker{
+		pool:        pool,
+		repo:        repo,
+		scraper:     s,
+		interval:    DefaultCheckInterval,
+		brokenAfter: DefaultBrokenAfter,
+		concurrency: DefaultCheckConcurrency,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.concurrency < 1 {
+		c.concurrency = 1
+	}
+	if c.brokenAfter < 1 {
+		c.brokenAfter = 1
+	}
+	c.runner = &batchRunner{
+		what: "bookmarks to check",
+		due: func(ctx context.Context, limit int) ([]models.Bookmark, error) {
+			return c.repo.ListBookmarksToCheck(ctx, time.Now().Add(-c.interval), limit)
+		},
+		handle:       c.check,
+		batchSize:    checkBatchSize,
+		pollInterval: checkPollInterval,
+		minInterval:  batchMinInterval,
+		concurrency:  c.concurrency,
+	}
+	return c
+}
+
+// Start starts checking links
+func (c *LinkChecker) Start() {
+	c.runner.start()
+}
+
+// Stop stops checking links and waits for running checks to finish
+func (c *LinkChecker) Stop() {
+	c.runner.stop()
+}
+
+// check checks the link of one bookmark and records the result
+func (c *LinkChecker) check(ctx context.Context, bookmark *models.Bookmark) {
+	status, err := c.scraper.CheckLink(scraper.BackgroundContext(ctx), bookmark.URL)
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		// Record the failure so the bookmark does not stay first in line
+		status = &scraper.LinkStatus{Error: err.Error()}
+	}
+
+	check := &models.LinkCheck{
+		BookmarkID:   bookmark.ID,
+		OK:           status.OK(),
+		StatusCode:   status.StatusCode,
+		FinalURL:     status.FinalURL,
+		LatencyMS:    status.Latency.Milliseconds(),
+		Error:        status.Error,
+		TLSError:     status.TLSError,
+		Inconclusive: status.Inconclusive,
+	}
+	if err := c.repo.RecordLinkCheck(ctx, check, c.brokenAfter); err != nil {
+		if err != storage.ErrNotFound {
+			log.Printf("Failed to record link check of bookmark %d: %v", bookmark.ID, err)
+		}
+		return
+	}
+
+	breaks := !check.OK && !check.Inconclusive &&
+		bookmark.Health != models.HealthBroken && bookmark.CheckFailures+1 >= c.brokenAfter
+	if breaks && c.pool.Handles(models.JobArchiveLookup) {
+		if _, err := c.pool.Enqueue(ctx, models.JobArchiveLookup, bookmark.ID); err != nil {
+			log.Printf("Failed to enqueue archive lookup of bookmark %d: %v", bookmark.ID, err)
+		}
+	}
+}
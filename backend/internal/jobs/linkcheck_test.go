@@ -0,0 +1,78 @@
+package jobs
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"bookmarks-go/internal/models"
+	"bookmarks-go/internal/scraper"
+	"bookmarks-go/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// checkRepository serves bookmarks to check and collects recorded checks
+type checkRepository struct {
+	storage.Repository
+	due []models.Bookmark
+
+	mu          sync.Mutex
+	checks      map[int64]*models.LinkCheck
+	brokenAfter int
+}
+
+func (r *checkRepository) ListBookmarksToCheck(ctx context.Context, before time.Time, limit int) ([]models.Bookmark, error) {
+	return r.due, nil
+}
+
+func (r *checkRepository) RecordLinkCheck(ctx context.Context, check *models.LinkCheck, brokenAfter int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks[check.BookmarkID] = check
+	r.brokenAfter = brokenAfter
+	return nil
+}
+
+func TestLinkCheckerRecordsChecks(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "gone", http.StatusGone)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	loopback, err := scraper.ParseNetworks("127.0.0.0/8")
+	require.NoError(t, err)
+	s := scraper.NewScraper(5*time.Second, scraper.WithAllowedNetworks(loopback...))
+
+	repo := &checkRepository{
+		due: []models.Bookmark{
+			{ID: 1, URL: ts.URL + "/ok"},
+			{ID: 2, URL: ts.URL + "/gone"},
+			{ID: 3, URL: "not a url\x7f"},
+		},
+		checks: make(map[int64]*models.LinkCheck),
+	}
+	checker := NewLinkChecker(NewPool(storage.NewMemoryJobQueue()), repo, s, WithBrokenAfter(5), WithCheckConcurrency(2))
+
+	n := checker.runner.runDue(context.Background())
+	assert.Equal(t, 3, n)
+	assert.Equal(t, 5, repo.brokenAfter)
+
+	require.Len(t, repo.checks, 3)
+	assert.True(t, repo.checks[1].OK)
+	assert.Equal(t, http.StatusOK, repo.checks[1].StatusCode)
+	assert.Equal(t, ts.URL+"/ok", repo.checks[1].FinalURL)
+
+	assert.False(t, repo.checks[2].OK)
+	assert.Equal(t, http.StatusGone, repo.checks[2].StatusCode)
+
+	assert.False(t, repo.checks[3].OK)
+	assert.NotEmpty(t, repo.checks[3].Error)
+}
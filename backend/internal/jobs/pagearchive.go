@@ -0,0 +1,53 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"bookmarks-go/internal/archiver"
+	"bookmarks-go/internal/models"
+	"bookmarks-go/internal/scraper"
+	"bookmarks-go/internal/storage"
+)
+
+// PageArchiver runs JobArchivePage jobs, saving bookmarked pages and their
+// subresources to WARC files
+type PageArchiver struct {
+	repo     storage.Repository
+	archives *archiver.Archiver
+}
+
+// NewPageArchiver registers page archiving with pool
+func NewPageArchiver(pool *Pool, repo storage.Repository, archives *archiver.Archiver) *PageArchiver {
+	p := &PageArchiver{
+		repo:     repo,
+		archives: archives,
+	}
+	pool.Handle(models.JobArchivePage, p.Run)
+	return p
+}
+
+// Run archives the page of the job's bookmark
+func (p *PageArchiver) Run(ctx context.Context, job *models.Job) error {
+	bookmark, err := p.repo.GetBookmark(ctx, job.BookmarkID)
+	if err == storage.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	result, err := p.archives.Archive(scraper.BackgroundContext(ctx), bookmark.ID, bookmark.URL)
+	if err != nil {
+		return err
+	}
+	log.Printf("Archived bookmark %d with %d subresources, %d bytes", bookmark.ID, result.Resources, result.Size)
+
+	err = p.repo.SetPageArchived(ctx, bookmark.ID, time.Now().UTC())
+	if err == storage.ErrNotFound {
+		// Deleted while archiving
+		return p.archives.Delete(ctx, bookmark.ID)
+	}
+	return err
+}
@@ -0,0 +1,87 @@
+package jobs
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"bookmarks-go/internal/archiver"
+	"bookmarks-go/internal/blobstore"
+	"bookmarks-go/internal/models"
+	"bookmarks-go/internal/scraper"
+	"bookmarks-go/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pageArchiveRepository serves bookmarks and records archive times
+type pageArchiveRepository struct {
+	storage.Repository
+	bookmarks map[int64]*models.Bookmark
+	archived  map[int64]time.Time
+}
+
+func (r *pageArchiveRepository) GetBookmark(ctx context.Context, id int64) (*models.Bookmark, error) {
+	bookmark, ok := r.bookmarks[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return bookmark, nil
+}
+
+func (r *pageArchiveRepository) SetPageArchived(ctx context.Context, id int64, at time.Time) error {
+	if id == 2 {
+		// Deleted while archiving
+		return storage.ErrNotFound
+	}
+	r.archived[id] = at
+	return nil
+}
+
+func TestPageArchiver(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><link rel="stylesheet" href="/style.css"></head><body></body></html>`))
+	})
+	mux.HandleFunc("/style.css", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/css")
+		w.Write([]byte(`body { margin: 0 }`))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	loopback, err := scraper.ParseNetworks("127.0.0.0/8")
+	require.NoError(t, err)
+	s := scraper.NewScraper(5*time.Second, scraper.WithAllowedNetworks(loopback...), scraper.WithHostRateLimit(0, 0))
+	store, err := blobstore.NewFileStore(t.TempDir())
+	require.NoError(t, err)
+	archives := archiver.New(store, s)
+
+	repo := &pageArchiveRepository{
+		bookmarks: map[int64]*models.Bookmark{
+			1: {ID: 1, URL: ts.URL + "/page"},
+			2: {ID: 2, URL: ts.URL + "/page"},
+		},
+		archived: make(map[int64]time.Time),
+	}
+	pool := NewPool(storage.NewMemoryJobQueue())
+	p := NewPageArchiver(pool, repo, archives)
+	assert.True(t, pool.Handles(models.JobArchivePage))
+
+	require.NoError(t, p.Run(context.Background(), &models.Job{BookmarkID: 1}))
+	assert.Contains(t, repo.archived, int64(1))
+	replayed, err := archives.Replay(context.Background(), 1, ts.URL+"/style.css", func(ref string) string { return ref })
+	require.NoError(t, err)
+	assert.Equal(t, "body { margin: 0 }", string(replayed.Body))
+
+	// The archive of a bookmark deleted in the meantime is removed
+	require.NoError(t, p.Run(context.Background(), &models.Job{BookmarkID: 2}))
+	_, _, err = archives.Open(context.Background(), 2)
+	assert.Equal(t, archiver.ErrNotArchived, err)
+
+	require.NoError(t, p.Run(context.Background(), &models.Job{BookmarkID: 3}))
+}
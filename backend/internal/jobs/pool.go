@@ -0,0 +1,266 @@
+// Package jobs runs background work stored in a durable job queue
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"bookmarks-go/internal/models"
+	"bookmarks-go/internal/storage"
+)
+
+const (
+	// DefaultWorkers is the number of jobs run concurrently unless configured otherwise
+	DefaultWorkers = 4
+
+	// DefaultMaxAttempts is how often a job is run before it is marked dead
+	DefaultMaxAttempts = 4
+
+	// DefaultPollInterval is how long an idle worker waits before looking for due jobs
+	DefaultPollInterval = time.Second
+
+	// DefaultLease is how long a job may run before another worker may claim it again
+	DefaultLease = 5 * time.Minute
+)
+
+// DefaultRetryDelays are the waits after each failed attempt; the last delay
+// is used for any further attempts
+var DefaultRetryDelays = []time.Duration{30 * time.Second, 5 * time.Minute, 30 * time.Minute}
+
+// Handler runs a job. A returned error counts as a failed attempt.
+type Handler func(ctx context.Context, job *models.Job) error
+
+// Pool runs jobs from a queue on a fixed number of workers
+type Pool struct {
+	queue        storage.JobQueue
+	handlers     map[string]Handler
+	workers      int
+	maxAttempts  int
+	pollInterval time.Duration
+	lease        time.Duration
+	retryDelays  []time.Duration
+
+	wake   chan struct{}
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Option configures a Pool
+type Option func(*Pool)
+
+// WithWorkers sets the number of jobs run concurrently
+func WithWorkers(n int) Option {
+	return func(p *Pool) {
+		p.workers = n
+	}
+}
+
+// WithMaxAttempts sets how often a job is run before it is marked dead
+func WithMaxAttempts(n int) Option {
+	return func(p *Pool) {
+		p.maxAttempts = n
+	}
+}
+
+// WithPollInterval sets how long an idle worker waits before looking for due jobs
+func WithPollInterval(d time.Duration) Option {
+	return func(p *Pool) {
+		p.pollInterval = d
+	}
+}
+
+// WithLease sets how long a job may run. The job's context is cancelled when
+// the lease expires, after which the job may be claimed again.
+func WithLease(d time.Duration) Option {
+	return func(p *Pool) {
+		p.lease = d
+	}
+}
+
+// WithRetryDelays sets the waits after each failed attempt
+func WithRetryDelays(delays ...time.Duration) Option {
+	return func(p *Pool) {
+		p.retryDelays = delays
+	}
+}
+
+// NewPool creates a pool running jobs from queue. Handlers must be
+// registered with Handle before the pool is started.
+func NewPool(queue storage.JobQueue, opts ...Option) *Pool {
+	p := &Pool{
+		queue:        queue,
+		handlers:     make(map[string]Handler),
+		workers:      DefaultWorkers,
+		maxAttempts:  DefaultMaxAttempts,
+		pollInterval: DefaultPollInterval,
+		lease:        DefaultLease,
+		retryDelays:  DefaultRetryDelays,
+		wake:         make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.workers < 1 {
+		p.workers = 1
+	}
+	if p.maxAttempts < 1 {
+		p.maxAttempts = 1
+	}
+	return p
+}
+
+// Handle registers the handler for jobs of kind
+func (p *Pool) Handle(kind string, handler Handler) {
+	p.handlers[kind] = handler
+}
+
+// Handles reports whether a handler is registered for jobs of kind
+func (p *Pool) Handles(kind string) bool {
+	_, ok := p.handlers[kind]
+	return ok
+}
+
+// Enqueue adds a job to the queue and wakes an idle worker
+func (p *Pool) Enqueue(ctx context.Context, kind string, bookmarkID int64) (*models.Job, error) {
+	job := &models.Job{
+		Kind:        kind,
+		BookmarkID:  bookmarkID,
+		MaxAttempts: p.maxAttempts,
+	}
+	if err := p.queue.EnqueueJob(ctx, job); err != nil {
+		return nil, err
+	}
+
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+// Job retrieves a job by ID
+func (p *Pool) Job(ctx context.Context, id int64) (*models.Job, error) {
+	return p.queue.GetJob(ctx, id)
+}
+
+// Start starts the workers
+func (p *Pool) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	for i := 0; i < p.workers; i++ {
+		p.wg.Add(1)
+		go p.work(ctx)
+	}
+}
+
+// Stop cancels running jobs and waits for the workers to exit. Cancelled
+// jobs are queued again and resume after a restart.
+func (p *Pool) Stop() {
+	if p.cancel == nil {
+		return
+	}
+	p.cancel()
+	p.wg.Wait()
+}
+
+// work claims and runs jobs until ctx is cancelled
+func (p *Pool) work(ctx context.Context) {
+	defer p.wg.Done()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		case <-p.wake:
+		}
+
+		// Drain the queue before waiting again
+		for ctx.Err() == nil {
+			job, err := p.queue.ClaimJob(ctx, p.lease)
+			if err != nil {
+				if !errors.Is(err, storage.ErrNoJob) && ctx.Err() == nil {
+					log.Printf("Failed to claim job: %v", err)
+				}
+				break
+			}
+			p.run(ctx, job)
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(p.pollInterval)
+	}
+}
+
+// run runs a claimed job and records its outcome
+func (p *Pool) run(ctx context.Context, job *models.Job) {
+	// Bookkeeping must succeed even while the pool is stopping
+	record := context.Background()
+
+	err := p.call(ctx, job)
+	if err == nil {
+		if err := p.queue.CompleteJob(record, job.ID, job.Attempts); err != nil {
+			log.Printf("Failed to complete job %d: %v", job.ID, err)
+		}
+		return
+	}
+
+	if ctx.Err() != nil {
+		// Interrupted by shutdown rather than failed; run again right after a
+		// restart without using up an attempt
+		if err := p.queue.ReleaseJob(record, job.ID, job.Attempts); err != nil {
+			log.Printf("Failed to release job %d: %v", job.ID, err)
+		}
+		return
+	}
+
+	log.Printf("Job %d (%s) attempt %d/%d failed: %v", job.ID, job.Kind, job.Attempts, job.MaxAttempts, err)
+	retryAt := time.Now().Add(p.retryDelay(job.Attempts))
+	if err := p.queue.FailJob(record, job.ID, job.Attempts, err.Error(), retryAt); err != nil {
+		log.Printf("Failed to record failure of job %d: %v", job.ID, err)
+	}
+}
+
+// call runs the handler of job within its lease, turning panics into errors
+func (p *Pool) call(ctx context.Context, job *models.Job) (err error) {
+	handler, ok := p.handlers[job.Kind]
+	if !ok {
+		return fmt.Errorf("no handler for job kind %q", job.Kind)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.lease)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// retryDelay returns the wait after the given failed attempt
+func (p *Pool) retryDelay(attempt int) time.Duration {
+	if len(p.retryDelays) == 0 {
+		return 0
+	}
+	if attempt > len(p.retryDelays) {
+		attempt = len(p.retryDelays)
+	}
+	if attempt < 1 {
+		attempt = 1
+	}
+	return p.retryDelays[attempt-1]
+}
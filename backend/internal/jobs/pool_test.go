@@ -0,0 +1,129 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"bookmarks-go/internal/models"
+	"bookmarks-go/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitForStatus polls the queue until the job reaches status
+func waitForStatus(t *testing.T, pool *Pool, id int64, status string) *models.Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := pool.Job(context.Background(), id)
+		require.NoError(t, err)
+		if job.Status == status {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %d is %s, want %s", id, job.Status, status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestPoolRunsJobs(t *testing.T) {
+	pool := NewPool(storage.NewMemoryJobQueue(), WithWorkers(2), WithPollInterval(time.Hour))
+
+	var ran int32
+	pool.Handle("test", func(ctx context.Context, job *models.Job) error {
+		assert.Equal(t, int64(42), job.BookmarkID)
+		atomic.AddInt32(&ran, 1)
+		return nil
+	})
+	pool.Start()
+	defer pool.Stop()
+
+	// Enqueueing wakes a worker without waiting for the poll interval
+	job, err := pool.Enqueue(context.Background(), "test", 42)
+	require.NoError(t, err)
+
+	done := waitForStatus(t, pool, job.ID, models.JobSucceeded)
+	assert.Equal(t, 1, done.Attempts)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&ran))
+}
+
+func TestPoolRetriesUntilDead(t *testing.T) {
+	pool := NewPool(
+		storage.NewMemoryJobQueue(),
+		WithMaxAttempts(3),
+		WithPollInterval(time.Millisecond),
+		WithRetryDelays(time.Millisecond),
+	)
+
+	var attempts int32
+	pool.Handle("test", func(ctx context.Context, job *models.Job) error {
+		atomic.AddInt32(&attempts, 1)
+		return errors.New("unreachable")
+	})
+	pool.Start()
+	defer pool.Stop()
+
+	job, err := pool.Enqueue(context.Background(), "test", 1)
+	require.NoError(t, err)
+
+	dead := waitForStatus(t, pool, job.ID, models.JobDead)
+	assert.Equal(t, 3, dead.Attempts)
+	assert.Equal(t, "unreachable", dead.LastError)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+}
+
+func TestPoolRecoversPanicsAndUnknownKinds(t *testing.T) {
+	pool := NewPool(storage.NewMemoryJobQueue(), WithMaxAttempts(1), WithPollInterval(time.Millisecond))
+	pool.Handle("panics", func(ctx context.Context, job *models.Job) error {
+		panic("boom")
+	})
+	pool.Start()
+	defer pool.Stop()
+
+	panicked, err := pool.Enqueue(context.Background(), "panics", 1)
+	require.NoError(t, err)
+	unknown, err := pool.Enqueue(context.Background(), "unknown", 1)
+	require.NoError(t, err)
+
+	job := waitForStatus(t, pool, panicked.ID, models.JobDead)
+	assert.Contains(t, job.LastError, "boom")
+	job = waitForStatus(t, pool, unknown.ID, models.JobDead)
+	assert.Contains(t, job.LastError, "no handler")
+}
+
+func TestPoolStopRequeuesRunningJobs(t *testing.T) {
+	pool := NewPool(storage.NewMemoryJobQueue(), WithRetryDelays(time.Hour), WithMaxAttempts(1))
+
+	started := make(chan struct{})
+	pool.Handle("test", func(ctx context.Context, job *models.Job) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	pool.Start()
+
+	job, err := pool.Enqueue(context.Background(), "test", 1)
+	require.NoError(t, err)
+	<-started
+	pool.Stop()
+
+	// The interrupted job is due again immediately rather than after the retry
+	// delay, and its last attempt is not used up
+	stopped, err := pool.Job(context.Background(), job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobQueued, stopped.Status)
+	assert.Zero(t, stopped.Attempts)
+	assert.False(t, stopped.RunAt.After(time.Now()))
+}
+
+func TestRetryDelay(t *testing.T) {
+	pool := NewPool(storage.NewMemoryJobQueue(), WithRetryDelays(time.Second, time.Minute))
+	assert.Equal(t, time.Second, pool.retryDelay(1))
+	assert.Equal(t, time.Minute, pool.retryDelay(2))
+	assert.Equal(t, time.Minute, pool.retryDelay(5))
+}
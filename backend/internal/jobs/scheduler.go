@@ -0,0 +1,173 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"bookmarks-go/internal/models"
+	"bookmarks-go/internal/storage"
+)
+
+const (
+	// DefaultRefreshInterval is how often the scheduler looks for stale bookmarks
+	DefaultRefreshInterval = time.Hour
+
+	// DefaultRefreshConcurrency is how many refresh jobs may be outstanding at once
+	DefaultRefreshConcurrency = 2
+
+	// DefaultRefreshTopUpInterval is how often the scheduler replaces finished
+	// refresh jobs while more bookmarks are stale than it may refresh at once
+	DefaultRefreshTopUpInterval = 10 * time.Second
+)
+
+// Scheduler periodically enqueues refresh jobs for bookmarks whose metadata is
+// older than a maximum age, most urgent first. Only a limited number of refresh
+// jobs is outstanding at any time, so refreshes never crowd out new bookmarks;
+// while a backlog remains, finished jobs are replaced every top-up interval.
+type Scheduler struct {
+	pool          *Pool
+	repo          storage.Repository
+	maxAge        time.Duration
+	interval      time.Duration
+	topUpInterval time.Duration
+	concurrency   int
+
+	// outstanding maps bookmark IDs to their unfinished refresh jobs
+	outstanding map[int64]int64
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// SchedulerOption configures a Scheduler
+type SchedulerOption func(*Scheduler)
+
+// WithRefreshInterval sets how often the scheduler looks for stale bookmarks
+func WithRefreshInterval(d time.Duration) SchedulerOption {
+	return func(s *Scheduler) {
+		s.interval = d
+	}
+}
+
+// WithRefreshTopUpInterval sets how often finished refresh jobs are replaced
+// while stale bookmarks remain
+func WithRefreshTopUpInterval(d time.Duration) SchedulerOption {
+	return func(s *Scheduler) {
+		s.topUpInterval = d
+	}
+}
+
+// WithRefreshConcurrency sets how many refresh jobs may be outstanding at once
+func WithRefreshConcurrency(n int) SchedulerOption {
+	return func(s *Scheduler) {
+		s.concurrency = n
+	}
+}
+
+// NewScheduler creates a scheduler refreshing bookmarks older than maxAge
+// through JobRefreshMetadata jobs run by pool
+func NewScheduler(pool *Pool, repo storage.Repository, maxAge time.Duration, opts ...SchedulerOption) *Scheduler {
+	s := &Scheduler{
+		pool:          pool,
+		repo:          repo,
+		maxAge:        maxAge,
+		interval:      DefaultRefreshInterval,
+		topUpInterval: DefaultRefreshTopUpInterval,
+		concurrency:   DefaultRefreshConcurrency,
+		outstanding:   make(map[int64]int64),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.concurrency < 1 {
+		s.concurrency = 1
+	}
+	return s
+}
+
+// Start starts scheduling refreshes
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.wg.Add(1)
+	go s.run(ctx)
+}
+
+// Stop stops scheduling refreshes. Jobs already enqueued still run.
+func (s *Scheduler) Stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	s.wg.Wait()
+}
+
+// run schedules refreshes every interval, or every top-up interval while
+// behind, until ctx is cancelled
+func (s *Scheduler) run(ctx context.Context) {
+	defer s.wg.Done()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		wait := s.interval
+		if s.schedule(ctx) && s.topUpInterval < wait {
+			wait = s.topUpInterval
+		}
+		timer.Reset(wait)
+	}
+}
+
+// schedule enqueues refresh jobs for the most urgent stale bookmarks, up to
+// the concurrency limit. It reports whether more bookmarks may be stale than
+// are outstanding now.
+func (s *Scheduler) schedule(ctx context.Context) bool {
+	// Forget jobs that have finished
+	for bookmarkID, jobID := range s.outstanding {
+		job, err := s.pool.Job(ctx, jobID)
+		if err == storage.ErrNotFound || (err == nil && (job.Status == models.JobSucceeded || job.Status == models.JobDead)) {
+			delete(s.outstanding, bookmarkID)
+		}
+	}
+
+	free := s.concurrency - len(s.outstanding)
+	if free <= 0 {
+		return true
+	}
+
+	// Outstanding bookmarks are still stale, so fetch enough to skip them
+	limit := free + len(s.outstanding)
+	stale, err := s.repo.ListStaleBookmarks(ctx, time.Now().Add(-s.maxAge), limit)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Failed to list stale bookmarks: %v", err)
+		}
+		return false
+	}
+
+	for _, bookmark := range stale {
+		if free == 0 {
+			break
+		}
+		if _, ok := s.outstanding[bookmark.ID]; ok {
+			continue
+		}
+		job, err := s.pool.Enqueue(ctx, models.JobRefreshMetadata, bookmark.ID)
+		if err != nil {
+			log.Printf("Failed to enqueue refresh of bookmark %d: %v", bookmark.ID, err)
+			return false
+		}
+		s.outstanding[bookmark.ID] = job.ID
+		free--
+	}
+	return len(stale) == limit
+}
@@ -0,0 +1,122 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"bookmarks-go/internal/models"
+	"bookmarks-go/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staleRepository serves a fixed list of stale bookmarks
+type staleRepository struct {
+	storage.Repository
+	stale  []models.Bookmark
+	before time.Time
+}
+
+func (r *staleRepository) ListStaleBookmarks(ctx context.Context, before time.Time, limit int) ([]models.Bookmark, error) {
+	r.before = before
+	if limit > len(r.stale) {
+		limit = len(r.stale)
+	}
+	return r.stale[:limit], nil
+}
+
+// queuedBookmarks returns the bookmark IDs of all refresh jobs in queue
+func queuedBookmarks(t *testing.T, queue storage.JobQueue) []int64 {
+	var ids []int64
+	for id := int64(1); ; id++ {
+		job, err := queue.GetJob(context.Background(), id)
+		if err == storage.ErrNotFound {
+			return ids
+		}
+		require.NoError(t, err)
+		assert.Equal(t, models.JobRefreshMetadata, job.Kind)
+		ids = append(ids, job.BookmarkID)
+	}
+}
+
+func TestSchedulerLimitsOutstandingRefreshes(t *testing.T) {
+	queue := storage.NewMemoryJobQueue()
+	pool := NewPool(queue)
+	repo := &staleRepository{stale: []models.Bookmark{{ID: 3}, {ID: 1}, {ID: 2}}}
+	scheduler := NewScheduler(pool, repo, 24*time.Hour, WithRefreshConcurrency(2))
+
+	// The most urgent bookmarks are enqueued first, up to the limit
+	scheduler.schedule(context.Background())
+	assert.Equal(t, []int64{3, 1}, queuedBookmarks(t, queue))
+	assert.WithinDuration(t, time.Now().Add(-24*time.Hour), repo.before, time.Minute)
+
+	// Nothing more is enqueued while both jobs are outstanding
+	scheduler.schedule(context.Background())
+	assert.Equal(t, []int64{3, 1}, queuedBookmarks(t, queue))
+
+	// A finished job frees a slot for the next bookmark, skipping outstanding ones
+	job, err := queue.ClaimJob(context.Background(), time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, queue.CompleteJob(context.Background(), job.ID, job.Attempts))
+	repo.stale = []models.Bookmark{{ID: 1}, {ID: 2}}
+
+	scheduler.schedule(context.Background())
+	assert.Equal(t, []int64{3, 1, 2}, queuedBookmarks(t, queue))
+}
+
+// drainingRepository lists bookmarks as stale until they are refreshed
+type drainingRepository struct {
+	storage.Repository
+	mu    sync.Mutex
+	stale []models.Bookmark
+}
+
+func (r *drainingRepository) ListStaleBookmarks(ctx context.Context, before time.Time, limit int) ([]models.Bookmark, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if limit > len(r.stale) {
+		limit = len(r.stale)
+	}
+	return append([]models.Bookmark(nil), r.stale[:limit]...), nil
+}
+
+func (r *drainingRepository) refresh(ctx context.Context, job *models.Job) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.stale {
+		if r.stale[i].ID == job.BookmarkID {
+			r.stale = append(r.stale[:i], r.stale[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (r *drainingRepository) remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.stale)
+}
+
+func TestSchedulerDrainsBacklog(t *testing.T) {
+	repo := &drainingRepository{}
+	for id := int64(1); id <= 7; id++ {
+		repo.stale = append(repo.stale, models.Bookmark{ID: id})
+	}
+	pool := NewPool(storage.NewMemoryJobQueue(), WithPollInterval(time.Millisecond))
+	pool.Handle(models.JobRefreshMetadata, repo.refresh)
+	pool.Start()
+	defer pool.Stop()
+
+	// More bookmarks are stale than may be refreshed at once, and the next
+	// regular run is far away
+	scheduler := NewScheduler(pool, repo, 24*time.Hour,
+		WithRefreshConcurrency(2), WithRefreshInterval(time.Hour), WithRefreshTopUpInterval(5*time.Millisecond))
+	scheduler.Start()
+	defer scheduler.Stop()
+
+	assert.Eventually(t, func() bool { return repo.remaining() == 0 }, 5*time.Second, 5*time.Millisecond)
+}
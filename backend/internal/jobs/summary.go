@@ -0,0 +1,63 @@
+package jobs
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"bookmarks-go/internal/ai"
+	"bookmarks-go/internal/models"
+	"bookmarks-go/internal/storage"
+)
+
+// BookmarkSummarizer runs JobSummarize jobs, summarizing the extracted content
+// of bookmarks with a language model
+type BookmarkSummarizer struct {
+	repo       storage.Repository
+	summarizer ai.Summarizer
+}
+
+// NewBookmarkSummarizer registers summarizing with pool
+func NewBookmarkSummarizer(pool *Pool, repo storage.Repository, summarizer ai.Summarizer) *BookmarkSummarizer {
+	s := &BookmarkSummarizer{
+		repo:       repo,
+		summarizer: summarizer,
+	}
+	pool.Handle(models.JobSummarize, s.Run)
+	return s
+}
+
+// Run summarizes the content of the job's bookmark. Bookmarks without
+// extracted content are skipped.
+func (s *BookmarkSummarizer) Run(ctx context.Context, job *models.Job) error {
+	bookmark, err := s.repo.GetBookmark(ctx, job.BookmarkID)
+	if err == storage.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	content, err := s.repo.GetContent(ctx, bookmark.ID)
+	if err == storage.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(content.Text) == "" {
+		return nil
+	}
+
+	summary, err := s.summarizer.Summarize(ctx, bookmark.Title, content.Text)
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.SetSummary(ctx, bookmark.ID, summary, time.Now().UTC())
+	if err == storage.ErrNotFound {
+		// Deleted while summarizing
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,83 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"bookmarks-go/internal/models"
+	"bookmarks-go/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// summaryRepository serves bookmarks and their content and records summaries
+type summaryRepository struct {
+	storage.Repository
+	bookmarks map[int64]*models.Bookmark
+	contents  map[int64]*models.BookmarkContent
+	summaries map[int64]string
+}
+
+func (r *summaryRepository) GetBookmark(ctx context.Context, id int64) (*models.Bookmark, error) {
+	bookmark, ok := r.bookmarks[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return bookmark, nil
+}
+
+func (r *summaryRepository) GetContent(ctx context.Context, bookmarkID int64) (*models.BookmarkContent, error) {
+	content, ok := r.contents[bookmarkID]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return content, nil
+}
+
+func (r *summaryRepository) SetSummary(ctx context.Context, id int64, summary string, at time.Time) error {
+	r.summaries[id] = summary
+	return nil
+}
+
+// stubSummarizer returns the title and text it was given, or err
+type stubSummarizer struct {
+	err error
+}
+
+func (s stubSummarizer) Summarize(ctx context.Context, title, text string) (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	return title + ": " + text, nil
+}
+
+func TestBookmarkSummarizer(t *testing.T) {
+	repo := &summaryRepository{
+		bookmarks: map[int64]*models.Bookmark{
+			1: {ID: 1, Title: "Article"},
+			2: {ID: 2, Title: "Image"},
+		},
+		contents: map[int64]*models.BookmarkContent{
+			1: {BookmarkID: 1, Text: "Body text"},
+		},
+		summaries: make(map[int64]string),
+	}
+	pool := NewPool(storage.NewMemoryJobQueue())
+	s := NewBookmarkSummarizer(pool, repo, stubSummarizer{})
+	assert.True(t, pool.Handles(models.JobSummarize))
+
+	require.NoError(t, s.Run(context.Background(), &models.Job{BookmarkID: 1}))
+	assert.Equal(t, "Article: Body text", repo.summaries[1])
+
+	// Bookmarks without content or deleted ones have nothing to summarize
+	require.NoError(t, s.Run(context.Background(), &models.Job{BookmarkID: 2}))
+	require.NoError(t, s.Run(context.Background(), &models.Job{BookmarkID: 3}))
+	assert.Len(t, repo.summaries, 1)
+
+	// Failures are retried by the pool
+	failing := &BookmarkSummarizer{repo: repo, summarizer: stubSummarizer{err: errors.New("rate limited")}}
+	assert.Error(t, failing.Run(context.Background(), &models.Job{BookmarkID: 1}))
+}
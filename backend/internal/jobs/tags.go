@@ -0,0 +1,93 @@
+package jobs
+
+import (
+	"context"
+	"strings"
+
+	"bookmarks-go/internal/ai"
+	"bookmarks-go/internal/models"
+	"bookmarks-go/internal/storage"
+)
+
+// TagSuggester runs JobSuggestTags jobs, asking a language model for tags of
+// bookmarks from the tags already in use
+type TagSuggester struct {
+	repo      storage.Repository
+	tagger    ai.Tagger
+	autoApply bool
+}
+
+// NewTagSuggester registers tag suggestions with pool. With autoApply,
+// suggested tags are added to bookmarks instead of awaiting review.
+func NewTagSuggester(pool *Pool, repo storage.Repository, tagger ai.Tagger, autoApply bool) *TagSuggester {
+	s := &TagSuggester{
+		repo:      repo,
+		tagger:    tagger,
+		autoApply: autoApply,
+	}
+	pool.Handle(models.JobSuggestTags, s.Run)
+	return s
+}
+
+// Run suggests tags for the job's bookmark from its extracted content, or its
+// description if it has none. Tags the bookmark has or that were suggested
+// for it before are skipped.
+func (s *TagSuggester) Run(ctx context.Context, job *models.Job) error {
+	bookmark, err := s.repo.GetBookmark(ctx, job.BookmarkID)
+	if err == storage.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	text := bookmark.Description
+	content, err := s.repo.GetContent(ctx, bookmark.ID)
+	if err == nil && strings.TrimSpace(content.Text) != "" {
+		text = content.Text
+	} else if err != nil && err != storage.ErrNotFound {
+		return err
+	}
+	if strings.TrimSpace(bookmark.Title) == "" && strings.TrimSpace(text) == "" {
+		return nil
+	}
+
+	counts, err := s.repo.ListTags(ctx)
+	if err != nil {
+		return err
+	}
+	vocabulary := make([]string, len(counts))
+	for i, count := range counts {
+		vocabulary[i] = count.Tag
+	}
+
+	suggested, err := s.tagger.SuggestTags(ctx, bookmark.Title, text, vocabulary)
+	if err != nil {
+		return err
+	}
+
+	previous, err := s.repo.ListTagSuggestions(ctx, bookmark.ID)
+	if err != nil {
+		return err
+	}
+	seen := append(models.Tags(nil), bookmark.Tags...)
+	for _, suggestion := range previous {
+		seen = seen.Add(suggestion.Tag)
+	}
+	var tags []string
+	for _, tag := range suggested {
+		if !seen.Has(tag) {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+
+	err = s.repo.AddTagSuggestions(ctx, bookmark.ID, tags, s.autoApply)
+	if err == storage.ErrNotFound {
+		// Deleted while suggesting
+		return nil
+	}
+	return err
+}
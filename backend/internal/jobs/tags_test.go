@@ -0,0 +1,92 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+
+	"bookmarks-go/internal/models"
+	"bookmarks-go/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tagRepository serves bookmarks, their content and tags and records suggestions
+type tagRepository struct {
+	summaryRepository
+	tags        []models.TagCount
+	suggestions map[int64][]models.TagSuggestion
+	applied     map[int64]bool
+}
+
+func (r *tagRepository) ListTags(ctx context.Context) ([]models.TagCount, error) {
+	return r.tags, nil
+}
+
+func (r *tagRepository) ListTagSuggestions(ctx context.Context, bookmarkID int64) ([]models.TagSuggestion, error) {
+	return r.suggestions[bookmarkID], nil
+}
+
+func (r *tagRepository) AddTagSuggestions(ctx context.Context, bookmarkID int64, tags []string, apply bool) error {
+	for _, tag := range tags {
+		r.suggestions[bookmarkID] = append(r.suggestions[bookmarkID], models.TagSuggestion{BookmarkID: bookmarkID, Tag: tag})
+	}
+	r.applied[bookmarkID] = apply
+	return nil
+}
+
+// stubTagger suggests fixed tags and records the input it was given
+type stubTagger struct {
+	tags       []string
+	text       string
+	vocabulary []string
+}
+
+func (s *stubTagger) SuggestTags(ctx context.Context, title, text string, vocabulary []string) ([]string, error) {
+	s.text, s.vocabulary = text, vocabulary
+	return s.tags, nil
+}
+
+func TestTagSuggester(t *testing.T) {
+	repo := &tagRepository{
+		summaryRepository: summaryRepository{
+			bookmarks: map[int64]*models.Bookmark{
+				1: {ID: 1, Title: "Article", Tags: models.Tags{"go"}},
+				2: {ID: 2, Title: "Image", Description: "A photo"},
+				3: {ID: 3},
+			},
+			contents: map[int64]*models.BookmarkContent{
+				1: {BookmarkID: 1, Text: "Body text"},
+			},
+		},
+		tags: []models.TagCount{{Tag: "go", Count: 3}, {Tag: "photos", Count: 1}},
+		suggestions: map[int64][]models.TagSuggestion{
+			1: {{BookmarkID: 1, Tag: "rust", Status: models.TagSuggestionRejected}},
+		},
+		applied: make(map[int64]bool),
+	}
+	pool := NewPool(storage.NewMemoryJobQueue())
+	tagger := &stubTagger{tags: []string{"go", "rust", "databases"}}
+	s := NewTagSuggester(pool, repo, tagger, false)
+	assert.True(t, pool.Handles(models.JobSuggestTags))
+
+	require.NoError(t, s.Run(context.Background(), &models.Job{BookmarkID: 1}))
+	assert.Equal(t, "Body text", tagger.text)
+	assert.Equal(t, []string{"go", "photos"}, tagger.vocabulary)
+	// Tags the bookmark has and rejected ones are not suggested again
+	require.Len(t, repo.suggestions[1], 2)
+	assert.Equal(t, "databases", repo.suggestions[1][1].Tag)
+	assert.False(t, repo.applied[1])
+
+	// Without content the description is tagged
+	auto := NewTagSuggester(NewPool(storage.NewMemoryJobQueue()), repo, tagger, true)
+	require.NoError(t, auto.Run(context.Background(), &models.Job{BookmarkID: 2}))
+	assert.Equal(t, "A photo", tagger.text)
+	assert.Len(t, repo.suggestions[2], 3)
+	assert.True(t, repo.applied[2])
+
+	// Empty and deleted bookmarks have nothing to tag
+	require.NoError(t, s.Run(context.Background(), &models.Job{BookmarkID: 3}))
+	require.NoError(t, s.Run(context.Background(), &models.Job{BookmarkID: 4}))
+	assert.Empty(t, repo.suggestions[3])
+}
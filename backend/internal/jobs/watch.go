@@ -0,0 +1,163 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"bookmarks-go/internal/diff"
+	"bookmarks-go/internal/models"
+	"bookmarks-go/internal/scraper"
+	"bookmarks-go/internal/storage"
+)
+
+const (
+	// DefaultWatchInterval is how often each watched page is checked for changes
+	DefaultWatchInterval = 6 * time.Hour
+
+	// DefaultWatchConcurrency is how many watched pages are fetched at once
+	DefaultWatchConcurrency = 2
+
+	// watchBatchSize is how many watched bookmarks are loaded per round of checks
+	watchBatchSize = 100
+
+	// watchPollInterval is how long the watcher waits when no bookmark is due
+	watchPollInterval = 5 * time.Minute
+
+	// watchTimeFormat labels the versions compared in a diff
+	watchTimeFormat = "2006-01-02 15:04:05 MST"
+)
+
+// Watcher periodically fetches the pages of watched bookmarks and compares
+// their main content text with the text seen last, recording a unified diff
+// whenever it changed
+type Watcher struct {
+	repo        storage.Repository
+	scraper     *scraper.Scraper
+	interval    time.Duration
+	concurrency int
+	now         func() time.Time
+
+	runner *batchRunner
+}
+
+// WatcherOption configures a Watcher
+type WatcherOption func(*Watcher)
+
+// WithWatchInterval sets how often each watched page is checked for changes
+func WithWatchInterval(d time.Duration) WatcherOption {
+	return func(w *Watcher) {
+		w.interval = d
+	}
+}
+
+// WithWatchConcurrency sets how many watched pages are fetched at once
+func WithWatchConcurrency(n int) WatcherOption {
+	return func(w *Watcher) {
+		w.concurrency = n
+	}
+}
+
+// NewWatcher creates a watcher fetching with s, which must extract content
+func NewWatcher(repo storage.Repository, s *scraper.Scraper, opts ...WatcherOption) *Watcher {
+	w := &Watcher{
+		repo:        repo,
+		scraper:     s,
+		interval:    DefaultWatchInterval,
+		concurrency: DefaultWatchConcurrency,
+		now:         time.Now,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	if w.concurrency < 1 {
+		w.concurrency = 1
+	}
+	w.runner = &batchRunner{
+		what: "watched bookmarks to check",
+		due: func(ctx context.Context, limit int) ([]models.Bookmark, error) {
+			return w.repo.ListWatchedToCheck(ctx, w.now().Add(-w.interval), limit)
+		},
+		handle:       w.check,
+		batchSize:    watchBatchSize,
+		pollInterval: watchPollInterval,
+		minInterval:  batchMinInterval,
+		concurrency:  w.concurrency,
+	}
+	return w
+}
+
+// Start starts checking watched pages
+func (w *Watcher) Start() {
+	w.runner.start()
+}
+
+// Stop stops checking watched pages and waits for running checks to finish
+func (w *Watcher) Stop() {
+	w.runner.stop()
+}
+
+// check fetches the page of one bookmark and records whether its text changed.
+// The first check only stores the text to compare later checks with.
+func (w *Watcher) check(ctx context.Context, bookmark *models.Bookmark) {
+	checkedAt := w.now()
+	metadata, err := w.scraper.GetMetadata(scraper.BackgroundContext(ctx), bookmark.URL)
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+
+	// Pages that cannot be fetched or have no content keep their last text, so
+	// that an outage does not show up as the whole page being removed
+	var text *models.PageText
+	var change *models.PageChange
+	if err == nil && metadata.Content != nil {
+		text = &models.PageText{
+			BookmarkID: bookmark.ID,
+			Text:       strings.Join(diff.Lines(metadata.Content.Text), "\n"),
+			FetchedAt:  checkedAt,
+		}
+		change, err = w.compare(ctx, bookmark, text)
+		if err != nil {
+			// The check is still recorded below, keeping the last text for the next one
+			log.Printf("Failed to compare page of bookmark %d: %v", bookmark.ID, err)
+			text = nil
+		}
+	}
+
+	// Record the check even without a text so the bookmark does not stay first in line
+	if err := w.repo.RecordPageCheck(ctx, bookmark.ID, checkedAt, text, change); err != nil {
+		if err != storage.ErrNotFound {
+			log.Printf("Failed to record page check of bookmark %d: %v", bookmark.ID, err)
+		}
+	}
+}
+
+// compare returns the change from the last seen text of a bookmark to text,
+// or nil if there is no earlier text or it is unchanged
+func (w *Watcher) compare(ctx context.Context, bookmark *models.Bookmark, text *models.PageText) (*models.PageChange, error) {
+	previous, err := w.repo.GetPageText(ctx, bookmark.ID)
+	if err == storage.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := diff.Unified(
+		diff.Lines(previous.Text),
+		diff.Lines(text.Text),
+		bookmark.URL+"\t"+previous.FetchedAt.UTC().Format(watchTimeFormat),
+		bookmark.URL+"\t"+text.FetchedAt.UTC().Format(watchTimeFormat),
+	)
+	if result.Text == "" {
+		return nil, nil
+	}
+	return &models.PageChange{
+		BookmarkID: bookmark.ID,
+		DetectedAt: text.FetchedAt,
+		Diff:       result.Text,
+		Added:      result.Added,
+		Removed:    result.Removed,
+	}, nil
+}
@@ -0,0 +1,167 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"bookmarks-go/internal/models"
+	"bookmarks-go/internal/scraper"
+	"bookmarks-go/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// watchRepository keeps page texts and changes of watched bookmarks in memory
+type watchRepository struct {
+	storage.Repository
+	due []models.Bookmark
+	// textErr fails loading the last texts
+	textErr error
+
+	mu      sync.Mutex
+	texts   map[int64]models.PageText
+	checked map[int64]time.Time
+	changes []models.PageChange
+}
+
+func (r *watchRepository) ListWatchedToCheck(ctx context.Context, before time.Time, limit int) ([]models.Bookmark, error) {
+	return r.due, nil
+}
+
+func (r *watchRepository) GetPageText(ctx context.Context, bookmarkID int64) (*models.PageText, error) {
+	if r.textErr != nil {
+		return nil, r.textErr
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	text, ok := r.texts[bookmarkID]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return &text, nil
+}
+
+func (r *watchRepository) RecordPageCheck(ctx context.Context, bookmarkID int64, checkedAt time.Time, text *models.PageText, change *models.PageChange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checked[bookmarkID] = checkedAt
+	if text != nil {
+		r.texts[bookmarkID] = *text
+	}
+	if change != nil {
+		r.changes = append(r.changes, *change)
+	}
+	return nil
+}
+
+// article renders a page whose main content is the given paragraphs
+func article(paragraphs ...string) string {
+	var sb strings.Builder
+	sb.WriteString("<html><body><nav>Home</nav><article>")
+	for _, p := range paragraphs {
+		fmt.Fprintf(&sb, "<p>%s</p>", p)
+	}
+	sb.WriteString("</article></body></html>")
+	return sb.String()
+}
+
+func TestWatcherRecordsChanges(t *testing.T) {
+	filler := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 4)
+	var mu sync.Mutex
+	page := article("First paragraph. "+filler, "Second paragraph. "+filler)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path != "/page" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, page)
+	}))
+	defer ts.Close()
+
+	loopback, err := scraper.ParseNetworks("127.0.0.0/8")
+	require.NoError(t, err)
+	s := scraper.NewScraper(5*time.Second, scraper.WithAllowedNetworks(loopback...), scraper.WithContentExtraction())
+
+	repo := &watchRepository{
+		due: []models.Bookmark{
+			{ID: 1, URL: ts.URL + "/page", Watched: true},
+			{ID: 2, URL: ts.URL + "/missing", Watched: true},
+		},
+		texts:   make(map[int64]models.PageText),
+		checked: make(map[int64]time.Time),
+	}
+	watcher := NewWatcher(repo, s)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	watcher.now = func() time.Time { return now }
+
+	// The first check stores the text without a change
+	n := watcher.runner.runDue(context.Background())
+	assert.Equal(t, 2, n)
+	assert.Empty(t, repo.changes)
+	assert.Contains(t, repo.texts[1].Text, "Second paragraph.")
+	assert.Equal(t, now, repo.checked[1])
+
+	// Failed fetches are checked without a text
+	assert.Equal(t, now, repo.checked[2])
+	assert.NotContains(t, repo.texts, int64(2))
+
+	now = now.Add(time.Hour)
+	watcher.runner.runDue(context.Background())
+	assert.Empty(t, repo.changes)
+
+	mu.Lock()
+	page = article("First paragraph. "+filler, "Changed paragraph. "+filler)
+	mu.Unlock()
+	now = now.Add(time.Hour)
+	watcher.runner.runDue(context.Background())
+
+	require.Len(t, repo.changes, 1)
+	change := repo.changes[0]
+	assert.Equal(t, int64(1), change.BookmarkID)
+	assert.Equal(t, now, change.DetectedAt)
+	assert.Equal(t, 1, change.Added)
+	assert.Equal(t, 1, change.Removed)
+	assert.True(t, strings.HasPrefix(change.Diff, "--- "+ts.URL+"/page\t2024-05-01 13:00:00 UTC\n+++ "+ts.URL+"/page\t2024-05-01 14:00:00 UTC\n"))
+	assert.Contains(t, change.Diff, "\n-Second paragraph. ")
+	assert.Contains(t, change.Diff, "\n+Changed paragraph. ")
+	assert.Contains(t, repo.texts[1].Text, "Changed paragraph.")
+}
+
+func TestWatcherRecordsChecksWhenComparingFails(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, article("Paragraph. "+strings.Repeat("The quick brown fox jumps over the lazy dog. ", 4)))
+	}))
+	defer ts.Close()
+
+	loopback, err := scraper.ParseNetworks("127.0.0.0/8")
+	require.NoError(t, err)
+	s := scraper.NewScraper(5*time.Second, scraper.WithAllowedNetworks(loopback...), scraper.WithContentExtraction())
+
+	repo := &watchRepository{
+		due:     []models.Bookmark{{ID: 1, URL: ts.URL + "/page", Watched: true}},
+		textErr: errors.New("connection reset"),
+		texts:   map[int64]models.PageText{1: {BookmarkID: 1, Text: "Old text"}},
+		checked: make(map[int64]time.Time),
+	}
+	watcher := NewWatcher(repo, s)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	watcher.now = func() time.Time { return now }
+
+	// The bookmark leaves the front of the line, and keeps its text for the next check
+	watcher.runner.runDue(context.Background())
+	assert.Equal(t, now, repo.checked[1])
+	assert.Equal(t, "Old text", repo.texts[1].Text)
+	assert.Empty(t, repo.changes)
+}
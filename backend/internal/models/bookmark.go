@@ -1,27 +1,219 @@
 package models
 
-import "time"
+import (
+	"database/sql/driver"
+	"time"
+)
 
 // Bookmark represents a stored bookmark with metadata
 type Bookmark struct {
-	ID          int64     `json:"id" db:"id"`
-	URL         string    `json:"url" db:"url"`
-	Title       string    `json:"title" db:"title"`
-	Description string    `json:"description" db:"description"`
-	FaviconURL  string    `json:"favicon_url" db:"favicon_url"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	ID            int64         `json:"id" db:"id"`
+	URL           string        `json:"url" db:"url"`
+	Title         string        `json:"title" db:"title"`
+	Description   string        `json:"description" db:"description"`
+	Author        string        `json:"author" db:"author"`
+	FaviconURL    string        `json:"favicon_url" db:"favicon_url"`
+	Icons         IconList      `json:"icons,omitempty" db:"icons"`
+	ImageURL      string        `json:"image_url" db:"image_url"`
+	FinalURL      string        `json:"final_url" db:"final_url"`
+	Redirects     RedirectChain `json:"redirects,omitempty" db:"redirects"`
+	MediaType     string        `json:"media_type" db:"media_type"`
+	ContentLength int64         `json:"content_length,omitempty" db:"content_length"`
+	PageCount     int           `json:"page_count,omitempty" db:"page_count"`
+	WordCount     int           `json:"word_count" db:"word_count"`
+	ReadingTime   int           `json:"reading_time_minutes" db:"reading_time_minutes"`
+	Extra         *Extra        `json:"extra,omitempty" db:"extra"`
+	ETag          string        `json:"-" db:"etag"`
+	LastModified  string        `json:"-" db:"last_modified"`
+	ContentHash   string        `json:"-" db:"content_hash"`
+	// MetadataStatus is pending while fetching is retried, then ok or failed
+	MetadataStatus string `json:"metadata_status" db:"metadata_status"`
+	MetadataError  string `json:"metadata_error,omitempty" db:"metadata_error"`
+	// RefreshedAt is when metadata was last fetched or found unchanged
+	RefreshedAt time.Time `json:"refreshed_at" db:"refreshed_at"`
+	// LockedFields are edited by the user and kept when metadata is refreshed
+	LockedFields FieldLocks `json:"locked_fields,omitempty" db:"locked_fields"`
+	// Provenance records where the values of the editable fields came from
+	Provenance Provenance `json:"provenance,omitempty" db:"provenance"`
+	// Health is broken after several consecutive failed link checks
+	Health        string     `json:"health" db:"health"`
+	CheckFailures int        `json:"check_failures" db:"check_failures"`
+	LastCheckedAt *time.Time `json:"last_checked_at,omitempty" db:"last_checked_at"`
+	// ArchiveURL is the nearest web archive snapshot, looked up when the link is dead or unreachable
+	ArchiveURL string     `json:"archive_url,omitempty" db:"archive_url"`
+	ArchivedAt *time.Time `json:"archived_at,omitempty" db:"archived_at"`
+	// PageArchivedAt is when the page and its subresources were saved to a WARC file
+	PageArchivedAt *time.Time `json:"page_archived_at,omitempty" db:"page_archived_at"`
+	// Watched bookmarks are checked for changes to their content
+	Watched        bool       `json:"watched" db:"watched"`
+	WatchCheckedAt *time.Time `json:"watch_checked_at,omitempty" db:"watch_checked_at"`
+	// Tags are set by the user or accepted from suggestions
+	Tags Tags `json:"tags,omitempty" db:"tags"`
+	// Summary is generated from the extracted content by a language model
+	Summary      string     `json:"summary,omitempty" db:"summary"`
+	SummarizedAt *time.Time `json:"summarized_at,omitempty" db:"summarized_at"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// Metadata statuses of a bookmark
+const (
+	MetadataPending = "pending"
+	MetadataOK      = "ok"
+	MetadataFailed  = "failed"
+)
+
+// Fields of a bookmark the user may edit, named as in JSON
+const (
+	FieldTitle       = "title"
+	FieldDescription = "description"
+	FieldAuthor      = "author"
+	FieldFaviconURL  = "favicon_url"
+	FieldImageURL    = "image_url"
+)
+
+// FieldPublished is the publication date of a page. It is scraped for
+// debugging but not stored.
+const FieldPublished = "published"
+
+// EditableFields lists the fields that can be edited and locked
+var EditableFields = []string{FieldTitle, FieldDescription, FieldAuthor, FieldFaviconURL, FieldImageURL}
+
+// FieldLocks is the set of locked field names, stored as JSON
+type FieldLocks []string
+
+// Has reports whether field is locked
+func (l FieldLocks) Has(field string) bool {
+	for _, locked := range l {
+		if locked == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Lock returns the locks with field added
+func (l FieldLocks) Lock(field string) FieldLocks {
+	if l.Has(field) {
+		return l
+	}
+	return append(l, field)
+}
+
+// Unlock returns the locks with field removed
+func (l FieldLocks) Unlock(field string) FieldLocks {
+	var kept FieldLocks
+	for _, locked := range l {
+		if locked != field {
+			kept = append(kept, locked)
+		}
+	}
+	return kept
+}
+
+// Value implements driver.Valuer
+func (l FieldLocks) Value() (driver.Value, error) {
+	return jsonValue(l)
+}
+
+// Scan implements sql.Scanner
+func (l *FieldLocks) Scan(src interface{}) error {
+	return scanJSON(src, l)
+}
+
+// Redirect represents a single hop in the redirect chain of a bookmarked URL
+type Redirect struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+	Kind       string `json:"kind"`
+}
+
+// RedirectChain is the ordered list of redirect hops, stored as JSON
+type RedirectChain []Redirect
+
+// Value implements driver.Valuer
+func (c RedirectChain) Value() (driver.Value, error) {
+	return jsonValue(c)
+}
+
+// Scan implements sql.Scanner
+func (c *RedirectChain) Scan(src interface{}) error {
+	return scanJSON(src, c)
+}
+
+// Icon represents a candidate icon declared by a bookmarked page
+type Icon struct {
+	URL     string `json:"url"`
+	Rel     string `json:"rel"`
+	Type    string `json:"type,omitempty"`
+	Sizes   string `json:"sizes,omitempty"`
+	Purpose string `json:"purpose,omitempty"`
+}
+
+// IconList is the list of candidate icons, stored as JSON
+type IconList []Icon
+
+// Value implements driver.Valuer
+func (l IconList) Value() (driver.Value, error) {
+	return jsonValue(l)
+}
+
+// Scan implements sql.Scanner
+func (l *IconList) Scan(src interface{}) error {
+	return scanJSON(src, l)
+}
+
+// BookmarkContent represents the extracted main article body of a bookmark
+type BookmarkContent struct {
+	BookmarkID  int64     `json:"bookmark_id" db:"bookmark_id"`
+	Text        string    `json:"text" db:"text"`
+	HTML        string    `json:"html" db:"html"`
+	WordCount   int       `json:"word_count" db:"word_count"`
+	ReadingTime int       `json:"reading_time_minutes" db:"reading_time_minutes"`
+	ExtractedAt time.Time `json:"extracted_at" db:"extracted_at"`
+}
+
+// BookmarkFilter narrows the bookmarks listed; zero values match everything
+type BookmarkFilter struct {
+	Health string
+	Tag    string
 }
 
 // CreateBookmarkRequest represents the request body for creating a bookmark
 type CreateBookmarkRequest struct {
 	URL string `json:"url"`
+	// Title is kept instead of the scraped title when set, and locked
+	Title string `json:"title,omitempty"`
+}
+
+// UpdateBookmarkRequest represents the request body for editing a bookmark.
+// Fields that are set are stored and locked against refreshes; fields listed
+// in Unlock are overwritten by the next refresh again.
+type UpdateBookmarkRequest struct {
+	Title       *string  `json:"title,omitempty"`
+	Description *string  `json:"description,omitempty"`
+	Author      *string  `json:"author,omitempty"`
+	FaviconURL  *string  `json:"favicon_url,omitempty"`
+	ImageURL    *string  `json:"image_url,omitempty"`
+	Unlock      []string `json:"unlock,omitempty"`
+	// Watched turns change monitoring on or off
+	Watched *bool `json:"watched,omitempty"`
+	// Tags replaces the bookmark's tags
+	Tags *[]string `json:"tags,omitempty"`
 }
 
 // BookmarkResponse represents the response for bookmark endpoints
 type BookmarkResponse struct {
 	Bookmark *Bookmark `json:"bookmark,omitempty"`
-	Error    string    `json:"error,omitempty"`
+	// Job fetches the bookmark's metadata in the background, when it is still pending
+	Job   *Job   `json:"job,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// ContentResponse represents the response for the bookmark content endpoint
+type ContentResponse struct {
+	Content *BookmarkContent `json:"content,omitempty"`
+	Error   string           `json:"error,omitempty"`
 }
 
 // BookmarksResponse represents the response for listing bookmarks
@@ -0,0 +1,69 @@
+package models
+
+import "database/sql/driver"
+
+// Extra holds site-specific metadata filled by the scraper's extractors.
+// At most one of the site fields is set, matching Site.
+type Extra struct {
+	Site       string            `json:"site"`
+	GitHub     *GitHubRepo       `json:"github,omitempty"`
+	YouTube    *YouTubeVideo     `json:"youtube,omitempty"`
+	HackerNews *HackerNewsItem   `json:"hacker_news,omitempty"`
+	Wikipedia  *WikipediaArticle `json:"wikipedia,omitempty"`
+	ArXiv      *ArXivPaper       `json:"arxiv,omitempty"`
+}
+
+// Value implements driver.Valuer
+func (e Extra) Value() (driver.Value, error) {
+	return jsonValue(e)
+}
+
+// Scan implements sql.Scanner
+func (e *Extra) Scan(src interface{}) error {
+	return scanJSON(src, e)
+}
+
+// GitHubRepo describes a GitHub repository
+type GitHubRepo struct {
+	Owner       string `json:"owner"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Stars       int    `json:"stars"`
+	Language    string `json:"language,omitempty"`
+}
+
+// YouTubeVideo describes a YouTube video
+type YouTubeVideo struct {
+	VideoID         string `json:"video_id"`
+	Channel         string `json:"channel,omitempty"`
+	ChannelURL      string `json:"channel_url,omitempty"`
+	DurationSeconds int    `json:"duration_seconds,omitempty"`
+}
+
+// HackerNewsItem describes a Hacker News story or comment thread
+type HackerNewsItem struct {
+	ItemID   int64  `json:"item_id"`
+	Title    string `json:"title,omitempty"`
+	StoryURL string `json:"story_url,omitempty"`
+	Author   string `json:"author,omitempty"`
+	Points   int    `json:"points"`
+	Comments int    `json:"comments"`
+}
+
+// WikipediaArticle describes a Wikipedia article
+type WikipediaArticle struct {
+	Title    string `json:"title"`
+	Language string `json:"language"`
+	Summary  string `json:"summary,omitempty"`
+}
+
+// ArXivPaper describes an arXiv preprint
+type ArXivPaper struct {
+	ID         string   `json:"id"`
+	Title      string   `json:"title"`
+	Authors    []string `json:"authors,omitempty"`
+	Abstract   string   `json:"abstract,omitempty"`
+	Published  string   `json:"published,omitempty"`
+	PDFURL     string   `json:"pdf_url,omitempty"`
+	Categories []string `json:"categories,omitempty"`
+}
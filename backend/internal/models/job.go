@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Job kinds
+const (
+	// JobScrapeMetadata fetches the metadata of a bookmark saved without it
+	JobScrapeMetadata = "scrape_metadata"
+	// JobRefreshMetadata fetches the metadata of a stale bookmark again
+	JobRefreshMetadata = "refresh_metadata"
+	// JobArchiveLookup looks up a web archive snapshot of a dead or unreachable bookmark
+	JobArchiveLookup = "archive_lookup"
+	// JobArchivePage saves a bookmarked page and its subresources to a WARC file
+	JobArchivePage = "archive_page"
+	// JobSummarize generates a summary of the extracted content of a bookmark
+	JobSummarize = "summarize"
+	// JobSuggestTags asks a language model for tags of a newly scraped bookmark
+	JobSuggestTags = "suggest_tags"
+)
+
+// Job statuses. A failed job is queued again until its attempts are used up,
+// after which it is dead and no longer run.
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobDead      = "dead"
+)
+
+// Job represents a unit of background work
+type Job struct {
+	ID          int64  `json:"id" db:"id"`
+	Kind        string `json:"kind" db:"kind"`
+	BookmarkID  int64  `json:"bookmark_id" db:"bookmark_id"`
+	Status      string `json:"status" db:"status"`
+	Attempts    int    `json:"attempts" db:"attempts"`
+	MaxAttempts int    `json:"max_attempts" db:"max_attempts"`
+	LastError   string `json:"last_error,omitempty" db:"last_error"`
+	// RunAt is when a queued job becomes due, or when the lease of a running job expires
+	RunAt     time.Time `json:"run_at" db:"run_at"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// JobResponse represents the response for the job status endpoint
+type JobResponse struct {
+	Job   *Job   `json:"job,omitempty"`
+	Error string `json:"error,omitempty"`
+}
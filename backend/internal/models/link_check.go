@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Link health of a bookmark
+const (
+	HealthUnknown = "unknown"
+	HealthOK      = "ok"
+	HealthBroken  = "broken"
+)
+
+// LinkCheck records one check of whether a bookmarked URL still works
+type LinkCheck struct {
+	ID         int64     `json:"id" db:"id"`
+	BookmarkID int64     `json:"bookmark_id" db:"bookmark_id"`
+	CheckedAt  time.Time `json:"checked_at" db:"checked_at"`
+	OK         bool      `json:"ok" db:"ok"`
+	// StatusCode is 0 when no response was received
+	StatusCode int    `json:"status_code" db:"status_code"`
+	FinalURL   string `json:"final_url,omitempty" db:"final_url"`
+	LatencyMS  int64  `json:"latency_ms" db:"latency_ms"`
+	Error      string `json:"error,omitempty" db:"error"`
+	TLSError   string `json:"tls_error,omitempty" db:"tls_error"`
+	// Inconclusive checks, e.g. disallowed by robots.txt, do not change the bookmark's health
+	Inconclusive bool `json:"inconclusive,omitempty" db:"inconclusive"`
+}
+
+// LinkChecksResponse represents the response for the link check history endpoint
+type LinkChecksResponse struct {
+	Checks []LinkCheck `json:"checks"`
+	Error  string      `json:"error,omitempty"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// EventChanged is the type of events raised when a watched page changes
+const EventChanged = "changed"
+
+// PageText is the content text of a watched bookmark as last fetched
+type PageText struct {
+	BookmarkID int64     `json:"bookmark_id" db:"bookmark_id"`
+	Text       string    `json:"text" db:"text"`
+	FetchedAt  time.Time `json:"fetched_at" db:"fetched_at"`
+}
+
+// PageChange records a change to the content text of a watched bookmark
+type PageChange struct {
+	ID         int64     `json:"id" db:"id"`
+	BookmarkID int64     `json:"bookmark_id" db:"bookmark_id"`
+	DetectedAt time.Time `json:"detected_at" db:"detected_at"`
+	// Diff is a unified diff of the text lines
+	Diff    string `json:"diff" db:"diff"`
+	Added   int    `json:"added" db:"added"`
+	Removed int    `json:"removed" db:"removed"`
+}
+
+// PageChangesResponse represents the response for the change history endpoint
+type PageChangesResponse struct {
+	Changes []PageChange `json:"changes"`
+	Error   string       `json:"error,omitempty"`
+}
+
+// Event is a notification about a bookmark, polled in ID order
+type Event struct {
+	ID         int64     `json:"id"`
+	Type       string    `json:"type"`
+	BookmarkID int64     `json:"bookmark_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	// Change is set for EventChanged
+	Change *PageChange `json:"change,omitempty"`
+}
+
+// EventsResponse represents the response for the events endpoint
+type EventsResponse struct {
+	Events []Event `json:"events"`
+	Error  string  `json:"error,omitempty"`
+}
@@ -0,0 +1,78 @@
+package models
+
+import "database/sql/driver"
+
+// Sources of field values
+const (
+	// SourceHTMLTitle is the <title> element
+	SourceHTMLTitle = "html_title"
+	// SourceOpenGraph is an og: meta tag
+	SourceOpenGraph = "open_graph"
+	// SourceTwitterCard is a twitter: meta tag
+	SourceTwitterCard = "twitter_card"
+	// SourceJSONLD is JSON-LD structured data, named by type and property
+	SourceJSONLD = "json_ld"
+	// SourceMeta is a standard meta tag such as description or author
+	SourceMeta = "meta"
+	// SourceIconLink is an icon declared by a <link> element
+	SourceIconLink = "icon_link"
+	// SourceManifest is an icon listed in the web app manifest
+	SourceManifest = "manifest"
+	// SourceDefaultIcon is /favicon.ico, found without being declared
+	SourceDefaultIcon = "default_icon"
+	// SourceExtractor is a site-specific extractor, named by the rule
+	SourceExtractor = "extractor"
+	// SourcePDF is the document information of a PDF
+	SourcePDF = "pdf"
+	// SourceFileName is the last segment of the URL path
+	SourceFileName = "file_name"
+	// SourceWebArchive is a web archive snapshot of a page that could not be fetched
+	SourceWebArchive = "web_archive"
+	// SourceUser is an edit by the user
+	SourceUser = "user"
+)
+
+// FieldProvenance describes where the value of a field came from and how far
+// it can be trusted, from 0 to 1
+type FieldProvenance struct {
+	Source string `json:"source"`
+	// Rule is the rule that found the value, e.g. meta[property=og:title] or
+	// the name of a site extractor
+	Rule       string  `json:"rule,omitempty"`
+	Confidence float64 `json:"confidence"`
+}
+
+// UserProvenance is the provenance of values edited by the user
+var UserProvenance = FieldProvenance{Source: SourceUser, Confidence: 1}
+
+// Scraped reports whether the value was found on the bookmarked page itself.
+// Values without a recorded source are assumed to be.
+func (p FieldProvenance) Scraped() bool {
+	switch p.Source {
+	case SourceWebArchive, SourceUser:
+		return false
+	}
+	return true
+}
+
+// Provenance maps field names to the provenance of their values, stored as JSON
+type Provenance map[string]FieldProvenance
+
+// Set returns the provenance with field set to fp
+func (p Provenance) Set(field string, fp FieldProvenance) Provenance {
+	if p == nil {
+		p = make(Provenance)
+	}
+	p[field] = fp
+	return p
+}
+
+// Value implements driver.Valuer
+func (p Provenance) Value() (driver.Value, error) {
+	return jsonValue(p)
+}
+
+// Scan implements sql.Scanner
+func (p *Provenance) Scan(src interface{}) error {
+	return scanJSON(src, p)
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"database/sql/driver"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxTagLength is the maximum length of a tag in characters
+const MaxTagLength = 40
+
+// NormalizeTag returns tag in lower case, with surrounding whitespace removed
+// and inner whitespace collapsed to single spaces
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.Join(strings.Fields(tag), " "))
+}
+
+// ValidTag reports whether a normalized tag is neither empty nor too long
+func ValidTag(tag string) bool {
+	return tag != "" && utf8.RuneCountInString(tag) <= MaxTagLength
+}
+
+// Tags is the set of tags of a bookmark, stored as JSON
+type Tags []string
+
+// Has reports whether tag is in the set
+func (t Tags) Has(tag string) bool {
+	for _, existing := range t {
+		if existing == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Add returns the set with tag added
+func (t Tags) Add(tag string) Tags {
+	if t.Has(tag) {
+		return t
+	}
+	return append(t, tag)
+}
+
+// Value implements driver.Valuer
+func (t Tags) Value() (driver.Value, error) {
+	return jsonValue(t)
+}
+
+// Scan implements sql.Scanner
+func (t *Tags) Scan(src interface{}) error {
+	return scanJSON(src, t)
+}
+
+// TagCount is a tag and the number of bookmarks carrying it
+type TagCount struct {
+	Tag   string `json:"tag" db:"tag"`
+	Count int    `json:"count" db:"count"`
+}
+
+// Tag suggestion statuses
+const (
+	TagSuggestionPending  = "pending"
+	TagSuggestionAccepted = "accepted"
+	TagSuggestionRejected = "rejected"
+)
+
+// TagSuggestion is a tag proposed for a bookmark by a language model. Each
+// tag is only suggested once per bookmark, so rejected tags stay rejected.
+type TagSuggestion struct {
+	ID         int64      `json:"id" db:"id"`
+	BookmarkID int64      `json:"bookmark_id" db:"bookmark_id"`
+	Tag        string     `json:"tag" db:"tag"`
+	Status     string     `json:"status" db:"status"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	DecidedAt  *time.Time `json:"decided_at,omitempty" db:"decided_at"`
+}
+
+// TagsResponse represents the response for listing tags
+type TagsResponse struct {
+	Tags  []TagCount `json:"tags"`
+	Error string     `json:"error,omitempty"`
+}
+
+// TagSuggestionsResponse represents the response for listing the tag suggestions of a bookmark
+type TagSuggestionsResponse struct {
+	Suggestions []TagSuggestion `json:"suggestions"`
+	Error       string          `json:"error,omitempty"`
+}
+
+// TagSuggestionResponse represents the response for accepting or rejecting a tag suggestion
+type TagSuggestionResponse struct {
+	Suggestion *TagSuggestion `json:"suggestion,omitempty"`
+	// Bookmark carries the bookmark's tags after the decision
+	Bookmark *Bookmark `json:"bookmark,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
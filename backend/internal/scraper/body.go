@@ -0,0 +1,148 @@
+package scraper
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// DefaultMaxBodySize is the maximum number of response bytes read unless configured otherwise.
+// The limit applies after transparent gzip decompression, so compressed bombs are cut off too.
+const DefaultMaxBodySize = 10 << 20
+
+// sniffLength is the number of bytes inspected when a response has no usable Content-Type
+const sniffLength = 512
+
+// WithMaxBodySize sets the maximum number of response bytes read from a page
+func WithMaxBodySize(n int64) Option {
+	return func(s *Scraper) {
+		s.maxBodySize = n
+	}
+}
+
+// isHTMLType reports whether mediaType should be parsed as an HTML document
+func isHTMLType(mediaType string) bool {
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
+
+// responseMediaType returns the media type of resp without parameters. When the
+// Content-Type header is missing or generic, the first bytes of body are sniffed.
+func responseMediaType(resp *http.Response, body *bufio.Reader) string {
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err == nil && mediaType != "" && mediaType != "application/octet-stream" {
+		return strings.ToLower(mediaType)
+	}
+
+	// Peek returns what is available even if the body is shorter than sniffLength
+	peeked, _ := body.Peek(sniffLength)
+	sniffed, _, err := mime.ParseMediaType(http.DetectContentType(peeked))
+	if err != nil {
+		return "application/octet-stream"
+	}
+	return sniffed
+}
+
+// maxDrainSize is the most unread body bytes discarded so that a connection
+// can be reused; connections with more left are closed instead
+const maxDrainSize = 64 << 10
+
+// closeBody drains what is left of a small response body before closing it,
+// which returns the connection to the idle pool
+func closeBody(body io.ReadCloser) {
+	io.CopyN(io.Discard, body, maxDrainSize)
+	body.Close()
+}
+
+// headElements may appear in a document head; any other start tag begins the body
+var headElements = map[atom.Atom]bool{
+	atom.Html: true, atom.Head: true, atom.Title: true, atom.Meta: true, atom.Link: true,
+	atom.Base: true, atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+}
+
+// parseHead tokenizes r as it streams in and returns a head element holding
+// the title, meta, link and base elements and the JSON-LD scripts of the
+// document head. It stops reading where the body begins, so the rest of the
+// page is never downloaded or parsed. The raw bytes of the tokens read are written to raw.
+func parseHead(r io.Reader, raw io.Writer) (*html.Node, error) {
+	doc := &html.Node{Type: html.DocumentNode}
+	root := &html.Node{Type: html.ElementNode, Data: "html", DataAtom: atom.Html}
+	head := &html.Node{Type: html.ElementNode, Data: "head", DataAtom: atom.Head}
+	doc.AppendChild(root)
+	root.AppendChild(head)
+
+	z := html.NewTokenizer(r)
+	var title, script *html.Node
+	var rawText bool
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if z.Err() == io.EOF {
+				return head, nil
+			}
+			return nil, z.Err()
+		}
+		raw.Write(z.Raw())
+
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			a := atom.Lookup(name)
+			if !headElements[a] {
+				return head, nil
+			}
+			switch a {
+			case atom.Title, atom.Meta, atom.Link, atom.Base, atom.Script:
+				n := &html.Node{Type: html.ElementNode, Data: a.String(), DataAtom: a}
+				for hasAttr {
+					var key, val []byte
+					key, val, hasAttr = z.TagAttr()
+					n.Attr = append(n.Attr, html.Attribute{Key: string(key), Val: string(val)})
+				}
+				if a == atom.Script {
+					// Only scripts holding structured data are kept
+					rawText = tt == html.StartTagToken
+					if rawText && isJSONLD(n) {
+						head.AppendChild(n)
+						script = n
+					}
+					break
+				}
+				head.AppendChild(n)
+				if a == atom.Title && tt == html.StartTagToken {
+					title = n
+				}
+			case atom.Style, atom.Noscript:
+				// The tokenizer returns the markup inside noscript as text, such
+				// as the tracking pixels often found in heads
+				rawText = tt == html.StartTagToken
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch atom.Lookup(name) {
+			case atom.Head, atom.Body, atom.Html:
+				return head, nil
+			case atom.Title:
+				title = nil
+			case atom.Script, atom.Style, atom.Noscript:
+				rawText = false
+				script = nil
+			}
+		case html.TextToken:
+			switch {
+			case title != nil:
+				title.AppendChild(&html.Node{Type: html.TextNode, Data: string(z.Text())})
+			case script != nil:
+				script.AppendChild(&html.Node{Type: html.TextNode, Data: string(z.Text())})
+			case !rawText && len(bytes.TrimSpace(z.Raw())) > 0:
+				// Text outside of head elements begins the body
+				return head, nil
+			}
+		}
+	}
+}
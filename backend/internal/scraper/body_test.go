@@ -0,0 +1,185 @@
+package scraper
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHead(t *testing.T) {
+	doc := `<!DOCTYPE html><html><HEAD><title>A &amp; B</title>
+<meta name="description" content="Desc">
+<script>var s = "<body>";</script>
+<noscript><img height="1" width="1" src="https://tracker.example/pixel.gif"></noscript>
+<link rel="icon" href="/favicon.png">
+</Head><body><meta name="author" content="Body">` + strings.Repeat("x", 10000) + `</body></html>`
+
+	// OneByteReader makes the tokenizer read the document a byte at a time
+	var raw strings.Builder
+	head, err := parseHead(iotest.OneByteReader(strings.NewReader(doc)), &raw)
+	require.NoError(t, err)
+	assert.Equal(t, doc[:strings.Index(doc, "</Head>")+len("</Head>")], raw.String())
+
+	var m Metadata
+	m.extractMetadata(head)
+	assert.Equal(t, "A & B", m.Title)
+	assert.Equal(t, "Desc", m.Description)
+	assert.Empty(t, m.Author)
+	// The icon after the noscript element is still part of the head
+	icons, _ := collectIcons(head, &url.URL{Scheme: "https", Host: "example.com"})
+	require.Len(t, icons, 1)
+	assert.Equal(t, "https://example.com/favicon.png", icons[0].URL)
+
+	// Without a head, the first body content ends it
+	raw.Reset()
+	head, err = parseHead(strings.NewReader(`<meta name="description" content="D">Text<title>Late</title>`), &raw)
+	require.NoError(t, err)
+	m = Metadata{}
+	m.extractMetadata(head)
+	assert.Equal(t, "D", m.Description)
+	assert.Empty(t, m.Title)
+}
+
+func TestGetMetadataMediaTypes(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n" + strings.Repeat("\x00", 100))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/files/photo%20one.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(png)
+	})
+	mux.HandleFunc("/archive.zip", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Length", "5000000")
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/untyped", func(w http.ResponseWriter, r *http.Request) {
+		w.Header()["Content-Type"] = nil
+		w.Write([]byte(`<!DOCTYPE html><html><head><title>Sniffed</title></head></html>`))
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<html><head><title>Large</title></head><body><p>`))
+		w.Write([]byte(strings.Repeat("word ", 100000)))
+		w.Write([]byte(`</p><p>UNREACHABLE</p></body></html>`))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	t.Run("image", func(t *testing.T) {
+		scraper := NewScraper(5*time.Second, allowLoopback)
+
+		metadata, err := scraper.GetMetadata(context.Background(), ts.URL+"/files/photo%20one.png")
+		require.NoError(t, err)
+		assert.Equal(t, "image/png", metadata.MediaType)
+		assert.Equal(t, int64(len(png)), metadata.ContentLength)
+		assert.Equal(t, "photo one.png", metadata.Title)
+	})
+
+	t.Run("archive is not downloaded", func(t *testing.T) {
+		scraper := NewScraper(5*time.Second, allowLoopback)
+
+		metadata, err := scraper.GetMetadata(context.Background(), ts.URL+"/archive.zip")
+		require.NoError(t, err)
+		assert.Equal(t, "application/zip", metadata.MediaType)
+		assert.Equal(t, int64(5000000), metadata.ContentLength)
+		assert.Equal(t, "archive.zip", metadata.Title)
+	})
+
+	t.Run("sniffed html", func(t *testing.T) {
+		scraper := NewScraper(5*time.Second, allowLoopback)
+
+		metadata, err := scraper.GetMetadata(context.Background(), ts.URL+"/untyped")
+		require.NoError(t, err)
+		assert.Equal(t, "text/html", metadata.MediaType)
+		assert.Equal(t, "Sniffed", metadata.Title)
+	})
+
+	t.Run("body size limit", func(t *testing.T) {
+		scraper := NewScraper(5*time.Second, allowLoopback, WithContentExtraction(), WithMaxBodySize(64<<10))
+
+		metadata, err := scraper.GetMetadata(context.Background(), ts.URL+"/large")
+		require.NoError(t, err)
+		assert.Equal(t, "Large", metadata.Title)
+		require.NotNil(t, metadata.Content)
+		assert.NotContains(t, metadata.Content.Text, "UNREACHABLE")
+		assert.LessOrEqual(t, len(metadata.Content.Text), 64<<10)
+	})
+}
+
+func TestFetchResource(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/icon":
+			// No Content-Type, so the type is sniffed
+			w.Header().Set("Content-Type", "")
+			w.Write(png)
+		case "/moved":
+			http.Redirect(w, r, "/icon", http.StatusFound)
+		case "/large":
+			w.Write(make([]byte, 2048))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	s := NewScraper(5*time.Second, allowLoopback)
+	resource, err := s.FetchResource(context.Background(), server.URL+"/moved", 1024)
+	require.NoError(t, err)
+	assert.Equal(t, png, resource.Data)
+	assert.Equal(t, "image/png", resource.MediaType)
+	assert.Equal(t, server.URL+"/icon", resource.URL)
+
+	_, err = s.FetchResource(context.Background(), server.URL+"/large", 1024)
+	assert.ErrorIs(t, err, ErrTooLarge)
+
+	_, err = s.FetchResource(context.Background(), server.URL+"/missing", 1024)
+	assert.Error(t, err)
+
+	_, err = s.FetchResource(context.Background(), "file:///etc/passwd", 1024)
+	assert.Error(t, err)
+}
+
+func TestUnchangedPagesAreNotParsedWhole(t *testing.T) {
+	page := `<html><head><title>Article</title><meta name="description" content="Desc"></head>
+<body><article><p>` + strings.Repeat("Some words of the article. ", 50) + `</p></article></body></html>`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(page))
+	}))
+	defer server.Close()
+
+	// steps returns the steps timed by a trace
+	steps := func(trace *Trace) []string {
+		var names []string
+		for _, timing := range trace.Timings {
+			names = append(names, timing.Step)
+		}
+		return names
+	}
+
+	s := NewScraper(5*time.Second, allowLoopback, WithContentExtraction())
+	var first Trace
+	metadata, err := s.GetMetadata(WithTrace(context.Background(), &first), server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "Article", metadata.Title)
+	assert.Equal(t, "Desc", metadata.Description)
+	require.NotNil(t, metadata.Content)
+	assert.Contains(t, steps(&first), "parse document")
+
+	var second Trace
+	unchanged, err := s.Revalidate(WithTrace(context.Background(), &second), server.URL, metadata.Validators)
+	require.NoError(t, err)
+	assert.True(t, unchanged.NotModified)
+	assert.NotContains(t, steps(&second), "parse document")
+}
@@ -0,0 +1,181 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBreakerThreshold is the number of consecutive failures that opens a host's circuit
+	DefaultBreakerThreshold = 5
+	// DefaultBreakerCooldown is how long an open circuit fails fast before a probe is allowed
+	DefaultBreakerCooldown = 30 * time.Second
+)
+
+// Circuit breaker states
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// ErrCircuitOpen is returned without contacting a host whose circuit breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// WithCircuitBreaker configures the per-host circuit breaker. A threshold of zero disables it.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(s *Scraper) {
+		s.breakers = newBreakerRegistry(threshold, cooldown)
+	}
+}
+
+// BreakerState is a snapshot of a host's circuit breaker
+type BreakerState struct {
+	Host                string     `json:"host"`
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+}
+
+// Stats is a snapshot of the scraper's retry counters and circuit breakers
+type Stats struct {
+	Requests          int64          `json:"requests"`
+	Retries           int64          `json:"retries"`
+	RetriesExhausted  int64          `json:"retries_exhausted"`
+	MaxAttempts       int            `json:"max_attempts"`
+	BreakerThreshold  int            `json:"breaker_threshold"`
+	BreakerCooldownMS int64          `json:"breaker_cooldown_ms"`
+	Breakers          []BreakerState `json:"breakers"`
+}
+
+// Stats returns the current retry counters and the state of every circuit breaker
+// that has seen a failure
+func (s *Scraper) Stats() Stats {
+	return Stats{
+		Requests:          s.retryStats.requests.Load(),
+		Retries:           s.retryStats.retries.Load(),
+		RetriesExhausted:  s.retryStats.exhausted.Load(),
+		MaxAttempts:       s.retry.MaxAttempts,
+		BreakerThreshold:  s.breakers.threshold,
+		BreakerCooldownMS: s.breakers.cooldown.Milliseconds(),
+		Breakers:          s.breakers.snapshot(),
+	}
+}
+
+// breakerRegistry holds a circuit breaker per host
+type breakerRegistry struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	hosts     map[string]*breaker
+	now       func() time.Time
+}
+
+// breaker is the state of a single host's circuit
+type breaker struct {
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// newBreakerRegistry creates a registry that opens a circuit after threshold failures
+func newBreakerRegistry(threshold int, cooldown time.Duration) *breakerRegistry {
+	return &breakerRegistry{
+		threshold: threshold,
+		cooldown:  cooldown,
+		hosts:     make(map[string]*breaker),
+		now:       time.Now,
+	}
+}
+
+// allow returns ErrCircuitOpen if requests to host should fail fast. After the
+// cooldown a single probe request is let through in the half-open state.
+func (r *breakerRegistry) allow(host string) error {
+	if r.threshold <= 0 {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b, ok := r.hosts[strings.ToLower(host)]
+	if !ok || b.failures < r.threshold {
+		return nil
+	}
+	if r.now().Sub(b.openedAt) < r.cooldown || b.probing {
+		return fmt.Errorf("%w for %s", ErrCircuitOpen, host)
+	}
+	b.probing = true
+	return nil
+}
+
+// record updates host's circuit with the outcome of a request
+func (r *breakerRegistry) record(host string, success bool) {
+	if r.threshold <= 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	host = strings.ToLower(host)
+	b, ok := r.hosts[host]
+	if success {
+		if ok {
+			delete(r.hosts, host)
+		}
+		return
+	}
+	if !ok {
+		b = &breaker{}
+		r.hosts[host] = b
+	}
+	b.failures++
+	b.probing = false
+	if b.failures >= r.threshold {
+		// Opening, or re-opening after a failed probe, restarts the cooldown
+		b.openedAt = r.now()
+	}
+}
+
+// release ends a probe of host without recording an outcome, so that the next
+// request may probe again
+func (r *breakerRegistry) release(host string) {
+	if r.threshold <= 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if b, ok := r.hosts[strings.ToLower(host)]; ok {
+		b.probing = false
+	}
+}
+
+// snapshot returns the state of every tracked host, sorted by host
+func (r *breakerRegistry) snapshot() []BreakerState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	states := make([]BreakerState, 0, len(r.hosts))
+	for host, b := range r.hosts {
+		state := BreakerState{
+			Host:                host,
+			State:               BreakerClosed,
+			ConsecutiveFailures: b.failures,
+		}
+		if b.failures >= r.threshold {
+			openedAt := b.openedAt
+			state.OpenedAt = &openedAt
+			state.State = BreakerOpen
+			if b.probing || r.now().Sub(b.openedAt) >= r.cooldown {
+				state.State = BreakerHalfOpen
+			}
+		}
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Host < states[j].Host })
+	return states
+}
@@ -0,0 +1,139 @@
+package scraper
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBreakerRegistry(t *testing.T) {
+	now := time.Unix(0, 0)
+	registry := newBreakerRegistry(2, time.Minute)
+	registry.now = func() time.Time { return now }
+
+	assert.NoError(t, registry.allow("example.com"))
+	registry.record("example.com", false)
+	assert.NoError(t, registry.allow("example.com"))
+	registry.record("example.com", false)
+
+	// Open: fail fast until the cooldown has passed
+	assert.ErrorIs(t, registry.allow("example.com"), ErrCircuitOpen)
+	assert.NoError(t, registry.allow("other.example"))
+	require.Len(t, registry.snapshot(), 1)
+	assert.Equal(t, BreakerOpen, registry.snapshot()[0].State)
+
+	// Half-open: a single probe is let through
+	now = now.Add(time.Minute)
+	assert.Equal(t, BreakerHalfOpen, registry.snapshot()[0].State)
+	assert.NoError(t, registry.allow("example.com"))
+	assert.ErrorIs(t, registry.allow("example.com"), ErrCircuitOpen)
+
+	// A failed probe re-opens the circuit
+	registry.record("example.com", false)
+	assert.ErrorIs(t, registry.allow("example.com"), ErrCircuitOpen)
+
+	// A released probe records nothing and lets another probe through
+	now = now.Add(time.Minute)
+	assert.NoError(t, registry.allow("example.com"))
+	registry.release("example.com")
+	assert.Equal(t, 3, registry.snapshot()[0].ConsecutiveFailures)
+
+	// A successful probe closes it
+	assert.NoError(t, registry.allow("example.com"))
+	registry.record("example.com", true)
+	assert.NoError(t, registry.allow("example.com"))
+	assert.Empty(t, registry.snapshot())
+}
+
+func TestGetMetadataCircuitBreaker(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	scraper := NewScraper(5*time.Second, allowLoopback, WithCircuitBreaker(2, time.Minute))
+
+	for i := 0; i < 2; i++ {
+		_, err := scraper.GetMetadata(context.Background(), ts.URL)
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrCircuitOpen)
+	}
+
+	_, err := scraper.GetMetadata(context.Background(), ts.URL)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	stats := scraper.Stats()
+	require.Len(t, stats.Breakers, 1)
+	assert.Equal(t, BreakerOpen, stats.Breakers[0].State)
+	assert.Equal(t, 2, stats.Breakers[0].ConsecutiveFailures)
+}
+
+func TestBreakerIgnoresCallerAndBlockedOutcomes(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	}))
+	defer ts.Close()
+	host := ts.Listener.Addr().String()
+
+	// failures returns the consecutive failures recorded for the test server
+	failures := func(s *Scraper) int {
+		for _, state := range s.Stats().Breakers {
+			if state.Host == host {
+				return state.ConsecutiveFailures
+			}
+		}
+		return 0
+	}
+
+	t.Run("caller cancellation", func(t *testing.T) {
+		scraper := NewScraper(5*time.Second, allowLoopback, WithCircuitBreaker(2, time.Minute))
+		scraper.breakers.record(host, false)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+		_, err := scraper.GetMetadata(ctx, ts.URL)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, 1, failures(scraper))
+	})
+
+	t.Run("caller deadline", func(t *testing.T) {
+		scraper := NewScraper(5*time.Second, allowLoopback, WithCircuitBreaker(2, time.Minute))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := scraper.GetMetadata(ctx, ts.URL)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Zero(t, failures(scraper))
+	})
+
+	t.Run("blocked address", func(t *testing.T) {
+		// With no cooldown the open circuit lets a probe through at once
+		scraper := NewScraper(5*time.Second, WithCircuitBreaker(1, 0))
+		scraper.breakers.record(host, false)
+
+		_, err := scraper.GetMetadata(context.Background(), ts.URL)
+		assert.ErrorIs(t, err, ErrBlockedAddress)
+		assert.Equal(t, 1, failures(scraper))
+		assert.NoError(t, scraper.breakers.allow(host))
+	})
+
+	t.Run("host timeout", func(t *testing.T) {
+		scraper := NewScraper(50*time.Millisecond, allowLoopback, WithCircuitBreaker(2, time.Minute))
+
+		_, err := scraper.GetMetadata(context.Background(), ts.URL)
+		assert.Error(t, err)
+		assert.Equal(t, 1, failures(scraper))
+	})
+}
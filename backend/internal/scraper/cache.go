@@ -0,0 +1,123 @@
+package scraper
+
+import (
+	"container/list"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Validators identify a fetched version of a page for conditional requests
+type Validators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	// BodyHash is the hex SHA-256 of the body bytes the scraper read; it detects
+	// unchanged pages on servers that send neither ETag nor Last-Modified
+	BodyHash string `json:"body_hash,omitempty"`
+}
+
+// WithResultCache keeps the results of the size most recent URLs. Results younger
+// than ttl are returned without a request; older ones are revalidated with a
+// conditional request. A size of zero disables the cache.
+func WithResultCache(size int, ttl time.Duration) Option {
+	return func(s *Scraper) {
+		s.cache = newResultCache(size, ttl)
+	}
+}
+
+// setConditionalHeaders asks the server to answer 304 if the page still matches v
+func setConditionalHeaders(req *http.Request, v *Validators) {
+	if v == nil {
+		return
+	}
+	if v.ETag != "" {
+		req.Header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		req.Header.Set("If-Modified-Since", v.LastModified)
+	}
+}
+
+// merge returns the validators of a newer response, keeping the previous
+// values the response did not repeat
+func (v Validators) merge(previous Validators) Validators {
+	if v.ETag == "" {
+		v.ETag = previous.ETag
+	}
+	if v.LastModified == "" {
+		v.LastModified = previous.LastModified
+	}
+	if v.BodyHash == "" {
+		v.BodyHash = previous.BodyHash
+	}
+	return v
+}
+
+// resultCache is an LRU cache of GetMetadata results keyed by requested URL
+type resultCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	order   *list.List
+	entries map[string]*list.Element
+	now     func() time.Time
+}
+
+// cacheEntry is a cached result and the time it was fetched or revalidated
+type cacheEntry struct {
+	url       string
+	metadata  Metadata
+	fetchedAt time.Time
+}
+
+// newResultCache creates a cache holding up to size results considered fresh for ttl
+func newResultCache(size int, ttl time.Duration) *resultCache {
+	return &resultCache{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+		now:     time.Now,
+	}
+}
+
+// get returns a copy of the cached result for rawURL, if any, and whether it is still fresh
+func (c *resultCache) get(rawURL string) (*Metadata, bool) {
+	if c.size <= 0 {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[rawURL]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	entry := elem.Value.(*cacheEntry)
+	metadata := entry.metadata
+	return &metadata, c.now().Sub(entry.fetchedAt) < c.ttl
+}
+
+// put stores a copy of metadata as the latest result for rawURL
+func (c *resultCache) put(rawURL string, metadata *Metadata) {
+	if c.size <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &cacheEntry{url: rawURL, metadata: *metadata, fetchedAt: c.now()}
+	entry.metadata.NotModified = false
+	if elem, ok := c.entries[rawURL]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[rawURL] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).url)
+	}
+}
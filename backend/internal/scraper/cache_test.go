@@ -0,0 +1,149 @@
+package scraper
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const cachedPage = `<html><head><title>Cached</title></head><body></body></html>`
+
+func TestConditionalRequestWithETag(t *testing.T) {
+	var requests atomic.Int32
+	var lastIfNoneMatch atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		requests.Add(1)
+		lastIfNoneMatch.Store(r.Header.Get("If-None-Match"))
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(cachedPage))
+	}))
+	defer server.Close()
+
+	// A zero TTL revalidates on every call
+	s := NewScraper(5*time.Second, allowLoopback, WithResultCache(10, 0))
+	first, err := s.GetMetadata(context.Background(), server.URL)
+	require.NoError(t, err)
+	assert.False(t, first.NotModified)
+	assert.Equal(t, `"v1"`, first.Validators.ETag)
+	assert.NotEmpty(t, first.Validators.BodyHash)
+
+	second, err := s.GetMetadata(context.Background(), server.URL)
+	require.NoError(t, err)
+	assert.True(t, second.NotModified)
+	assert.Equal(t, "Cached", second.Title)
+	assert.Equal(t, first.Validators, second.Validators)
+	assert.Equal(t, `"v1"`, lastIfNoneMatch.Load())
+	assert.Equal(t, int32(2), requests.Load())
+}
+
+func TestFreshResultsAreServedFromCache(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		requests.Add(1)
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(cachedPage))
+	}))
+	defer server.Close()
+
+	s := NewScraper(5*time.Second, allowLoopback, WithResultCache(10, time.Hour))
+	for i := 0; i < 3; i++ {
+		metadata, err := s.GetMetadata(context.Background(), server.URL)
+		require.NoError(t, err)
+		assert.Equal(t, "Cached", metadata.Title)
+		assert.False(t, metadata.NotModified)
+	}
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestUnchangedBodyHashIsNotModified(t *testing.T) {
+	body := cachedPage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	s := NewScraper(5*time.Second, allowLoopback, WithResultCache(10, 0))
+	_, err := s.GetMetadata(context.Background(), server.URL)
+	require.NoError(t, err)
+
+	metadata, err := s.GetMetadata(context.Background(), server.URL)
+	require.NoError(t, err)
+	assert.True(t, metadata.NotModified)
+
+	body = `<html><head><title>Changed</title></head><body></body></html>`
+	metadata, err = s.GetMetadata(context.Background(), server.URL)
+	require.NoError(t, err)
+	assert.False(t, metadata.NotModified)
+	assert.Equal(t, "Changed", metadata.Title)
+}
+
+func TestRevalidateWithStoredValidators(t *testing.T) {
+	lastModified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).Format(http.TimeFormat)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-Modified-Since") == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Last-Modified", lastModified)
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(cachedPage))
+	}))
+	defer server.Close()
+
+	// Without a cached result a 304 carries only the validators
+	s := NewScraper(5*time.Second, allowLoopback)
+	metadata, err := s.Revalidate(context.Background(), server.URL, Validators{LastModified: lastModified, BodyHash: "abc"})
+	require.NoError(t, err)
+	assert.True(t, metadata.NotModified)
+	assert.Empty(t, metadata.Title)
+	assert.Equal(t, Validators{LastModified: lastModified, BodyHash: "abc"}, metadata.Validators)
+
+	metadata, err = s.Revalidate(context.Background(), server.URL, Validators{})
+	require.NoError(t, err)
+	assert.False(t, metadata.NotModified)
+	assert.Equal(t, "Cached", metadata.Title)
+	assert.Equal(t, lastModified, metadata.Validators.LastModified)
+}
+
+func TestResultCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newResultCache(2, time.Minute)
+	c.put("a", &Metadata{Title: "A"})
+	c.put("b", &Metadata{Title: "B"})
+
+	// Touch "a" so that "b" is the least recently used
+	_, fresh := c.get("a")
+	assert.True(t, fresh)
+	c.put("c", &Metadata{Title: "C"})
+
+	cached, _ := c.get("b")
+	assert.Nil(t, cached)
+	cached, _ = c.get("a")
+	require.NotNil(t, cached)
+	assert.Equal(t, "A", cached.Title)
+
+	now := time.Now()
+	c.now = func() time.Time { return now.Add(2 * time.Minute) }
+	cached, fresh = c.get("c")
+	require.NotNil(t, cached)
+	assert.False(t, fresh)
+}
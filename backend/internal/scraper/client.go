@@ -0,0 +1,229 @@
+package scraper
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+const (
+	// DefaultTimeout bounds each request unless configured otherwise
+	DefaultTimeout = 10 * time.Second
+	// DefaultUserAgent is sent with every request unless configured otherwise
+	DefaultUserAgent = "Mozilla/5.0 (compatible; " + botName + "/1.0)"
+)
+
+// WithUserAgent sets the User-Agent header sent with every request. robots.txt
+// rules are still looked up for BookmarksBot.
+func WithUserAgent(ua string) Option {
+	return func(s *Scraper) {
+		s.userAgent = ua
+	}
+}
+
+// WithProxy sends all requests through an HTTP, HTTPS or SOCKS5 proxy.
+// User info in proxyURL authenticates with the proxy.
+func WithProxy(proxyURL *url.URL) Option {
+	return func(s *Scraper) {
+		s.proxyURL = proxyURL
+	}
+}
+
+// WithRootCAs verifies TLS certificates against pool instead of the system roots
+func WithRootCAs(pool *x509.CertPool) Option {
+	return func(s *Scraper) {
+		s.rootCAs = pool
+	}
+}
+
+// WithDomainRules adds headers and cookies to requests for matching hosts,
+// e.g. to send session cookies to sites behind single sign-on
+func WithDomainRules(rules ...DomainRule) Option {
+	return func(s *Scraper) {
+		s.domainRules = append(s.domainRules, rules...)
+	}
+}
+
+// ParseProxyURL parses a proxy URL with an http, https, socks5 or socks5h
+// scheme. An empty string means no proxy.
+func ParseProxyURL(raw string) (*url.URL, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme: %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, errors.New("proxy URL has no host")
+	}
+	return u, nil
+}
+
+// LoadCABundle returns the system roots together with the PEM certificates in
+// the file at path, for sites signed by a private certificate authority
+func LoadCABundle(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errors.New("no certificates found in CA bundle")
+	}
+	return pool, nil
+}
+
+// DomainRule adds headers and cookies to requests for a domain and its subdomains
+type DomainRule struct {
+	Domain  string            `json:"domain"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Cookies map[string]string `json:"cookies,omitempty"`
+}
+
+// matches reports whether host is the rule's domain or one of its subdomains
+func (r DomainRule) matches(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	domain := strings.ToLower(strings.Trim(r.Domain, "."))
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// LoadDomainRules reads domain rules from a JSON file holding an array of rules
+func LoadDomainRules(path string) ([]DomainRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read domain rules: %w", err)
+	}
+	var rules []DomainRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("invalid domain rules: %w", err)
+	}
+	for i, rule := range rules {
+		if strings.Trim(rule.Domain, ".") == "" {
+			return nil, fmt.Errorf("domain rule %d has no domain", i)
+		}
+	}
+	return rules, nil
+}
+
+// newTransport creates the transport of the scraper's client. Without a proxy
+// every connection goes through the address check, including redirect hops.
+// With one only the proxy is dialed, so target hosts are resolved and checked
+// before each request instead.
+func (s *Scraper) newTransport(timeout time.Duration) http.RoundTripper {
+	dialer := &net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+		Control:   s.controlDial,
+	}
+	// Bulk imports and refreshes fetch many pages from the same hosts, so
+	// more idle connections per host are kept than the default of two
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          256,
+		MaxIdleConnsPerHost:   16,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: timeout,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+	if s.rootCAs != nil {
+		transport.TLSClientConfig = &tls.Config{RootCAs: s.rootCAs}
+	}
+
+	if s.proxyURL != nil {
+		direct := &net.Dialer{
+			Timeout:   timeout,
+			KeepAlive: 30 * time.Second,
+		}
+		switch s.proxyURL.Scheme {
+		case "socks5", "socks5h":
+			var auth *proxy.Auth
+			if s.proxyURL.User != nil {
+				password, _ := s.proxyURL.User.Password()
+				auth = &proxy.Auth{User: s.proxyURL.User.Username(), Password: password}
+			}
+			socks, _ := proxy.SOCKS5("tcp", s.proxyURL.Host, auth, direct)
+			transport.DialContext = socks.(proxy.ContextDialer).DialContext
+		default:
+			transport.Proxy = http.ProxyURL(s.proxyURL)
+			transport.DialContext = direct.DialContext
+		}
+	}
+
+	return &requestTransport{scraper: s, base: transport}
+}
+
+// requestTransport sets the User-Agent and the headers and cookies of
+// matching domain rules on every request
+type requestTransport struct {
+	scraper *Scraper
+	base    http.RoundTripper
+}
+
+func (t *requestTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	s := t.scraper
+	host := req.URL.Hostname()
+	if s.proxyURL != nil {
+		if err := s.checkHost(req.Context(), host); err != nil {
+			return nil, err
+		}
+	}
+
+	// A RoundTripper must not modify the request it was given
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", s.userAgent)
+	for _, rule := range s.domainRules {
+		if !rule.matches(host) {
+			continue
+		}
+		for name, value := range rule.Headers {
+			req.Header.Set(name, value)
+		}
+		for name, value := range rule.Cookies {
+			req.AddCookie(&http.Cookie{Name: name, Value: value})
+		}
+	}
+	return t.base.RoundTrip(req)
+}
+
+// checkHost resolves host and fails unless every address may be dialed.
+// The proxy resolves the host again, so this cannot rule out DNS rebinding.
+func (s *Scraper) checkHost(ctx context.Context, host string) error {
+	if ip := net.ParseIP(host); ip != nil {
+		if !s.isAllowedIP(ip) {
+			return fmt.Errorf("%w: %s", ErrBlockedAddress, ip)
+		}
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if !s.isAllowedIP(addr.IP) {
+			return fmt.Errorf("%w: %s", ErrBlockedAddress, addr.IP)
+		}
+	}
+	return nil
+}
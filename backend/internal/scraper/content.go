@@ -0,0 +1,453 @@
+package scraper
+
+import (
+	"math"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	// wordsPerMinute is the assumed reading speed for space-delimited languages
+	wordsPerMinute = 200
+	// charsPerMinute is the assumed reading speed for CJK text, which has no word separators
+	charsPerMinute = 500
+	// minContentLength is the minimum text length for a node to be considered article content
+	minContentLength = 140
+)
+
+var (
+	// unlikelyCandidates matches class and id values of elements that are almost never article content
+	unlikelyCandidates = regexp.MustCompile(`(?i)ad-|ads|advert|banner|breadcrumb|combx|comment|community|cookie|disqus|extra|footer|gdpr|header|legends|menu|modal|nav|newsletter|popup|promo|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|tags|tool|widget`)
+	// maybeCandidates rescues elements matching unlikelyCandidates that also look like content
+	maybeCandidates = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	// positiveHints matches class and id values that raise a candidate's score
+	positiveHints = regexp.MustCompile(`(?i)article|blog|body|content|entry|hentry|h-entry|main|page|post|story|text`)
+	// negativeHints matches class and id values that lower a candidate's score
+	negativeHints = regexp.MustCompile(`(?i)-ad-|hidden|^hid$| hid$| hid |^hid |banner|combx|comment|com-|contact|footer|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|widget`)
+	// whitespace matches runs of whitespace to be collapsed
+	whitespace = regexp.MustCompile(`\s+`)
+)
+
+// removedElements are dropped together with their children before scoring
+var removedElements = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true,
+	"iframe": true, "object": true, "embed": true, "canvas": true, "svg": true,
+	"form": true, "button": true, "input": true, "select": true, "textarea": true,
+	"nav": true, "aside": true, "footer": true, "header": true, "dialog": true,
+}
+
+// allowedElements are kept in sanitized HTML; any other element is unwrapped
+var allowedElements = map[string]bool{
+	"p": true, "br": true, "hr": true, "a": true, "img": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"ul": true, "ol": true, "li": true, "dl": true, "dt": true, "dd": true,
+	"blockquote": true, "pre": true, "code": true, "em": true, "strong": true,
+	"b": true, "i": true, "u": true, "s": true, "sub": true, "sup": true, "mark": true,
+	"figure": true, "figcaption": true, "table": true, "thead": true, "tbody": true,
+	"tfoot": true, "tr": true, "th": true, "td": true, "caption": true,
+}
+
+// allowedAttributes lists the attributes kept per element in sanitized HTML
+var allowedAttributes = map[string]map[string]bool{
+	"a":   {"href": true, "title": true},
+	"img": {"src": true, "alt": true, "title": true, "width": true, "height": true},
+	"td":  {"colspan": true, "rowspan": true},
+	"th":  {"colspan": true, "rowspan": true},
+}
+
+// blockElements get a paragraph break around them in the plain text output
+var blockElements = map[string]bool{
+	"p": true, "div": true, "section": true, "article": true, "main": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"ul": true, "ol": true, "li": true, "dl": true, "dt": true, "dd": true,
+	"blockquote": true, "pre": true, "figure": true, "figcaption": true,
+	"table": true, "tr": true, "br": true, "hr": true,
+}
+
+// Content represents the main article body extracted from a webpage
+type Content struct {
+	Text               string
+	HTML               string
+	WordCount          int
+	ReadingTimeMinutes int
+}
+
+// ExtractContent finds the main article body of a parsed document and returns
+// it as plain text and sanitized HTML. It returns nil if no content was found.
+func ExtractContent(doc *html.Node, baseURL *url.URL) *Content {
+	body := findElement(doc, "body")
+	if body == nil {
+		return nil
+	}
+	body = cloneNode(body)
+	pruneUnlikely(body)
+
+	top := selectCandidate(body)
+	if top == nil {
+		return nil
+	}
+
+	sanitizeNode(top, baseURL)
+
+	text := nodeText(top)
+	if text == "" {
+		return nil
+	}
+
+	var sb strings.Builder
+	for c := top.FirstChild; c != nil; c = c.NextSibling {
+		if err := html.Render(&sb, c); err != nil {
+			return nil
+		}
+	}
+
+	words := countWords(text)
+	return &Content{
+		Text:               text,
+		HTML:               strings.TrimSpace(sb.String()),
+		WordCount:          words.words + words.cjkChars,
+		ReadingTimeMinutes: words.readingTime(),
+	}
+}
+
+// findElement returns the first element with the given tag in document order
+func findElement(n *html.Node, tag string) *html.Node {
+	if n.Type == html.ElementNode && n.Data == tag {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, tag); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// cloneNode returns a deep copy of n detached from its parent
+func cloneNode(n *html.Node) *html.Node {
+	clone := &html.Node{
+		Type:      n.Type,
+		DataAtom:  n.DataAtom,
+		Data:      n.Data,
+		Namespace: n.Namespace,
+		Attr:      append([]html.Attribute(nil), n.Attr...),
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		clone.AppendChild(cloneNode(c))
+	}
+	return clone
+}
+
+// pruneUnlikely removes boilerplate elements such as navigation, ads and comments
+func pruneUnlikely(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		switch c.Type {
+		case html.CommentNode:
+			n.RemoveChild(c)
+		case html.ElementNode:
+			if isUnlikely(c) {
+				n.RemoveChild(c)
+			} else {
+				pruneUnlikely(c)
+			}
+		}
+		c = next
+	}
+}
+
+// isUnlikely reports whether an element should be dropped before scoring
+func isUnlikely(n *html.Node) bool {
+	if removedElements[n.Data] {
+		return true
+	}
+	if hasAttr(n, "hidden") || attrValue(n, "aria-hidden") == "true" {
+		return true
+	}
+	if role := attrValue(n, "role"); role == "navigation" || role == "complementary" || role == "banner" || role == "contentinfo" {
+		return true
+	}
+	if n.Data == "article" || n.Data == "main" || n.Data == "body" {
+		return false
+	}
+	hints := attrValue(n, "class") + " " + attrValue(n, "id")
+	return unlikelyCandidates.MatchString(hints) && !maybeCandidates.MatchString(hints)
+}
+
+// selectCandidate scores the containers of every paragraph and returns the best one
+func selectCandidate(body *html.Node) *html.Node {
+	scores := make(map[*html.Node]float64)
+	var order []*html.Node
+
+	addScore := func(n *html.Node, score float64) {
+		if n == nil || n.Type != html.ElementNode {
+			return
+		}
+		if _, ok := scores[n]; !ok {
+			scores[n] = classWeight(n)
+			switch n.Data {
+			case "article", "main":
+				scores[n] += 25
+			case "div", "section":
+				scores[n] += 5
+			case "pre", "td", "blockquote":
+				scores[n] += 3
+			case "form", "ol", "ul", "dl", "li", "th":
+				scores[n] -= 3
+			}
+			order = append(order, n)
+		}
+		scores[n] += score
+	}
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "p", "pre", "td", "blockquote", "li":
+				text := nodeText(n)
+				if len([]rune(text)) >= 25 {
+					score := 1 + float64(strings.Count(text, ",")+strings.Count(text, "、")+strings.Count(text, "，"))
+					score += math.Min(float64(len([]rune(text)))/100, 3)
+					addScore(n.Parent, score)
+					if n.Parent != nil {
+						addScore(n.Parent.Parent, score/2)
+					}
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(body)
+
+	var top *html.Node
+	var topScore float64
+	for _, n := range order {
+		score := scores[n] * (1 - linkDensity(n))
+		if top == nil || score > topScore {
+			top, topScore = n, score
+		}
+	}
+
+	if top == nil {
+		// Pages without paragraph markup: fall back to <article>, <main> or the body itself
+		for _, tag := range []string{"article", "main"} {
+			if n := findElement(body, tag); n != nil {
+				top = n
+				break
+			}
+		}
+		if top == nil {
+			top = body
+		}
+	}
+
+	if len([]rune(nodeText(top))) < minContentLength && top != body {
+		if len([]rune(nodeText(body))) >= minContentLength {
+			return body
+		}
+	}
+	return top
+}
+
+// classWeight scores an element by its class and id hints
+func classWeight(n *html.Node) float64 {
+	var weight float64
+	for _, hint := range []string{attrValue(n, "class"), attrValue(n, "id")} {
+		if hint == "" {
+			continue
+		}
+		if negativeHints.MatchString(hint) {
+			weight -= 25
+		}
+		if positiveHints.MatchString(hint) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// linkDensity returns the fraction of an element's text that is inside links
+func linkDensity(n *html.Node) float64 {
+	total := len([]rune(nodeText(n)))
+	if total == 0 {
+		return 0
+	}
+	var linked int
+	var walk func(*html.Node)
+	walk = func(c *html.Node) {
+		if c.Type == html.ElementNode && c.Data == "a" {
+			linked += len([]rune(nodeText(c)))
+			return
+		}
+		for cc := c.FirstChild; cc != nil; cc = cc.NextSibling {
+			walk(cc)
+		}
+	}
+	walk(n)
+	return float64(linked) / float64(total)
+}
+
+// sanitizeNode strips disallowed elements and attributes from n's subtree in place.
+// Links and images are resolved against baseURL; unsafe URLs are removed.
+func sanitizeNode(n *html.Node, baseURL *url.URL) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		switch c.Type {
+		case html.ElementNode:
+			sanitizeNode(c, baseURL)
+			if !allowedElements[c.Data] {
+				// Unwrap: keep the children, drop the element itself
+				for gc := c.FirstChild; gc != nil; {
+					gcNext := gc.NextSibling
+					c.RemoveChild(gc)
+					n.InsertBefore(gc, c)
+					gc = gcNext
+				}
+				n.RemoveChild(c)
+			} else {
+				sanitizeAttributes(c, baseURL)
+				if c.Data == "img" && attrValue(c, "src") == "" {
+					n.RemoveChild(c)
+				}
+			}
+		case html.TextNode:
+		default:
+			n.RemoveChild(c)
+		}
+		c = next
+	}
+}
+
+// sanitizeAttributes keeps only allowed attributes and makes URLs absolute
+func sanitizeAttributes(n *html.Node, baseURL *url.URL) {
+	allowed := allowedAttributes[n.Data]
+	attrs := n.Attr[:0]
+	for _, attr := range n.Attr {
+		if attr.Namespace != "" || !allowed[attr.Key] {
+			continue
+		}
+		if attr.Key == "href" || attr.Key == "src" {
+			resolved, ok := safeURL(attr.Val, baseURL)
+			if !ok {
+				continue
+			}
+			attr.Val = resolved
+		}
+		attrs = append(attrs, attr)
+	}
+	n.Attr = attrs
+}
+
+// safeURL resolves ref against baseURL and accepts only http(s) results
+func safeURL(ref string, baseURL *url.URL) (string, bool) {
+	u, err := baseURL.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return "", false
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", false
+	}
+	return u.String(), true
+}
+
+// nodeText returns the visible text of n with whitespace collapsed and
+// paragraph breaks between block-level elements
+func nodeText(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(c *html.Node) {
+		switch c.Type {
+		case html.TextNode:
+			sb.WriteString(c.Data)
+		case html.ElementNode:
+			if removedElements[c.Data] {
+				return
+			}
+			if blockElements[c.Data] {
+				sb.WriteString("\n\n")
+			}
+		}
+		for cc := c.FirstChild; cc != nil; cc = cc.NextSibling {
+			walk(cc)
+		}
+		if c.Type == html.ElementNode && blockElements[c.Data] {
+			sb.WriteString("\n\n")
+		}
+	}
+	walk(n)
+
+	var paragraphs []string
+	for _, p := range strings.Split(sb.String(), "\n\n") {
+		p = strings.TrimSpace(whitespace.ReplaceAllString(p, " "))
+		if p != "" {
+			paragraphs = append(paragraphs, p)
+		}
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// attrValue returns the value of the named attribute, or an empty string
+func attrValue(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// hasAttr reports whether the element has the named attribute, even if empty
+func hasAttr(n *html.Node, key string) bool {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// wordStats holds counts used for reading time estimation
+type wordStats struct {
+	words    int
+	cjkChars int
+}
+
+// countWords counts space-delimited words and CJK characters separately,
+// since Japanese and Chinese text is not separated by spaces
+func countWords(text string) wordStats {
+	var stats wordStats
+	inWord := false
+	for _, r := range text {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			stats.cjkChars++
+			inWord = false
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if !inWord {
+				stats.words++
+				inWord = true
+			}
+		case r == '\'' || r == '-' || r == '’':
+			// Apostrophes and hyphens continue the current word
+		default:
+			inWord = false
+		}
+	}
+	return stats
+}
+
+// readingTime returns the estimated reading time in whole minutes, at least one
+func (w wordStats) readingTime() int {
+	minutes := float64(w.words)/wordsPerMinute + float64(w.cjkChars)/charsPerMinute
+	if minutes < 1 {
+		return 1
+	}
+	return int(math.Round(minutes))
+}
@@ -0,0 +1,117 @@
+package scraper
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/html"
+)
+
+func TestExtractContent(t *testing.T) {
+	baseURL, _ := url.Parse("https://example.com/posts/1")
+
+	tests := []struct {
+		name        string
+		html        string
+		wantText    []string
+		notWantText []string
+		wantHTML    []string
+		notWantHTML []string
+	}{
+		{
+			name: "article with boilerplate",
+			html: `
+				<html><body>
+					<nav><a href="/">Home</a><a href="/about">About</a></nav>
+					<div class="sidebar-ads">Buy our product now, limited offer, act fast!</div>
+					<article>
+						<h1>Article Heading</h1>
+						<p>This is the first paragraph of the article, which has enough text to be scored as content.</p>
+						<p>The second paragraph continues, with commas, more words, and a <a href="/ref">relative link</a>.</p>
+						<script>alert("x")</script>
+					</article>
+					<footer>Copyright notice and footer links</footer>
+				</body></html>
+			`,
+			wantText:    []string{"Article Heading", "first paragraph", "second paragraph"},
+			notWantText: []string{"Home", "Buy our product", "Copyright", "alert"},
+			wantHTML:    []string{`<a href="https://example.com/ref">relative link</a>`, "<h1>Article Heading</h1>"},
+			notWantHTML: []string{"<script", "<article"},
+		},
+		{
+			name: "unsafe attributes removed",
+			html: `
+				<html><body><div class="content">
+					<p onclick="steal()" style="color:red">A paragraph that is long enough, with commas, to be picked as the main content.</p>
+					<p><a href="javascript:alert(1)">bad link</a> and <img src="/img.png" onerror="x()"> in a second paragraph of text.</p>
+				</div></body></html>
+			`,
+			wantText:    []string{"A paragraph that is long enough"},
+			wantHTML:    []string{`<img src="https://example.com/img.png"/>`, "<a>bad link</a>"},
+			notWantHTML: []string{"onclick", "style=", "javascript:", "onerror"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := html.Parse(strings.NewReader(tt.html))
+			require.NoError(t, err)
+
+			content := ExtractContent(doc, baseURL)
+			require.NotNil(t, content)
+
+			for _, want := range tt.wantText {
+				assert.Contains(t, content.Text, want)
+			}
+			for _, notWant := range tt.notWantText {
+				assert.NotContains(t, content.Text, notWant)
+			}
+			for _, want := range tt.wantHTML {
+				assert.Contains(t, content.HTML, want)
+			}
+			for _, notWant := range tt.notWantHTML {
+				assert.NotContains(t, content.HTML, notWant)
+			}
+			assert.Positive(t, content.WordCount)
+			assert.Equal(t, 1, content.ReadingTimeMinutes)
+		})
+	}
+}
+
+func TestExtractContentEmpty(t *testing.T) {
+	baseURL, _ := url.Parse("https://example.com")
+	doc, err := html.Parse(strings.NewReader(`<html><head><title>Empty</title></head><body><nav>Menu</nav></body></html>`))
+	require.NoError(t, err)
+
+	assert.Nil(t, ExtractContent(doc, baseURL))
+}
+
+func TestCountWords(t *testing.T) {
+	tests := []struct {
+		name         string
+		text         string
+		wantWords    int
+		wantCJKChars int
+	}{
+		{name: "english", text: "It's a well-known fact, isn't it?", wantWords: 6},
+		{name: "japanese", text: "これは日本語です", wantCJKChars: 8},
+		{name: "mixed", text: "Go言語 is fun", wantWords: 3, wantCJKChars: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := countWords(tt.text)
+			assert.Equal(t, tt.wantWords, stats.words)
+			assert.Equal(t, tt.wantCJKChars, stats.cjkChars)
+		})
+	}
+}
+
+func TestReadingTime(t *testing.T) {
+	assert.Equal(t, 1, wordStats{words: 10}.readingTime())
+	assert.Equal(t, 5, wordStats{words: 1000}.readingTime())
+	assert.Equal(t, 2, wordStats{cjkChars: 1000}.readingTime())
+}
@@ -0,0 +1,192 @@
+package scraper
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+)
+
+// builtinPriority is the priority of the built-in site extractors; custom
+// extractors with a higher priority take precedence over them
+const builtinPriority = 10
+
+// Extractor refines metadata for the pages of a specific site
+type Extractor interface {
+	// Name identifies the extractor and is recorded as the site of the extra metadata
+	Name() string
+	// Priority decides which extractor runs when several match a URL; the highest wins
+	Priority() int
+	// Match reports whether the extractor handles pages at u
+	Match(u *url.URL) bool
+	// Extract refines m from the parsed page at u. It runs after the generic
+	// extraction, so it only needs to override what the site does better.
+	Extract(doc *html.Node, u *url.URL, m *Metadata)
+}
+
+// Registry holds site extractors ordered by priority
+type Registry struct {
+	mu         sync.RWMutex
+	extractors []Extractor
+}
+
+// NewRegistry creates a registry with the given extractors
+func NewRegistry(extractors ...Extractor) *Registry {
+	r := &Registry{}
+	for _, e := range extractors {
+		r.Register(e)
+	}
+	return r
+}
+
+// DefaultRegistry creates a registry with the built-in extractors for GitHub,
+// YouTube, Hacker News, Wikipedia and arXiv
+func DefaultRegistry() *Registry {
+	return NewRegistry(
+		githubExtractor{},
+		youtubeExtractor{},
+		hackerNewsExtractor{},
+		wikipediaExtractor{},
+		arxivExtractor{},
+	)
+}
+
+// Register adds an extractor. Among extractors of equal priority, the one
+// registered first wins.
+func (r *Registry) Register(e Extractor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.extractors = append(r.extractors, e)
+	sort.SliceStable(r.extractors, func(i, j int) bool {
+		return r.extractors[i].Priority() > r.extractors[j].Priority()
+	})
+}
+
+// Lookup returns the highest priority extractor matching u, or nil
+func (r *Registry) Lookup(u *url.URL) Extractor {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, e := range r.extractors {
+		if e.Match(u) {
+			return e
+		}
+	}
+	return nil
+}
+
+// WithExtractors registers additional site extractors next to the built-in ones
+func WithExtractors(extractors ...Extractor) Option {
+	return func(s *Scraper) {
+		if s.extractors == nil {
+			s.extractors = NewRegistry()
+		}
+		for _, e := range extractors {
+			s.extractors.Register(e)
+		}
+	}
+}
+
+// WithRegistry replaces the extractor registry; a nil registry disables
+// site-specific extraction
+func WithRegistry(r *Registry) Option {
+	return func(s *Scraper) {
+		s.extractors = r
+	}
+}
+
+// matchHost reports whether the host of u is domain or one of its subdomains
+func matchHost(u *url.URL, domain string) bool {
+	host := strings.ToLower(u.Hostname())
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// pathSegments returns the non-empty segments of the path of u
+func pathSegments(u *url.URL) []string {
+	var segments []string
+	for _, segment := range strings.Split(u.Path, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+// findFirst returns the first node in document order for which match is true
+func findFirst(n *html.Node, match func(*html.Node) bool) *html.Node {
+	if match(n) {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findFirst(c, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// findAll returns every node in document order for which match is true
+func findAll(n *html.Node, match func(*html.Node) bool) []*html.Node {
+	var nodes []*html.Node
+	var walk func(*html.Node)
+	walk = func(c *html.Node) {
+		if match(c) {
+			nodes = append(nodes, c)
+		}
+		for cc := c.FirstChild; cc != nil; cc = cc.NextSibling {
+			walk(cc)
+		}
+	}
+	walk(n)
+	return nodes
+}
+
+// byAttr matches elements of the given tag whose attribute key equals value;
+// an empty tag matches any element
+func byAttr(tag, key, value string) func(*html.Node) bool {
+	return func(n *html.Node) bool {
+		return n.Type == html.ElementNode && (tag == "" || n.Data == tag) && attrValue(n, key) == value
+	}
+}
+
+// byClass matches elements of the given tag carrying class; an empty tag matches any element
+func byClass(tag, class string) func(*html.Node) bool {
+	return func(n *html.Node) bool {
+		return n.Type == html.ElementNode && (tag == "" || n.Data == tag) && hasClass(n, class)
+	}
+}
+
+// hasClass reports whether the element's class attribute contains class
+func hasClass(n *html.Node, class string) bool {
+	for _, c := range strings.Fields(attrValue(n, "class")) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// metaValues returns the content of every <meta> whose name, property or itemprop is key
+func metaValues(doc *html.Node, key string) []string {
+	var values []string
+	for _, n := range findAll(doc, func(n *html.Node) bool { return n.Type == html.ElementNode && n.Data == "meta" }) {
+		if attrValue(n, "name") == key || attrValue(n, "property") == key || attrValue(n, "itemprop") == key {
+			values = append(values, strings.TrimSpace(attrValue(n, "content")))
+		}
+	}
+	return values
+}
+
+// metaValue returns the content of the first <meta> whose name, property or itemprop is key
+func metaValue(doc *html.Node, key string) string {
+	if values := metaValues(doc, key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
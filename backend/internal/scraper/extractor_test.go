@@ -0,0 +1,243 @@
+package scraper
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"bookmarks-go/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/html"
+)
+
+// testExtractor is a configurable extractor for registry tests
+type testExtractor struct {
+	name     string
+	priority int
+	host     string
+}
+
+func (e testExtractor) Name() string          { return e.name }
+func (e testExtractor) Priority() int         { return e.priority }
+func (e testExtractor) Match(u *url.URL) bool { return u.Hostname() == e.host }
+func (e testExtractor) Extract(doc *html.Node, u *url.URL, m *Metadata) {
+	m.Title = e.name
+	m.Extra = &models.Extra{Site: e.name}
+}
+
+func TestRegistryLookup(t *testing.T) {
+	low := testExtractor{name: "low", priority: 1, host: "example.com"}
+	high := testExtractor{name: "high", priority: 5, host: "example.com"}
+	tie := testExtractor{name: "tie", priority: 5, host: "example.com"}
+	registry := NewRegistry(low, high, tie)
+
+	assert.Equal(t, "high", registry.Lookup(mustParseURL(t, "https://example.com/a")).Name())
+	assert.Nil(t, registry.Lookup(mustParseURL(t, "https://other.com/")))
+
+	var disabled *Registry
+	assert.Nil(t, disabled.Lookup(mustParseURL(t, "https://example.com/a")))
+}
+
+func TestDefaultRegistryMatches(t *testing.T) {
+	registry := DefaultRegistry()
+	tests := map[string]string{
+		"https://github.com/golang/go":                            "github",
+		"https://github.com/golang/go/issues":                     "",
+		"https://github.com/settings/profile":                     "",
+		"https://www.youtube.com/watch?v=dQw4w9WgXcQ":             "youtube",
+		"https://youtu.be/dQw4w9WgXcQ":                            "youtube",
+		"https://www.youtube.com/@channel":                        "",
+		"https://news.ycombinator.com/item?id=1":                  "hacker_news",
+		"https://news.ycombinator.com/news":                       "",
+		"https://en.wikipedia.org/wiki/Go_(programming_language)": "wikipedia",
+		"https://arxiv.org/abs/1706.03762":                        "arxiv",
+		"https://arxiv.org/list/cs.CL/recent":                     "",
+	}
+	for rawURL, want := range tests {
+		got := ""
+		if extractor := registry.Lookup(mustParseURL(t, rawURL)); extractor != nil {
+			got = extractor.Name()
+		}
+		assert.Equal(t, want, got, rawURL)
+	}
+}
+
+func TestGitHubExtractor(t *testing.T) {
+	doc := `<html><head>
+		<title>GitHub - golang/go: The Go programming language</title>
+		<meta name="description" content="The Go programming language. Contribute to golang/go development by creating an account on GitHub.">
+	</head><body>
+		<span id="repo-stars-counter-star" title="125,482" class="Counter">125k</span>
+		<p class="f4 my-3">The Go programming language</p>
+		<span class="color-fg-default text-bold mr-1" itemprop="programmingLanguage">Go</span>
+	</body></html>`
+
+	m := runExtractor(t, githubExtractor{}, "https://github.com/golang/go", doc)
+	assert.Equal(t, "golang/go", m.Title)
+	assert.Equal(t, "The Go programming language", m.Description)
+	require.NotNil(t, m.Extra)
+	assert.Equal(t, "github", m.Extra.Site)
+	assert.Equal(t, &models.GitHubRepo{
+		Owner:       "golang",
+		Name:        "go",
+		Description: "The Go programming language",
+		Stars:       125482,
+		Language:    "Go",
+	}, m.Extra.GitHub)
+}
+
+func TestYouTubeExtractor(t *testing.T) {
+	doc := `<html><body>
+		<div itemscope itemtype="http://schema.org/VideoObject">
+			<span itemprop="author" itemscope itemtype="http://schema.org/Person">
+				<link itemprop="url" href="https://www.youtube.com/@GoogleDevelopers">
+				<link itemprop="name" content="Google for Developers">
+			</span>
+			<meta itemprop="duration" content="PT1H2M3S">
+		</div>
+	</body></html>`
+
+	m := runExtractor(t, youtubeExtractor{}, "https://www.youtube.com/watch?v=abc123", doc)
+	assert.Equal(t, "Google for Developers", m.Author)
+	require.NotNil(t, m.Extra)
+	assert.Equal(t, &models.YouTubeVideo{
+		VideoID:         "abc123",
+		Channel:         "Google for Developers",
+		ChannelURL:      "https://www.youtube.com/@GoogleDevelopers",
+		DurationSeconds: 3723,
+	}, m.Extra.YouTube)
+}
+
+func TestHackerNewsExtractor(t *testing.T) {
+	doc := `<html><body><table>
+		<tr class="athing submission" id="42">
+			<td class="title"><span class="titleline"><a href="https://example.com/story">A story</a></span></td>
+		</tr>
+		<tr><td class="subtext"><span class="subline">
+			<span class="score" id="score_42">128 points</span> by
+			<a href="user?id=pg" class="hnuser">pg</a>
+			<a href="item?id=42">hide</a> | <a href="item?id=42">57&nbsp;comments</a>
+		</span></td></tr>
+	</table></body></html>`
+
+	m := runExtractor(t, hackerNewsExtractor{}, "https://news.ycombinator.com/item?id=42", doc)
+	assert.Equal(t, "A story", m.Title)
+	assert.Equal(t, "pg", m.Author)
+	require.NotNil(t, m.Extra)
+	assert.Equal(t, &models.HackerNewsItem{
+		ItemID:   42,
+		Title:    "A story",
+		StoryURL: "https://example.com/story",
+		Author:   "pg",
+		Points:   128,
+		Comments: 57,
+	}, m.Extra.HackerNews)
+}
+
+func TestWikipediaExtractor(t *testing.T) {
+	doc := `<html><body>
+		<h1 id="firstHeading" class="firstHeading">Go (programming language)</h1>
+		<div class="mw-parser-output">
+			<p class="mw-empty-elt"></p>
+			<p><b>Go</b> is a high-level programming language designed at Google.<sup class="reference">[11]</sup> It is statically typed.[citation needed]</p>
+			<p>Second paragraph.</p>
+		</div>
+	</body></html>`
+
+	m := runExtractor(t, wikipediaExtractor{}, "https://en.m.wikipedia.org/wiki/Go_(programming_language)", doc)
+	assert.Equal(t, "Go (programming language)", m.Title)
+	assert.Equal(t, "Go is a high-level programming language designed at Google. It is statically typed.", m.Description)
+	require.NotNil(t, m.Extra)
+	assert.Equal(t, "en", m.Extra.Wikipedia.Language)
+}
+
+func TestArXivExtractor(t *testing.T) {
+	doc := `<html><head>
+		<meta name="citation_title" content="Attention Is All You Need">
+		<meta name="citation_author" content="Vaswani, Ashish">
+		<meta name="citation_author" content="Shazeer, Noam">
+		<meta name="citation_date" content="2017/06/12">
+		<meta name="citation_pdf_url" content="http://arxiv.org/pdf/1706.03762">
+		<meta name="citation_arxiv_id" content="1706.03762">
+		<meta name="citation_abstract" content="The dominant sequence
+			transduction models are based on recurrent networks.">
+	</head><body>
+		<table><tr><td class="tablecell subjects"><span class="primary-subject">Computation and Language (cs.CL)</span>; Machine Learning (cs.LG)</td></tr></table>
+	</body></html>`
+
+	m := runExtractor(t, arxivExtractor{}, "https://arxiv.org/abs/1706.03762", doc)
+	assert.Equal(t, "Attention Is All You Need", m.Title)
+	assert.Equal(t, "Vaswani, Ashish; Shazeer, Noam", m.Author)
+	require.NotNil(t, m.Extra)
+	assert.Equal(t, &models.ArXivPaper{
+		ID:         "1706.03762",
+		Title:      "Attention Is All You Need",
+		Authors:    []string{"Vaswani, Ashish", "Shazeer, Noam"},
+		Abstract:   "The dominant sequence transduction models are based on recurrent networks.",
+		Published:  "2017/06/12",
+		PDFURL:     "http://arxiv.org/pdf/1706.03762",
+		Categories: []string{"cs.CL", "cs.LG"},
+	}, m.Extra.ArXiv)
+}
+
+func TestGetMetadataUsesRegisteredExtractor(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Generic</title></head><body></body></html>`))
+	}))
+	defer server.Close()
+
+	s := NewScraper(5*time.Second, allowLoopback, WithExtractors(testExtractor{name: "local", priority: 1, host: "127.0.0.1"}))
+	metadata, err := s.GetMetadata(context.Background(), server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "local", metadata.Title)
+	require.NotNil(t, metadata.Extra)
+	assert.Equal(t, "local", metadata.Extra.Site)
+
+	s = NewScraper(5*time.Second, allowLoopback, WithRegistry(nil))
+	metadata, err = s.GetMetadata(context.Background(), server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "Generic", metadata.Title)
+	assert.Nil(t, metadata.Extra)
+}
+
+func TestParseCount(t *testing.T) {
+	assert.Equal(t, 1234, parseCount("1,234"))
+	assert.Equal(t, 12500, parseCount("12.5k"))
+	assert.Equal(t, 3, parseCount("3 points"))
+	assert.Equal(t, 0, parseCount("discuss"))
+}
+
+func TestParseISODuration(t *testing.T) {
+	assert.Equal(t, 253, parseISODuration("PT4M13S"))
+	assert.Equal(t, 90000, parseISODuration("P1DT1H"))
+	assert.Equal(t, 0, parseISODuration("4:13"))
+}
+
+// runExtractor parses doc and applies e to fresh metadata
+func runExtractor(t *testing.T, e Extractor, rawURL, doc string) *Metadata {
+	t.Helper()
+	u := mustParseURL(t, rawURL)
+	require.True(t, e.Match(u), "extractor should match %s", rawURL)
+	node, err := html.Parse(strings.NewReader(doc))
+	require.NoError(t, err)
+
+	m := &Metadata{}
+	m.extractMetadata(node)
+	e.Extract(node, u, m)
+	return m
+}
+
+// mustParseURL parses rawURL or fails the test
+func mustParseURL(t *testing.T, rawURL string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	require.NoError(t, err)
+	return u
+}
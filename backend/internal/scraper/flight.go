@@ -0,0 +1,94 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+)
+
+// flightGroup collapses concurrent fetches of the same resource into one
+// request, e.g. when an import saves a URL twice or a refresh overlaps a save,
+// or when the first pages of a host all need its robots.txt
+type flightGroup[T any] struct {
+	mu    sync.Mutex
+	calls map[string]*flight[T]
+}
+
+// flight is a fetch in progress; its result is set before done is closed
+type flight[T any] struct {
+	done   chan struct{}
+	result *T
+	err    error
+}
+
+// do returns the result of fn, unless a call with the same key is in
+// progress, in which case it waits for that call and returns a copy of its
+// result. Waiters whose own context is still live fetch again if the call
+// they waited for was cancelled.
+func (g *flightGroup[T]) do(ctx context.Context, key string, fn func() (*T, error)) (*T, error) {
+	for {
+		g.mu.Lock()
+		if g.calls == nil {
+			g.calls = make(map[string]*flight[T])
+		}
+		f, ok := g.calls[key]
+		if !ok {
+			f = &flight[T]{done: make(chan struct{})}
+			g.calls[key] = f
+			g.mu.Unlock()
+			return g.run(key, f, fn)
+		}
+		g.mu.Unlock()
+
+		select {
+		case <-f.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if f.err != nil {
+			cancelled := errors.Is(f.err, context.Canceled) || errors.Is(f.err, context.DeadlineExceeded)
+			if cancelled && ctx.Err() == nil {
+				continue
+			}
+			return nil, f.err
+		}
+		result := *f.result
+		return &result, nil
+	}
+}
+
+// errFlightAborted is returned to waiters when the fetch they waited for panicked
+var errFlightAborted = errors.New("fetch aborted")
+
+// run calls fn for the flight f and publishes its result to waiters, who are
+// released even if fn panics
+func (g *flightGroup[T]) run(key string, f *flight[T], fn func() (*T, error)) (*T, error) {
+	f.err = errFlightAborted
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(f.done)
+	}()
+
+	result, err := fn()
+	if err == nil {
+		// Waiters copy their result from a snapshot, since the caller may
+		// modify the result it gets back
+		shared := *result
+		f.result = &shared
+	}
+	f.err = err
+	return result, err
+}
+
+// flightKey identifies scrapes that are interchangeable: the same URL, sent
+// with the same conditional headers and under the same robots.txt policy
+func flightKey(ctx context.Context, urlStr string, validators *Validators) string {
+	key := strconv.FormatBool(isBackground(ctx)) + " " + urlStr
+	if validators != nil {
+		key += "\x00" + validators.ETag + "\x00" + validators.LastModified + "\x00" + validators.BodyHash
+	}
+	return key
+}
@@ -0,0 +1,90 @@
+package scraper
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetMetadataSharesConcurrentScrapes(t *testing.T) {
+	var requests atomic.Int32
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/page" {
+			http.NotFound(w, r)
+			return
+		}
+		requests.Add(1)
+		<-release
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Shared</title></head></html>`))
+	}))
+	defer ts.Close()
+
+	s := NewScraper(5*time.Second, allowLoopback)
+	results := make([]*Metadata, 5)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			metadata, err := s.GetMetadata(context.Background(), ts.URL+"/page")
+			assert.NoError(t, err)
+			results[i] = metadata
+		}(i)
+	}
+
+	// Give every call time to join the first one
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), requests.Load())
+	for _, metadata := range results {
+		require.NotNil(t, metadata)
+		assert.Equal(t, "Shared", metadata.Title)
+	}
+	// Callers get their own copies
+	results[0].Title = "Changed"
+	assert.Equal(t, "Shared", results[1].Title)
+}
+
+func TestFlightGroupRetriesCancelledCalls(t *testing.T) {
+	var g flightGroup[Metadata]
+	started := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var leaderErr error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, leaderErr = g.do(ctx, "key", func() (*Metadata, error) {
+			close(started)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+	}()
+	<-started
+
+	waiter := make(chan *Metadata)
+	go func() {
+		metadata, err := g.do(context.Background(), "key", func() (*Metadata, error) {
+			return &Metadata{Title: "Retried"}, nil
+		})
+		assert.NoError(t, err)
+		waiter <- metadata
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	<-done
+	assert.ErrorIs(t, leaderErr, context.Canceled)
+	assert.Equal(t, "Retried", (<-waiter).Title)
+}
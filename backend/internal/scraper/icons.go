@@ -0,0 +1,279 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"bookmarks-go/internal/models"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	// DefaultIconSize is the icon size in pixels selected unless configured otherwise
+	DefaultIconSize = 32
+	// maxManifestSize is the maximum web app manifest size read
+	maxManifestSize = 256 << 10
+	// svgMediaType is the media type of scalable icons
+	svgMediaType = "image/svg+xml"
+	// scalableIconSide marks icons that fit any size
+	scalableIconSide = -1
+)
+
+// Icon relations, recording where a candidate icon was declared
+const (
+	IconRelIcon     = "icon"
+	IconRelApple    = "apple-touch-icon"
+	IconRelMask     = "mask-icon"
+	IconRelManifest = "manifest"
+	IconRelDefault  = "default"
+)
+
+// Icon is a candidate icon of a page
+type Icon struct {
+	URL string `json:"url"`
+	// Rel is where the icon was declared: icon, apple-touch-icon, mask-icon,
+	// manifest, or default for /favicon.ico
+	Rel     string `json:"rel"`
+	Type    string `json:"type,omitempty"`
+	Sizes   string `json:"sizes,omitempty"`
+	Purpose string `json:"purpose,omitempty"`
+}
+
+// WithIconSize sets the icon size in pixels that the favicon is chosen for
+func WithIconSize(px int) Option {
+	return func(s *Scraper) {
+		s.iconSize = px
+	}
+}
+
+// collectIcons returns every icon declared by <link> elements of doc and the
+// URL of its web app manifest, if any
+func collectIcons(doc *html.Node, baseURL *url.URL) ([]Icon, *url.URL) {
+	var icons []Icon
+	var manifest *url.URL
+	for _, link := range findAll(doc, func(n *html.Node) bool { return n.Type == html.ElementNode && n.Data == "link" }) {
+		href := strings.TrimSpace(attrValue(link, "href"))
+		if href == "" {
+			continue
+		}
+		resolved, err := baseURL.Parse(href)
+		if err != nil {
+			continue
+		}
+
+		// rel is a space-separated token list, e.g. "shortcut icon"
+		for _, rel := range strings.Fields(strings.ToLower(attrValue(link, "rel"))) {
+			icon := Icon{
+				URL:   resolved.String(),
+				Type:  strings.ToLower(attrValue(link, "type")),
+				Sizes: strings.ToLower(attrValue(link, "sizes")),
+			}
+			switch rel {
+			case "icon":
+				icon.Rel = IconRelIcon
+			case "apple-touch-icon", "apple-touch-icon-precomposed":
+				icon.Rel = IconRelApple
+			case "mask-icon":
+				icon.Rel = IconRelMask
+			case "manifest":
+				if manifest == nil {
+					manifest = resolved
+				}
+				continue
+			default:
+				continue
+			}
+			icons = append(icons, icon)
+			break
+		}
+	}
+	return icons, manifest
+}
+
+// manifestIcons fetches a web app manifest and returns its icons. Failures are
+// ignored: the manifest only adds candidates.
+func (s *Scraper) manifestIcons(ctx context.Context, manifestURL *url.URL) []Icon {
+	req, err := http.NewRequestWithContext(ctx, "GET", manifestURL.String(), nil)
+	if err != nil {
+		return nil
+	}
+	req.Header.Set("Accept", "application/manifest+json, application/json;q=0.9")
+
+	resp, err := s.do(ctx, req)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil
+	}
+
+	var manifest struct {
+		Icons []struct {
+			Src     string `json:"src"`
+			Sizes   string `json:"sizes"`
+			Type    string `json:"type"`
+			Purpose string `json:"purpose"`
+		} `json:"icons"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxManifestSize)).Decode(&manifest); err != nil {
+		return nil
+	}
+
+	var icons []Icon
+	for _, entry := range manifest.Icons {
+		// Icon URLs are relative to the manifest, not to the page
+		resolved, err := manifestURL.Parse(strings.TrimSpace(entry.Src))
+		if err != nil || entry.Src == "" {
+			continue
+		}
+		icons = append(icons, Icon{
+			URL:     resolved.String(),
+			Rel:     IconRelManifest,
+			Type:    strings.ToLower(entry.Type),
+			Sizes:   strings.ToLower(entry.Sizes),
+			Purpose: strings.ToLower(entry.Purpose),
+		})
+	}
+	return icons
+}
+
+// BestIcon returns the icon best suited for display at size pixels, or nil.
+// Scalable icons fit any size; otherwise the smallest icon at least as large
+// as size wins, then the largest smaller one. Monochrome mask icons are only
+// used when nothing else is available.
+func BestIcon(icons []Icon, size int) *Icon {
+	var best *Icon
+	bestCost := 0
+	for i := range icons {
+		cost := iconCost(icons[i], size)
+		if best == nil || cost < bestCost {
+			best, bestCost = &icons[i], cost
+		}
+	}
+	return best
+}
+
+// iconCost rates how badly icon fits size; lower is better
+func iconCost(icon Icon, size int) int {
+	// Penalties order the tiers: fitting, too small, monochrome
+	const (
+		tooSmall   = 1 << 16
+		monochrome = 1 << 20
+	)
+	cost := 0
+	if icon.Rel == IconRelMask || strings.Contains(icon.Purpose, "monochrome") {
+		cost += monochrome
+	}
+
+	best := -1
+	for _, side := range iconSides(icon) {
+		var c int
+		switch {
+		case side == scalableIconSide:
+			c = 0
+		case side >= size:
+			c = side - size
+		default:
+			c = tooSmall + size - side
+		}
+		if best < 0 || c < best {
+			best = c
+		}
+	}
+	return cost + best
+}
+
+// iconSides returns the square sizes an icon declares, guessing from its
+// relation when it declares none
+func iconSides(icon Icon) []int {
+	if icon.Type == svgMediaType || strings.EqualFold(path.Ext(iconPath(icon.URL)), ".svg") {
+		return []int{scalableIconSide}
+	}
+
+	var sides []int
+	for _, token := range strings.Fields(icon.Sizes) {
+		if token == "any" {
+			return []int{scalableIconSide}
+		}
+		width, height, ok := strings.Cut(token, "x")
+		if !ok {
+			continue
+		}
+		w, errW := strconv.Atoi(width)
+		h, errH := strconv.Atoi(height)
+		if errW != nil || errH != nil || w <= 0 || h <= 0 {
+			continue
+		}
+		if h < w {
+			w = h
+		}
+		sides = append(sides, w)
+	}
+	if len(sides) > 0 {
+		return sides
+	}
+
+	switch icon.Rel {
+	case IconRelApple:
+		// Apple's default touch icon size
+		return []int{180}
+	case IconRelMask:
+		return []int{scalableIconSide}
+	default:
+		return []int{16}
+	}
+}
+
+// iconPath returns the path of an icon URL, ignoring unparseable ones
+func iconPath(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Path
+}
+
+// findDefaultFavicon checks whether /favicon.ico exists on the host of baseURL
+func (s *Scraper) findDefaultFavicon(ctx context.Context, baseURL *url.URL) string {
+	defaultFaviconURL := *baseURL
+	defaultFaviconURL.Path = "/favicon.ico"
+	defaultFaviconURL.RawPath = ""
+	defaultFaviconURL.RawQuery = ""
+	defaultFaviconURL.Fragment = ""
+
+	req, err := http.NewRequestWithContext(ctx, "HEAD", defaultFaviconURL.String(), nil)
+	if err != nil {
+		return ""
+	}
+
+	resp, err := s.do(ctx, req)
+	if err != nil {
+		return ""
+	}
+	defer closeBody(resp.Body)
+
+	if resp.StatusCode == http.StatusOK {
+		return defaultFaviconURL.String()
+	}
+
+	return ""
+}
+
+// iconCandidate returns icon as a candidate favicon
+func iconCandidate(icon Icon) Candidate {
+	switch icon.Rel {
+	case IconRelManifest:
+		return found(models.FieldFaviconURL, icon.URL, models.SourceManifest, "")
+	case IconRelDefault:
+		return found(models.FieldFaviconURL, icon.URL, models.SourceDefaultIcon, "/favicon.ico")
+	}
+	return found(models.FieldFaviconURL, icon.URL, models.SourceIconLink, "link[rel="+icon.Rel+"]")
+}
@@ -0,0 +1,125 @@
+package scraper
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/html"
+)
+
+func TestCollectIcons(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<html><head>
+		<link rel="Shortcut Icon" href="/favicon.ico">
+		<link rel="icon" type="image/png" sizes="32x32" href="/icon-32.png">
+		<link rel="apple-touch-icon" sizes="180x180" href="/apple.png">
+		<link rel="mask-icon" href="/mask.svg" color="#000">
+		<link rel="manifest" href="/site.webmanifest">
+		<link rel="stylesheet" href="/style.css">
+		<link rel="icon" href="">
+	</head></html>`))
+	require.NoError(t, err)
+
+	icons, manifest := collectIcons(doc, mustParseURL(t, "https://example.com/blog/post"))
+	assert.Equal(t, []Icon{
+		{URL: "https://example.com/favicon.ico", Rel: IconRelIcon},
+		{URL: "https://example.com/icon-32.png", Rel: IconRelIcon, Type: "image/png", Sizes: "32x32"},
+		{URL: "https://example.com/apple.png", Rel: IconRelApple, Sizes: "180x180"},
+		{URL: "https://example.com/mask.svg", Rel: IconRelMask},
+	}, icons)
+	require.NotNil(t, manifest)
+	assert.Equal(t, "https://example.com/site.webmanifest", manifest.String())
+}
+
+func TestBestIcon(t *testing.T) {
+	ico := Icon{URL: "/favicon.ico", Rel: IconRelIcon}
+	png32 := Icon{URL: "/icon-32.png", Rel: IconRelIcon, Sizes: "32x32"}
+	multi := Icon{URL: "/multi.ico", Rel: IconRelIcon, Sizes: "16x16 48x48"}
+	apple := Icon{URL: "/apple.png", Rel: IconRelApple}
+	large := Icon{URL: "/icon-512.png", Rel: IconRelManifest, Sizes: "512x512"}
+	svg := Icon{URL: "/icon.svg", Rel: IconRelIcon, Type: "image/svg+xml"}
+	mask := Icon{URL: "/mask.svg", Rel: IconRelMask}
+
+	tests := []struct {
+		name  string
+		icons []Icon
+		size  int
+		want  string
+	}{
+		{"exact size wins", []Icon{ico, png32, apple}, 32, "/icon-32.png"},
+		{"smallest larger icon", []Icon{ico, apple, large}, 64, "/apple.png"},
+		{"largest icon when all are too small", []Icon{ico, png32}, 128, "/icon-32.png"},
+		{"declared sizes are considered individually", []Icon{png32, multi}, 48, "/multi.ico"},
+		{"scalable icons fit any size", []Icon{png32, svg, large}, 256, "/icon.svg"},
+		{"mask icons are a last resort", []Icon{mask, ico}, 64, "/favicon.ico"},
+		{"mask icon when alone", []Icon{mask}, 64, "/mask.svg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			best := BestIcon(tt.icons, tt.size)
+			require.NotNil(t, best)
+			assert.Equal(t, tt.want, best.URL)
+		})
+	}
+
+	assert.Nil(t, BestIcon(nil, 32))
+}
+
+func TestGetMetadataUsesManifestIcons(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head>
+				<link rel="icon" href="/favicon.ico">
+				<link rel="manifest" href="/static/manifest.json">
+			</head></html>`))
+		case "/static/manifest.json":
+			w.Header().Set("Content-Type", "application/manifest+json")
+			w.Write([]byte(`{"icons": [
+				{"src": "icons/192.png", "sizes": "192x192", "type": "image/png"},
+				{"src": "icons/mono.png", "sizes": "64x64", "purpose": "monochrome"}
+			]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	s := NewScraper(5*time.Second, allowLoopback, WithIconSize(128))
+	metadata, err := s.GetMetadata(context.Background(), server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/static/icons/192.png", metadata.FaviconURL)
+	assert.Len(t, metadata.Icons, 3)
+}
+
+func TestGetMetadataFallsBackToDefaultFavicon(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/page":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head><title>No icons</title></head></html>`))
+		case "/favicon.ico":
+			w.Header().Set("Content-Type", "image/x-icon")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	s := NewScraper(5*time.Second, allowLoopback)
+	metadata, err := s.GetMetadata(context.Background(), server.URL+"/page?q=1")
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/favicon.ico", metadata.FaviconURL)
+	assert.Equal(t, []Icon{{URL: server.URL + "/favicon.ico", Rel: IconRelDefault}}, metadata.Icons)
+
+	// The default favicon check honors the request context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Empty(t, s.findDefaultFavicon(ctx, mustParseURL(t, server.URL)))
+}
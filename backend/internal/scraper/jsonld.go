@@ -0,0 +1,165 @@
+package scraper
+
+import (
+	"encoding/json"
+	"mime"
+	"strings"
+
+	"bookmarks-go/internal/models"
+
+	"golang.org/x/net/html"
+)
+
+// jsonLDType is the media type of script elements holding JSON-LD structured data
+const jsonLDType = "application/ld+json"
+
+// jsonLDWorkTypes are the schema.org types whose properties describe the page
+// itself, rather than e.g. the publisher or the breadcrumbs
+var jsonLDWorkTypes = map[string]bool{
+	"Article": true, "NewsArticle": true, "BlogPosting": true, "TechArticle": true,
+	"ScholarlyArticle": true, "Report": true, "SocialMediaPosting": true, "LiveBlogPosting": true,
+	"WebPage": true, "AboutPage": true, "FAQPage": true, "QAPage": true, "ItemPage": true,
+	"CreativeWork": true, "VideoObject": true, "Recipe": true, "Book": true, "Course": true,
+}
+
+// isJSONLD reports whether n is a script element holding JSON-LD
+func isJSONLD(n *html.Node) bool {
+	if n.Type != html.ElementNode || n.Data != "script" {
+		return false
+	}
+	for _, attr := range n.Attr {
+		if attr.Key == "type" {
+			mediaType, _, err := mime.ParseMediaType(attr.Val)
+			return err == nil && mediaType == jsonLDType
+		}
+	}
+	return false
+}
+
+// jsonLDCandidates returns the values of the first node describing the page
+// in the JSON-LD of a script element. Invalid JSON yields no candidates.
+func jsonLDCandidates(script *html.Node) []Candidate {
+	var text strings.Builder
+	for c := script.FirstChild; c != nil; c = c.NextSibling {
+		text.WriteString(c.Data)
+	}
+	var data interface{}
+	if err := json.Unmarshal([]byte(text.String()), &data); err != nil {
+		return nil
+	}
+
+	node, typ := findJSONLDWork(data)
+	if node == nil {
+		return nil
+	}
+	var candidates []Candidate
+	add := func(field, value, property string) {
+		if value = strings.TrimSpace(value); value != "" {
+			candidates = append(candidates, found(field, value, models.SourceJSONLD, typ+"."+property))
+		}
+	}
+	if headline := jsonLDString(node["headline"]); headline != "" {
+		add(models.FieldTitle, headline, "headline")
+	} else {
+		add(models.FieldTitle, jsonLDString(node["name"]), "name")
+	}
+	add(models.FieldDescription, jsonLDString(node["description"]), "description")
+	add(models.FieldAuthor, strings.Join(jsonLDNames(node["author"]), ", "), "author")
+	add(models.FieldImageURL, jsonLDURL(node["image"]), "image")
+	add(models.FieldPublished, jsonLDString(node["datePublished"]), "datePublished")
+	return candidates
+}
+
+// findJSONLDWork returns the first node of a work type and that type, looking
+// through arrays and @graph lists
+func findJSONLDWork(data interface{}) (map[string]interface{}, string) {
+	switch v := data.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if node, typ := findJSONLDWork(item); node != nil {
+				return node, typ
+			}
+		}
+	case map[string]interface{}:
+		for _, typ := range jsonLDTypes(v["@type"]) {
+			if jsonLDWorkTypes[typ] {
+				return v, typ
+			}
+		}
+		if graph, ok := v["@graph"]; ok {
+			return findJSONLDWork(graph)
+		}
+	}
+	return nil, ""
+}
+
+// jsonLDTypes returns the types of a node, given as a string or a list
+func jsonLDTypes(v interface{}) []string {
+	switch t := v.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		var types []string
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+// jsonLDString returns a text property, or the first of a list of texts
+func jsonLDString(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case []interface{}:
+		if len(t) > 0 {
+			return jsonLDString(t[0])
+		}
+	}
+	return ""
+}
+
+// jsonLDNames returns the names of people or organizations given as texts,
+// nodes with a name, or a list of either
+func jsonLDNames(v interface{}) []string {
+	switch t := v.(type) {
+	case string:
+		if t = strings.TrimSpace(t); t != "" {
+			return []string{t}
+		}
+	case map[string]interface{}:
+		return jsonLDNames(t["name"])
+	case []interface{}:
+		var names []string
+		for _, item := range t {
+			names = append(names, jsonLDNames(item)...)
+		}
+		return names
+	}
+	return nil
+}
+
+// jsonLDURL returns the URL of an image given as a URL, an ImageObject, or a
+// list of either
+func jsonLDURL(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case map[string]interface{}:
+		if url := jsonLDURL(t["url"]); url != "" {
+			return url
+		}
+		return jsonLDURL(t["contentUrl"])
+	case []interface{}:
+		for _, item := range t {
+			if url := jsonLDURL(item); url != "" {
+				return url
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,77 @@
+package scraper
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"bookmarks-go/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/html"
+)
+
+// jsonLDScript returns a script element holding data
+func jsonLDScript(data string) *html.Node {
+	script := &html.Node{Type: html.ElementNode, Data: "script", Attr: []html.Attribute{{Key: "type", Val: "application/ld+json; charset=utf-8"}}}
+	script.AppendChild(&html.Node{Type: html.TextNode, Data: data})
+	return script
+}
+
+func TestJSONLDCandidates(t *testing.T) {
+	candidates := jsonLDCandidates(jsonLDScript(`{"@context": "https://schema.org", "@graph": [
+		{"@type": "Organization", "name": "Publisher", "description": "Not the page"},
+		{"@type": ["BlogPosting", "Article"], "headline": " Post title ", "name": "Other",
+			"description": "Post summary", "datePublished": "2024-03-01T10:00:00Z",
+			"author": [{"@type": "Person", "name": "Ada"}, "Grace"],
+			"image": [{"@type": "ImageObject", "url": "/cover.jpg"}, "/other.jpg"]}
+	]}`))
+
+	values := make(map[string]string)
+	for _, c := range candidates {
+		values[c.Field] = c.Value
+		assert.Equal(t, models.SourceJSONLD, c.Source)
+		assert.Equal(t, 0.85, c.Confidence)
+	}
+	assert.Equal(t, map[string]string{
+		models.FieldTitle:       "Post title",
+		models.FieldDescription: "Post summary",
+		models.FieldAuthor:      "Ada, Grace",
+		models.FieldImageURL:    "/cover.jpg",
+		models.FieldPublished:   "2024-03-01T10:00:00Z",
+	}, values)
+	assert.Equal(t, "BlogPosting.headline", candidates[0].Rule)
+
+	// Works without a headline are named by their name
+	candidates = jsonLDCandidates(jsonLDScript(`[{"@type": "VideoObject", "name": "Clip", "author": {"name": "Studio"}}]`))
+	require.Len(t, candidates, 2)
+	assert.Equal(t, Candidate{Field: models.FieldTitle, Value: "Clip", FieldProvenance: models.FieldProvenance{
+		Source: models.SourceJSONLD, Rule: "VideoObject.name", Confidence: 0.85,
+	}}, candidates[0])
+	assert.Equal(t, "Studio", candidates[1].Value)
+
+	// Nodes that do not describe the page and invalid JSON yield nothing
+	assert.Empty(t, jsonLDCandidates(jsonLDScript(`{"@type": "BreadcrumbList", "name": "Home"}`)))
+	assert.Empty(t, jsonLDCandidates(jsonLDScript(`{"@type": "Article", "headline": `)))
+}
+
+func TestJSONLDFillsEmptyFields(t *testing.T) {
+	doc := `<html><head><title>Page title</title>
+<script type="application/ld+json">{"@type": "NewsArticle", "headline": "Headline",
+	"author": {"name": "Reporter"}, "image": "https://example.com/a.jpg", "datePublished": "2024-03-01"}</script>
+<script>var ignored = {"@type": "Article"};</script>
+</head><body></body></html>`
+
+	head, err := parseHead(strings.NewReader(doc), io.Discard)
+	require.NoError(t, err)
+
+	var m Metadata
+	m.extractMetadata(head)
+	assert.Equal(t, "Page title", m.Title)
+	assert.Equal(t, "Reporter", m.Author)
+	assert.Equal(t, "https://example.com/a.jpg", m.ImageURL)
+	assert.Equal(t, "2024-03-01", m.Published)
+	assert.Equal(t, models.SourceHTMLTitle, m.Provenance[models.FieldTitle].Source)
+	assert.Equal(t, models.FieldProvenance{Source: models.SourceJSONLD, Rule: "NewsArticle.author", Confidence: 0.85}, m.Provenance[models.FieldAuthor])
+}
@@ -0,0 +1,89 @@
+package scraper
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// LinkStatus is the outcome of checking whether a URL still resolves
+type LinkStatus struct {
+	// StatusCode is the status of the final response, or 0 if none was received
+	StatusCode int
+	FinalURL   string
+	Latency    time.Duration
+	// Error describes why no response was received
+	Error string
+	// TLSError is set when the connection failed certificate verification or the handshake
+	TLSError string
+	// Inconclusive is set when the link was not requested, because robots.txt
+	// disallows it or the host's circuit breaker is open
+	Inconclusive bool
+}
+
+// OK reports whether the link works. 401, 403 and 429 responses count as
+// working, since they come from sites that are up but refuse anonymous or bot traffic.
+func (l *LinkStatus) OK() bool {
+	if l.StatusCode == 0 {
+		return false
+	}
+	switch l.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests:
+		return true
+	}
+	return l.StatusCode < 400
+}
+
+// CheckLink requests rawURL, following redirects, without reading the body.
+// Failures are reported in the result; an error is returned only for invalid
+// URLs and when ctx is done.
+func (s *Scraper) CheckLink(ctx context.Context, rawURL string) (*LinkStatus, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return nil, errors.New("URL must start with http:// or https://")
+	}
+
+	var chain []Redirect
+	start := time.Now()
+	resp, err := s.fetch(ctx, target, &chain, nil)
+	status := &LinkStatus{Latency: time.Since(start)}
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		status.Error = err.Error()
+		status.Inconclusive = errors.Is(err, ErrDisallowedByRobots) || errors.Is(err, ErrCircuitOpen)
+		if isTLSError(err) {
+			status.TLSError = err.Error()
+		}
+		return status, nil
+	}
+	resp.Body.Close()
+
+	status.StatusCode = resp.StatusCode
+	status.FinalURL = resp.Request.URL.String()
+	return status, nil
+}
+
+// isTLSError reports whether err was caused by certificate verification or the TLS handshake
+func isTLSError(err error) bool {
+	var verification *tls.CertificateVerificationError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	var header tls.RecordHeaderError
+	var alert tls.AlertError
+	return errors.As(err, &verification) ||
+		errors.As(err, &unknownAuthority) ||
+		errors.As(err, &hostname) ||
+		errors.As(err, &invalid) ||
+		errors.As(err, &header) ||
+		errors.As(err, &alert)
+}
@@ -0,0 +1,84 @@
+package scraper
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckLink(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "gone", http.StatusGone)
+	})
+	mux.HandleFunc("/private", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	scraper := NewScraper(5*time.Second, allowLoopback)
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantFinal  string
+		wantOK     bool
+	}{
+		{path: "/ok", wantStatus: http.StatusOK, wantFinal: ts.URL + "/ok", wantOK: true},
+		{path: "/moved", wantStatus: http.StatusOK, wantFinal: ts.URL + "/ok", wantOK: true},
+		{path: "/gone", wantStatus: http.StatusGone, wantFinal: ts.URL + "/gone", wantOK: false},
+		{path: "/private", wantStatus: http.StatusForbidden, wantFinal: ts.URL + "/private", wantOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			status, err := scraper.CheckLink(context.Background(), ts.URL+tt.path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, status.StatusCode)
+			assert.Equal(t, tt.wantFinal, status.FinalURL)
+			assert.Equal(t, tt.wantOK, status.OK())
+			assert.Empty(t, status.Error)
+		})
+	}
+}
+
+func TestCheckLinkTLSError(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	// The test server's certificate is not trusted by the scraper
+	scraper := NewScraper(5*time.Second, allowLoopback, WithRetryPolicy(RetryPolicy{MaxAttempts: 1}))
+
+	status, err := scraper.CheckLink(context.Background(), ts.URL)
+	require.NoError(t, err)
+	assert.Zero(t, status.StatusCode)
+	assert.False(t, status.OK())
+	assert.NotEmpty(t, status.Error)
+	assert.NotEmpty(t, status.TLSError)
+}
+
+func TestCheckLinkConnectionRefused(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	addr := ts.URL
+	ts.Close()
+
+	scraper := NewScraper(5*time.Second, allowLoopback, WithRetryPolicy(RetryPolicy{MaxAttempts: 1}))
+
+	status, err := scraper.CheckLink(context.Background(), addr)
+	require.NoError(t, err)
+	assert.False(t, status.OK())
+	assert.NotEmpty(t, status.Error)
+	assert.Empty(t, status.TLSError)
+}
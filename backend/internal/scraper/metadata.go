@@ -1,40 +1,166 @@
 package scraper
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/url"
+	"path"
 	"strings"
 	"time"
 
+	"bookmarks-go/internal/models"
+
 	"golang.org/x/net/html"
 )
 
 // Metadata represents the scraped information from a webpage
 type Metadata struct {
-	Title       string
-	Description string
-	FaviconURL  string
+	Title         string
+	Description   string
+	Author        string
+	FaviconURL    string
+	Icons         []Icon
+	ImageURL      string
+	FinalURL      string
+	Redirects     []Redirect
+	MediaType     string
+	ContentLength int64
+	PageCount     int
+	Content       *Content
+	Extra         *models.Extra
+	// Published is the publication date declared by the page, as written
+	Published string
+	// Provenance records where the values of the descriptive fields were found
+	Provenance models.Provenance
+	Validators Validators
+	// NotModified is set when the page is unchanged since the validators passed
+	// to the fetch; the other fields then repeat the previous result, if known
+	NotModified bool
 }
 
 // Scraper handles webpage metadata extraction
 type Scraper struct {
-	client *http.Client
+	client          *http.Client
+	maxRedirects    int
+	maxBodySize     int64
+	extractContent  bool
+	allowedNetworks []*net.IPNet
+	robotsPolicy    RobotsPolicy
+	robots          *robotsCache
+	limiter         *hostLimiter
+	retry           RetryPolicy
+	retryStats      retryStats
+	breakers        *breakerRegistry
+	extractors      *Registry
+	cache           *resultCache
+	iconSize        int
+	flights         flightGroup[Metadata]
+	userAgent       string
+	proxyURL        *url.URL
+	rootCAs         *x509.CertPool
+	domainRules     []DomainRule
+}
+
+// Option configures optional Scraper behavior
+type Option func(*Scraper)
+
+// WithContentExtraction enables extraction of the main article body
+func WithContentExtraction() Option {
+	return func(s *Scraper) {
+		s.extractContent = true
+	}
 }
 
 // NewScraper creates a new metadata scraper with configured timeout
-func NewScraper(timeout time.Duration) *Scraper {
-	return &Scraper{
-		client: &http.Client{
-			Timeout: timeout,
+func NewScraper(timeout time.Duration, opts ...Option) *Scraper {
+	s := &Scraper{
+		maxRedirects: DefaultMaxRedirects,
+		maxBodySize:  DefaultMaxBodySize,
+		robotsPolicy: RobotsIgnore,
+		limiter:      newHostLimiter(0, 0),
+		retry:        RetryPolicy{MaxAttempts: 1},
+		breakers:     newBreakerRegistry(DefaultBreakerThreshold, DefaultBreakerCooldown),
+		extractors:   DefaultRegistry(),
+		cache:        newResultCache(0, 0),
+		iconSize:     DefaultIconSize,
+		userAgent:    DefaultUserAgent,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.client = &http.Client{
+		Timeout:   timeout,
+		Transport: s.newTransport(timeout),
+		// Redirects are followed manually so that every hop can be recorded
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
 		},
 	}
+	s.robots = newRobotsCache(s.client)
+	return s
 }
 
-// GetMetadata fetches and extracts metadata from the given URL
+// GetMetadata fetches and extracts metadata from the given URL. With a result
+// cache, recent results are reused and older ones are revalidated.
 func (s *Scraper) GetMetadata(ctx context.Context, urlStr string) (*Metadata, error) {
+	if traceFrom(ctx) != nil {
+		return s.scrape(ctx, urlStr, nil)
+	}
+	cached, fresh := s.cache.get(urlStr)
+	if fresh {
+		return cached, nil
+	}
+	var validators *Validators
+	if cached != nil {
+		validators = &cached.Validators
+	}
+	return s.getMetadata(ctx, urlStr, validators, cached)
+}
+
+// Revalidate fetches urlStr with a conditional request for the given validators,
+// such as those stored with a bookmark. If the page is unchanged the result has
+// NotModified set and, unless a previous result is cached, no other metadata.
+func (s *Scraper) Revalidate(ctx context.Context, urlStr string, validators Validators) (*Metadata, error) {
+	cached, _ := s.cache.get(urlStr)
+	return s.getMetadata(ctx, urlStr, &validators, cached)
+}
+
+// getMetadata scrapes urlStr and updates the result cache. Concurrent calls
+// for the same page share one scrape.
+func (s *Scraper) getMetadata(ctx context.Context, urlStr string, validators *Validators, previous *Metadata) (*Metadata, error) {
+	metadata, err := s.flights.do(ctx, flightKey(ctx, urlStr, validators), func() (*Metadata, error) {
+		return s.scrape(ctx, urlStr, validators)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if metadata.NotModified {
+		if previous == nil {
+			return metadata, nil
+		}
+		// A 304 has no body, so the previous result still describes the page
+		merged := metadata.Validators.merge(previous.Validators)
+		metadata = previous
+		metadata.Validators = merged
+		metadata.NotModified = true
+	}
+	s.cache.put(urlStr, metadata)
+	return metadata, nil
+}
+
+// scrape fetches urlStr and extracts its metadata, sending conditional
+// headers for validators if given
+func (s *Scraper) scrape(ctx context.Context, urlStr string, validators *Validators) (*Metadata, error) {
 	// Validate URL
 	parsedURL, err := url.Parse(urlStr)
 	if err != nil {
@@ -45,118 +171,421 @@ func (s *Scraper) GetMetadata(ctx context.Context, urlStr string) (*Metadata, er
 		return nil, errors.New("URL must start with http:// or https://")
 	}
 
-	// Create request with context
-	req, err := http.NewRequestWithContext(ctx, "GET", urlStr, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	trace := traceFrom(ctx)
+	defer trace.timing("total", time.Now())
+
+	metadata := &Metadata{}
+	currentURL := parsedURL
+	var pg *page
+	for {
+		pg, err = s.fetchPage(ctx, currentURL, &metadata.Redirects, validators)
+		if err != nil {
+			return nil, err
+		}
+		currentURL = pg.url
+		if pg.notModified {
+			metadata.FinalURL = currentURL.String()
+			metadata.Validators = pg.validators
+			metadata.NotModified = true
+			return metadata, nil
+		}
+		if pg.head == nil {
+			break
+		}
+
+		// Follow <meta http-equiv="refresh"> redirects like a browser would
+		target := metaRefreshTarget(pg.head, currentURL)
+		if target == nil {
+			break
+		}
+		metadata.Redirects = append(metadata.Redirects, Redirect{
+			URL:        currentURL.String(),
+			StatusCode: http.StatusOK,
+			Kind:       RedirectMetaRefresh,
+		})
+		if len(metadata.Redirects) > s.maxRedirects {
+			return nil, ErrTooManyRedirects
+		}
+		currentURL = target
+	}
+	metadata.FinalURL = currentURL.String()
+	metadata.MediaType = pg.mediaType
+	metadata.ContentLength = pg.contentLength
+	metadata.Validators = pg.validators
+	if validators != nil && validators.BodyHash != "" && validators.BodyHash == pg.validators.BodyHash {
+		// Unchanged like after a 304, so nothing is extracted again
+		metadata.NotModified = true
+		return metadata, nil
+	}
+
+	if pg.mediaType == pdfMediaType {
+		before := *metadata
+		metadata.applyPDF(pg.data, s.extractContent)
+		metadata.recordChanges(&before, models.SourcePDF, "document info", trace)
+	}
+
+	if pg.head == nil {
+		// Non-HTML resources without a title are named after the file they point to
+		if metadata.Title == "" {
+			c := found(models.FieldTitle, fileName(currentURL), models.SourceFileName, "")
+			trace.candidate(c)
+			metadata.choose(c, trace)
+		}
+		return metadata, nil
+	}
+
+	// Extract generic metadata from the document
+	metadata.applyCandidates(headCandidates(pg.head), trace)
+	if metadata.ImageURL != "" {
+		metadata.ImageURL = resolveURL(currentURL, metadata.ImageURL)
+	}
+
+	// Choose the favicon among every declared icon, including manifest icons
+	icons, manifest := collectIcons(pg.head, currentURL)
+	if manifest != nil {
+		start := time.Now()
+		icons = append(icons, s.manifestIcons(ctx, manifest)...)
+		trace.timing("manifest", start)
+	}
+	metadata.Icons = icons
+	for _, icon := range icons {
+		trace.candidate(iconCandidate(icon))
+	}
+	if best := BestIcon(icons, s.iconSize); best != nil {
+		metadata.choose(iconCandidate(*best), trace)
 	}
 
-	// Set user agent to avoid being blocked
-	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; BookmarksBot/1.0)")
+	var doc *html.Node
+	if pg.html != nil {
+		start := time.Now()
+		doc, err = html.Parse(bytes.NewReader(pg.html))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse HTML: %w", err)
+		}
+		trace.timing("parse document", start)
+	}
+
+	// Site-specific extractors refine the generic results
+	if extractor := s.extractors.Lookup(currentURL); extractor != nil && doc != nil {
+		start := time.Now()
+		before := *metadata
+		extractor.Extract(doc, currentURL, metadata)
+		metadata.recordChanges(&before, models.SourceExtractor, extractor.Name(), trace)
+		trace.timing("extractor", start)
+	}
+
+	if s.extractContent && doc != nil {
+		start := time.Now()
+		metadata.Content = ExtractContent(doc, currentURL)
+		trace.timing("content", start)
+	}
+
+	// If favicon not found in metadata, try default location
+	if metadata.FaviconURL == "" {
+		start := time.Now()
+		faviconURL := s.findDefaultFavicon(ctx, currentURL)
+		trace.timing("default favicon", start)
+		if faviconURL != "" {
+			icon := Icon{URL: faviconURL, Rel: IconRelDefault}
+			metadata.Icons = append(metadata.Icons, icon)
+			c := iconCandidate(icon)
+			trace.candidate(c)
+			metadata.choose(c, trace)
+		}
+	}
+
+	return metadata, nil
+}
+
+// page is a fetched resource. head is nil unless the response was HTML, and
+// html only holds the whole document when it is needed.
+type page struct {
+	url           *url.URL
+	head          *html.Node
+	html          []byte
+	data          []byte
+	mediaType     string
+	contentLength int64
+	validators    Validators
+	notModified   bool
+}
 
-	// Perform request
-	resp, err := s.client.Do(req)
+// fetchPage fetches target, following HTTP redirects, and parses HTML responses.
+// Bodies are read up to the configured limit; other media types are not downloaded.
+// With validators the request is conditional and a 304 yields a notModified page.
+func (s *Scraper) fetchPage(ctx context.Context, target *url.URL, chain *[]Redirect, validators *Validators) (*page, error) {
+	trace := traceFrom(ctx)
+	start := time.Now()
+	resp, err := s.fetch(ctx, target, chain, validators)
+	trace.timing("fetch "+target.String(), start)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch URL: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
+	defer closeBody(resp.Body)
 
+	pg := &page{
+		url: resp.Request.URL,
+		validators: Validators{
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+		},
+	}
+	if resp.StatusCode == http.StatusNotModified && validators != nil {
+		pg.notModified = true
+		pg.validators = pg.validators.merge(*validators)
+		return pg, nil
+	}
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
-	// Parse HTML
-	doc, err := html.Parse(resp.Body)
+	body := bufio.NewReaderSize(io.LimitReader(resp.Body, s.maxBodySize), sniffLength)
+	pg.mediaType = responseMediaType(resp, body)
+	pg.contentLength = resp.ContentLength
+	if pg.contentLength < 0 {
+		pg.contentLength = 0
+	}
+	if pg.mediaType == pdfMediaType {
+		// PDF metadata may live anywhere in the file, so read it whole
+		pg.data, err = io.ReadAll(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read PDF: %w", err)
+		}
+		if pg.contentLength == 0 {
+			pg.contentLength = int64(len(pg.data))
+		}
+		sum := sha256.Sum256(pg.data)
+		pg.validators.BodyHash = hex.EncodeToString(sum[:])
+		return pg, nil
+	}
+	if !isHTMLType(pg.mediaType) {
+		return pg, nil
+	}
+
+	// Metadata lives in the head, which is tokenized as it streams in. Only
+	// content extraction and site extractors need the whole document, which is
+	// read here and parsed by scrape once it is known to have changed.
+	start = time.Now()
+	defer trace.timing("parse", start)
+	hash := sha256.New()
+	if !s.extractContent && s.extractors.Lookup(pg.url) == nil {
+		pg.head, err = parseHead(body, hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse HTML: %w", err)
+		}
+		pg.validators.BodyHash = hex.EncodeToString(hash.Sum(nil))
+		return pg, nil
+	}
+
+	// The tokenizer reads ahead, so everything it reads is kept, hashing
+	// exactly the bytes of the document
+	var document bytes.Buffer
+	sink := io.MultiWriter(&document, hash)
+	pg.head, err = parseHead(io.TeeReader(body, sink), io.Discard)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse HTML: %w", err)
 	}
+	if _, err := io.Copy(sink, body); err != nil {
+		return nil, fmt.Errorf("failed to read HTML: %w", err)
+	}
+	pg.html = document.Bytes()
+	pg.validators.BodyHash = hex.EncodeToString(hash.Sum(nil))
 
-	// Extract metadata
-	metadata := &Metadata{}
-	metadata.extractMetadata(doc, parsedURL)
+	return pg, nil
+}
 
-	// If favicon not found in metadata, try default location
-	if metadata.FaviconURL == "" {
-		metadata.FaviconURL = s.findDefaultFavicon(parsedURL)
+// applyPDF fills metadata from the document information of a PDF.
+// Unparseable documents leave the metadata unchanged.
+func (m *Metadata) applyPDF(data []byte, withContent bool) {
+	info, err := ParsePDF(data)
+	if err != nil {
+		return
 	}
 
-	return metadata, nil
+	m.Title = info.Title
+	m.Author = info.Author
+	m.Description = info.Subject
+	m.PageCount = info.PageCount
+
+	if withContent && info.FirstPageText != "" {
+		var sb strings.Builder
+		for _, line := range strings.Split(info.FirstPageText, "\n") {
+			sb.WriteString("<p>" + html.EscapeString(line) + "</p>")
+		}
+		// The first page says little about the length of the document, so the
+		// word count and reading time are left unset
+		m.Content = &Content{
+			Text: info.FirstPageText,
+			HTML: sb.String(),
+		}
+	}
+}
+
+// resolveURL resolves ref against base, returning an empty string if it is invalid
+func resolveURL(base *url.URL, ref string) string {
+	resolved, err := base.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return ""
+	}
+	return resolved.String()
+}
+
+// fileName returns the last path segment of u, or its host if the path is empty
+func fileName(u *url.URL) string {
+	name := path.Base(u.Path)
+	if name == "/" || name == "." {
+		return u.Host
+	}
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		return unescaped
+	}
+	return name
 }
 
 // extractMetadata traverses the HTML tree to find metadata
-func (m *Metadata) extractMetadata(n *html.Node, baseURL *url.URL) {
-	if n.Type == html.ElementNode {
-		switch n.Data {
-		case "title":
-			if m.Title == "" && n.FirstChild != nil {
-				m.Title = n.FirstChild.Data
-			}
-		case "meta":
-			var name, content string
-			for _, attr := range n.Attr {
-				switch attr.Key {
-				case "name", "property":
-					name = attr.Val
-				case "content":
-					content = attr.Val
+func (m *Metadata) extractMetadata(n *html.Node) {
+	m.applyCandidates(headCandidates(n), nil)
+}
+
+// headCandidates returns the metadata values declared in the HTML tree, in
+// document order, followed by those of JSON-LD structured data, which only
+// fill fields the tags leave empty
+func headCandidates(n *html.Node) []Candidate {
+	var candidates, structured []Candidate
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "script":
+				if isJSONLD(n) {
+					structured = append(structured, jsonLDCandidates(n)...)
 				}
-			}
-			switch name {
-			case "description", "og:description":
-				if m.Description == "" {
-					m.Description = content
+			case "title":
+				if n.FirstChild != nil && n.FirstChild.Data != "" {
+					candidates = append(candidates, found(models.FieldTitle, n.FirstChild.Data, models.SourceHTMLTitle, "title"))
 				}
-			case "og:title":
-				if m.Title == "" {
-					m.Title = content
+			case "meta":
+				var key, name, content string
+				for _, attr := range n.Attr {
+					switch attr.Key {
+					case "name", "property":
+						key, name = attr.Key, attr.Val
+					case "content":
+						content = attr.Val
+					}
 				}
-			}
-		case "link":
-			var rel, href string
-			for _, attr := range n.Attr {
-				switch attr.Key {
-				case "rel":
-					rel = attr.Val
-				case "href":
-					href = attr.Val
+				var field string
+				switch name {
+				case "description", "og:description":
+					field = models.FieldDescription
+				case "og:title":
+					field = models.FieldTitle
+				case "author":
+					field = models.FieldAuthor
+				case "og:image", "og:image:url", "og:image:secure_url", "twitter:image":
+					field = models.FieldImageURL
 				}
-			}
-			if rel == "icon" || rel == "shortcut icon" {
-				if href != "" {
-					faviconURL, err := baseURL.Parse(href)
-					if err == nil {
-						m.FaviconURL = faviconURL.String()
+				if field != "" && content != "" {
+					source := models.SourceMeta
+					switch {
+					case strings.HasPrefix(name, "og:"):
+						source = models.SourceOpenGraph
+					case strings.HasPrefix(name, "twitter:"):
+						source = models.SourceTwitterCard
 					}
+					candidates = append(candidates, found(field, content, source, "meta["+key+"="+name+"]"))
 				}
 			}
 		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
 	}
+	walk(n)
+	return append(candidates, structured...)
+}
 
-	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		m.extractMetadata(c, baseURL)
+// applyCandidates sets each empty field to the first of its candidates and
+// records every candidate in t
+func (m *Metadata) applyCandidates(candidates []Candidate, t *Trace) {
+	for _, c := range candidates {
+		t.candidate(c)
+		if field := m.field(c.Field); field != nil && *field == "" {
+			m.choose(c, t)
+		}
 	}
 }
 
-// findDefaultFavicon attempts to find favicon at the default location
-func (s *Scraper) findDefaultFavicon(baseURL *url.URL) string {
-	defaultFaviconURL := *baseURL
-	defaultFaviconURL.Path = "/favicon.ico"
+// confidences is how far values are trusted by source: site extractors know
+// the layout of their pages, Open Graph tags are written for link previews,
+// and names taken from the URL are a last resort
+var confidences = map[string]float64{
+	models.SourceExtractor:   0.95,
+	models.SourceOpenGraph:   0.9,
+	models.SourceJSONLD:      0.85,
+	models.SourceIconLink:    0.9,
+	models.SourceManifest:    0.9,
+	models.SourceHTMLTitle:   0.8,
+	models.SourceMeta:        0.8,
+	models.SourceTwitterCard: 0.8,
+	models.SourcePDF:         0.7,
+	models.SourceDefaultIcon: 0.6,
+	models.SourceFileName:    0.3,
+}
 
-	// Try to fetch favicon
-	req, err := http.NewRequest("HEAD", defaultFaviconURL.String(), nil)
-	if err != nil {
-		return ""
+// found returns value as a candidate for field, found by rule in source
+func found(field, value, source, rule string) Candidate {
+	return Candidate{
+		Field: field,
+		Value: value,
+		FieldProvenance: models.FieldProvenance{
+			Source:     source,
+			Rule:       rule,
+			Confidence: confidences[source],
+		},
 	}
+}
 
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return ""
-	}
-	defer resp.Body.Close()
+// choose sets the field of c to its value and records its provenance
+func (m *Metadata) choose(c Candidate, t *Trace) {
+	*m.field(c.Field) = c.Value
+	m.Provenance = m.Provenance.Set(c.Field, c.FieldProvenance)
+	t.selected(c)
+}
 
-	if resp.StatusCode == http.StatusOK {
-		return defaultFaviconURL.String()
+// recordChanges records the descriptive fields that differ from before as
+// found by rule in source, e.g. after a site extractor ran
+func (m *Metadata) recordChanges(before *Metadata, source, rule string, t *Trace) {
+	for _, name := range models.EditableFields {
+		value := *m.field(name)
+		if value == *before.field(name) {
+			continue
+		}
+		if value == "" {
+			delete(m.Provenance, name)
+			continue
+		}
+		c := found(name, value, source, rule)
+		t.candidate(c)
+		m.choose(c, t)
 	}
+}
 
-	return ""
+// field returns the descriptive field with the given name, or nil
+func (m *Metadata) field(name string) *string {
+	switch name {
+	case models.FieldTitle:
+		return &m.Title
+	case models.FieldDescription:
+		return &m.Description
+	case models.FieldAuthor:
+		return &m.Author
+	case models.FieldFaviconURL:
+		return &m.FaviconURL
+	case models.FieldImageURL:
+		return &m.ImageURL
+	case models.FieldPublished:
+		return &m.Published
+	}
+	return nil
 }
@@ -17,6 +17,7 @@ func TestGetMetadata(t *testing.T) {
 		wantTitle   string
 		wantDesc    string
 		wantFavicon string
+		wantImage   string
 	}{
 		{
 			name: "complete metadata",
@@ -58,6 +59,7 @@ func TestGetMetadata(t *testing.T) {
 				<head>
 					<meta property="og:title" content="OG Title">
 					<meta property="og:description" content="OG Description">
+					<meta property="og:image" content="/images/preview.jpg">
 					<link rel="shortcut icon" href="/favicon.png">
 				</head>
 				<body>Test content</body>
@@ -66,6 +68,7 @@ func TestGetMetadata(t *testing.T) {
 			wantTitle:   "OG Title",
 			wantDesc:    "OG Description",
 			wantFavicon: "/favicon.png",
+			wantImage:   "/images/preview.jpg",
 		},
 	}
 
@@ -79,7 +82,7 @@ func TestGetMetadata(t *testing.T) {
 			defer ts.Close()
 
 			// Create scraper with timeout
-			scraper := NewScraper(5 * time.Second)
+			scraper := NewScraper(5*time.Second, allowLoopback)
 
 			// Extract metadata
 			metadata, err := scraper.GetMetadata(context.Background(), ts.URL)
@@ -91,6 +94,9 @@ func TestGetMetadata(t *testing.T) {
 			if tt.wantFavicon != "" {
 				assert.NotEmpty(t, metadata.FaviconURL)
 			}
+			if tt.wantImage != "" {
+				assert.Equal(t, ts.URL+tt.wantImage, metadata.ImageURL)
+			}
 		})
 	}
 }
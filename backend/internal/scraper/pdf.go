@@ -0,0 +1,842 @@
+package scraper
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// pdfMediaType is the media type of PDF documents
+const pdfMediaType = "application/pdf"
+
+// maxFirstPageText limits the amount of first-page text kept from a PDF
+const maxFirstPageText = 5000
+
+// maxPageTreeDepth guards against cyclic page trees in malformed documents
+const maxPageTreeDepth = 32
+
+// ErrInvalidPDF is returned when data does not look like a PDF document
+var ErrInvalidPDF = errors.New("invalid PDF document")
+
+var (
+	pdfObjectHeader = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+	pdfReference    = regexp.MustCompile(`^(\d+)\s+(\d+)\s+R`)
+	pdfReferences   = regexp.MustCompile(`(\d+)\s+\d+\s+R`)
+	pdfInfoRef      = regexp.MustCompile(`/Info\s*(\d+)\s+\d+\s+R`)
+	pdfFontRefs     = regexp.MustCompile(`/([^\s/<>\[\]()]+)\s*(\d+)\s+\d+\s+R`)
+)
+
+// PDFInfo holds document information extracted from a PDF file
+type PDFInfo struct {
+	Title         string
+	Author        string
+	Subject       string
+	PageCount     int
+	FirstPageText string
+}
+
+// pdfDocument is a loosely parsed PDF: a map of object numbers to object bodies.
+// It does not rely on the cross-reference table, so truncated and slightly
+// damaged files still yield whatever objects can be found.
+type pdfDocument struct {
+	data    []byte
+	objects map[int][]byte
+}
+
+// ParsePDF extracts the document information, page count and first-page text from a PDF
+func ParsePDF(data []byte) (*PDFInfo, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, "\x00\t\r\n "), []byte("%PDF-")) {
+		return nil, ErrInvalidPDF
+	}
+
+	doc := &pdfDocument{data: data, objects: make(map[int][]byte)}
+	doc.scanObjects()
+	doc.expandObjectStreams()
+
+	info := &PDFInfo{}
+	if matches := pdfInfoRef.FindAllSubmatch(data, -1); len(matches) > 0 {
+		// The last trailer wins, as incremental updates append a new one
+		num, _ := strconv.Atoi(string(matches[len(matches)-1][1]))
+		if dict, ok := doc.objects[num]; ok {
+			info.Title = doc.stringValue(dict, "Title")
+			info.Author = doc.stringValue(dict, "Author")
+			info.Subject = doc.stringValue(dict, "Subject")
+		}
+	}
+
+	root := doc.pageTreeRoot()
+	if root != nil {
+		if count, err := strconv.Atoi(string(doc.resolve(dictValue(root, "Count")))); err == nil {
+			info.PageCount = count
+		}
+		if page := doc.firstPage(root, 0); page != nil {
+			info.FirstPageText = doc.pageText(page)
+		}
+	}
+	if info.PageCount == 0 {
+		info.PageCount = doc.countPageObjects()
+	}
+
+	return info, nil
+}
+
+// scanObjects collects every "N G obj ... endobj" body in file order,
+// so that objects from later incremental updates replace earlier ones
+func (d *pdfDocument) scanObjects() {
+	locs := pdfObjectHeader.FindAllSubmatchIndex(d.data, -1)
+	for i, loc := range locs {
+		num, err := strconv.Atoi(string(d.data[loc[2]:loc[3]]))
+		if err != nil {
+			continue
+		}
+		start := loc[1]
+		end := len(d.data)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+		body := d.data[start:end]
+		if idx := bytes.LastIndex(body, []byte("endobj")); idx >= 0 {
+			body = body[:idx]
+		}
+		d.objects[num] = bytes.TrimSpace(body)
+	}
+}
+
+// expandObjectStreams adds the objects stored in compressed object streams (PDF 1.5+)
+func (d *pdfDocument) expandObjectStreams() {
+	for _, body := range d.objects {
+		dict, _ := splitStream(body)
+		if nameValue(dict, "Type") != "ObjStm" {
+			continue
+		}
+		data, ok := d.streamData(body)
+		if !ok {
+			continue
+		}
+		n, _ := strconv.Atoi(string(dictValue(dict, "N")))
+		first, _ := strconv.Atoi(string(dictValue(dict, "First")))
+		if n <= 0 || first <= 0 || first > len(data) {
+			continue
+		}
+
+		header := strings.Fields(string(data[:first]))
+		type entry struct{ num, offset int }
+		var entries []entry
+		for i := 0; i+1 < len(header) && len(entries) < n; i += 2 {
+			num, err1 := strconv.Atoi(header[i])
+			offset, err2 := strconv.Atoi(header[i+1])
+			if err1 != nil || err2 != nil {
+				break
+			}
+			entries = append(entries, entry{num, offset})
+		}
+		for i, e := range entries {
+			start := first + e.offset
+			end := len(data)
+			if i+1 < len(entries) {
+				end = first + entries[i+1].offset
+			}
+			if start < 0 || start > end || end > len(data) {
+				continue
+			}
+			if _, exists := d.objects[e.num]; !exists {
+				d.objects[e.num] = bytes.TrimSpace(data[start:end])
+			}
+		}
+	}
+}
+
+// pageTreeRoot returns the root /Pages dictionary of the document
+func (d *pdfDocument) pageTreeRoot() []byte {
+	for _, body := range d.objects {
+		body, _ = splitStream(body)
+		if nameValue(body, "Type") == "Catalog" {
+			if pages := d.resolve(dictValue(body, "Pages")); pages != nil {
+				return pages
+			}
+		}
+	}
+	// Fall back to a /Pages node without a parent
+	for _, body := range d.objects {
+		body, _ = splitStream(body)
+		if nameValue(body, "Type") == "Pages" && dictValue(body, "Parent") == nil {
+			return body
+		}
+	}
+	return nil
+}
+
+// firstPage descends the page tree along the first kid of every node
+func (d *pdfDocument) firstPage(node []byte, depth int) []byte {
+	if depth > maxPageTreeDepth {
+		return nil
+	}
+	if nameValue(node, "Type") == "Page" {
+		return node
+	}
+	kids := dictValue(node, "Kids")
+	refs := pdfReferences.FindSubmatch(kids)
+	if refs == nil {
+		return nil
+	}
+	num, _ := strconv.Atoi(string(refs[1]))
+	kid, ok := d.objects[num]
+	if !ok {
+		return nil
+	}
+	return d.firstPage(kid, depth+1)
+}
+
+// countPageObjects counts page objects when the page tree has no usable /Count
+func (d *pdfDocument) countPageObjects() int {
+	var count int
+	for _, body := range d.objects {
+		body, _ = splitStream(body)
+		if nameValue(body, "Type") == "Page" {
+			count++
+		}
+	}
+	return count
+}
+
+// pageText decodes the content streams of a page and extracts their text
+func (d *pdfDocument) pageText(page []byte) string {
+	dict, _ := splitStream(page)
+	contents := dictValue(dict, "Contents")
+
+	var nums []int
+	for _, m := range pdfReferences.FindAllSubmatch(contents, -1) {
+		if num, err := strconv.Atoi(string(m[1])); err == nil {
+			nums = append(nums, num)
+		}
+	}
+	if len(nums) == 1 {
+		// A single reference may point to an array of content stream references
+		if body, ok := d.objects[nums[0]]; ok && bytes.HasPrefix(body, []byte("[")) {
+			nums = nums[:0]
+			for _, m := range pdfReferences.FindAllSubmatch(body, -1) {
+				if num, err := strconv.Atoi(string(m[1])); err == nil {
+					nums = append(nums, num)
+				}
+			}
+		}
+	}
+
+	var content bytes.Buffer
+	for _, num := range nums {
+		if data, ok := d.streamData(d.objects[num]); ok {
+			content.Write(data)
+			content.WriteByte('\n')
+		}
+	}
+
+	text := extractPDFText(content.Bytes(), d.pageFonts(page))
+	if !mostlyText(text) {
+		// Text shown with fonts of unknown encodings decodes to garbage
+		return ""
+	}
+	if runes := []rune(text); len(runes) > maxFirstPageText {
+		text = strings.TrimSpace(string(runes[:maxFirstPageText]))
+	}
+	return text
+}
+
+// pdfFont decodes the strings shown with a font
+type pdfFont struct {
+	// composite fonts show multi-byte codes, usually glyph IDs as with the
+	// Identity-H encoding, that only a ToUnicode CMap maps to text
+	composite bool
+	toUnicode *pdfCMap
+}
+
+// decode returns the text of a string shown with the font. Strings shown
+// with unknown simple fonts are decoded as PDFDocEncoding.
+func (f *pdfFont) decode(raw []byte) string {
+	switch {
+	case f != nil && f.toUnicode != nil:
+		return f.toUnicode.decode(raw)
+	case f != nil && f.composite:
+		return ""
+	default:
+		return decodeTextString(raw)
+	}
+}
+
+// pageFonts returns the fonts in the resources of a page by resource name.
+// Pages inherit the resources of their ancestors in the page tree.
+func (d *pdfDocument) pageFonts(page []byte) map[string]*pdfFont {
+	var fontDict []byte
+	for node, depth := page, 0; node != nil && fontDict == nil && depth < maxPageTreeDepth; depth++ {
+		dict, _ := splitStream(node)
+		if fontDict = d.resolve(dictValue(dict, "Font")); fontDict == nil {
+			fontDict = d.resolve(dictValue(d.resolve(dictValue(dict, "Resources")), "Font"))
+		}
+		node = d.resolve(dictValue(dict, "Parent"))
+	}
+
+	fonts := make(map[string]*pdfFont)
+	for _, m := range pdfFontRefs.FindAllSubmatch(fontDict, -1) {
+		if num, err := strconv.Atoi(string(m[2])); err == nil {
+			fonts[string(m[1])] = d.font(d.objects[num])
+		}
+	}
+	return fonts
+}
+
+// font reads how a font dictionary maps character codes to text
+func (d *pdfDocument) font(dict []byte) *pdfFont {
+	font := &pdfFont{composite: nameValue(dict, "Subtype") == "Type0"}
+	if data, ok := d.streamData(d.resolve(dictValue(dict, "ToUnicode"))); ok {
+		font.toUnicode = parseCMap(data, font.composite)
+	}
+	return font
+}
+
+// pdfCMap maps character codes of a fixed length to text
+type pdfCMap struct {
+	codeLength int
+	chars      map[uint32]string
+}
+
+// maxCMapRange limits the codes a single bfrange entry maps
+const maxCMapRange = 0xFFFF
+
+// parseCMap reads the codespace and the bfchar and bfrange mappings of a
+// ToUnicode CMap. Codes are one byte long unless the codespace says otherwise,
+// or two bytes for composite fonts.
+func parseCMap(data []byte, composite bool) *pdfCMap {
+	cmap := &pdfCMap{codeLength: 1, chars: make(map[uint32]string)}
+	if composite {
+		cmap.codeLength = 2
+	}
+	for _, section := range cmapSections(data, "codespacerange") {
+		if values := cmapValues(section); len(values) > 0 && len(values[0].hex) > 0 && len(values[0].hex) <= 4 {
+			cmap.codeLength = len(values[0].hex)
+			break
+		}
+	}
+
+	for _, section := range cmapSections(data, "bfchar") {
+		values := cmapValues(section)
+		for i := 0; i+1 < len(values); i += 2 {
+			cmap.chars[cmapCode(values[i].hex)] = string(utf16Runes(values[i+1].hex))
+		}
+	}
+	for _, section := range cmapSections(data, "bfrange") {
+		values := cmapValues(section)
+		for i := 0; i+2 < len(values); i += 3 {
+			lo, hi := cmapCode(values[i].hex), cmapCode(values[i+1].hex)
+			if hi < lo || hi-lo > maxCMapRange {
+				continue
+			}
+			if dst := values[i+2]; dst.array != nil {
+				for k, hex := range dst.array {
+					if uint32(k) > hi-lo {
+						break
+					}
+					cmap.chars[lo+uint32(k)] = string(utf16Runes(hex))
+				}
+				continue
+			}
+			// The last character of the destination is incremented along the range
+			runes := utf16Runes(values[i+2].hex)
+			if len(runes) == 0 {
+				continue
+			}
+			for code := lo; code <= hi; code++ {
+				runes[len(runes)-1] += rune(code - lo)
+				cmap.chars[code] = string(runes)
+				runes[len(runes)-1] -= rune(code - lo)
+			}
+		}
+	}
+	return cmap
+}
+
+// decode maps the character codes of raw to text, dropping unmapped codes
+func (c *pdfCMap) decode(raw []byte) string {
+	var sb strings.Builder
+	for i := 0; i+c.codeLength <= len(raw); i += c.codeLength {
+		sb.WriteString(c.chars[cmapCode(raw[i:i+c.codeLength])])
+	}
+	return sb.String()
+}
+
+// cmapSections returns the contents of every begin<name> ... end<name> section
+func cmapSections(data []byte, name string) [][]byte {
+	var sections [][]byte
+	begin, end := []byte("begin"+name), []byte("end"+name)
+	for {
+		idx := bytes.Index(data, begin)
+		if idx < 0 {
+			return sections
+		}
+		data = data[idx+len(begin):]
+		idx = bytes.Index(data, end)
+		if idx < 0 {
+			return append(sections, data)
+		}
+		sections = append(sections, data[:idx])
+		data = data[idx+len(end):]
+	}
+}
+
+// cmapValue is a hex string of a CMap section, or an array of them
+type cmapValue struct {
+	hex   []byte
+	array [][]byte
+}
+
+// cmapValues returns the hex strings and arrays of hex strings of a CMap section
+func cmapValues(section []byte) []cmapValue {
+	var values []cmapValue
+	var array [][]byte
+	inArray := false
+	for i := 0; i < len(section); {
+		switch section[i] {
+		case '<':
+			hex, end := readHexString(section, i)
+			if inArray {
+				array = append(array, hex)
+			} else {
+				values = append(values, cmapValue{hex: hex})
+			}
+			i = end
+		case '[':
+			inArray, array = true, [][]byte{}
+			i++
+		case ']':
+			if inArray {
+				values = append(values, cmapValue{array: array})
+			}
+			inArray = false
+			i++
+		default:
+			i++
+		}
+	}
+	return values
+}
+
+// cmapCode returns the big-endian value of a character code
+func cmapCode(hex []byte) uint32 {
+	var code uint32
+	for _, b := range hex {
+		code = code<<8 | uint32(b)
+	}
+	return code
+}
+
+// utf16Runes decodes UTF-16BE text
+func utf16Runes(raw []byte) []rune {
+	units := make([]uint16, 0, len(raw)/2)
+	for i := 0; i+1 < len(raw); i += 2 {
+		units = append(units, uint16(raw[i])<<8|uint16(raw[i+1]))
+	}
+	return utf16.Decode(units)
+}
+
+// mostlyText reports whether most characters of text other than spaces are
+// letters or digits, which text decoded with the wrong encoding is not
+func mostlyText(text string) bool {
+	letters, others := 0, 0
+	for _, r := range text {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			letters++
+		case !unicode.IsSpace(r):
+			others++
+		}
+	}
+	return letters > others
+}
+
+// streamData returns the decoded data of a stream object
+func (d *pdfDocument) streamData(body []byte) ([]byte, bool) {
+	dict, raw := splitStream(body)
+	if raw == nil {
+		return nil, false
+	}
+	if length, err := strconv.Atoi(string(d.resolve(dictValue(dict, "Length")))); err == nil && length >= 0 && length <= len(raw) {
+		raw = raw[:length]
+	}
+
+	filter := string(dictValue(dict, "Filter"))
+	switch {
+	case filter == "":
+		return raw, true
+	case strings.Contains(filter, "FlateDecode") && strings.Count(filter, "/") == 1:
+		r, err := zlib.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, false
+		}
+		defer r.Close()
+		// Truncated streams still yield their decoded prefix
+		data, _ := io.ReadAll(io.LimitReader(r, DefaultMaxBodySize))
+		return data, len(data) > 0
+	default:
+		return nil, false
+	}
+}
+
+// resolve follows an indirect reference to the referenced object body
+func (d *pdfDocument) resolve(value []byte) []byte {
+	m := pdfReference.FindSubmatch(value)
+	if m == nil {
+		return value
+	}
+	num, _ := strconv.Atoi(string(m[1]))
+	return d.objects[num]
+}
+
+// stringValue returns the decoded text string stored under key
+func (d *pdfDocument) stringValue(dict []byte, key string) string {
+	value := d.resolve(dictValue(dict, key))
+	if len(value) == 0 {
+		return ""
+	}
+	var raw []byte
+	switch value[0] {
+	case '(':
+		raw, _ = readLiteralString(value, 0)
+	case '<':
+		raw, _ = readHexString(value, 0)
+	default:
+		return ""
+	}
+	return strings.TrimSpace(decodeTextString(raw))
+}
+
+// splitStream splits an object body into its dictionary and raw stream data.
+// The stream data is nil for objects that are not streams.
+func splitStream(body []byte) ([]byte, []byte) {
+	idx := bytes.Index(body, []byte("stream"))
+	if idx < 0 {
+		return body, nil
+	}
+	dict := body[:idx]
+	raw := body[idx+len("stream"):]
+	raw = bytes.TrimPrefix(raw, []byte("\r"))
+	raw = bytes.TrimPrefix(raw, []byte("\n"))
+	if end := bytes.LastIndex(raw, []byte("endstream")); end >= 0 {
+		raw = raw[:end]
+	}
+	return dict, raw
+}
+
+// dictValue returns the raw token stored under /key in a dictionary, or nil.
+// Nested dictionaries are not distinguished, which is good enough for metadata.
+func dictValue(dict []byte, key string) []byte {
+	needle := []byte("/" + key)
+	for offset := 0; ; {
+		idx := bytes.Index(dict[offset:], needle)
+		if idx < 0 {
+			return nil
+		}
+		pos := offset + idx + len(needle)
+		offset = pos
+		if pos < len(dict) && !isPDFDelimiter(dict[pos]) && !isPDFWhitespace(dict[pos]) {
+			continue // a longer key such as /Pages when looking for /Page
+		}
+		return readValue(dict[pos:])
+	}
+}
+
+// nameValue returns the name stored under /key without its leading slash
+func nameValue(dict []byte, key string) string {
+	value := dictValue(dict, key)
+	if len(value) < 2 || value[0] != '/' {
+		return ""
+	}
+	return string(value[1:])
+}
+
+// readValue returns the first complete token of data: a reference, string, array,
+// dictionary, name or number
+func readValue(data []byte) []byte {
+	data = bytes.TrimLeft(data, "\x00\t\n\f\r ")
+	if len(data) == 0 {
+		return nil
+	}
+	if m := pdfReference.Find(data); m != nil {
+		return m
+	}
+	switch data[0] {
+	case '(':
+		_, end := readLiteralString(data, 0)
+		return data[:end]
+	case '[':
+		if end := matchingBracket(data, '[', ']'); end > 0 {
+			return data[:end]
+		}
+		return data
+	case '<':
+		if len(data) > 1 && data[1] == '<' {
+			if end := bytes.Index(data, []byte(">>")); end >= 0 {
+				return data[:end+2]
+			}
+			return data
+		}
+		_, end := readHexString(data, 0)
+		return data[:end]
+	}
+	end := 1
+	for end < len(data) && !isPDFDelimiter(data[end]) && !isPDFWhitespace(data[end]) {
+		end++
+	}
+	return data[:end]
+}
+
+// matchingBracket returns the index after the bracket closing data[0]
+func matchingBracket(data []byte, open, close byte) int {
+	depth := 0
+	for i := 0; i < len(data); i++ {
+		switch data[i] {
+		case '(':
+			_, end := readLiteralString(data, i)
+			i = end - 1
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
+
+// readLiteralString decodes the (...) string starting at data[start] and returns
+// its bytes and the index after the closing parenthesis
+func readLiteralString(data []byte, start int) ([]byte, int) {
+	var out []byte
+	depth := 0
+	for i := start; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case c == '\\' && i+1 < len(data):
+			i++
+			switch e := data[i]; e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b':
+				out = append(out, '\b')
+			case 'f':
+				out = append(out, '\f')
+			case '\r', '\n':
+				// Line continuation
+				if e == '\r' && i+1 < len(data) && data[i+1] == '\n' {
+					i++
+				}
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for j := 0; j < 2 && i+1 < len(data) && data[i+1] >= '0' && data[i+1] <= '7'; j++ {
+						i++
+						v = v*8 + int(data[i]-'0')
+					}
+					out = append(out, byte(v))
+				} else {
+					out = append(out, e)
+				}
+			}
+		case c == '(':
+			if depth > 0 {
+				out = append(out, c)
+			}
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return out, i + 1
+			}
+			out = append(out, c)
+		default:
+			if depth > 0 {
+				out = append(out, c)
+			}
+		}
+	}
+	return out, len(data)
+}
+
+// readHexString decodes the <...> string starting at data[start] and returns
+// its bytes and the index after the closing bracket
+func readHexString(data []byte, start int) ([]byte, int) {
+	var out []byte
+	var digits []byte
+	end := len(data)
+	for i := start + 1; i < len(data); i++ {
+		c := data[i]
+		if c == '>' {
+			end = i + 1
+			break
+		}
+		if v, err := strconv.ParseUint(string(c), 16, 8); err == nil {
+			digits = append(digits, byte(v))
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, 0)
+	}
+	for i := 0; i+1 < len(digits); i += 2 {
+		out = append(out, digits[i]<<4|digits[i+1])
+	}
+	return out, end
+}
+
+// decodeTextString decodes a PDF text string, which is either UTF-16BE with a
+// byte order mark, UTF-8 with a byte order mark (PDF 2.0) or PDFDocEncoding
+func decodeTextString(raw []byte) string {
+	switch {
+	case len(raw) >= 2 && raw[0] == 0xFE && raw[1] == 0xFF:
+		return string(utf16Runes(raw[2:]))
+	case bytes.HasPrefix(raw, []byte("\xEF\xBB\xBF")):
+		return string(raw[3:])
+	default:
+		// PDFDocEncoding matches Latin-1 for printable characters
+		runes := make([]rune, len(raw))
+		for i, b := range raw {
+			runes[i] = rune(b)
+		}
+		return string(runes)
+	}
+}
+
+// extractPDFText pulls the text shown by Tj, TJ, ' and " operators out of a
+// content stream, decoded with the fonts selected by Tf, inserting line breaks
+// on text positioning operators
+func extractPDFText(content []byte, fonts map[string]*pdfFont) string {
+	var sb strings.Builder
+	var operands [][]byte
+	var numbers []float64
+	var font *pdfFont
+	inArray := false
+
+	emit := func(raw []byte) {
+		for _, r := range font.decode(raw) {
+			if unicode.IsPrint(r) || r == '\n' || r == '\t' {
+				sb.WriteRune(r)
+			}
+		}
+	}
+
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case isPDFWhitespace(c):
+			i++
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case c == '(':
+			s, end := readLiteralString(content, i)
+			operands = append(operands, append([]byte{'('}, s...))
+			i = end
+		case c == '<' && i+1 < len(content) && content[i+1] != '<':
+			s, end := readHexString(content, i)
+			operands = append(operands, append([]byte{'<'}, s...))
+			i = end
+		case c == '[':
+			inArray = true
+			i++
+		case c == ']':
+			inArray = false
+			i++
+		case c == '/':
+			// Names are kept as operands of Tf
+			start := i
+			i++
+			for i < len(content) && !isPDFDelimiter(content[i]) && !isPDFWhitespace(content[i]) {
+				i++
+			}
+			operands = append(operands, content[start:i])
+		case c == '<' || c == '>' || c == '{' || c == '}':
+			i++
+			for i < len(content) && !isPDFDelimiter(content[i]) && !isPDFWhitespace(content[i]) {
+				i++
+			}
+		default:
+			start := i
+			for i < len(content) && !isPDFDelimiter(content[i]) && !isPDFWhitespace(content[i]) {
+				i++
+			}
+			if i == start {
+				i++
+				continue
+			}
+			token := content[start:i]
+			if inArray {
+				// Large negative kerning inside TJ arrays separates words
+				if n, err := strconv.ParseFloat(string(token), 64); err == nil && n < -200 {
+					operands = append(operands, []byte{'(', ' '})
+				}
+				continue
+			}
+			if n, err := strconv.ParseFloat(string(token), 64); err == nil {
+				numbers = append(numbers, n)
+				continue
+			}
+
+			switch string(token) {
+			case "Tf":
+				if len(operands) > 0 && len(operands[0]) > 0 && operands[0][0] == '/' {
+					font = fonts[string(operands[0][1:])]
+				}
+			case "Tj", "TJ":
+				for _, op := range operands {
+					if len(op) > 0 && op[0] != '/' {
+						emit(op[1:])
+					}
+				}
+			case "'", `"`:
+				sb.WriteByte('\n')
+				if len(operands) > 0 && len(operands[len(operands)-1]) > 0 {
+					emit(operands[len(operands)-1][1:])
+				}
+			case "Td", "TD":
+				// Only vertical movement starts a new line
+				if len(numbers) >= 2 && numbers[len(numbers)-1] != 0 {
+					sb.WriteByte('\n')
+				} else {
+					sb.WriteByte(' ')
+				}
+			case "T*", "Tm", "ET":
+				sb.WriteByte('\n')
+			}
+			operands = operands[:0]
+			numbers = numbers[:0]
+		}
+	}
+
+	var lines []string
+	for _, line := range strings.Split(sb.String(), "\n") {
+		line = strings.TrimSpace(whitespace.ReplaceAllString(line, " "))
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// isPDFWhitespace reports whether c is a PDF whitespace character
+func isPDFWhitespace(c byte) bool {
+	return c == 0 || c == '\t' || c == '\n' || c == '\f' || c == '\r' || c == ' '
+}
+
+// isPDFDelimiter reports whether c is a PDF delimiter character
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
@@ -0,0 +1,172 @@
+package scraper
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"bookmarks-go/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildPDF assembles a PDF from object bodies numbered from 1 and a trailer dictionary.
+// Empty bodies are skipped, for objects stored in object streams.
+func buildPDF(objects []string, trailer string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.5\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		if obj == "" {
+			continue
+		}
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n%s\nstartxref\n%d\n%%%%EOF\n", trailer, xref)
+	return buf.Bytes()
+}
+
+// flateStream returns a stream object body with data compressed by FlateDecode
+func flateStream(dict string, data string) string {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write([]byte(data))
+	w.Close()
+	return fmt.Sprintf("<< %s /Filter /FlateDecode /Length %d >>\nstream\n%s\nendstream", dict, buf.Len(), buf.String())
+}
+
+func TestParsePDF(t *testing.T) {
+	content := `BT /F1 24 Tf 72 720 Td (A Study of \(Things\)) Tj 0 -30 Td [(Second) -250 (line)] TJ ET`
+
+	t.Run("classic cross-reference table", func(t *testing.T) {
+		data := buildPDF([]string{
+			"<< /Type /Catalog /Pages 2 0 R >>",
+			"<< /Type /Pages /Kids [3 0 R 5 0 R] /Count 2 >>",
+			"<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>",
+			flateStream("", content),
+			"<< /Type /Page /Parent 2 0 R >>",
+			"<< /Title (Paper Title) /Author (Jane Doe) /Subject (On things) >>",
+		}, "<< /Size 7 /Root 1 0 R /Info 6 0 R >>")
+
+		info, err := ParsePDF(data)
+		require.NoError(t, err)
+		assert.Equal(t, "Paper Title", info.Title)
+		assert.Equal(t, "Jane Doe", info.Author)
+		assert.Equal(t, "On things", info.Subject)
+		assert.Equal(t, 2, info.PageCount)
+		assert.Equal(t, "A Study of (Things)\nSecond line", info.FirstPageText)
+	})
+
+	t.Run("object stream with UTF-16 strings", func(t *testing.T) {
+		// Objects 4 and 5 live in the compressed object stream 3
+		infoDict := "<< /Title <FEFF65E5672C8A9E> /Author (\\376\\377\\000A) >> "
+		catalog := "<< /Type /Catalog /Pages 6 0 R >>"
+		header := fmt.Sprintf("4 0 5 %d ", len(infoDict))
+		data := buildPDF([]string{
+			"<< /Type /XRef /Info 4 0 R /Root 5 0 R >>",
+			"<< >>",
+			flateStream(fmt.Sprintf("/Type /ObjStm /N 2 /First %d", len(header)), header+infoDict+catalog),
+			"",
+			"",
+			"<< /Type /Pages /Kids [7 0 R] /Count 1 >>",
+			"<< /Type /Page /Parent 6 0 R /Contents [8 0 R] >>",
+			flateStream("", "BT <48656c6c6f> Tj ET"),
+		}, "<< >>")
+
+		info, err := ParsePDF(data)
+		require.NoError(t, err)
+		assert.Equal(t, "日本語", info.Title)
+		assert.Equal(t, "A", info.Author)
+		assert.Equal(t, 1, info.PageCount)
+		assert.Equal(t, "Hello", info.FirstPageText)
+	})
+
+	t.Run("fonts with ToUnicode CMaps", func(t *testing.T) {
+		// F1 maps two-byte glyph IDs to text, F2 is a composite font without a
+		// CMap and F3 a simple font mapping single bytes
+		glyphs := `/CIDInit /ProcSet findresource begin 12 dict begin begincmap
+1 begincodespacerange <0000> <FFFF> endcodespacerange
+1 beginbfchar <0003> <0020> endbfchar
+1 beginbfrange <0041> <005A> <0061> endbfrange
+endcmap end end`
+		singleBytes := `1 begincodespacerange <00> <FF> endcodespacerange
+1 beginbfrange <01> <02> [<006F> <006B>] endbfrange`
+		data := buildPDF([]string{
+			"<< /Type /Catalog /Pages 2 0 R >>",
+			"<< /Type /Pages /Kids [3 0 R] /Count 1 /Resources << /Font << /F1 4 0 R /F2 6 0 R /F3 7 0 R >> >> >>",
+			"<< /Type /Page /Parent 2 0 R /Contents 5 0 R >>",
+			"<< /Type /Font /Subtype /Type0 /BaseFont /ABCDEF+Sans /Encoding /Identity-H /ToUnicode 8 0 R >>",
+			flateStream("", `BT /F1 12 Tf <00480049000300540048004500520045> Tj 0 -14 Td /F2 12 Tf <00480049> Tj 0 -14 Td /F3 12 Tf (\001\002\003) Tj ET`),
+			"<< /Type /Font /Subtype /Type0 /BaseFont /ABCDEF+Serif /Encoding /Identity-H >>",
+			"<< /Type /Font /Subtype /TrueType /BaseFont /Mono /ToUnicode 9 0 R >>",
+			flateStream("", glyphs),
+			flateStream("", singleBytes),
+		}, "<< /Root 1 0 R >>")
+
+		info, err := ParsePDF(data)
+		require.NoError(t, err)
+		assert.Equal(t, "hi there\nok", info.FirstPageText)
+	})
+
+	t.Run("text that is not mostly letters", func(t *testing.T) {
+		data := buildPDF([]string{
+			"<< /Type /Catalog /Pages 2 0 R >>",
+			"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+			"<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>",
+			flateStream("", "BT (#$%&'*+,-./:;) Tj (ab) Tj ET"),
+		}, "<< /Root 1 0 R >>")
+
+		info, err := ParsePDF(data)
+		require.NoError(t, err)
+		assert.Empty(t, info.FirstPageText)
+	})
+
+	t.Run("not a pdf", func(t *testing.T) {
+		_, err := ParsePDF([]byte("<html></html>"))
+		assert.ErrorIs(t, err, ErrInvalidPDF)
+	})
+}
+
+func TestGetMetadataPDF(t *testing.T) {
+	data := buildPDF([]string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>",
+		flateStream("", "BT (Abstract text of the paper) Tj ET"),
+		"<< /Title (Spec v2) /Author (Team) >>",
+	}, "<< /Root 1 0 R /Info 5 0 R >>")
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write(data)
+	}))
+	defer ts.Close()
+
+	scraper := NewScraper(5*time.Second, allowLoopback, WithContentExtraction())
+
+	metadata, err := scraper.GetMetadata(context.Background(), ts.URL+"/spec.pdf")
+	require.NoError(t, err)
+	assert.Equal(t, "application/pdf", metadata.MediaType)
+	assert.Equal(t, "Spec v2", metadata.Title)
+	assert.Equal(t, "Team", metadata.Author)
+	assert.Equal(t, models.SourcePDF, metadata.Provenance[models.FieldTitle].Source)
+	assert.Equal(t, 1, metadata.PageCount)
+	assert.Equal(t, int64(len(data)), metadata.ContentLength)
+	require.NotNil(t, metadata.Content)
+	assert.Equal(t, "Abstract text of the paper", metadata.Content.Text)
+	// The first page says nothing about the length of the document
+	assert.Zero(t, metadata.Content.WordCount)
+	assert.Zero(t, metadata.Content.ReadingTimeMinutes)
+}
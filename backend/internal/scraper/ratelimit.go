@@ -0,0 +1,123 @@
+package scraper
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WithHostRateLimit limits requests to each host to rate per second with the given burst.
+// A rate of zero disables the limit, although robots.txt crawl delays still apply.
+func WithHostRateLimit(rate float64, burst int) Option {
+	return func(s *Scraper) {
+		s.limiter = newHostLimiter(rate, burst)
+	}
+}
+
+// bucketSweepInterval is how often buckets of idle hosts are dropped
+const bucketSweepInterval = time.Minute
+
+// hostLimiter is a token bucket per host
+type hostLimiter struct {
+	mu        sync.Mutex
+	rate      float64
+	burst     int
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// bucket is the token bucket state of a single host
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newHostLimiter creates a limiter allowing rate requests per second per host;
+// a rate of zero means unlimited
+func newHostLimiter(rate float64, burst int) *hostLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &hostLimiter{
+		rate:    rate,
+		burst:   burst,
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// wait blocks until a request to host may be made. A positive crawlDelay from
+// robots.txt lowers the rate for that host to one request per delay.
+func (l *hostLimiter) wait(ctx context.Context, host string, crawlDelay time.Duration) error {
+	for {
+		delay := l.reserve(strings.ToLower(host), crawlDelay)
+		if delay <= 0 {
+			return nil
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve takes a token if one is available and otherwise returns how long to wait
+func (l *hostLimiter) reserve(host string, crawlDelay time.Duration) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rate, burst := l.rate, float64(l.burst)
+	if crawlDelay > 0 {
+		if delayRate := float64(time.Second) / float64(crawlDelay); rate <= 0 || delayRate < rate {
+			rate = delayRate
+		}
+		burst = 1
+	} else if rate <= 0 {
+		return 0
+	}
+
+	now := l.now()
+	l.sweep(now)
+	b, ok := l.buckets[host]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[host] = b
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * rate
+	if b.tokens > burst {
+		b.tokens = burst
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / rate * float64(time.Second))
+}
+
+// sweep drops the buckets of hosts idle long enough for them to have filled
+// up again, as those behave like buckets of new hosts. The caller must hold the lock.
+func (l *hostLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < bucketSweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	// Buckets slowed down by a crawl delay hold one token, refilled within maxCrawlDelay
+	idle := maxCrawlDelay
+	if l.rate > 0 {
+		idle = max(idle, time.Duration(float64(l.burst)/l.rate*float64(time.Second)))
+	}
+	for host, b := range l.buckets {
+		if now.Sub(b.last) >= idle {
+			delete(l.buckets, host)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package scraper
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHostLimiterReserve(t *testing.T) {
+	now := time.Unix(0, 0)
+	limiter := newHostLimiter(2, 2)
+	limiter.now = func() time.Time { return now }
+
+	// The burst is available immediately
+	assert.Zero(t, limiter.reserve("example.com", 0))
+	assert.Zero(t, limiter.reserve("example.com", 0))
+	assert.Equal(t, 500*time.Millisecond, limiter.reserve("example.com", 0))
+
+	// Other hosts have their own bucket
+	assert.Zero(t, limiter.reserve("other.example", 0))
+
+	now = now.Add(500 * time.Millisecond)
+	assert.Zero(t, limiter.reserve("example.com", 0))
+}
+
+func TestHostLimiterCrawlDelay(t *testing.T) {
+	now := time.Unix(0, 0)
+	limiter := newHostLimiter(0, 0)
+	limiter.now = func() time.Time { return now }
+
+	// Without a rate or crawl delay requests are unlimited
+	for i := 0; i < 10; i++ {
+		assert.Zero(t, limiter.reserve("example.com", 0))
+	}
+
+	assert.Zero(t, limiter.reserve("slow.example", 3*time.Second))
+	assert.Equal(t, 3*time.Second, limiter.reserve("slow.example", 3*time.Second))
+
+	now = now.Add(3 * time.Second)
+	assert.Zero(t, limiter.reserve("slow.example", 3*time.Second))
+}
+
+func TestHostLimiterDropsIdleBuckets(t *testing.T) {
+	now := time.Unix(0, 0)
+	limiter := newHostLimiter(1, 5)
+	limiter.now = func() time.Time { return now }
+
+	assert.Zero(t, limiter.reserve("idle.example", 0))
+	now = now.Add(bucketSweepInterval / 2)
+	assert.Zero(t, limiter.reserve("busy.example", 0))
+	assert.Len(t, limiter.buckets, 2)
+
+	// The next sweep drops buckets idle for maxCrawlDelay, by when any bucket has refilled
+	now = now.Add(bucketSweepInterval / 2)
+	assert.Zero(t, limiter.reserve("busy.example", 0))
+	assert.Len(t, limiter.buckets, 1)
+	assert.Contains(t, limiter.buckets, "busy.example")
+}
+
+func TestHostLimiterWaitCanceled(t *testing.T) {
+	limiter := newHostLimiter(0.01, 1)
+	assert.NoError(t, limiter.wait(context.Background(), "example.com", 0))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, limiter.wait(ctx, "example.com", 0), context.DeadlineExceeded)
+}
@@ -0,0 +1,166 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	// DefaultMaxRedirects is the number of redirect hops followed unless configured otherwise
+	DefaultMaxRedirects = 10
+	// maxMetaRefreshDelay is the longest meta refresh delay still treated as a redirect
+	maxMetaRefreshDelay = 10 * time.Second
+)
+
+// Redirect kinds recorded in a redirect chain
+const (
+	RedirectHTTP        = "http"
+	RedirectMetaRefresh = "meta-refresh"
+)
+
+// ErrTooManyRedirects is returned when a URL redirects more than the configured maximum
+var ErrTooManyRedirects = errors.New("too many redirects")
+
+// Redirect represents a single hop in a redirect chain
+type Redirect struct {
+	URL        string
+	StatusCode int
+	Kind       string
+}
+
+// WithMaxRedirects sets the maximum number of redirect hops to follow
+func WithMaxRedirects(n int) Option {
+	return func(s *Scraper) {
+		s.maxRedirects = n
+	}
+}
+
+// fetch performs a GET request for target and follows HTTP redirects manually,
+// appending every hop to chain. Conditional headers for validators are sent on
+// every hop. The caller must close the response body.
+func (s *Scraper) fetch(ctx context.Context, target *url.URL, chain *[]Redirect, validators *Validators) (*http.Response, error) {
+	current := target
+	for {
+		req, err := http.NewRequestWithContext(ctx, "GET", current.String(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.8")
+		setConditionalHeaders(req, validators)
+
+		resp, err := s.do(ctx, req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch URL: %w", err)
+		}
+
+		if !isRedirect(resp.StatusCode) {
+			return resp, nil
+		}
+		closeBody(resp.Body)
+
+		location := resp.Header.Get("Location")
+		if location == "" {
+			return nil, fmt.Errorf("redirect status %d without Location header", resp.StatusCode)
+		}
+		next, err := current.Parse(location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redirect location: %w", err)
+		}
+		if next.Scheme != "http" && next.Scheme != "https" {
+			return nil, fmt.Errorf("redirect to unsupported scheme: %s", next.Scheme)
+		}
+
+		*chain = append(*chain, Redirect{
+			URL:        current.String(),
+			StatusCode: resp.StatusCode,
+			Kind:       RedirectHTTP,
+		})
+		if len(*chain) > s.maxRedirects {
+			return nil, ErrTooManyRedirects
+		}
+		current = next
+	}
+}
+
+// isRedirect reports whether status is an HTTP redirect that carries a Location
+func isRedirect(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// metaRefreshTarget returns the URL of a <meta http-equiv="refresh"> redirect in the
+// document head, or nil if there is none or it points back to the current page
+func metaRefreshTarget(doc *html.Node, baseURL *url.URL) *url.URL {
+	head := findElement(doc, "head")
+	if head == nil {
+		return nil
+	}
+
+	var target *url.URL
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if target != nil {
+			return
+		}
+		if n.Type == html.ElementNode && n.Data == "meta" &&
+			strings.EqualFold(attrValue(n, "http-equiv"), "refresh") {
+			target = parseMetaRefresh(attrValue(n, "content"), baseURL)
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(head)
+
+	if target == nil || target.String() == baseURL.String() {
+		return nil
+	}
+	return target
+}
+
+// parseMetaRefresh parses a refresh content value such as "0; url=https://example.com/"
+func parseMetaRefresh(content string, baseURL *url.URL) *url.URL {
+	delayStr, rest, found := strings.Cut(content, ";")
+	if !found {
+		delayStr, rest, found = strings.Cut(content, ",")
+	}
+	if !found {
+		return nil
+	}
+
+	delay, err := strconv.ParseFloat(strings.TrimSpace(delayStr), 64)
+	if err != nil || delay < 0 || time.Duration(delay*float64(time.Second)) > maxMetaRefreshDelay {
+		return nil
+	}
+
+	rest = strings.TrimSpace(rest)
+	if len(rest) >= 3 && strings.EqualFold(rest[:3], "url") {
+		rest = strings.TrimSpace(rest[3:])
+		rest = strings.TrimPrefix(rest, "=")
+		rest = strings.TrimSpace(rest)
+	}
+	rest = strings.Trim(rest, `'"`)
+	if rest == "" {
+		return nil
+	}
+
+	target, err := baseURL.Parse(rest)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") {
+		return nil
+	}
+	return target
+}
@@ -0,0 +1,103 @@
+package scraper
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetMetadataRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/short", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/moved", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/refresh", http.StatusFound)
+	})
+	mux.HandleFunc("/refresh", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><meta http-equiv="Refresh" content="0; URL='/final'"></head></html>`))
+	})
+	mux.HandleFunc("/final", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Final Page</title></head><body></body></html>`))
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	t.Run("records every hop", func(t *testing.T) {
+		scraper := NewScraper(5*time.Second, allowLoopback)
+
+		metadata, err := scraper.GetMetadata(context.Background(), ts.URL+"/short")
+		require.NoError(t, err)
+		assert.Equal(t, "Final Page", metadata.Title)
+		assert.Equal(t, ts.URL+"/final", metadata.FinalURL)
+		assert.Equal(t, []Redirect{
+			{URL: ts.URL + "/short", StatusCode: http.StatusMovedPermanently, Kind: RedirectHTTP},
+			{URL: ts.URL + "/moved", StatusCode: http.StatusFound, Kind: RedirectHTTP},
+			{URL: ts.URL + "/refresh", StatusCode: http.StatusOK, Kind: RedirectMetaRefresh},
+		}, metadata.Redirects)
+	})
+
+	t.Run("no redirects", func(t *testing.T) {
+		scraper := NewScraper(5*time.Second, allowLoopback)
+
+		metadata, err := scraper.GetMetadata(context.Background(), ts.URL+"/final")
+		require.NoError(t, err)
+		assert.Equal(t, ts.URL+"/final", metadata.FinalURL)
+		assert.Empty(t, metadata.Redirects)
+	})
+
+	t.Run("redirect loop", func(t *testing.T) {
+		scraper := NewScraper(5*time.Second, allowLoopback, WithMaxRedirects(3))
+
+		_, err := scraper.GetMetadata(context.Background(), ts.URL+"/loop")
+		assert.ErrorIs(t, err, ErrTooManyRedirects)
+	})
+
+	t.Run("cap includes meta refresh", func(t *testing.T) {
+		scraper := NewScraper(5*time.Second, allowLoopback, WithMaxRedirects(2))
+
+		_, err := scraper.GetMetadata(context.Background(), ts.URL+"/short")
+		assert.ErrorIs(t, err, ErrTooManyRedirects)
+	})
+}
+
+func TestParseMetaRefresh(t *testing.T) {
+	baseURL, _ := url.Parse("https://example.com/dir/page")
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "absolute", content: "0; url=https://other.example/", want: "https://other.example/"},
+		{name: "relative quoted", content: `0;URL='next'`, want: "https://example.com/dir/next"},
+		{name: "comma separator", content: "1, url=/root", want: "https://example.com/root"},
+		{name: "bare url", content: "0; /bare", want: "https://example.com/bare"},
+		{name: "reload only", content: "30", want: ""},
+		{name: "long delay", content: "600; url=/later", want: ""},
+		{name: "javascript scheme", content: "0; url=javascript:alert(1)", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseMetaRefresh(tt.content, baseURL)
+			if tt.want == "" {
+				assert.Nil(t, got)
+				return
+			}
+			require.NotNil(t, got)
+			assert.Equal(t, tt.want, got.String())
+		})
+	}
+}
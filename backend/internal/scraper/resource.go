@@ -0,0 +1,70 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+)
+
+// ErrTooLarge is returned when a resource exceeds the requested size limit
+var ErrTooLarge = errors.New("resource exceeds size limit")
+
+// Resource is a downloaded file such as an icon or a preview image
+type Resource struct {
+	Data      []byte
+	MediaType string
+	// URL is the final URL after redirects
+	URL string
+	// Header holds the response headers. Content-Encoding and Content-Length
+	// are not included when the body was decompressed.
+	Header http.Header
+}
+
+// FetchResource downloads rawURL with the same address checks, politeness and
+// retries as page fetches. Bodies larger than maxSize fail with ErrTooLarge.
+func (s *Scraper) FetchResource(ctx context.Context, rawURL string, maxSize int64) (*Resource, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return nil, errors.New("URL must start with http:// or https://")
+	}
+
+	var chain []Redirect
+	resp, err := s.fetch(ctx, target, &chain, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	if resp.ContentLength > maxSize {
+		return nil, ErrTooLarge
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read resource: %w", err)
+	}
+	if int64(len(data)) > maxSize {
+		return nil, ErrTooLarge
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "" || mediaType == "application/octet-stream" {
+		mediaType, _, _ = mime.ParseMediaType(http.DetectContentType(data))
+	}
+	return &Resource{
+		Data:      data,
+		MediaType: mediaType,
+		URL:       resp.Request.URL.String(),
+		Header:    resp.Header,
+	}, nil
+}
@@ -0,0 +1,167 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// RetryPolicy controls how transient fetch failures are retried
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts per request, including the first
+	MaxAttempts int
+	// BaseDelay is the backoff before the first retry; it doubles on every attempt
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff and the Retry-After delay that is still honored
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy retries transient failures twice within a few seconds
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    5 * time.Second,
+}
+
+// WithRetryPolicy enables retries of transient failures
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(s *Scraper) {
+		s.retry = policy
+	}
+}
+
+// retryStats counts fetch attempts for monitoring
+type retryStats struct {
+	requests  atomic.Int64
+	retries   atomic.Int64
+	exhausted atomic.Int64
+}
+
+// do sends req with politeness checks, the per-host circuit breaker and retries.
+// Transient failures are network errors and 429, 502, 503 and 504 responses.
+func (s *Scraper) do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	attempts := s.retry.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	s.retryStats.requests.Add(1)
+	for attempt := 1; ; attempt++ {
+		if err := s.beforeRequest(ctx, req.URL); err != nil {
+			return nil, err
+		}
+		if err := s.breakers.allow(host); err != nil {
+			return nil, err
+		}
+
+		resp, err := s.client.Do(req.Clone(ctx))
+		transient := isTransient(resp, err)
+		if isHostOutcome(ctx, err) {
+			s.breakers.record(host, !transient)
+		} else {
+			s.breakers.release(host)
+		}
+		if !transient {
+			return resp, err
+		}
+
+		delay, ok := s.retryDelay(attempt, resp)
+		if attempt >= attempts || !ok {
+			if attempts > 1 {
+				s.retryStats.exhausted.Add(1)
+			}
+			return resp, err
+		}
+		if resp != nil {
+			closeBody(resp.Body)
+		}
+
+		s.retryStats.retries.Add(1)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryDelay returns the wait before the next attempt: the server's Retry-After
+// if present, otherwise full-jitter exponential backoff. It returns false when
+// the server asks for a longer wait than the policy allows.
+func (s *Scraper) retryDelay(attempt int, resp *http.Response) (time.Duration, bool) {
+	if resp != nil {
+		if after, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			return after, after <= s.retry.MaxDelay
+		}
+	}
+
+	backoff := s.retry.BaseDelay << (attempt - 1)
+	if backoff <= 0 || backoff > s.retry.MaxDelay {
+		backoff = s.retry.MaxDelay
+	}
+	if backoff <= 0 {
+		return 0, true
+	}
+	return time.Duration(rand.Int63n(int64(backoff) + 1)), true
+}
+
+// isTransient reports whether a request outcome is worth retrying
+func isTransient(resp *http.Response, err error) bool {
+	if err != nil {
+		if errors.Is(err, ErrBlockedAddress) || errors.Is(err, context.Canceled) {
+			return false
+		}
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) {
+			return dnsErr.IsTemporary || dnsErr.IsTimeout
+		}
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// isHostOutcome reports whether the outcome of a request tells how the host is
+// doing. Requests abandoned by the caller, through cancellation or its own
+// deadline, and connections refused to blocked addresses say nothing about it.
+func isHostOutcome(ctx context.Context, err error) bool {
+	if err == nil {
+		return true
+	}
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	return !errors.Is(err, ErrBlockedAddress)
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := date.Sub(now); delay > 0 {
+			return delay, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
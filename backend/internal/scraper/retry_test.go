@@ -0,0 +1,113 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetMetadataRetries(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 50 * time.Millisecond}
+
+	t.Run("recovers from transient errors", func(t *testing.T) {
+		var calls int32
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch atomic.AddInt32(&calls, 1) {
+			case 1:
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+			case 2:
+				w.WriteHeader(http.StatusServiceUnavailable)
+			default:
+				w.Header().Set("Content-Type", "text/html")
+				w.Write([]byte(`<html><head><title>Recovered</title></head></html>`))
+			}
+		}))
+		defer ts.Close()
+
+		scraper := NewScraper(5*time.Second, allowLoopback, WithRetryPolicy(policy))
+		metadata, err := scraper.GetMetadata(context.Background(), ts.URL)
+		require.NoError(t, err)
+		assert.Equal(t, "Recovered", metadata.Title)
+
+		stats := scraper.Stats()
+		assert.Equal(t, int64(2), stats.Retries)
+		assert.Zero(t, stats.RetriesExhausted)
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		var calls int32
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer ts.Close()
+
+		scraper := NewScraper(5*time.Second, allowLoopback, WithRetryPolicy(policy))
+		_, err := scraper.GetMetadata(context.Background(), ts.URL)
+		assert.Error(t, err)
+		assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+		assert.Equal(t, int64(1), scraper.Stats().RetriesExhausted)
+	})
+
+	t.Run("does not retry client errors", func(t *testing.T) {
+		var calls int32
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer ts.Close()
+
+		scraper := NewScraper(5*time.Second, allowLoopback, WithRetryPolicy(policy))
+		_, err := scraper.GetMetadata(context.Background(), ts.URL)
+		assert.Error(t, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("long Retry-After is not waited for", func(t *testing.T) {
+		var calls int32
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer ts.Close()
+
+		scraper := NewScraper(5*time.Second, allowLoopback, WithRetryPolicy(policy))
+		_, err := scraper.GetMetadata(context.Background(), ts.URL)
+		assert.Error(t, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	delay, ok := parseRetryAfter("120", now)
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Minute, delay)
+
+	delay, ok = parseRetryAfter("Wed, 01 Jan 2025 12:00:30 GMT", now)
+	assert.True(t, ok)
+	assert.Equal(t, 30*time.Second, delay)
+
+	_, ok = parseRetryAfter("", now)
+	assert.False(t, ok)
+	_, ok = parseRetryAfter("soon", now)
+	assert.False(t, ok)
+}
+
+func TestIsTransient(t *testing.T) {
+	assert.True(t, isTransient(nil, errors.New("connection reset")))
+	assert.False(t, isTransient(nil, ErrBlockedAddress))
+	assert.True(t, isTransient(&http.Response{StatusCode: http.StatusGatewayTimeout}, nil))
+	assert.False(t, isTransient(&http.Response{StatusCode: http.StatusOK}, nil))
+	assert.False(t, isTransient(&http.Response{StatusCode: http.StatusInternalServerError}, nil))
+}
@@ -0,0 +1,383 @@
+package scraper
+
+import (
+	"bufio"
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// botName is the product token matched against robots.txt user-agent lines
+	botName = "BookmarksBot"
+	// robotsTTL is how long robots.txt rules are cached per host
+	robotsTTL = 24 * time.Hour
+	// robotsErrorTTL is how long a failed robots.txt fetch is cached
+	robotsErrorTTL = 10 * time.Minute
+	// maxRobotsSize is the maximum robots.txt size read, as recommended by RFC 9309
+	maxRobotsSize = 500 << 10
+	// maxCrawlDelay caps crawl-delay values so a hostile robots.txt cannot stall workers
+	maxCrawlDelay = 60 * time.Second
+	// maxRobotsHosts is the number of hosts whose robots.txt rules are cached
+	maxRobotsHosts = 10000
+)
+
+// RobotsPolicy controls when robots.txt rules are enforced
+type RobotsPolicy string
+
+// Supported robots.txt policies
+const (
+	// RobotsRespect enforces robots.txt for every fetch
+	RobotsRespect RobotsPolicy = "respect"
+	// RobotsIgnore never fetches robots.txt
+	RobotsIgnore RobotsPolicy = "ignore"
+	// RobotsBackground enforces robots.txt only for fetches marked as background work;
+	// a user saving a single page acts like a browser, not a crawler
+	RobotsBackground RobotsPolicy = "background"
+)
+
+// ErrDisallowedByRobots is returned when robots.txt forbids fetching a URL
+var ErrDisallowedByRobots = errors.New("disallowed by robots.txt")
+
+// ParseRobotsPolicy parses a robots.txt policy name
+func ParseRobotsPolicy(name string) (RobotsPolicy, error) {
+	switch policy := RobotsPolicy(strings.ToLower(strings.TrimSpace(name))); policy {
+	case RobotsRespect, RobotsIgnore, RobotsBackground:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown robots policy: %q", name)
+	}
+}
+
+// WithRobotsPolicy sets when robots.txt rules are enforced
+func WithRobotsPolicy(policy RobotsPolicy) Option {
+	return func(s *Scraper) {
+		s.robotsPolicy = policy
+	}
+}
+
+// backgroundKey marks contexts of background fetches
+type backgroundKey struct{}
+
+// BackgroundContext marks fetches made with ctx as background work such as
+// refreshes and bulk imports, which are subject to the RobotsBackground policy
+func BackgroundContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, backgroundKey{}, true)
+}
+
+// isBackground reports whether ctx was marked by BackgroundContext
+func isBackground(ctx context.Context) bool {
+	background, _ := ctx.Value(backgroundKey{}).(bool)
+	return background
+}
+
+// robotsRules holds the parsed robots.txt group that applies to BookmarksBot
+type robotsRules struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+	disallowed bool // the whole site is off limits, e.g. robots.txt returned 5xx
+	expires    time.Time
+}
+
+// robotsRule is a single allow or disallow path pattern
+type robotsRule struct {
+	pattern string
+	allow   bool
+}
+
+// robotsCache fetches robots.txt rules per scheme and host and keeps those of
+// the most recently used hosts
+type robotsCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+	flights flightGroup[robotsRules]
+	fetch   func(ctx context.Context, robotsURL string) (*robotsRules, error)
+}
+
+// robotsEntry is the cached rules of a scheme and host
+type robotsEntry struct {
+	key   string
+	rules *robotsRules
+}
+
+// newRobotsCache creates a robots.txt cache that downloads files with client
+func newRobotsCache(client *http.Client) *robotsCache {
+	c := &robotsCache{
+		size:    maxRobotsHosts,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+	c.fetch = func(ctx context.Context, robotsURL string) (*robotsRules, error) {
+		return fetchRobots(ctx, client, robotsURL)
+	}
+	return c
+}
+
+// rulesFor returns the cached rules for the host of u, fetching them if needed.
+// Concurrent requests to a host share one fetch. It fails only if ctx ends
+// before robots.txt could be fetched.
+func (c *robotsCache) rulesFor(ctx context.Context, u *url.URL) (*robotsRules, error) {
+	key := u.Scheme + "://" + u.Host
+	if rules := c.get(key); rules != nil {
+		return rules, nil
+	}
+
+	return c.flights.do(ctx, key, func() (*robotsRules, error) {
+		rules, err := c.fetch(ctx, key+"/robots.txt")
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			// A cancelled caller says nothing about the site
+			return nil, err
+		}
+		if err != nil {
+			// Unreachable robots.txt means the site is treated as fully disallowed (RFC 9309)
+			rules = &robotsRules{disallowed: true, expires: time.Now().Add(robotsErrorTTL)}
+		}
+		c.put(key, rules)
+		return rules, nil
+	})
+}
+
+// get returns the cached rules for key unless they have expired
+func (c *robotsCache) get(key string) *robotsRules {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	entry := elem.Value.(*robotsEntry)
+	if !time.Now().Before(entry.rules.expires) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return nil
+	}
+	c.order.MoveToFront(elem)
+	return entry.rules
+}
+
+// put caches the rules for key, evicting the least recently used hosts
+func (c *robotsCache) put(key string, rules *robotsRules) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = &robotsEntry{key: key, rules: rules}
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&robotsEntry{key: key, rules: rules})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*robotsEntry).key)
+	}
+}
+
+// fetchRobots downloads and parses a robots.txt file
+func fetchRobots(ctx context.Context, client *http.Client, robotsURL string) (*robotsRules, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", robotsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// The client does not follow redirects, so follow a few here as RFC 9309 requires
+	var resp *http.Response
+	for hops := 0; ; hops++ {
+		resp, err = client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if !isRedirect(resp.StatusCode) || hops >= 5 {
+			break
+		}
+		resp.Body.Close()
+		next, err := req.URL.Parse(resp.Header.Get("Location"))
+		if err != nil {
+			return nil, err
+		}
+		req, err = http.NewRequestWithContext(ctx, "GET", next.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		rules := parseRobots(io.LimitReader(resp.Body, maxRobotsSize), botName)
+		rules.expires = time.Now().Add(robotsTTL)
+		return rules, nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		// A missing robots.txt allows everything
+		return &robotsRules{expires: time.Now().Add(robotsTTL)}, nil
+	default:
+		return nil, fmt.Errorf("unexpected robots.txt status code: %d", resp.StatusCode)
+	}
+}
+
+// parseRobots parses robots.txt and returns the group that applies to agent,
+// falling back to the "*" group. User-agent lines must name the product token
+// of agent exactly, ignoring case.
+func parseRobots(r io.Reader, agent string) *robotsRules {
+	agent = strings.ToLower(agent)
+	var matched, wildcard *robotsRules
+	var current []*robotsRules
+	inAgents := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if idx := strings.IndexByte(line, '#'); idx >= 0 {
+			line = line[:idx]
+		}
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				current = nil
+			}
+			inAgents = true
+			name := strings.ToLower(value)
+			switch {
+			case name == "*":
+				if wildcard == nil {
+					wildcard = &robotsRules{}
+				}
+				current = append(current, wildcard)
+			case name == agent:
+				if matched == nil {
+					matched = &robotsRules{}
+				}
+				current = append(current, matched)
+			}
+		case "allow", "disallow":
+			inAgents = false
+			if value == "" {
+				continue // an empty disallow allows everything
+			}
+			for _, group := range current {
+				group.rules = append(group.rules, robotsRule{pattern: value, allow: key == "allow"})
+			}
+		case "crawl-delay":
+			inAgents = false
+			seconds, err := strconv.ParseFloat(value, 64)
+			if err != nil || seconds < 0 {
+				continue
+			}
+			delay := time.Duration(seconds * float64(time.Second))
+			if delay > maxCrawlDelay {
+				delay = maxCrawlDelay
+			}
+			for _, group := range current {
+				group.crawlDelay = delay
+			}
+		default:
+			inAgents = false
+		}
+	}
+
+	switch {
+	case matched != nil:
+		return matched
+	case wildcard != nil:
+		return wildcard
+	default:
+		return &robotsRules{}
+	}
+}
+
+// allowed reports whether the rules permit fetching the path and query of u.
+// The longest matching pattern wins; allow wins ties.
+func (r *robotsRules) allowed(u *url.URL) bool {
+	if r.disallowed {
+		return false
+	}
+	target := u.EscapedPath()
+	if target == "" {
+		target = "/"
+	}
+	if u.RawQuery != "" {
+		target += "?" + u.RawQuery
+	}
+
+	best := -1
+	allow := true
+	for _, rule := range r.rules {
+		if !matchRobotsPattern(rule.pattern, target) {
+			continue
+		}
+		length := len(rule.pattern)
+		if length > best || (length == best && rule.allow) {
+			best = length
+			allow = rule.allow
+		}
+	}
+	return allow
+}
+
+// matchRobotsPattern matches a robots.txt path pattern with "*" wildcards and a "$" anchor
+func matchRobotsPattern(pattern, target string) bool {
+	if strings.HasSuffix(pattern, "$") {
+		pattern = strings.TrimSuffix(pattern, "$")
+	} else {
+		// Unanchored patterns match any path starting with them
+		pattern += "*"
+	}
+
+	// Greedy wildcard matching with backtracking to the last "*"
+	p, t := 0, 0
+	star, mark := -1, 0
+	for t < len(target) {
+		switch {
+		case p < len(pattern) && pattern[p] == '*':
+			star, mark = p, t
+			p++
+		case p < len(pattern) && pattern[p] == target[t]:
+			p++
+			t++
+		case star >= 0:
+			p = star + 1
+			mark++
+			t = mark
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// beforeRequest enforces the robots.txt policy and per-host rate limit for u
+func (s *Scraper) beforeRequest(ctx context.Context, u *url.URL) error {
+	var crawlDelay time.Duration
+	if s.robotsPolicy == RobotsRespect || (s.robotsPolicy == RobotsBackground && isBackground(ctx)) {
+		rules, err := s.robots.rulesFor(ctx, u)
+		if err != nil {
+			return err
+		}
+		if !rules.allowed(u) {
+			return fmt.Errorf("%w: %s", ErrDisallowedByRobots, u)
+		}
+		crawlDelay = rules.crawlDelay
+	}
+	return s.limiter.wait(ctx, u.Host, crawlDelay)
+}
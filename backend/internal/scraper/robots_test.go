@@ -0,0 +1,248 @@
+package scraper
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRobots(t *testing.T) {
+	robots := `
+# Example robots.txt
+User-agent: *
+Disallow: /private/
+Crawl-delay: 2
+
+User-agent: Googlebot
+User-agent: bookmarksbot
+Disallow: /
+Allow: /public/
+Allow: /*.html$
+Disallow: /public/secret
+Crawl-delay: 120
+
+Sitemap: https://example.com/sitemap.xml
+`
+	rules := parseRobots(strings.NewReader(robots), botName)
+	assert.Equal(t, maxCrawlDelay, rules.crawlDelay)
+
+	tests := []struct {
+		path    string
+		allowed bool
+	}{
+		{path: "/", allowed: false},
+		{path: "/private/page", allowed: false},
+		{path: "/public/page", allowed: true},
+		{path: "/public/secret/file", allowed: false},
+		{path: "/docs/index.html", allowed: true},
+		{path: "/docs/index.html?x=1", allowed: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			u, _ := url.Parse("https://example.com" + tt.path)
+			assert.Equal(t, tt.allowed, rules.allowed(u))
+		})
+	}
+
+	wildcard := parseRobots(strings.NewReader(robots), "OtherBot")
+	assert.Equal(t, 2*time.Second, wildcard.crawlDelay)
+	u, _ := url.Parse("https://example.com/private/x")
+	assert.False(t, wildcard.allowed(u))
+	u, _ = url.Parse("https://example.com/other")
+	assert.True(t, wildcard.allowed(u))
+}
+
+func TestParseRobotsMatchesProductTokenExactly(t *testing.T) {
+	robots := `
+User-agent: bot
+Disallow: /
+
+User-agent: BOOKMARKSBOT
+Disallow: /private/
+`
+	rules := parseRobots(strings.NewReader(robots), botName)
+	u, _ := url.Parse("https://example.com/page")
+	assert.True(t, rules.allowed(u), "bot names a different crawler")
+	u, _ = url.Parse("https://example.com/private/page")
+	assert.False(t, rules.allowed(u))
+}
+
+func TestMatchRobotsPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		target  string
+		want    bool
+	}{
+		{pattern: "/fish", target: "/fish.html", want: true},
+		{pattern: "/fish", target: "/Fish", want: false},
+		{pattern: "/fish$", target: "/fish", want: true},
+		{pattern: "/fish$", target: "/fish/", want: false},
+		{pattern: "/*.php", target: "/folder/index.php?x", want: true},
+		{pattern: "/*.php$", target: "/folder/index.php?x", want: false},
+		{pattern: "/a*b*c", target: "/axxbyyc", want: true},
+		{pattern: "/a*b*c", target: "/axxcyyb", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.target, func(t *testing.T) {
+			assert.Equal(t, tt.want, matchRobotsPattern(tt.pattern, tt.target))
+		})
+	}
+}
+
+func TestRobotsPolicy(t *testing.T) {
+	var robotsFetches int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&robotsFetches, 1)
+		w.Write([]byte("User-agent: BookmarksBot\nDisallow: /blocked\n"))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Page</title></head></html>`))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	t.Run("respect", func(t *testing.T) {
+		atomic.StoreInt32(&robotsFetches, 0)
+		scraper := NewScraper(5*time.Second, allowLoopback, WithRobotsPolicy(RobotsRespect))
+
+		_, err := scraper.GetMetadata(context.Background(), ts.URL+"/blocked/page")
+		assert.ErrorIs(t, err, ErrDisallowedByRobots)
+
+		_, err = scraper.GetMetadata(context.Background(), ts.URL+"/open")
+		assert.NoError(t, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&robotsFetches), "rules are cached per host")
+	})
+
+	t.Run("ignore", func(t *testing.T) {
+		atomic.StoreInt32(&robotsFetches, 0)
+		scraper := NewScraper(5*time.Second, allowLoopback, WithRobotsPolicy(RobotsIgnore))
+
+		_, err := scraper.GetMetadata(BackgroundContext(context.Background()), ts.URL+"/blocked/page")
+		assert.NoError(t, err)
+		assert.Zero(t, atomic.LoadInt32(&robotsFetches))
+	})
+
+	t.Run("background only", func(t *testing.T) {
+		scraper := NewScraper(5*time.Second, allowLoopback, WithRobotsPolicy(RobotsBackground))
+
+		_, err := scraper.GetMetadata(context.Background(), ts.URL+"/blocked/page")
+		assert.NoError(t, err)
+
+		_, err = scraper.GetMetadata(BackgroundContext(context.Background()), ts.URL+"/blocked/page")
+		assert.ErrorIs(t, err, ErrDisallowedByRobots)
+	})
+}
+
+func TestRobotsUnavailable(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		allowed bool
+	}{
+		{name: "not found allows everything", status: http.StatusNotFound, allowed: true},
+		{name: "server error disallows everything", status: http.StatusServiceUnavailable, allowed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer ts.Close()
+
+			scraper := NewScraper(5*time.Second, allowLoopback)
+			u, err := url.Parse(ts.URL + "/page")
+			require.NoError(t, err)
+			rules, err := scraper.robots.rulesFor(context.Background(), u)
+			require.NoError(t, err)
+			assert.Equal(t, tt.allowed, rules.allowed(u))
+		})
+	}
+}
+
+func TestRobotsCacheDoesNotCacheCancelledFetches(t *testing.T) {
+	var fetches atomic.Int32
+	c := newRobotsCache(nil)
+	c.fetch = func(ctx context.Context, robotsURL string) (*robotsRules, error) {
+		if fetches.Add(1) == 1 {
+			return nil, context.DeadlineExceeded
+		}
+		return &robotsRules{expires: time.Now().Add(robotsTTL)}, nil
+	}
+
+	u, _ := url.Parse("https://example.com/page")
+	_, err := c.rulesFor(context.Background(), u)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	rules, err := c.rulesFor(context.Background(), u)
+	require.NoError(t, err)
+	assert.True(t, rules.allowed(u))
+	_, err = c.rulesFor(context.Background(), u)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), fetches.Load())
+}
+
+func TestRobotsCacheSharesConcurrentFetches(t *testing.T) {
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	c := newRobotsCache(nil)
+	c.fetch = func(ctx context.Context, robotsURL string) (*robotsRules, error) {
+		fetches.Add(1)
+		<-release
+		return &robotsRules{disallowed: true, expires: time.Now().Add(robotsTTL)}, nil
+	}
+
+	u, _ := url.Parse("https://example.com/page")
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rules, err := c.rulesFor(context.Background(), u)
+			assert.NoError(t, err)
+			assert.False(t, rules.allowed(u))
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), fetches.Load())
+}
+
+func TestRobotsCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newRobotsCache(nil)
+	c.size = 2
+	c.put("https://a.example", &robotsRules{expires: time.Now().Add(time.Hour)})
+	c.put("https://b.example", &robotsRules{expires: time.Now().Add(time.Hour)})
+
+	// Touch a so that b is the least recently used
+	assert.NotNil(t, c.get("https://a.example"))
+	c.put("https://c.example", &robotsRules{expires: time.Now().Add(time.Hour)})
+	assert.Nil(t, c.get("https://b.example"))
+	assert.NotNil(t, c.get("https://a.example"))
+
+	// Expired rules are dropped
+	c.put("https://d.example", &robotsRules{expires: time.Now().Add(-time.Second)})
+	assert.Nil(t, c.get("https://d.example"))
+	assert.Len(t, c.entries, 1)
+}
+
+func TestParseRobotsPolicy(t *testing.T) {
+	policy, err := ParseRobotsPolicy(" Respect ")
+	assert.NoError(t, err)
+	assert.Equal(t, RobotsRespect, policy)
+
+	_, err = ParseRobotsPolicy("sometimes")
+	assert.Error(t, err)
+}
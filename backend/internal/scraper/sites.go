@@ -0,0 +1,311 @@
+package scraper
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"bookmarks-go/internal/models"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	// isoDuration matches ISO 8601 durations such as PT1H2M3S
+	isoDuration = regexp.MustCompile(`^P(?:(\d+)D)?T?(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?$`)
+	// leadingNumber matches the number at the start of texts such as "123 points"
+	leadingNumber = regexp.MustCompile(`^\s*(\d+)`)
+	// citationMarker matches Wikipedia footnote markers such as [1] or [citation needed]
+	citationMarker = regexp.MustCompile(`\[(?:\d+|[a-z ]+)\]`)
+	// arxivCategory matches the category codes in arXiv subject lists, e.g. (cs.CL)
+	arxivCategory = regexp.MustCompile(`\(([a-z\-]+(?:\.[A-Za-z\-]+)?)\)`)
+)
+
+// githubReserved lists top-level GitHub paths that are not user or organization names
+var githubReserved = map[string]bool{
+	"about": true, "apps": true, "collections": true, "enterprise": true, "events": true,
+	"explore": true, "features": true, "login": true, "marketplace": true, "notifications": true,
+	"orgs": true, "pricing": true, "pulls": true, "issues": true, "search": true,
+	"settings": true, "sponsors": true, "topics": true, "trending": true,
+}
+
+// githubExtractor reads repository details from GitHub repository pages
+type githubExtractor struct{}
+
+func (githubExtractor) Name() string  { return "github" }
+func (githubExtractor) Priority() int { return builtinPriority }
+
+func (githubExtractor) Match(u *url.URL) bool {
+	if strings.ToLower(u.Hostname()) != "github.com" {
+		return false
+	}
+	segments := pathSegments(u)
+	return len(segments) == 2 && !githubReserved[strings.ToLower(segments[0])]
+}
+
+func (e githubExtractor) Extract(doc *html.Node, u *url.URL, m *Metadata) {
+	segments := pathSegments(u)
+	repo := &models.GitHubRepo{
+		Owner: segments[0],
+		Name:  strings.TrimSuffix(segments[1], ".git"),
+	}
+
+	// The About sidebar holds the bare description; meta tags append boilerplate
+	if about := findFirst(doc, byClass("p", "f4")); about != nil {
+		repo.Description = nodeText(about)
+	}
+	if repo.Description == "" {
+		description := metaValue(doc, "description")
+		if idx := strings.Index(description, ". Contribute to "); idx >= 0 {
+			description = description[:idx+1]
+		}
+		repo.Description = description
+	}
+
+	if counter := findFirst(doc, byAttr("", "id", "repo-stars-counter-star")); counter != nil {
+		stars := attrValue(counter, "title")
+		if stars == "" {
+			stars = nodeText(counter)
+		}
+		repo.Stars = parseCount(stars)
+	}
+	if language := findFirst(doc, byAttr("", "itemprop", "programmingLanguage")); language != nil {
+		repo.Language = nodeText(language)
+	}
+
+	m.Title = repo.Owner + "/" + repo.Name
+	if repo.Description != "" {
+		m.Description = repo.Description
+	}
+	m.Extra = &models.Extra{Site: e.Name(), GitHub: repo}
+}
+
+// youtubeExtractor reads channel and duration from YouTube video pages
+type youtubeExtractor struct{}
+
+func (youtubeExtractor) Name() string  { return "youtube" }
+func (youtubeExtractor) Priority() int { return builtinPriority }
+
+func (youtubeExtractor) Match(u *url.URL) bool {
+	return youtubeVideoID(u) != ""
+}
+
+func (e youtubeExtractor) Extract(doc *html.Node, u *url.URL, m *Metadata) {
+	video := &models.YouTubeVideo{VideoID: youtubeVideoID(u)}
+
+	// Channel details are microdata inside <span itemprop="author">
+	if author := findFirst(doc, byAttr("span", "itemprop", "author")); author != nil {
+		if name := findFirst(author, byAttr("link", "itemprop", "name")); name != nil {
+			video.Channel = attrValue(name, "content")
+		}
+		if channelURL := findFirst(author, byAttr("link", "itemprop", "url")); channelURL != nil {
+			video.ChannelURL = attrValue(channelURL, "href")
+		}
+	}
+	video.DurationSeconds = parseISODuration(metaValue(doc, "duration"))
+
+	if video.Channel != "" {
+		m.Author = video.Channel
+	}
+	m.Extra = &models.Extra{Site: e.Name(), YouTube: video}
+}
+
+// youtubeVideoID returns the video ID of watch, shorts and youtu.be URLs, or an empty string
+func youtubeVideoID(u *url.URL) string {
+	host := strings.ToLower(u.Hostname())
+	segments := pathSegments(u)
+	switch {
+	case host == "youtu.be" && len(segments) == 1:
+		return segments[0]
+	case !matchHost(u, "youtube.com"):
+		return ""
+	case len(segments) == 1 && segments[0] == "watch":
+		return u.Query().Get("v")
+	case len(segments) == 2 && (segments[0] == "shorts" || segments[0] == "embed" || segments[0] == "live"):
+		return segments[1]
+	}
+	return ""
+}
+
+// hackerNewsExtractor reads story details from Hacker News item pages
+type hackerNewsExtractor struct{}
+
+func (hackerNewsExtractor) Name() string  { return "hacker_news" }
+func (hackerNewsExtractor) Priority() int { return builtinPriority }
+
+func (hackerNewsExtractor) Match(u *url.URL) bool {
+	return strings.ToLower(u.Hostname()) == "news.ycombinator.com" && u.Path == "/item" && u.Query().Get("id") != ""
+}
+
+func (e hackerNewsExtractor) Extract(doc *html.Node, u *url.URL, m *Metadata) {
+	id, err := strconv.ParseInt(u.Query().Get("id"), 10, 64)
+	if err != nil {
+		return
+	}
+	item := &models.HackerNewsItem{ItemID: id}
+
+	if titleLine := findFirst(doc, byClass("span", "titleline")); titleLine != nil {
+		if link := findElement(titleLine, "a"); link != nil {
+			item.Title = nodeText(link)
+			if storyURL, err := u.Parse(attrValue(link, "href")); err == nil {
+				item.StoryURL = storyURL.String()
+			}
+		}
+	}
+	if user := findFirst(doc, byClass("a", "hnuser")); user != nil {
+		item.Author = nodeText(user)
+	}
+	if score := findFirst(doc, byClass("span", "score")); score != nil {
+		item.Points = parseCount(nodeText(score))
+	}
+	// The comment count is the subline link reading "N comments"; "discuss" means none
+	if subline := findFirst(doc, byClass("span", "subline")); subline != nil {
+		for _, link := range findAll(subline, func(n *html.Node) bool { return n.Type == html.ElementNode && n.Data == "a" }) {
+			if text := strings.ReplaceAll(nodeText(link), "\u00a0", " "); strings.HasSuffix(text, "comments") || strings.HasSuffix(text, "comment") {
+				item.Comments = parseCount(text)
+			}
+		}
+	}
+
+	if item.Title != "" {
+		m.Title = item.Title
+	}
+	if item.Author != "" {
+		m.Author = item.Author
+	}
+	m.Extra = &models.Extra{Site: e.Name(), HackerNews: item}
+}
+
+// wikipediaExtractor reads the title and lead paragraph of Wikipedia articles
+type wikipediaExtractor struct{}
+
+func (wikipediaExtractor) Name() string  { return "wikipedia" }
+func (wikipediaExtractor) Priority() int { return builtinPriority }
+
+func (wikipediaExtractor) Match(u *url.URL) bool {
+	segments := pathSegments(u)
+	return matchHost(u, "wikipedia.org") && len(segments) >= 2 && segments[0] == "wiki"
+}
+
+func (e wikipediaExtractor) Extract(doc *html.Node, u *url.URL, m *Metadata) {
+	article := &models.WikipediaArticle{
+		// Language editions are subdomains, including mobile ones such as en.m.wikipedia.org
+		Language: strings.SplitN(strings.ToLower(u.Hostname()), ".", 2)[0],
+	}
+	if article.Language == "wikipedia" || article.Language == "www" || article.Language == "m" {
+		article.Language = ""
+	}
+
+	if heading := findFirst(doc, byAttr("h1", "id", "firstHeading")); heading != nil {
+		article.Title = nodeText(heading)
+	}
+	if article.Title == "" {
+		title := strings.Join(pathSegments(u)[1:], "/")
+		if unescaped, err := url.PathUnescape(title); err == nil {
+			title = unescaped
+		}
+		article.Title = strings.ReplaceAll(title, "_", " ")
+	}
+
+	// The summary is the first non-empty paragraph of the article body
+	if body := findFirst(doc, byClass("div", "mw-parser-output")); body != nil {
+		for _, p := range findAll(body, func(n *html.Node) bool { return n.Type == html.ElementNode && n.Data == "p" }) {
+			if hasClass(p, "mw-empty-elt") {
+				continue
+			}
+			summary := strings.TrimSpace(citationMarker.ReplaceAllString(nodeText(p), ""))
+			if summary != "" {
+				article.Summary = whitespace.ReplaceAllString(summary, " ")
+				break
+			}
+		}
+	}
+
+	m.Title = article.Title
+	if m.Description == "" {
+		m.Description = article.Summary
+	}
+	m.Extra = &models.Extra{Site: e.Name(), Wikipedia: article}
+}
+
+// arxivExtractor reads the citation metadata of arXiv abstract pages
+type arxivExtractor struct{}
+
+func (arxivExtractor) Name() string  { return "arxiv" }
+func (arxivExtractor) Priority() int { return builtinPriority }
+
+func (arxivExtractor) Match(u *url.URL) bool {
+	segments := pathSegments(u)
+	return matchHost(u, "arxiv.org") && len(segments) >= 2 && segments[0] == "abs"
+}
+
+func (e arxivExtractor) Extract(doc *html.Node, u *url.URL, m *Metadata) {
+	paper := &models.ArXivPaper{
+		ID:        metaValue(doc, "citation_arxiv_id"),
+		Title:     metaValue(doc, "citation_title"),
+		Authors:   metaValues(doc, "citation_author"),
+		Abstract:  metaValue(doc, "citation_abstract"),
+		Published: metaValue(doc, "citation_date"),
+		PDFURL:    metaValue(doc, "citation_pdf_url"),
+	}
+	if paper.ID == "" {
+		paper.ID = strings.Join(pathSegments(u)[1:], "/")
+	}
+	if paper.Abstract == "" {
+		if abstract := findFirst(doc, byClass("blockquote", "abstract")); abstract != nil {
+			paper.Abstract = strings.TrimSpace(strings.TrimPrefix(nodeText(abstract), "Abstract:"))
+		}
+	}
+	paper.Abstract = whitespace.ReplaceAllString(paper.Abstract, " ")
+	if subjects := findFirst(doc, byClass("td", "subjects")); subjects != nil {
+		for _, match := range arxivCategory.FindAllStringSubmatch(nodeText(subjects), -1) {
+			paper.Categories = append(paper.Categories, match[1])
+		}
+	}
+
+	if paper.Title != "" {
+		m.Title = paper.Title
+	}
+	if len(paper.Authors) > 0 {
+		m.Author = strings.Join(paper.Authors, "; ")
+	}
+	if paper.Abstract != "" {
+		m.Description = paper.Abstract
+	}
+	m.Extra = &models.Extra{Site: e.Name(), ArXiv: paper}
+}
+
+// parseCount parses counts such as "1,234", "12.5k" or "3 points"
+func parseCount(text string) int {
+	text = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(text), ",", ""))
+	multiplier := 1.0
+	for suffix, factor := range map[string]float64{"k": 1e3, "m": 1e6} {
+		if strings.HasSuffix(text, suffix) {
+			text, multiplier = strings.TrimSuffix(text, suffix), factor
+		}
+	}
+	if value, err := strconv.ParseFloat(text, 64); err == nil {
+		return int(value * multiplier)
+	}
+	if match := leadingNumber.FindStringSubmatch(text); match != nil {
+		value, _ := strconv.Atoi(match[1])
+		return value
+	}
+	return 0
+}
+
+// parseISODuration parses an ISO 8601 duration into seconds, or returns zero
+func parseISODuration(value string) int {
+	match := isoDuration.FindStringSubmatch(value)
+	if match == nil {
+		return 0
+	}
+	seconds := 0
+	for i, unit := range []int{86400, 3600, 60, 1} {
+		if n, err := strconv.Atoi(match[i+1]); err == nil {
+			seconds += n * unit
+		}
+	}
+	return seconds
+}
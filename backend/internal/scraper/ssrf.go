@@ -0,0 +1,167 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+)
+
+// ErrBlockedAddress is returned when a URL resolves to an address the scraper may not connect to
+var ErrBlockedAddress = errors.New("address is not allowed")
+
+// blockedNetworks are never dialed unless explicitly allowlisted: loopback, private,
+// link-local (including cloud metadata endpoints), carrier-grade NAT and reserved ranges
+var blockedNetworks = mustParseNetworks(
+	"0.0.0.0/8",       // "this" network
+	"10.0.0.0/8",      // RFC1918 private
+	"100.64.0.0/10",   // carrier-grade NAT
+	"127.0.0.0/8",     // loopback
+	"169.254.0.0/16",  // link-local, incl. 169.254.169.254 metadata
+	"172.16.0.0/12",   // RFC1918 private
+	"192.0.0.0/24",    // IETF protocol assignments
+	"192.0.2.0/24",    // TEST-NET-1
+	"192.168.0.0/16",  // RFC1918 private
+	"198.18.0.0/15",   // benchmarking
+	"198.51.100.0/24", // TEST-NET-2
+	"203.0.113.0/24",  // TEST-NET-3
+	"224.0.0.0/4",     // multicast
+	"240.0.0.0/4",     // reserved, incl. broadcast
+	"::/128",          // unspecified
+	"::1/128",         // loopback
+	"100::/64",        // discard prefix
+	"2001:db8::/32",   // documentation
+	"fc00::/7",        // unique local, incl. fd00:ec2::254 metadata
+	"fe80::/10",       // link-local
+	"ff00::/8",        // multicast
+)
+
+// nat64Prefix embeds IPv4 addresses in IPv6; the embedded address is checked as well
+var nat64Prefix = mustParseNetworks("64:ff9b::/96")[0]
+
+// WithAllowedNetworks allows connections to otherwise blocked networks,
+// for deployments that need to bookmark intranet pages
+func WithAllowedNetworks(networks ...*net.IPNet) Option {
+	return func(s *Scraper) {
+		s.allowedNetworks = append(s.allowedNetworks, networks...)
+	}
+}
+
+// ParseNetworks parses a comma-separated list of CIDRs or single IP addresses
+func ParseNetworks(list string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address: %s", entry)
+			}
+			if ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network: %w", err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// controlDial is a net.Dialer Control function that runs after DNS resolution,
+// so it sees the actual IP being connected to on every request and redirect hop
+func (s *Scraper) controlDial(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, address)
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, address)
+	}
+	if !s.isAllowedIP(ip) {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, ip)
+	}
+	return nil
+}
+
+// isAllowedIP reports whether ip may be dialed
+func (s *Scraper) isAllowedIP(ip net.IP) bool {
+	for _, network := range s.allowedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	if ip.To4() == nil && nat64Prefix.Contains(ip) {
+		return s.isAllowedIP(net.IP(ip[12:16]))
+	}
+	return !isBlockedIP(ip)
+}
+
+// isBlockedIP reports whether ip is in a blocked network, also when embedded
+// in a NAT64 address
+func isBlockedIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	} else if nat64Prefix.Contains(ip) {
+		return isBlockedIP(net.IP(ip[12:16]))
+	}
+
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// privateSuffixes end host names that are only meaningful on private networks
+var privateSuffixes = []string{".localhost", ".local", ".lan", ".home.arpa", ".internal", ".intranet", ".corp"}
+
+// IsPrivateHost reports whether host is an intranet name or resolves to a
+// blocked network, whether or not scraping it is allowed. Such hosts must not
+// be disclosed to third parties. Names that do not resolve are not private.
+func IsPrivateHost(ctx context.Context, host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if ip := net.ParseIP(host); ip != nil {
+		return isBlockedIP(ip)
+	}
+	if host == "localhost" || !strings.Contains(host, ".") {
+		return true
+	}
+	for _, suffix := range privateSuffixes {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return false
+	}
+	for _, addr := range addrs {
+		if isBlockedIP(addr.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// mustParseNetworks parses CIDRs known to be valid at compile time
+func mustParseNetworks(cidrs ...string) []*net.IPNet {
+	networks, err := ParseNetworks(strings.Join(cidrs, ","))
+	if err != nil {
+		panic(err)
+	}
+	return networks
+}
@@ -0,0 +1,132 @@
+package scraper
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// allowLoopback lets tests reach httptest servers, which listen on loopback
+var allowLoopback = WithAllowedNetworks(mustParseNetworks("127.0.0.0/8", "::1/128")...)
+
+func TestIsAllowedIP(t *testing.T) {
+	scraper := NewScraper(5 * time.Second)
+
+	tests := []struct {
+		ip      string
+		allowed bool
+	}{
+		{ip: "93.184.216.34", allowed: true},
+		{ip: "2606:2800:220:1:248:1893:25c8:1946", allowed: true},
+		{ip: "127.0.0.1", allowed: false},
+		{ip: "10.1.2.3", allowed: false},
+		{ip: "172.16.0.1", allowed: false},
+		{ip: "192.168.1.1", allowed: false},
+		{ip: "169.254.169.254", allowed: false},
+		{ip: "100.64.0.1", allowed: false},
+		{ip: "0.0.0.0", allowed: false},
+		{ip: "::1", allowed: false},
+		{ip: "::ffff:127.0.0.1", allowed: false},
+		{ip: "::ffff:169.254.169.254", allowed: false},
+		{ip: "fd00:ec2::254", allowed: false},
+		{ip: "fe80::1", allowed: false},
+		{ip: "64:ff9b::a9fe:a9fe", allowed: false},
+		{ip: "64:ff9b::5db8:d822", allowed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			assert.Equal(t, tt.allowed, scraper.isAllowedIP(net.ParseIP(tt.ip)))
+		})
+	}
+}
+
+func TestIsAllowedIPAllowlist(t *testing.T) {
+	networks, err := ParseNetworks("10.0.0.0/8, 192.168.1.5")
+	require.NoError(t, err)
+	scraper := NewScraper(5*time.Second, WithAllowedNetworks(networks...))
+
+	assert.True(t, scraper.isAllowedIP(net.ParseIP("10.20.30.40")))
+	assert.True(t, scraper.isAllowedIP(net.ParseIP("192.168.1.5")))
+	assert.False(t, scraper.isAllowedIP(net.ParseIP("192.168.1.6")))
+	assert.False(t, scraper.isAllowedIP(net.ParseIP("169.254.169.254")))
+}
+
+func TestParseNetworks(t *testing.T) {
+	networks, err := ParseNetworks("")
+	assert.NoError(t, err)
+	assert.Empty(t, networks)
+
+	networks, err = ParseNetworks("10.0.0.0/8,::1")
+	assert.NoError(t, err)
+	assert.Len(t, networks, 2)
+	assert.Equal(t, "::1/128", networks[1].String())
+
+	_, err = ParseNetworks("not-an-ip")
+	assert.Error(t, err)
+}
+
+func TestGetMetadataBlocksPrivateAddresses(t *testing.T) {
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Internal</title></head></html>`))
+	}))
+	defer internal.Close()
+
+	t.Run("direct request", func(t *testing.T) {
+		scraper := NewScraper(5 * time.Second)
+
+		_, err := scraper.GetMetadata(context.Background(), internal.URL)
+		assert.ErrorIs(t, err, ErrBlockedAddress)
+	})
+
+	t.Run("hostname resolving to loopback", func(t *testing.T) {
+		scraper := NewScraper(5 * time.Second)
+		_, port, _ := net.SplitHostPort(internal.Listener.Addr().String())
+
+		_, err := scraper.GetMetadata(context.Background(), "http://localhost:"+port)
+		assert.ErrorIs(t, err, ErrBlockedAddress)
+	})
+
+	t.Run("allowlisted", func(t *testing.T) {
+		scraper := NewScraper(5*time.Second, allowLoopback)
+
+		metadata, err := scraper.GetMetadata(context.Background(), internal.URL)
+		require.NoError(t, err)
+		assert.Equal(t, "Internal", metadata.Title)
+	})
+}
+
+func TestIsPrivateHost(t *testing.T) {
+	tests := []struct {
+		host    string
+		private bool
+	}{
+		{host: "93.184.216.34", private: false},
+		{host: "2606:2800:220:1:248:1893:25c8:1946", private: false},
+		{host: "10.1.2.3", private: true},
+		{host: "169.254.169.254", private: true},
+		{host: "::1", private: true},
+		{host: "64:ff9b::a9fe:a9fe", private: true},
+		{host: "localhost", private: true},
+		{host: "LOCALHOST.", private: true},
+		{host: "intranet", private: true},
+		{host: "wiki.corp", private: true},
+		{host: "nas.home.arpa", private: true},
+		{host: "printer.local", private: true},
+		{host: "app.localhost", private: true},
+		{host: "does-not-exist.invalid", private: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			assert.Equal(t, tt.private, IsPrivateHost(context.Background(), tt.host))
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package scraper
+
+import (
+	"context"
+	"time"
+
+	"bookmarks-go/internal/models"
+)
+
+// Candidate is a value found for a metadata field and where it was found
+type Candidate struct {
+	Field string `json:"field"`
+	Value string `json:"value"`
+	models.FieldProvenance
+	// Selected is set on the candidate the field was finally set to
+	Selected bool `json:"selected"`
+}
+
+// Timing is how long a step of a scrape took
+type Timing struct {
+	Step     string
+	Duration time.Duration
+}
+
+// Trace records how a scrape arrived at its result, for debugging wrong
+// metadata: every candidate value in the order found and the time each step
+// took. The provenance of the values chosen is part of the result.
+type Trace struct {
+	Candidates []Candidate
+	Timings    []Timing
+}
+
+// traceKey is the context key of the trace of a scrape
+type traceKey struct{}
+
+// WithTrace returns a context that records the scrapes made with it in t.
+// Traced scrapes bypass the result cache and are never shared with other calls.
+func WithTrace(ctx context.Context, t *Trace) context.Context {
+	return context.WithValue(ctx, traceKey{}, t)
+}
+
+// traceFrom returns the trace of ctx, or nil if it is not traced
+func traceFrom(ctx context.Context) *Trace {
+	t, _ := ctx.Value(traceKey{}).(*Trace)
+	return t
+}
+
+// candidate records a candidate that was found
+func (t *Trace) candidate(c Candidate) {
+	if t == nil {
+		return
+	}
+	t.Candidates = append(t.Candidates, c)
+}
+
+// selected records that the field of c was set to it, replacing any earlier
+// selection for the field
+func (t *Trace) selected(c Candidate) {
+	if t == nil {
+		return
+	}
+	found := false
+	for i := len(t.Candidates) - 1; i >= 0; i-- {
+		recorded := &t.Candidates[i]
+		if recorded.Field != c.Field {
+			continue
+		}
+		recorded.Selected = !found && recorded.Value == c.Value && recorded.FieldProvenance == c.FieldProvenance
+		found = found || recorded.Selected
+	}
+}
+
+// timing records a step that began at start and just ended
+func (t *Trace) timing(step string, start time.Time) {
+	if t == nil {
+		return
+	}
+	t.Timings = append(t.Timings, Timing{Step: step, Duration: time.Since(start)})
+}
@@ -0,0 +1,93 @@
+package scraper
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"bookmarks-go/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrace(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/page", http.StatusMovedPermanently)
+		case "/page":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head>
+				<meta property="og:title" content="OG Title">
+				<title>Page Title</title>
+				<meta name="description" content="Description">
+				<link rel="icon" href="/icon.png">
+			</head><body></body></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	s := NewScraper(5*time.Second, allowLoopback, WithResultCache(10, time.Minute))
+	_, err := s.GetMetadata(context.Background(), ts.URL+"/old")
+	require.NoError(t, err)
+
+	// Traced scrapes are not served from the cache
+	var trace Trace
+	metadata, err := s.GetMetadata(WithTrace(context.Background(), &trace), ts.URL+"/old")
+	require.NoError(t, err)
+	assert.Equal(t, "OG Title", metadata.Title)
+	assert.Len(t, metadata.Redirects, 1)
+
+	ogTitle := found(models.FieldTitle, "OG Title", models.SourceOpenGraph, "meta[property=og:title]")
+	description := found(models.FieldDescription, "Description", models.SourceMeta, "meta[name=description]")
+	icon := found(models.FieldFaviconURL, ts.URL+"/icon.png", models.SourceIconLink, "link[rel=icon]")
+	assert.Equal(t, []Candidate{
+		selected(ogTitle),
+		found(models.FieldTitle, "Page Title", models.SourceHTMLTitle, "title"),
+		selected(description),
+		selected(icon),
+	}, trace.Candidates)
+	assert.Equal(t, models.Provenance{
+		models.FieldTitle:       ogTitle.FieldProvenance,
+		models.FieldDescription: description.FieldProvenance,
+		models.FieldFaviconURL:  icon.FieldProvenance,
+	}, metadata.Provenance)
+
+	var steps []string
+	for _, timing := range trace.Timings {
+		steps = append(steps, timing.Step)
+	}
+	assert.Equal(t, []string{"fetch " + ts.URL + "/old", "parse", "total"}, steps)
+}
+
+func TestTraceExtractor(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Generic</title></head></html>`))
+	}))
+	defer ts.Close()
+
+	s := NewScraper(5*time.Second, allowLoopback, WithExtractors(testExtractor{name: "test", host: "127.0.0.1", priority: 1}))
+
+	var trace Trace
+	metadata, err := s.GetMetadata(WithTrace(context.Background(), &trace), ts.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "test", metadata.Title)
+	// The extractor overrides the generic title
+	extracted := found(models.FieldTitle, "test", models.SourceExtractor, "test")
+	assert.Contains(t, trace.Candidates, found(models.FieldTitle, "Generic", models.SourceHTMLTitle, "title"))
+	assert.Contains(t, trace.Candidates, selected(extracted))
+	assert.Equal(t, extracted.FieldProvenance, metadata.Provenance[models.FieldTitle])
+	assert.Equal(t, 0.95, metadata.Provenance[models.FieldTitle].Confidence)
+}
+
+// selected returns c marked as selected
+func selected(c Candidate) Candidate {
+	c.Selected = true
+	return c
+}
@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"bookmarks-go/internal/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrNoJob is returned by ClaimJob when no job is due
+var ErrNoJob = errors.New("no job due")
+
+// ErrJobNotClaimed is returned when recording the outcome of a job that is no
+// longer held by the claim, e.g. because its lease expired and it was claimed
+// again, or because it already finished
+var ErrJobNotClaimed = errors.New("job is not claimed")
+
+// JobQueue defines the interface for durable background job storage
+type JobQueue interface {
+	EnqueueJob(ctx context.Context, job *models.Job) error
+	// ClaimJob marks the next due job as running until lease expires and returns it.
+	// Running jobs whose lease has expired, e.g. after a crash, are claimed again,
+	// or marked dead if that was their last attempt.
+	ClaimJob(ctx context.Context, lease time.Duration) (*models.Job, error)
+	// CompleteJob, FailJob and ReleaseJob record the outcome of the given attempt
+	// of a job. They return ErrJobNotClaimed once the job has been claimed again
+	// or has finished.
+	CompleteJob(ctx context.Context, id int64, attempt int) error
+	// FailJob records cause and queues the job again at retryAt, or marks it dead
+	// once its attempts are used up
+	FailJob(ctx context.Context, id int64, attempt int, cause string, retryAt time.Time) error
+	// ReleaseJob queues a running job again right away without counting the
+	// attempt, e.g. when it was interrupted by a shutdown
+	ReleaseJob(ctx context.Context, id int64, attempt int) error
+	GetJob(ctx context.Context, id int64) (*models.Job, error)
+}
+
+// jobColumns lists the job columns selected by every query
+const jobColumns = `id, kind, bookmark_id, status, attempts, max_attempts, last_error, run_at, created_at, updated_at`
+
+// PostgresJobQueue implements JobQueue for PostgreSQL. Workers in any number
+// of processes claim jobs concurrently using SELECT ... FOR UPDATE SKIP LOCKED.
+type PostgresJobQueue struct {
+	db *sqlx.DB
+}
+
+// NewPostgresJobQueue creates a new PostgreSQL job queue
+func NewPostgresJobQueue(db *sqlx.DB) *PostgresJobQueue {
+	return &PostgresJobQueue{db: db}
+}
+
+// EnqueueJob inserts a new queued job
+func (q *PostgresJobQueue) EnqueueJob(ctx context.Context, job *models.Job) error {
+	query := `
+		INSERT INTO jobs (kind, bookmark_id, status, attempts, max_attempts, last_error, run_at, created_at, updated_at)
+		VALUES ($1, $2, $3, 0, $4, '', $5, $6, $7)
+		RETURNING id`
+
+	now := time.Now().UTC()
+	job.Status = models.JobQueued
+	job.Attempts = 0
+	job.LastError = ""
+	if job.RunAt.IsZero() {
+		job.RunAt = now
+	}
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	err := q.db.QueryRowxContext(
+		ctx,
+		query,
+		job.Kind,
+		job.BookmarkID,
+		job.Status,
+		job.MaxAttempts,
+		job.RunAt,
+		job.CreatedAt,
+		job.UpdatedAt,
+	).Scan(&job.ID)
+	if err != nil {
+		return errors.New("failed to enqueue job: " + err.Error())
+	}
+
+	return nil
+}
+
+// leaseExpired is the last error of jobs whose last attempt never finished
+const leaseExpired = "lease expired on the last attempt"
+
+// ClaimJob marks the job that has been due the longest as running
+func (q *PostgresJobQueue) ClaimJob(ctx context.Context, lease time.Duration) (*models.Job, error) {
+	now := time.Now().UTC()
+
+	// Jobs that crashed the process on their last attempt are not run again
+	deadLetter := `
+		UPDATE jobs
+		SET status = $1, last_error = $2, updated_at = $3
+		WHERE status = $4 AND run_at <= $3 AND attempts >= max_attempts`
+	if _, err := q.db.ExecContext(ctx, deadLetter, models.JobDead, leaseExpired, now, models.JobRunning); err != nil {
+		return nil, errors.New("failed to expire jobs: " + err.Error())
+	}
+
+	query := `
+		UPDATE jobs
+		SET status = $1, attempts = attempts + 1, run_at = $2, updated_at = $3
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE status IN ($4, $1) AND run_at <= $3
+			ORDER BY run_at, id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + jobColumns
+
+	job := &models.Job{}
+	err := q.db.GetContext(ctx, job, query, models.JobRunning, now.Add(lease), now, models.JobQueued)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNoJob
+		}
+		return nil, errors.New("failed to claim job: " + err.Error())
+	}
+
+	return job, nil
+}
+
+// CompleteJob marks a job as succeeded
+func (q *PostgresJobQueue) CompleteJob(ctx context.Context, id int64, attempt int) error {
+	query := `
+		UPDATE jobs SET status = $4, last_error = '', updated_at = $5
+		WHERE id = $1 AND status = $2 AND attempts = $3`
+	return q.updateClaimed(ctx, query, id, attempt, models.JobSucceeded, time.Now().UTC())
+}
+
+// FailJob records a failed attempt of a job
+func (q *PostgresJobQueue) FailJob(ctx context.Context, id int64, attempt int, cause string, retryAt time.Time) error {
+	query := `
+		UPDATE jobs
+		SET status = CASE WHEN attempts >= max_attempts THEN $4 ELSE $5 END,
+			last_error = $6, run_at = $7, updated_at = $8
+		WHERE id = $1 AND status = $2 AND attempts = $3`
+	return q.updateClaimed(ctx, query, id, attempt, models.JobDead, models.JobQueued, cause, retryAt.UTC(), time.Now().UTC())
+}
+
+// ReleaseJob queues a claimed job again and takes back its attempt
+func (q *PostgresJobQueue) ReleaseJob(ctx context.Context, id int64, attempt int) error {
+	query := `
+		UPDATE jobs
+		SET status = $4, attempts = GREATEST(attempts - 1, 0), run_at = $5, updated_at = $5
+		WHERE id = $1 AND status = $2 AND attempts = $3`
+	return q.updateClaimed(ctx, query, id, attempt, models.JobQueued, time.Now().UTC())
+}
+
+// updateClaimed runs a statement changing the job with the given ID while it
+// is running the given attempt. The statement takes the ID, running status and
+// attempt as its first parameters.
+func (q *PostgresJobQueue) updateClaimed(ctx context.Context, query string, id int64, attempt int, args ...interface{}) error {
+	result, err := q.db.ExecContext(ctx, query, append([]interface{}{id, models.JobRunning, attempt}, args...)...)
+	if err != nil {
+		return errors.New("failed to update job: " + err.Error())
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.New("failed to get rows affected: " + err.Error())
+	}
+
+	if rowsAffected == 0 {
+		var exists bool
+		if err := q.db.GetContext(ctx, &exists, `SELECT EXISTS (SELECT 1 FROM jobs WHERE id = $1)`, id); err != nil {
+			return errors.New("failed to check job: " + err.Error())
+		}
+		if exists {
+			return ErrJobNotClaimed
+		}
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// GetJob retrieves a job by ID
+func (q *PostgresJobQueue) GetJob(ctx context.Context, id int64) (*models.Job, error) {
+	job := &models.Job{}
+	query := `SELECT ` + jobColumns + ` FROM jobs WHERE id = $1`
+
+	err := q.db.GetContext(ctx, job, query, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, errors.New("failed to get job: " + err.Error())
+	}
+
+	return job, nil
+}
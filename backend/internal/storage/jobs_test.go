@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"bookmarks-go/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testJobQueue exercises the JobQueue contract against q
+func testJobQueue(t *testing.T, q JobQueue, bookmarkID int64) {
+	ctx := context.Background()
+
+	_, err := q.ClaimJob(ctx, time.Minute)
+	assert.Equal(t, ErrNoJob, err)
+
+	first := &models.Job{Kind: models.JobScrapeMetadata, BookmarkID: bookmarkID, MaxAttempts: 2}
+	require.NoError(t, q.EnqueueJob(ctx, first))
+	assert.NotZero(t, first.ID)
+	assert.Equal(t, models.JobQueued, first.Status)
+
+	later := &models.Job{Kind: models.JobScrapeMetadata, BookmarkID: bookmarkID, MaxAttempts: 1, RunAt: time.Now().Add(time.Hour)}
+	require.NoError(t, q.EnqueueJob(ctx, later))
+
+	// Only the due job is claimed, and only once while its lease lasts
+	claimed, err := q.ClaimJob(ctx, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, claimed.ID)
+	assert.Equal(t, models.JobRunning, claimed.Status)
+	assert.Equal(t, 1, claimed.Attempts)
+
+	_, err = q.ClaimJob(ctx, time.Minute)
+	assert.Equal(t, ErrNoJob, err)
+
+	// A failed attempt is retried while attempts remain
+	require.NoError(t, q.FailJob(ctx, first.ID, 1, "timeout", time.Now().Add(-time.Second)))
+	job, err := q.GetJob(ctx, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobQueued, job.Status)
+	assert.Equal(t, "timeout", job.LastError)
+
+	claimed, err = q.ClaimJob(ctx, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, 2, claimed.Attempts)
+
+	require.NoError(t, q.FailJob(ctx, first.ID, 2, "timeout again", time.Now()))
+	job, err = q.GetJob(ctx, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobDead, job.Status)
+	assert.Equal(t, "timeout again", job.LastError)
+
+	_, err = q.ClaimJob(ctx, time.Minute)
+	assert.Equal(t, ErrNoJob, err)
+
+	// An expired lease makes a running job claimable again
+	expired := &models.Job{Kind: models.JobScrapeMetadata, BookmarkID: bookmarkID, MaxAttempts: 3}
+	require.NoError(t, q.EnqueueJob(ctx, expired))
+	_, err = q.ClaimJob(ctx, -time.Second)
+	require.NoError(t, err)
+	claimed, err = q.ClaimJob(ctx, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, expired.ID, claimed.ID)
+	assert.Equal(t, 2, claimed.Attempts)
+
+	// The worker whose lease expired can no longer record an outcome
+	assert.Equal(t, ErrJobNotClaimed, q.FailJob(ctx, expired.ID, 1, "late", time.Now()))
+	assert.Equal(t, ErrJobNotClaimed, q.CompleteJob(ctx, expired.ID, 1))
+	assert.Equal(t, ErrJobNotClaimed, q.ReleaseJob(ctx, expired.ID, 1))
+	job, err = q.GetJob(ctx, expired.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobRunning, job.Status)
+
+	// A released job is due again without using up the attempt
+	require.NoError(t, q.ReleaseJob(ctx, expired.ID, 2))
+	job, err = q.GetJob(ctx, expired.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobQueued, job.Status)
+	assert.Equal(t, 1, job.Attempts)
+	claimed, err = q.ClaimJob(ctx, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, expired.ID, claimed.ID)
+	assert.Equal(t, 2, claimed.Attempts)
+
+	require.NoError(t, q.CompleteJob(ctx, expired.ID, 2))
+	job, err = q.GetJob(ctx, expired.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobSucceeded, job.Status)
+	assert.Equal(t, ErrJobNotClaimed, q.CompleteJob(ctx, expired.ID, 2))
+
+	// A job whose last attempt never finished is marked dead instead of run again
+	crashed := &models.Job{Kind: models.JobScrapeMetadata, BookmarkID: bookmarkID, MaxAttempts: 1}
+	require.NoError(t, q.EnqueueJob(ctx, crashed))
+	_, err = q.ClaimJob(ctx, -time.Second)
+	require.NoError(t, err)
+	_, err = q.ClaimJob(ctx, time.Minute)
+	assert.Equal(t, ErrNoJob, err)
+	job, err = q.GetJob(ctx, crashed.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobDead, job.Status)
+	assert.NotEmpty(t, job.LastError)
+
+	_, err = q.GetJob(ctx, 999)
+	assert.Equal(t, ErrNotFound, err)
+	assert.Equal(t, ErrNotFound, q.CompleteJob(ctx, 999, 1))
+	assert.Equal(t, ErrNotFound, q.ReleaseJob(ctx, 999, 1))
+}
+
+func TestMemoryJobQueue(t *testing.T) {
+	testJobQueue(t, NewMemoryJobQueue(), 1)
+}
+
+func TestMemoryJobQueueEvictsFinishedJobs(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemoryJobQueue()
+	q.maxFinished = 2
+
+	finish := func(maxAttempts int) *models.Job {
+		job := &models.Job{Kind: models.JobScrapeMetadata, BookmarkID: 1, MaxAttempts: maxAttempts}
+		require.NoError(t, q.EnqueueJob(ctx, job))
+		_, err := q.ClaimJob(ctx, time.Minute)
+		require.NoError(t, err)
+		if maxAttempts == 1 {
+			require.NoError(t, q.FailJob(ctx, job.ID, 1, "gone", time.Now()))
+		} else {
+			require.NoError(t, q.CompleteJob(ctx, job.ID, 1))
+		}
+		return job
+	}
+
+	// Beyond the cap, the jobs that finished first are forgotten
+	dead := finish(1)
+	succeeded := finish(2)
+	last := finish(2)
+	_, err := q.GetJob(ctx, dead.ID)
+	assert.Equal(t, ErrNotFound, err)
+	_, err = q.GetJob(ctx, succeeded.ID)
+	assert.NoError(t, err)
+
+	// Queued jobs are kept however old, finished ones only for the retention period
+	queued := &models.Job{Kind: models.JobScrapeMetadata, BookmarkID: 1, MaxAttempts: 1, RunAt: time.Now().Add(time.Hour)}
+	require.NoError(t, q.EnqueueJob(ctx, queued))
+	q.retention = 0
+	require.NoError(t, q.EnqueueJob(ctx, &models.Job{Kind: models.JobScrapeMetadata, BookmarkID: 1, MaxAttempts: 1}))
+	_, err = q.GetJob(ctx, last.ID)
+	assert.Equal(t, ErrNotFound, err)
+	_, err = q.GetJob(ctx, queued.ID)
+	assert.NoError(t, err)
+	assert.Len(t, q.jobs, 2)
+}
+
+func TestMemoryJobQueueKeepsFinishedJobs(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemoryJobQueue()
+
+	job := &models.Job{Kind: models.JobScrapeMetadata, BookmarkID: 1, MaxAttempts: 2}
+	require.NoError(t, q.EnqueueJob(ctx, job))
+	_, err := q.ClaimJob(ctx, time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, q.CompleteJob(ctx, job.ID, 1))
+
+	// A late worker cannot finish or requeue the job again
+	assert.Equal(t, ErrJobNotClaimed, q.CompleteJob(ctx, job.ID, 1))
+	assert.Equal(t, ErrJobNotClaimed, q.FailJob(ctx, job.ID, 1, "late", time.Now()))
+	assert.Equal(t, ErrJobNotClaimed, q.ReleaseJob(ctx, job.ID, 1))
+	stored, err := q.GetJob(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobSucceeded, stored.Status)
+	assert.Len(t, q.finished, 1)
+
+	q.retention = 0
+	require.NoError(t, q.EnqueueJob(ctx, &models.Job{Kind: models.JobScrapeMetadata, BookmarkID: 1, MaxAttempts: 1}))
+	_, err = q.GetJob(ctx, job.ID)
+	assert.Equal(t, ErrNotFound, err)
+}
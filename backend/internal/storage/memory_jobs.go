@@ -0,0 +1,208 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"bookmarks-go/internal/models"
+)
+
+const (
+	// MemoryJobRetention is how long the in-memory queue keeps jobs that
+	// succeeded or died, so that their status can still be looked up
+	MemoryJobRetention = time.Hour
+
+	// maxFinishedJobs bounds the finished jobs kept in memory however recent
+	maxFinishedJobs = 10000
+)
+
+// MemoryJobQueue implements JobQueue in memory, for deployments without
+// PostgreSQL and for tests. Jobs do not survive a restart, and finished jobs
+// are forgotten after MemoryJobRetention.
+type MemoryJobQueue struct {
+	mu     sync.Mutex
+	jobs   map[int64]*models.Job
+	nextID int64
+
+	// finished lists succeeded and dead jobs in the order they finished
+	finished    []int64
+	retention   time.Duration
+	maxFinished int
+}
+
+// NewMemoryJobQueue creates an empty in-memory job queue
+func NewMemoryJobQueue() *MemoryJobQueue {
+	return &MemoryJobQueue{
+		jobs:        make(map[int64]*models.Job),
+		retention:   MemoryJobRetention,
+		maxFinished: maxFinishedJobs,
+	}
+}
+
+// EnqueueJob adds a new queued job
+func (q *MemoryJobQueue) EnqueueJob(ctx context.Context, job *models.Job) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now().UTC()
+	q.evict(now)
+	q.nextID++
+	job.ID = q.nextID
+	job.Status = models.JobQueued
+	job.Attempts = 0
+	job.LastError = ""
+	if job.RunAt.IsZero() {
+		job.RunAt = now
+	}
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	stored := *job
+	q.jobs[job.ID] = &stored
+	return nil
+}
+
+// ClaimJob marks the job that has been due the longest as running
+func (q *MemoryJobQueue) ClaimJob(ctx context.Context, lease time.Duration) (*models.Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now().UTC()
+	var next *models.Job
+	var expired []*models.Job
+	for _, job := range q.jobs {
+		if job.Status != models.JobQueued && job.Status != models.JobRunning {
+			continue
+		}
+		if job.RunAt.After(now) {
+			continue
+		}
+		if job.Status == models.JobRunning && job.Attempts >= job.MaxAttempts {
+			// The last attempt never finished, e.g. because it crashed the process
+			expired = append(expired, job)
+			continue
+		}
+		if next == nil || job.RunAt.Before(next.RunAt) || (job.RunAt.Equal(next.RunAt) && job.ID < next.ID) {
+			next = job
+		}
+	}
+	for _, job := range expired {
+		job.Status = models.JobDead
+		job.LastError = leaseExpired
+		job.UpdatedAt = now
+		q.finish(job)
+	}
+	if next == nil {
+		return nil, ErrNoJob
+	}
+
+	next.Status = models.JobRunning
+	next.Attempts++
+	next.RunAt = now.Add(lease)
+	next.UpdatedAt = now
+
+	claimed := *next
+	return &claimed, nil
+}
+
+// CompleteJob marks a job as succeeded
+func (q *MemoryJobQueue) CompleteJob(ctx context.Context, id int64, attempt int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, ok := q.jobs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if job.Status != models.JobRunning || job.Attempts != attempt {
+		return ErrJobNotClaimed
+	}
+	job.Status = models.JobSucceeded
+	job.LastError = ""
+	job.UpdatedAt = time.Now().UTC()
+	q.finish(job)
+	return nil
+}
+
+// FailJob records a failed attempt of a job
+func (q *MemoryJobQueue) FailJob(ctx context.Context, id int64, attempt int, cause string, retryAt time.Time) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, ok := q.jobs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if job.Status != models.JobRunning || job.Attempts != attempt {
+		return ErrJobNotClaimed
+	}
+	job.Status = models.JobQueued
+	if job.Attempts >= job.MaxAttempts {
+		job.Status = models.JobDead
+	}
+	job.LastError = cause
+	job.RunAt = retryAt.UTC()
+	job.UpdatedAt = time.Now().UTC()
+	if job.Status == models.JobDead {
+		q.finish(job)
+	}
+	return nil
+}
+
+// ReleaseJob queues a claimed job again and takes back its attempt
+func (q *MemoryJobQueue) ReleaseJob(ctx context.Context, id int64, attempt int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, ok := q.jobs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if job.Status != models.JobRunning || job.Attempts != attempt {
+		return ErrJobNotClaimed
+	}
+	now := time.Now().UTC()
+	job.Status = models.JobQueued
+	if job.Attempts > 0 {
+		job.Attempts--
+	}
+	job.RunAt = now
+	job.UpdatedAt = now
+	return nil
+}
+
+// finish records that job succeeded or died and evicts old finished jobs
+func (q *MemoryJobQueue) finish(job *models.Job) {
+	q.finished = append(q.finished, job.ID)
+	q.evict(job.UpdatedAt)
+}
+
+// evict forgets finished jobs older than the retention period, and the
+// oldest ones beyond maxFinished
+func (q *MemoryJobQueue) evict(now time.Time) {
+	cutoff := now.Add(-q.retention)
+	n := 0
+	for n < len(q.finished) {
+		id := q.finished[n]
+		if job, ok := q.jobs[id]; ok && len(q.finished)-n <= q.maxFinished && !job.UpdatedAt.Before(cutoff) {
+			break
+		}
+		delete(q.jobs, id)
+		n++
+	}
+	q.finished = q.finished[n:]
+}
+
+// GetJob retrieves a job by ID
+func (q *MemoryJobQueue) GetJob(ctx context.Context, id int64) (*models.Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, ok := q.jobs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	found := *job
+	return &found, nil
+}
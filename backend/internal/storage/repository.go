@@ -14,6 +14,8 @@ import (
 
 var (
 	ErrNotFound = errors.New("bookmark not found")
+	// ErrConflict is returned when a bookmark was updated since it was read
+	ErrConflict = errors.New("bookmark was updated concurrently")
 	ErrDatabase = errors.New("database error")
 )
 
@@ -21,10 +23,33 @@ var (
 type Repository interface {
 	CreateBookmark(ctx context.Context, bookmark *models.Bookmark) error
 	GetBookmark(ctx context.Context, id int64) (*models.Bookmark, error)
-	ListBookmarks(ctx context.Context) ([]models.Bookmark, error)
+	ListBookmarks(ctx context.Context, filter models.BookmarkFilter) ([]models.Bookmark, error)
+	ListStaleBookmarks(ctx context.Context, before time.Time, limit int) ([]models.Bookmark, error)
+	UpdateBookmark(ctx context.Context, bookmark *models.Bookmark) error
+	ListBookmarksToCheck(ctx context.Context, before time.Time, limit int) ([]models.Bookmark, error)
+	RecordLinkCheck(ctx context.Context, check *models.LinkCheck, brokenAfter int) error
+	SetPageArchived(ctx context.Context, id int64, at time.Time) error
+	SetWatched(ctx context.Context, id int64, watched bool) error
+	SetSummary(ctx context.Context, id int64, summary string, at time.Time) error
+	SetTags(ctx context.Context, id int64, tags models.Tags) error
+	ListTags(ctx context.Context) ([]models.TagCount, error)
+	AddTagSuggestions(ctx context.Context, bookmarkID int64, tags []string, apply bool) error
+	ListTagSuggestions(ctx context.Context, bookmarkID int64) ([]models.TagSuggestion, error)
+	DecideTagSuggestion(ctx context.Context, bookmarkID, suggestionID int64, accept bool) (*models.TagSuggestion, error)
+	ListWatchedToCheck(ctx context.Context, before time.Time, limit int) ([]models.Bookmark, error)
+	GetPageText(ctx context.Context, bookmarkID int64) (*models.PageText, error)
+	RecordPageCheck(ctx context.Context, bookmarkID int64, checkedAt time.Time, text *models.PageText, change *models.PageChange) error
+	ListPageChanges(ctx context.Context, bookmarkID int64, limit int) ([]models.PageChange, error)
+	ListPageChangesAfter(ctx context.Context, after int64, limit int) ([]models.PageChange, error)
+	ListLinkChecks(ctx context.Context, bookmarkID int64, limit int) ([]models.LinkCheck, error)
 	DeleteBookmark(ctx context.Context, id int64) error
+	SaveContent(ctx context.Context, content *models.BookmarkContent) error
+	GetContent(ctx context.Context, bookmarkID int64) (*models.BookmarkContent, error)
 }
 
+// bookmarkColumns lists the bookmark columns selected by every query
+const bookmarkColumns = `id, url, title, description, author, favicon_url, icons, image_url, final_url, redirects, media_type, content_length, page_count, word_count, reading_time_minutes, extra, etag, last_modified, content_hash, metadata_status, metadata_error, refreshed_at, locked_fields, provenance, health, check_failures, last_checked_at, archive_url, archived_at, page_archived_at, watched, watch_checked_at, tags, summary, summarized_at, created_at, updated_at`
+
 // PostgresRepository implements Repository interface for PostgreSQL
 type PostgresRepository struct {
 	db *sqlx.DB
@@ -38,13 +63,19 @@ func NewPostgresRepository(db *sqlx.DB) *PostgresRepository {
 // CreateBookmark inserts a new bookmark into the database
 func (r *PostgresRepository) CreateBookmark(ctx context.Context, bookmark *models.Bookmark) error {
 	query := `
-		INSERT INTO bookmarks (url, title, description, favicon_url, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO bookmarks (url, title, description, author, favicon_url, icons, image_url, final_url, redirects, media_type, content_length, page_count, word_count, reading_time_minutes, extra, etag, last_modified, content_hash, metadata_status, metadata_error, refreshed_at, locked_fields, provenance, health, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26)
 		RETURNING id`
 
-	now := time.Now().UTC()
+	now := time.Now().UTC().Truncate(time.Microsecond)
 	bookmark.CreatedAt = now
 	bookmark.UpdatedAt = now
+	if bookmark.RefreshedAt.IsZero() {
+		bookmark.RefreshedAt = now
+	}
+	if bookmark.Health == "" {
+		bookmark.Health = models.HealthUnknown
+	}
 
 	err := r.db.QueryRowxContext(
 		ctx,
@@ -52,7 +83,27 @@ func (r *PostgresRepository) CreateBookmark(ctx context.Context, bookmark *model
 		bookmark.URL,
 		bookmark.Title,
 		bookmark.Description,
+		bookmark.Author,
 		bookmark.FaviconURL,
+		bookmark.Icons,
+		bookmark.ImageURL,
+		bookmark.FinalURL,
+		bookmark.Redirects,
+		bookmark.MediaType,
+		bookmark.ContentLength,
+		bookmark.PageCount,
+		bookmark.WordCount,
+		bookmark.ReadingTime,
+		bookmark.Extra,
+		bookmark.ETag,
+		bookmark.LastModified,
+		bookmark.ContentHash,
+		bookmark.MetadataStatus,
+		bookmark.MetadataError,
+		bookmark.RefreshedAt,
+		bookmark.LockedFields,
+		bookmark.Provenance,
+		bookmark.Health,
 		bookmark.CreatedAt,
 		bookmark.UpdatedAt,
 	).Scan(&bookmark.ID)
@@ -68,7 +119,7 @@ func (r *PostgresRepository) CreateBookmark(ctx context.Context, bookmark *model
 func (r *PostgresRepository) GetBookmark(ctx context.Context, id int64) (*models.Bookmark, error) {
 	bookmark := &models.Bookmark{}
 	query := `
-		SELECT id, url, title, description, favicon_url, created_at, updated_at
+		SELECT ` + bookmarkColumns + `
 		FROM bookmarks
 		WHERE id = $1`
 
@@ -83,15 +134,16 @@ func (r *PostgresRepository) GetBookmark(ctx context.Context, id int64) (*models
 	return bookmark, nil
 }
 
-// ListBookmarks retrieves all bookmarks
-func (r *PostgresRepository) ListBookmarks(ctx context.Context) ([]models.Bookmark, error) {
+// ListBookmarks retrieves all bookmarks matching filter
+func (r *PostgresRepository) ListBookmarks(ctx context.Context, filter models.BookmarkFilter) ([]models.Bookmark, error) {
 	var bookmarks []models.Bookmark
 	query := `
-		SELECT id, url, title, description, favicon_url, created_at, updated_at
+		SELECT ` + bookmarkColumns + `
 		FROM bookmarks
+		WHERE ($1 = '' OR health = $1) AND ($2 = '' OR tags @> jsonb_build_array($2::text))
 		ORDER BY created_at DESC`
 
-	err := r.db.SelectContext(ctx, &bookmarks, query)
+	err := r.db.SelectContext(ctx, &bookmarks, query, filter.Health, filter.Tag)
 	if err != nil {
 		return nil, errors.New("failed to list bookmarks: " + err.Error())
 	}
@@ -99,6 +151,568 @@ func (r *PostgresRepository) ListBookmarks(ctx context.Context) ([]models.Bookma
 	return bookmarks, nil
 }
 
+// ListStaleBookmarks retrieves up to limit bookmarks whose metadata was last
+// refreshed before the given time. Bookmarks whose metadata could not be
+// fetched come first, then the longest unrefreshed; pending ones are skipped.
+func (r *PostgresRepository) ListStaleBookmarks(ctx context.Context, before time.Time, limit int) ([]models.Bookmark, error) {
+	var bookmarks []models.Bookmark
+	query := `
+		SELECT ` + bookmarkColumns + `
+		FROM bookmarks
+		WHERE metadata_status <> $1 AND refreshed_at < $2
+		ORDER BY metadata_status = $3 DESC, refreshed_at, id
+		LIMIT $4`
+
+	err := r.db.SelectContext(ctx, &bookmarks, query, models.MetadataPending, before.UTC(), models.MetadataFailed, limit)
+	if err != nil {
+		return nil, errors.New("failed to list stale bookmarks: " + err.Error())
+	}
+
+	return bookmarks, nil
+}
+
+// UpdateBookmark stores the metadata of an existing bookmark; its URL and
+// creation time are kept. ErrConflict is returned when the bookmark was updated
+// since it was read, as told by its UpdatedAt; see UpdateWithRetry.
+func (r *PostgresRepository) UpdateBookmark(ctx context.Context, bookmark *models.Bookmark) error {
+	query := `
+		UPDATE bookmarks
+		SET title = $2, description = $3, author = $4, favicon_url = $5, icons = $6, image_url = $7,
+			final_url = $8, redirects = $9, media_type = $10, content_length = $11, page_count = $12,
+			word_count = $13, reading_time_minutes = $14, extra = $15, etag = $16, last_modified = $17,
+			content_hash = $18, metadata_status = $19, metadata_error = $20, refreshed_at = $21,
+			locked_fields = $22, provenance = $23, archive_url = $24, archived_at = $25, updated_at = $26
+		WHERE id = $1 AND updated_at = $27`
+
+	readAt := bookmark.UpdatedAt
+	// Stored timestamps have microsecond precision
+	updatedAt := time.Now().UTC().Truncate(time.Microsecond)
+
+	result, err := r.db.ExecContext(
+		ctx,
+		query,
+		bookmark.ID,
+		bookmark.Title,
+		bookmark.Description,
+		bookmark.Author,
+		bookmark.FaviconURL,
+		bookmark.Icons,
+		bookmark.ImageURL,
+		bookmark.FinalURL,
+		bookmark.Redirects,
+		bookmark.MediaType,
+		bookmark.ContentLength,
+		bookmark.PageCount,
+		bookmark.WordCount,
+		bookmark.ReadingTime,
+		bookmark.Extra,
+		bookmark.ETag,
+		bookmark.LastModified,
+		bookmark.ContentHash,
+		bookmark.MetadataStatus,
+		bookmark.MetadataError,
+		bookmark.RefreshedAt,
+		bookmark.LockedFields,
+		bookmark.Provenance,
+		bookmark.ArchiveURL,
+		bookmark.ArchivedAt,
+		updatedAt,
+		readAt,
+	)
+	if err != nil {
+		return errors.New("failed to update bookmark: " + err.Error())
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.New("failed to get rows affected: " + err.Error())
+	}
+
+	if rowsAffected == 0 {
+		var exists bool
+		if err := r.db.GetContext(ctx, &exists, `SELECT EXISTS (SELECT 1 FROM bookmarks WHERE id = $1)`, bookmark.ID); err != nil {
+			return errors.New("failed to check bookmark: " + err.Error())
+		}
+		if exists {
+			return ErrConflict
+		}
+		return ErrNotFound
+	}
+
+	bookmark.UpdatedAt = updatedAt
+	return nil
+}
+
+// ListBookmarksToCheck retrieves up to limit bookmarks whose link was never
+// checked or last checked before the given time, least recently checked first
+func (r *PostgresRepository) ListBookmarksToCheck(ctx context.Context, before time.Time, limit int) ([]models.Bookmark, error) {
+	var bookmarks []models.Bookmark
+	query := `
+		SELECT ` + bookmarkColumns + `
+		FROM bookmarks
+		WHERE last_checked_at IS NULL OR last_checked_at < $1
+		ORDER BY last_checked_at NULLS FIRST, id
+		LIMIT $2`
+
+	err := r.db.SelectContext(ctx, &bookmarks, query, before.UTC(), limit)
+	if err != nil {
+		return nil, errors.New("failed to list bookmarks to check: " + err.Error())
+	}
+
+	return bookmarks, nil
+}
+
+// RecordLinkCheck stores a link check and updates the bookmark's health. The
+// bookmark is marked broken after brokenAfter consecutive failed checks and
+// ok again after the first successful one; inconclusive checks count as neither.
+func (r *PostgresRepository) RecordLinkCheck(ctx context.Context, check *models.LinkCheck, brokenAfter int) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.New("failed to begin transaction: " + err.Error())
+	}
+	defer tx.Rollback()
+
+	if check.CheckedAt.IsZero() {
+		check.CheckedAt = time.Now().UTC()
+	}
+
+	result, err := tx.ExecContext(
+		ctx,
+		`UPDATE bookmarks
+		SET health = CASE WHEN $7 THEN health WHEN $2 THEN $3 WHEN check_failures + 1 >= $4 THEN $5 ELSE health END,
+			check_failures = CASE WHEN $7 THEN check_failures WHEN $2 THEN 0 ELSE check_failures + 1 END,
+			last_checked_at = $6
+		WHERE id = $1`,
+		check.BookmarkID,
+		check.OK,
+		models.HealthOK,
+		brokenAfter,
+		models.HealthBroken,
+		check.CheckedAt,
+		check.Inconclusive,
+	)
+	if err != nil {
+		return errors.New("failed to update bookmark health: " + err.Error())
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.New("failed to get rows affected: " + err.Error())
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	err = tx.QueryRowxContext(
+		ctx,
+		`INSERT INTO link_checks (bookmark_id, checked_at, ok, status_code, final_url, latency_ms, error, tls_error, inconclusive)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id`,
+		check.BookmarkID,
+		check.CheckedAt,
+		check.OK,
+		check.StatusCode,
+		check.FinalURL,
+		check.LatencyMS,
+		check.Error,
+		check.TLSError,
+		check.Inconclusive,
+	).Scan(&check.ID)
+	if err != nil {
+		return errors.New("failed to record link check: " + err.Error())
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.New("failed to commit link check: " + err.Error())
+	}
+	return nil
+}
+
+// SetPageArchived records when the page of a bookmark was archived. Only
+// that column is written, so that concurrent metadata updates are kept.
+func (r *PostgresRepository) SetPageArchived(ctx context.Context, id int64, at time.Time) error {
+	query := `UPDATE bookmarks SET page_archived_at = $2 WHERE id = $1`
+	result, err := r.db.ExecContext(ctx, query, id, at.UTC())
+	if err != nil {
+		return errors.New("failed to set page archive time: " + err.Error())
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.New("failed to get rows affected: " + err.Error())
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// SetSummary stores the generated summary of a bookmark's content. Only the
+// summary columns are written, so that concurrent metadata updates are kept.
+func (r *PostgresRepository) SetSummary(ctx context.Context, id int64, summary string, at time.Time) error {
+	query := `UPDATE bookmarks SET summary = $2, summarized_at = $3 WHERE id = $1`
+	result, err := r.db.ExecContext(ctx, query, id, summary, at.UTC())
+	if err != nil {
+		return errors.New("failed to set summary: " + err.Error())
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.New("failed to get rows affected: " + err.Error())
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// SetTags replaces the tags of a bookmark
+func (r *PostgresRepository) SetTags(ctx context.Context, id int64, tags models.Tags) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE bookmarks SET tags = $2 WHERE id = $1`, id, tags)
+	if err != nil {
+		return errors.New("failed to set tags: " + err.Error())
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.New("failed to get rows affected: " + err.Error())
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// ListTags retrieves every tag in use with the number of bookmarks carrying
+// it, most used first
+func (r *PostgresRepository) ListTags(ctx context.Context) ([]models.TagCount, error) {
+	tags := []models.TagCount{}
+	query := `
+		SELECT t.tag, COUNT(*) AS count
+		FROM bookmarks, jsonb_array_elements_text(bookmarks.tags) AS t(tag)
+		GROUP BY t.tag
+		ORDER BY count DESC, t.tag`
+
+	err := r.db.SelectContext(ctx, &tags, query)
+	if err != nil {
+		return nil, errors.New("failed to list tags: " + err.Error())
+	}
+
+	return tags, nil
+}
+
+// AddTagSuggestions records tags suggested for a bookmark; tags suggested
+// before are skipped. With apply, the tags are added to the bookmark right
+// away and recorded as accepted.
+func (r *PostgresRepository) AddTagSuggestions(ctx context.Context, bookmarkID int64, tags []string, apply bool) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.New("failed to begin transaction: " + err.Error())
+	}
+	defer tx.Rollback()
+
+	status := models.TagSuggestionPending
+	var decidedAt *time.Time
+	if apply {
+		now := time.Now().UTC()
+		status, decidedAt = models.TagSuggestionAccepted, &now
+		if err := addTags(ctx, tx, bookmarkID, tags); err != nil {
+			return err
+		}
+	}
+
+	for _, tag := range tags {
+		_, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO tag_suggestions (bookmark_id, tag, status, created_at, decided_at)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (bookmark_id, tag) DO NOTHING`,
+			bookmarkID,
+			tag,
+			status,
+			time.Now().UTC(),
+			decidedAt,
+		)
+		if err != nil {
+			return errors.New("failed to add tag suggestion: " + err.Error())
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.New("failed to commit tag suggestions: " + err.Error())
+	}
+	return nil
+}
+
+// ListTagSuggestions retrieves every tag suggested for a bookmark, oldest first
+func (r *PostgresRepository) ListTagSuggestions(ctx context.Context, bookmarkID int64) ([]models.TagSuggestion, error) {
+	suggestions := []models.TagSuggestion{}
+	query := `
+		SELECT id, bookmark_id, tag, status, created_at, decided_at
+		FROM tag_suggestions
+		WHERE bookmark_id = $1
+		ORDER BY id`
+
+	err := r.db.SelectContext(ctx, &suggestions, query, bookmarkID)
+	if err != nil {
+		return nil, errors.New("failed to list tag suggestions: " + err.Error())
+	}
+
+	return suggestions, nil
+}
+
+// DecideTagSuggestion accepts or rejects a pending tag suggestion of a
+// bookmark. Accepted tags are added to the bookmark. ErrNotFound is returned
+// when no such suggestion is pending.
+func (r *PostgresRepository) DecideTagSuggestion(ctx context.Context, bookmarkID, suggestionID int64, accept bool) (*models.TagSuggestion, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, errors.New("failed to begin transaction: " + err.Error())
+	}
+	defer tx.Rollback()
+
+	status := models.TagSuggestionRejected
+	if accept {
+		status = models.TagSuggestionAccepted
+	}
+	suggestion := &models.TagSuggestion{}
+	err = tx.GetContext(
+		ctx,
+		suggestion,
+		`UPDATE tag_suggestions SET status = $3, decided_at = $4
+		WHERE id = $1 AND bookmark_id = $2 AND status = $5
+		RETURNING id, bookmark_id, tag, status, created_at, decided_at`,
+		suggestionID,
+		bookmarkID,
+		status,
+		time.Now().UTC(),
+		models.TagSuggestionPending,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, errors.New("failed to decide tag suggestion: " + err.Error())
+	}
+
+	if accept {
+		if err := addTags(ctx, tx, bookmarkID, []string{suggestion.Tag}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.New("failed to commit tag suggestion: " + err.Error())
+	}
+	return suggestion, nil
+}
+
+// addTags adds tags to those of a bookmark within tx
+func addTags(ctx context.Context, tx *sqlx.Tx, bookmarkID int64, tags []string) error {
+	var current models.Tags
+	err := tx.QueryRowxContext(ctx, `SELECT tags FROM bookmarks WHERE id = $1 FOR UPDATE`, bookmarkID).Scan(&current)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		return errors.New("failed to get tags: " + err.Error())
+	}
+
+	for _, tag := range tags {
+		current = current.Add(tag)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE bookmarks SET tags = $2 WHERE id = $1`, bookmarkID, current); err != nil {
+		return errors.New("failed to set tags: " + err.Error())
+	}
+	return nil
+}
+
+// SetWatched turns change monitoring of a bookmark on or off. The last seen
+// text is discarded, so that the next check starts from the current page.
+func (r *PostgresRepository) SetWatched(ctx context.Context, id int64, watched bool) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.New("failed to begin transaction: " + err.Error())
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `UPDATE bookmarks SET watched = $2, watch_checked_at = NULL WHERE id = $1`, id, watched)
+	if err != nil {
+		return errors.New("failed to set watched: " + err.Error())
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.New("failed to get rows affected: " + err.Error())
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM page_texts WHERE bookmark_id = $1`, id); err != nil {
+		return errors.New("failed to delete page text: " + err.Error())
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.New("failed to commit watched: " + err.Error())
+	}
+	return nil
+}
+
+// ListWatchedToCheck retrieves up to limit watched bookmarks that were never
+// checked for changes or last checked before the given time, least recently checked first
+func (r *PostgresRepository) ListWatchedToCheck(ctx context.Context, before time.Time, limit int) ([]models.Bookmark, error) {
+	var bookmarks []models.Bookmark
+	query := `
+		SELECT ` + bookmarkColumns + `
+		FROM bookmarks
+		WHERE watched AND (watch_checked_at IS NULL OR watch_checked_at < $1)
+		ORDER BY watch_checked_at NULLS FIRST, id
+		LIMIT $2`
+
+	err := r.db.SelectContext(ctx, &bookmarks, query, before.UTC(), limit)
+	if err != nil {
+		return nil, errors.New("failed to list watched bookmarks to check: " + err.Error())
+	}
+
+	return bookmarks, nil
+}
+
+// GetPageText retrieves the last seen content text of a watched bookmark
+func (r *PostgresRepository) GetPageText(ctx context.Context, bookmarkID int64) (*models.PageText, error) {
+	var text models.PageText
+	query := `SELECT bookmark_id, text, fetched_at FROM page_texts WHERE bookmark_id = $1`
+
+	err := r.db.GetContext(ctx, &text, query, bookmarkID)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, errors.New("failed to get page text: " + err.Error())
+	}
+
+	return &text, nil
+}
+
+// RecordPageCheck records a check of a watched bookmark for changes. text
+// replaces the last seen text unless it is nil, as when the page could not be
+// fetched, and change is stored if it is not nil.
+func (r *PostgresRepository) RecordPageCheck(ctx context.Context, bookmarkID int64, checkedAt time.Time, text *models.PageText, change *models.PageChange) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.New("failed to begin transaction: " + err.Error())
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `UPDATE bookmarks SET watch_checked_at = $2 WHERE id = $1`, bookmarkID, checkedAt.UTC())
+	if err != nil {
+		return errors.New("failed to update watch check time: " + err.Error())
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.New("failed to get rows affected: " + err.Error())
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	if text != nil {
+		_, err = tx.ExecContext(
+			ctx,
+			`INSERT INTO page_texts (bookmark_id, text, fetched_at)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (bookmark_id) DO UPDATE SET text = EXCLUDED.text, fetched_at = EXCLUDED.fetched_at`,
+			bookmarkID,
+			text.Text,
+			text.FetchedAt.UTC(),
+		)
+		if err != nil {
+			return errors.New("failed to save page text: " + err.Error())
+		}
+	}
+
+	if change != nil {
+		err = tx.QueryRowxContext(
+			ctx,
+			`INSERT INTO page_changes (bookmark_id, detected_at, diff, added, removed)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id`,
+			bookmarkID,
+			change.DetectedAt.UTC(),
+			change.Diff,
+			change.Added,
+			change.Removed,
+		).Scan(&change.ID)
+		if err != nil {
+			return errors.New("failed to record page change: " + err.Error())
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.New("failed to commit page check: " + err.Error())
+	}
+	return nil
+}
+
+// ListPageChanges retrieves the most recent changes of a watched bookmark, newest first
+func (r *PostgresRepository) ListPageChanges(ctx context.Context, bookmarkID int64, limit int) ([]models.PageChange, error) {
+	changes := []models.PageChange{}
+	query := `
+		SELECT id, bookmark_id, detected_at, diff, added, removed
+		FROM page_changes
+		WHERE bookmark_id = $1
+		ORDER BY id DESC
+		LIMIT $2`
+
+	err := r.db.SelectContext(ctx, &changes, query, bookmarkID, limit)
+	if err != nil {
+		return nil, errors.New("failed to list page changes: " + err.Error())
+	}
+
+	return changes, nil
+}
+
+// ListPageChangesAfter retrieves up to limit changes of all bookmarks with an
+// ID greater than after, oldest first, for polling
+func (r *PostgresRepository) ListPageChangesAfter(ctx context.Context, after int64, limit int) ([]models.PageChange, error) {
+	changes := []models.PageChange{}
+	query := `
+		SELECT id, bookmark_id, detected_at, diff, added, removed
+		FROM page_changes
+		WHERE id > $1
+		ORDER BY id
+		LIMIT $2`
+
+	err := r.db.SelectContext(ctx, &changes, query, after, limit)
+	if err != nil {
+		return nil, errors.New("failed to list page changes: " + err.Error())
+	}
+
+	return changes, nil
+}
+
+// ListLinkChecks retrieves the most recent link checks of a bookmark, newest first
+func (r *PostgresRepository) ListLinkChecks(ctx context.Context, bookmarkID int64, limit int) ([]models.LinkCheck, error) {
+	checks := []models.LinkCheck{}
+	query := `
+		SELECT id, bookmark_id, checked_at, ok, status_code, final_url, latency_ms, error, tls_error, inconclusive
+		FROM link_checks
+		WHERE bookmark_id = $1
+		ORDER BY checked_at DESC, id DESC
+		LIMIT $2`
+
+	err := r.db.SelectContext(ctx, &checks, query, bookmarkID, limit)
+	if err != nil {
+		return nil, errors.New("failed to list link checks: " + err.Error())
+	}
+
+	return checks, nil
+}
+
 // DeleteBookmark removes a bookmark by ID
 func (r *PostgresRepository) DeleteBookmark(ctx context.Context, id int64) error {
 	query := `DELETE FROM bookmarks WHERE id = $1`
@@ -118,3 +732,53 @@ func (r *PostgresRepository) DeleteBookmark(ctx context.Context, id int64) error
 
 	return nil
 }
+
+// SaveContent inserts or replaces the extracted content of a bookmark
+func (r *PostgresRepository) SaveContent(ctx context.Context, content *models.BookmarkContent) error {
+	query := `
+		INSERT INTO bookmark_contents (bookmark_id, text, html, word_count, reading_time_minutes, extracted_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (bookmark_id) DO UPDATE SET
+			text = EXCLUDED.text,
+			html = EXCLUDED.html,
+			word_count = EXCLUDED.word_count,
+			reading_time_minutes = EXCLUDED.reading_time_minutes,
+			extracted_at = EXCLUDED.extracted_at`
+
+	content.ExtractedAt = time.Now().UTC()
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		content.BookmarkID,
+		content.Text,
+		content.HTML,
+		content.WordCount,
+		content.ReadingTime,
+		content.ExtractedAt,
+	)
+	if err != nil {
+		return errors.New("failed to save content: " + err.Error())
+	}
+
+	return nil
+}
+
+// GetContent retrieves the extracted content of a bookmark
+func (r *PostgresRepository) GetContent(ctx context.Context, bookmarkID int64) (*models.BookmarkContent, error) {
+	content := &models.BookmarkContent{}
+	query := `
+		SELECT bookmark_id, text, html, word_count, reading_time_minutes, extracted_at
+		FROM bookmark_contents
+		WHERE bookmark_id = $1`
+
+	err := r.db.GetContext(ctx, content, query, bookmarkID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, errors.New("failed to get content: " + err.Error())
+	}
+
+	return content, nil
+}
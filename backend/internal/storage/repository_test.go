@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"testing"
+	"time"
 
 	"bookmarks-go/internal/models"
 
@@ -33,10 +34,94 @@ func (s *RepositoryTestSuite) SetupSuite() {
 			url TEXT NOT NULL,
 			title TEXT,
 			description TEXT,
+			author TEXT NOT NULL DEFAULT '',
 			favicon_url TEXT,
+			icons JSONB,
+			image_url TEXT NOT NULL DEFAULT '',
+			final_url TEXT NOT NULL DEFAULT '',
+			redirects JSONB,
+			media_type TEXT NOT NULL DEFAULT '',
+			content_length BIGINT NOT NULL DEFAULT 0,
+			page_count INTEGER NOT NULL DEFAULT 0,
+			word_count INTEGER NOT NULL DEFAULT 0,
+			reading_time_minutes INTEGER NOT NULL DEFAULT 0,
+			extra JSONB,
+			etag TEXT NOT NULL DEFAULT '',
+			last_modified TEXT NOT NULL DEFAULT '',
+			content_hash TEXT NOT NULL DEFAULT '',
+			metadata_status TEXT NOT NULL DEFAULT 'ok',
+			metadata_error TEXT NOT NULL DEFAULT '',
+			refreshed_at TIMESTAMP NOT NULL,
+			locked_fields JSONB,
+			provenance JSONB,
+			health TEXT NOT NULL DEFAULT 'unknown',
+			check_failures INTEGER NOT NULL DEFAULT 0,
+			last_checked_at TIMESTAMP,
+			archive_url TEXT NOT NULL DEFAULT '',
+			archived_at TIMESTAMP,
+			page_archived_at TIMESTAMP,
+			watched BOOLEAN NOT NULL DEFAULT false,
+			watch_checked_at TIMESTAMP,
+			tags JSONB,
+			summary TEXT NOT NULL DEFAULT '',
+			summarized_at TIMESTAMP,
 			created_at TIMESTAMP NOT NULL,
 			updated_at TIMESTAMP NOT NULL
 		);
+		CREATE TABLE IF NOT EXISTS bookmark_contents (
+			bookmark_id INTEGER PRIMARY KEY REFERENCES bookmarks(id) ON DELETE CASCADE,
+			text TEXT NOT NULL,
+			html TEXT NOT NULL,
+			word_count INTEGER NOT NULL DEFAULT 0,
+			reading_time_minutes INTEGER NOT NULL DEFAULT 0,
+			extracted_at TIMESTAMP NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS page_texts (
+			bookmark_id INTEGER PRIMARY KEY REFERENCES bookmarks(id) ON DELETE CASCADE,
+			text TEXT NOT NULL,
+			fetched_at TIMESTAMP NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS page_changes (
+			id BIGSERIAL PRIMARY KEY,
+			bookmark_id INTEGER NOT NULL REFERENCES bookmarks(id) ON DELETE CASCADE,
+			detected_at TIMESTAMP NOT NULL,
+			diff TEXT NOT NULL,
+			added INTEGER NOT NULL DEFAULT 0,
+			removed INTEGER NOT NULL DEFAULT 0
+		);
+		CREATE TABLE IF NOT EXISTS tag_suggestions (
+			id BIGSERIAL PRIMARY KEY,
+			bookmark_id INTEGER NOT NULL REFERENCES bookmarks(id) ON DELETE CASCADE,
+			tag TEXT NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			created_at TIMESTAMP NOT NULL,
+			decided_at TIMESTAMP,
+			UNIQUE (bookmark_id, tag)
+		);
+		CREATE TABLE IF NOT EXISTS link_checks (
+			id BIGSERIAL PRIMARY KEY,
+			bookmark_id INTEGER NOT NULL REFERENCES bookmarks(id) ON DELETE CASCADE,
+			checked_at TIMESTAMP NOT NULL,
+			ok BOOLEAN NOT NULL,
+			status_code INTEGER NOT NULL DEFAULT 0,
+			final_url TEXT NOT NULL DEFAULT '',
+			latency_ms BIGINT NOT NULL DEFAULT 0,
+			error TEXT NOT NULL DEFAULT '',
+			tls_error TEXT NOT NULL DEFAULT '',
+			inconclusive BOOLEAN NOT NULL DEFAULT false
+		);
+		CREATE TABLE IF NOT EXISTS jobs (
+			id BIGSERIAL PRIMARY KEY,
+			kind TEXT NOT NULL,
+			bookmark_id INTEGER NOT NULL REFERENCES bookmarks(id) ON DELETE CASCADE,
+			status TEXT NOT NULL DEFAULT 'queued',
+			attempts INTEGER NOT NULL DEFAULT 0,
+			max_attempts INTEGER NOT NULL DEFAULT 1,
+			last_error TEXT NOT NULL DEFAULT '',
+			run_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+		);
 	`
 	_, err = db.Exec(schema)
 	if err != nil {
@@ -46,7 +131,7 @@ func (s *RepositoryTestSuite) SetupSuite() {
 
 func (s *RepositoryTestSuite) TearDownSuite() {
 	if s.db != nil {
-		_, err := s.db.Exec("DROP TABLE IF EXISTS bookmarks")
+		_, err := s.db.Exec("DROP TABLE IF EXISTS jobs, tag_suggestions, page_changes, page_texts, link_checks, bookmark_contents, bookmarks")
 		if err != nil {
 			s.T().Errorf("Failed to drop test tables: %v", err)
 		}
@@ -55,7 +140,7 @@ func (s *RepositoryTestSuite) TearDownSuite() {
 }
 
 func (s *RepositoryTestSuite) SetupTest() {
-	_, err := s.db.Exec("TRUNCATE TABLE bookmarks RESTART IDENTITY")
+	_, err := s.db.Exec("TRUNCATE TABLE bookmarks RESTART IDENTITY CASCADE")
 	if err != nil {
 		s.T().Fatalf("Failed to truncate test tables: %v", err)
 	}
@@ -96,6 +181,415 @@ func (s *RepositoryTestSuite) TestGetBookmark() {
 	s.Equal(bookmark.FaviconURL, retrieved.FaviconURL)
 }
 
+func (s *RepositoryTestSuite) TestRedirectChainRoundTrip() {
+	bookmark := &models.Bookmark{
+		URL:      "https://t.co/abc",
+		FinalURL: "https://example.com/article",
+		Redirects: models.RedirectChain{
+			{URL: "https://t.co/abc", StatusCode: 301, Kind: "http"},
+			{URL: "https://example.com/a", StatusCode: 200, Kind: "meta-refresh"},
+		},
+	}
+	err := s.repository.CreateBookmark(context.Background(), bookmark)
+	s.NoError(err)
+
+	retrieved, err := s.repository.GetBookmark(context.Background(), bookmark.ID)
+	s.NoError(err)
+	s.Equal(bookmark.FinalURL, retrieved.FinalURL)
+	s.Equal(bookmark.Redirects, retrieved.Redirects)
+}
+
+func (s *RepositoryTestSuite) TestExtraRoundTrip() {
+	bookmark := &models.Bookmark{
+		URL: "https://github.com/golang/go",
+		Extra: &models.Extra{
+			Site:   "github",
+			GitHub: &models.GitHubRepo{Owner: "golang", Name: "go", Stars: 125000, Language: "Go"},
+		},
+	}
+	err := s.repository.CreateBookmark(context.Background(), bookmark)
+	s.NoError(err)
+
+	retrieved, err := s.repository.GetBookmark(context.Background(), bookmark.ID)
+	s.NoError(err)
+	s.Equal(bookmark.Extra, retrieved.Extra)
+
+	plain := &models.Bookmark{URL: "https://example.com"}
+	err = s.repository.CreateBookmark(context.Background(), plain)
+	s.NoError(err)
+
+	retrieved, err = s.repository.GetBookmark(context.Background(), plain.ID)
+	s.NoError(err)
+	s.Nil(retrieved.Extra)
+}
+
+func (s *RepositoryTestSuite) TestValidatorsRoundTrip() {
+	bookmark := &models.Bookmark{
+		URL:          "https://example.com",
+		ETag:         `"v1"`,
+		LastModified: "Tue, 02 Jan 2024 03:04:05 GMT",
+		ContentHash:  "9f86d081884c7d65",
+	}
+	err := s.repository.CreateBookmark(context.Background(), bookmark)
+	s.NoError(err)
+
+	retrieved, err := s.repository.GetBookmark(context.Background(), bookmark.ID)
+	s.NoError(err)
+	s.Equal(bookmark.ETag, retrieved.ETag)
+	s.Equal(bookmark.LastModified, retrieved.LastModified)
+	s.Equal(bookmark.ContentHash, retrieved.ContentHash)
+}
+
+func (s *RepositoryTestSuite) TestIconsRoundTrip() {
+	bookmark := &models.Bookmark{
+		URL:        "https://example.com",
+		FaviconURL: "https://example.com/icon-32.png",
+		Icons: models.IconList{
+			{URL: "https://example.com/icon-32.png", Rel: "icon", Type: "image/png", Sizes: "32x32"},
+			{URL: "https://example.com/apple.png", Rel: "apple-touch-icon", Sizes: "180x180"},
+		},
+	}
+	err := s.repository.CreateBookmark(context.Background(), bookmark)
+	s.NoError(err)
+
+	retrieved, err := s.repository.GetBookmark(context.Background(), bookmark.ID)
+	s.NoError(err)
+	s.Equal(bookmark.Icons, retrieved.Icons)
+}
+
+func (s *RepositoryTestSuite) TestUpdateBookmark() {
+	bookmark := &models.Bookmark{
+		URL:            "https://example.com",
+		Title:          "My title",
+		MetadataStatus: models.MetadataPending,
+		MetadataError:  "connection refused",
+	}
+	err := s.repository.CreateBookmark(context.Background(), bookmark)
+	s.NoError(err)
+
+	bookmark.Description = "Scraped description"
+	bookmark.MetadataStatus = models.MetadataOK
+	bookmark.MetadataError = ""
+	archivedAt := time.Date(2019, 12, 31, 23, 59, 59, 0, time.UTC)
+	bookmark.ArchiveURL = "http://web.archive.org/web/20191231235959/https://example.com"
+	bookmark.ArchivedAt = &archivedAt
+	err = s.repository.UpdateBookmark(context.Background(), bookmark)
+	s.NoError(err)
+
+	retrieved, err := s.repository.GetBookmark(context.Background(), bookmark.ID)
+	s.NoError(err)
+	s.Equal("My title", retrieved.Title)
+	s.Equal("Scraped description", retrieved.Description)
+	s.Equal(bookmark.ArchiveURL, retrieved.ArchiveURL)
+	s.True(archivedAt.Equal(*retrieved.ArchivedAt))
+	s.Equal(models.MetadataOK, retrieved.MetadataStatus)
+	s.Empty(retrieved.MetadataError)
+
+	err = s.repository.UpdateBookmark(context.Background(), &models.Bookmark{ID: 999})
+	s.Equal(ErrNotFound, err)
+
+	// A copy read before the last update is stale
+	stale := *retrieved
+	retrieved.Description = "Edited description"
+	s.NoError(s.repository.UpdateBookmark(context.Background(), retrieved))
+	stale.Title = "Stale"
+	s.Equal(ErrConflict, s.repository.UpdateBookmark(context.Background(), &stale))
+	retrieved, err = s.repository.GetBookmark(context.Background(), bookmark.ID)
+	s.NoError(err)
+	s.NotEqual("Stale", retrieved.Title)
+}
+
+func (s *RepositoryTestSuite) TestListStaleBookmarks() {
+	now := time.Now().UTC()
+	bookmarks := []*models.Bookmark{
+		{URL: "https://example.com/fresh", MetadataStatus: models.MetadataOK, RefreshedAt: now},
+		{URL: "https://example.com/old", MetadataStatus: models.MetadataOK, RefreshedAt: now.Add(-48 * time.Hour)},
+		{URL: "https://example.com/older", MetadataStatus: models.MetadataOK, RefreshedAt: now.Add(-72 * time.Hour)},
+		{URL: "https://example.com/failed", MetadataStatus: models.MetadataFailed, RefreshedAt: now.Add(-25 * time.Hour)},
+		{URL: "https://example.com/pending", MetadataStatus: models.MetadataPending, RefreshedAt: now.Add(-96 * time.Hour)},
+	}
+	for _, bookmark := range bookmarks {
+		err := s.repository.CreateBookmark(context.Background(), bookmark)
+		s.NoError(err)
+	}
+
+	stale, err := s.repository.ListStaleBookmarks(context.Background(), now.Add(-24*time.Hour), 10)
+	s.NoError(err)
+	var urls []string
+	for _, bookmark := range stale {
+		urls = append(urls, bookmark.URL)
+	}
+	s.Equal([]string{"https://example.com/failed", "https://example.com/older", "https://example.com/old"}, urls)
+
+	stale, err = s.repository.ListStaleBookmarks(context.Background(), now.Add(-24*time.Hour), 1)
+	s.NoError(err)
+	s.Len(stale, 1)
+}
+
+func (s *RepositoryTestSuite) TestRecordLinkCheck() {
+	bookmark := &models.Bookmark{URL: "https://example.com"}
+	err := s.repository.CreateBookmark(context.Background(), bookmark)
+	s.NoError(err)
+	s.Equal(models.HealthUnknown, bookmark.Health)
+
+	// The bookmark is broken after the second consecutive failure
+	for i, health := range []string{models.HealthUnknown, models.HealthBroken} {
+		check := &models.LinkCheck{
+			BookmarkID: bookmark.ID,
+			CheckedAt:  time.Now().UTC().Add(time.Duration(i) * time.Second),
+			Error:      "connection refused",
+		}
+		err = s.repository.RecordLinkCheck(context.Background(), check, 2)
+		s.NoError(err)
+		s.NotZero(check.ID)
+
+		retrieved, err := s.repository.GetBookmark(context.Background(), bookmark.ID)
+		s.NoError(err)
+		s.Equal(health, retrieved.Health)
+		s.Equal(i+1, retrieved.CheckFailures)
+		s.NotNil(retrieved.LastCheckedAt)
+	}
+
+	broken, err := s.repository.ListBookmarks(context.Background(), models.BookmarkFilter{Health: models.HealthBroken})
+	s.NoError(err)
+	s.Len(broken, 1)
+
+	// A successful check resets the failure count
+	err = s.repository.RecordLinkCheck(context.Background(), &models.LinkCheck{
+		BookmarkID: bookmark.ID,
+		CheckedAt:  time.Now().UTC().Add(time.Minute),
+		OK:         true,
+		StatusCode: 200,
+		FinalURL:   "https://example.com/",
+		LatencyMS:  42,
+	}, 2)
+	s.NoError(err)
+
+	retrieved, err := s.repository.GetBookmark(context.Background(), bookmark.ID)
+	s.NoError(err)
+	s.Equal(models.HealthOK, retrieved.Health)
+	s.Zero(retrieved.CheckFailures)
+
+	// An inconclusive check leaves the health unchanged
+	err = s.repository.RecordLinkCheck(context.Background(), &models.LinkCheck{
+		BookmarkID:   bookmark.ID,
+		CheckedAt:    time.Now().UTC().Add(2 * time.Minute),
+		Error:        "disallowed by robots.txt",
+		Inconclusive: true,
+	}, 1)
+	s.NoError(err)
+
+	retrieved, err = s.repository.GetBookmark(context.Background(), bookmark.ID)
+	s.NoError(err)
+	s.Equal(models.HealthOK, retrieved.Health)
+
+	checks, err := s.repository.ListLinkChecks(context.Background(), bookmark.ID, 3)
+	s.NoError(err)
+	s.Len(checks, 3)
+	s.True(checks[0].Inconclusive)
+	s.True(checks[1].OK)
+	s.Equal(int64(42), checks[1].LatencyMS)
+	s.Equal("connection refused", checks[2].Error)
+
+	toCheck, err := s.repository.ListBookmarksToCheck(context.Background(), time.Now().UTC(), 10)
+	s.NoError(err)
+	s.Empty(toCheck)
+
+	err = s.repository.RecordLinkCheck(context.Background(), &models.LinkCheck{BookmarkID: 999}, 2)
+	s.Equal(ErrNotFound, err)
+}
+
+func (s *RepositoryTestSuite) TestSetPageArchived() {
+	bookmark := &models.Bookmark{URL: "https://example.com", Title: "Example"}
+	err := s.repository.CreateBookmark(context.Background(), bookmark)
+	s.NoError(err)
+	s.Nil(bookmark.PageArchivedAt)
+
+	at := time.Now().UTC().Truncate(time.Second)
+	err = s.repository.SetPageArchived(context.Background(), bookmark.ID, at)
+	s.NoError(err)
+
+	retrieved, err := s.repository.GetBookmark(context.Background(), bookmark.ID)
+	s.NoError(err)
+	s.Equal("Example", retrieved.Title)
+	s.NotNil(retrieved.PageArchivedAt)
+	s.True(at.Equal(*retrieved.PageArchivedAt))
+
+	err = s.repository.SetPageArchived(context.Background(), bookmark.ID+1, at)
+	s.Equal(ErrNotFound, err)
+}
+
+func (s *RepositoryTestSuite) TestSetSummary() {
+	bookmark := &models.Bookmark{URL: "https://example.com", Title: "Example"}
+	err := s.repository.CreateBookmark(context.Background(), bookmark)
+	s.NoError(err)
+
+	at := time.Now().UTC().Truncate(time.Second)
+	err = s.repository.SetSummary(context.Background(), bookmark.ID, "A summary.", at)
+	s.NoError(err)
+
+	retrieved, err := s.repository.GetBookmark(context.Background(), bookmark.ID)
+	s.NoError(err)
+	s.Equal("Example", retrieved.Title)
+	s.Equal("A summary.", retrieved.Summary)
+	s.NotNil(retrieved.SummarizedAt)
+	s.True(at.Equal(*retrieved.SummarizedAt))
+
+	err = s.repository.SetSummary(context.Background(), bookmark.ID+1, "A summary.", at)
+	s.Equal(ErrNotFound, err)
+}
+
+func (s *RepositoryTestSuite) TestTags() {
+	first := &models.Bookmark{URL: "https://example.com/1"}
+	second := &models.Bookmark{URL: "https://example.com/2"}
+	s.NoError(s.repository.CreateBookmark(context.Background(), first))
+	s.NoError(s.repository.CreateBookmark(context.Background(), second))
+
+	s.NoError(s.repository.SetTags(context.Background(), first.ID, models.Tags{"go", "databases"}))
+	s.NoError(s.repository.SetTags(context.Background(), second.ID, models.Tags{"go"}))
+	s.Equal(ErrNotFound, s.repository.SetTags(context.Background(), second.ID+1, models.Tags{"go"}))
+
+	retrieved, err := s.repository.GetBookmark(context.Background(), first.ID)
+	s.NoError(err)
+	s.Equal(models.Tags{"go", "databases"}, retrieved.Tags)
+
+	tags, err := s.repository.ListTags(context.Background())
+	s.NoError(err)
+	s.Equal([]models.TagCount{{Tag: "go", Count: 2}, {Tag: "databases", Count: 1}}, tags)
+
+	bookmarks, err := s.repository.ListBookmarks(context.Background(), models.BookmarkFilter{Tag: "databases"})
+	s.NoError(err)
+	s.Len(bookmarks, 1)
+	s.Equal(first.ID, bookmarks[0].ID)
+}
+
+func (s *RepositoryTestSuite) TestTagSuggestions() {
+	bookmark := &models.Bookmark{URL: "https://example.com"}
+	s.NoError(s.repository.CreateBookmark(context.Background(), bookmark))
+
+	s.NoError(s.repository.AddTagSuggestions(context.Background(), bookmark.ID, []string{"go", "sql"}, false))
+	// Suggesting a tag again keeps the first suggestion
+	s.NoError(s.repository.AddTagSuggestions(context.Background(), bookmark.ID, []string{"sql", "web"}, true))
+
+	suggestions, err := s.repository.ListTagSuggestions(context.Background(), bookmark.ID)
+	s.NoError(err)
+	s.Len(suggestions, 3)
+	s.Equal(models.TagSuggestionPending, suggestions[1].Status)
+	s.Equal("web", suggestions[2].Tag)
+	s.Equal(models.TagSuggestionAccepted, suggestions[2].Status)
+
+	accepted, err := s.repository.DecideTagSuggestion(context.Background(), bookmark.ID, suggestions[0].ID, true)
+	s.NoError(err)
+	s.Equal(models.TagSuggestionAccepted, accepted.Status)
+	s.NotNil(accepted.DecidedAt)
+	rejected, err := s.repository.DecideTagSuggestion(context.Background(), bookmark.ID, suggestions[1].ID, false)
+	s.NoError(err)
+	s.Equal(models.TagSuggestionRejected, rejected.Status)
+
+	// Only pending suggestions can be decided
+	_, err = s.repository.DecideTagSuggestion(context.Background(), bookmark.ID, suggestions[1].ID, true)
+	s.Equal(ErrNotFound, err)
+
+	retrieved, err := s.repository.GetBookmark(context.Background(), bookmark.ID)
+	s.NoError(err)
+	s.Equal(models.Tags{"web", "go"}, retrieved.Tags)
+}
+
+func (s *RepositoryTestSuite) TestPageChecks() {
+	bookmark := &models.Bookmark{URL: "https://example.com"}
+	err := s.repository.CreateBookmark(context.Background(), bookmark)
+	s.NoError(err)
+
+	due, err := s.repository.ListWatchedToCheck(context.Background(), time.Now(), 10)
+	s.NoError(err)
+	s.Empty(due)
+
+	err = s.repository.SetWatched(context.Background(), bookmark.ID, true)
+	s.NoError(err)
+	due, err = s.repository.ListWatchedToCheck(context.Background(), time.Now(), 10)
+	s.NoError(err)
+	s.Len(due, 1)
+	s.True(due[0].Watched)
+
+	_, err = s.repository.GetPageText(context.Background(), bookmark.ID)
+	s.Equal(ErrNotFound, err)
+
+	// The first check only stores the text
+	checkedAt := time.Now().UTC().Truncate(time.Second)
+	err = s.repository.RecordPageCheck(context.Background(), bookmark.ID, checkedAt,
+		&models.PageText{BookmarkID: bookmark.ID, Text: "one", FetchedAt: checkedAt}, nil)
+	s.NoError(err)
+	text, err := s.repository.GetPageText(context.Background(), bookmark.ID)
+	s.NoError(err)
+	s.Equal("one", text.Text)
+
+	due, err = s.repository.ListWatchedToCheck(context.Background(), checkedAt, 10)
+	s.NoError(err)
+	s.Empty(due)
+
+	change := &models.PageChange{BookmarkID: bookmark.ID, DetectedAt: checkedAt.Add(time.Hour), Diff: "-one\n+two\n", Added: 1, Removed: 1}
+	err = s.repository.RecordPageCheck(context.Background(), bookmark.ID, checkedAt.Add(time.Hour),
+		&models.PageText{BookmarkID: bookmark.ID, Text: "two", FetchedAt: checkedAt.Add(time.Hour)}, change)
+	s.NoError(err)
+	s.NotZero(change.ID)
+
+	changes, err := s.repository.ListPageChanges(context.Background(), bookmark.ID, 10)
+	s.NoError(err)
+	s.Len(changes, 1)
+	s.Equal("-one\n+two\n", changes[0].Diff)
+
+	changes, err = s.repository.ListPageChangesAfter(context.Background(), 0, 10)
+	s.NoError(err)
+	s.Len(changes, 1)
+	changes, err = s.repository.ListPageChangesAfter(context.Background(), change.ID, 10)
+	s.NoError(err)
+	s.Empty(changes)
+
+	// Unwatching discards the last seen text
+	err = s.repository.SetWatched(context.Background(), bookmark.ID, false)
+	s.NoError(err)
+	_, err = s.repository.GetPageText(context.Background(), bookmark.ID)
+	s.Equal(ErrNotFound, err)
+
+	err = s.repository.RecordPageCheck(context.Background(), bookmark.ID+1, checkedAt, nil, nil)
+	s.Equal(ErrNotFound, err)
+}
+
+func (s *RepositoryTestSuite) TestFieldLocksRoundTrip() {
+	bookmark := &models.Bookmark{
+		URL:          "https://example.com",
+		Title:        "My title",
+		LockedFields: models.FieldLocks{models.FieldTitle},
+	}
+	err := s.repository.CreateBookmark(context.Background(), bookmark)
+	s.NoError(err)
+
+	retrieved, err := s.repository.GetBookmark(context.Background(), bookmark.ID)
+	s.NoError(err)
+	s.True(retrieved.LockedFields.Has(models.FieldTitle))
+	s.False(retrieved.LockedFields.Has(models.FieldDescription))
+}
+
+func (s *RepositoryTestSuite) TestProvenanceRoundTrip() {
+	provenance := models.Provenance{
+		models.FieldTitle:       models.UserProvenance,
+		models.FieldDescription: {Source: models.SourceOpenGraph, Rule: "meta[property=og:description]", Confidence: 0.9},
+	}
+	bookmark := &models.Bookmark{URL: "https://example.com", Provenance: provenance}
+	err := s.repository.CreateBookmark(context.Background(), bookmark)
+	s.NoError(err)
+
+	retrieved, err := s.repository.GetBookmark(context.Background(), bookmark.ID)
+	s.NoError(err)
+	s.Equal(provenance, retrieved.Provenance)
+
+	retrieved.Provenance = retrieved.Provenance.Set(models.FieldAuthor, models.FieldProvenance{Source: models.SourceMeta, Confidence: 0.8})
+	s.NoError(s.repository.UpdateBookmark(context.Background(), retrieved))
+	updated, err := s.repository.GetBookmark(context.Background(), bookmark.ID)
+	s.NoError(err)
+	s.Len(updated.Provenance, 3)
+}
+
 func (s *RepositoryTestSuite) TestGetBookmarkNotFound() {
 	_, err := s.repository.GetBookmark(context.Background(), 999)
 	s.Equal(ErrNotFound, err)
@@ -115,7 +609,7 @@ func (s *RepositoryTestSuite) TestListBookmarks() {
 	}
 
 	// Test listing bookmarks
-	list, err := s.repository.ListBookmarks(context.Background())
+	list, err := s.repository.ListBookmarks(context.Background(), models.BookmarkFilter{})
 	s.NoError(err)
 	s.Len(list, len(bookmarks))
 }
@@ -143,6 +637,49 @@ func (s *RepositoryTestSuite) TestDeleteBookmarkNotFound() {
 	s.Equal(ErrNotFound, err)
 }
 
+func (s *RepositoryTestSuite) TestSaveAndGetContent() {
+	bookmark := &models.Bookmark{
+		URL:   "https://example.com",
+		Title: "Example",
+	}
+	err := s.repository.CreateBookmark(context.Background(), bookmark)
+	s.NoError(err)
+
+	content := &models.BookmarkContent{
+		BookmarkID:  bookmark.ID,
+		Text:        "First version",
+		HTML:        "<p>First version</p>",
+		WordCount:   2,
+		ReadingTime: 1,
+	}
+	err = s.repository.SaveContent(context.Background(), content)
+	s.NoError(err)
+
+	// Saving again replaces the previous content
+	content.Text = "Second version"
+	err = s.repository.SaveContent(context.Background(), content)
+	s.NoError(err)
+
+	retrieved, err := s.repository.GetContent(context.Background(), bookmark.ID)
+	s.NoError(err)
+	s.Equal("Second version", retrieved.Text)
+	s.Equal(content.HTML, retrieved.HTML)
+	s.Equal(content.WordCount, retrieved.WordCount)
+}
+
+func (s *RepositoryTestSuite) TestGetContentNotFound() {
+	_, err := s.repository.GetContent(context.Background(), 999)
+	s.Equal(ErrNotFound, err)
+}
+
+func (s *RepositoryTestSuite) TestPostgresJobQueue() {
+	bookmark := &models.Bookmark{URL: "https://example.com"}
+	err := s.repository.CreateBookmark(context.Background(), bookmark)
+	s.NoError(err)
+
+	testJobQueue(s.T(), NewPostgresJobQueue(s.db), bookmark.ID)
+}
+
 func TestRepositorySuite(t *testing.T) {
 	suite.Run(t, new(RepositoryTestSuite))
 }
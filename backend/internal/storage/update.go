@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"context"
+
+	"bookmarks-go/internal/models"
+)
+
+// maxUpdateAttempts bounds how often UpdateWithRetry applies a change
+const maxUpdateAttempts = 5
+
+// UpdateWithRetry applies change to bookmark and stores it. When another
+// writer updated the bookmark since it was read, such as a user edit during a
+// refresh, the bookmark is read again and change applied to the current
+// values, so that neither update is lost.
+func UpdateWithRetry(ctx context.Context, repo Repository, bookmark *models.Bookmark, change func(*models.Bookmark)) error {
+	for attempt := 1; ; attempt++ {
+		change(bookmark)
+		err := repo.UpdateBookmark(ctx, bookmark)
+		if err != ErrConflict || attempt == maxUpdateAttempts {
+			return err
+		}
+
+		current, err := repo.GetBookmark(ctx, bookmark.ID)
+		if err != nil {
+			return err
+		}
+		*bookmark = *current
+	}
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"bookmarks-go/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// conflictRepository stores one bookmark, failing updates with ErrConflict
+// while conflicts remain
+type conflictRepository struct {
+	Repository
+	stored    models.Bookmark
+	conflicts int
+	updates   int
+}
+
+func (r *conflictRepository) GetBookmark(ctx context.Context, id int64) (*models.Bookmark, error) {
+	bookmark := r.stored
+	return &bookmark, nil
+}
+
+func (r *conflictRepository) UpdateBookmark(ctx context.Context, bookmark *models.Bookmark) error {
+	r.updates++
+	if r.conflicts > 0 {
+		r.conflicts--
+		return ErrConflict
+	}
+	r.stored = *bookmark
+	return nil
+}
+
+func TestUpdateWithRetry(t *testing.T) {
+	// A user edit lands after the bookmark was read for a refresh
+	repo := &conflictRepository{conflicts: 1}
+	bookmark := &models.Bookmark{ID: 1, Title: "Old"}
+	repo.stored = models.Bookmark{ID: 1, Title: "Edited", LockedFields: models.FieldLocks{models.FieldTitle}}
+
+	err := UpdateWithRetry(context.Background(), repo, bookmark, func(b *models.Bookmark) {
+		if !b.LockedFields.Has(models.FieldTitle) {
+			b.Title = "Scraped"
+		}
+		b.Description = "Scraped description"
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, repo.updates)
+	assert.Equal(t, "Edited", repo.stored.Title)
+	assert.Equal(t, models.FieldLocks{models.FieldTitle}, repo.stored.LockedFields)
+	assert.Equal(t, "Scraped description", repo.stored.Description)
+
+	// Persistent conflicts are given up on
+	repo = &conflictRepository{conflicts: maxUpdateAttempts}
+	err = UpdateWithRetry(context.Background(), repo, &models.Bookmark{ID: 1}, func(b *models.Bookmark) {})
+	assert.Equal(t, ErrConflict, err)
+	assert.Equal(t, maxUpdateAttempts, repo.updates)
+}
@@ -0,0 +1,75 @@
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// MaxRecordSize is the largest record content read
+const MaxRecordSize = 64 << 20
+
+// Reader reads records from a WARC file
+type Reader struct {
+	r *textproto.Reader
+}
+
+// NewReader creates a reader of r, which may be gzip-compressed as a whole
+// or record by record
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		// Consecutive gzip members are read as one stream
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open archive: %w", err)
+		}
+		br = bufio.NewReader(zr)
+	}
+	return &Reader{r: textproto.NewReader(br)}, nil
+}
+
+// Next returns the next record, or io.EOF after the last one
+func (r *Reader) Next() (*Record, error) {
+	version, err := r.r.ReadLine()
+	for err == nil && version == "" {
+		// Tolerate extra blank lines between records
+		version, err = r.r.ReadLine()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(version, "WARC/") {
+		return nil, fmt.Errorf("invalid record version line: %q", version)
+	}
+
+	header, err := r.r.ReadMIMEHeader()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read record header: %w", err)
+	}
+	length, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	if err != nil || length < 0 {
+		return nil, errors.New("invalid record Content-Length")
+	}
+	if length > MaxRecordSize {
+		return nil, fmt.Errorf("record of %d bytes is too large", length)
+	}
+
+	content := make([]byte, length)
+	if _, err := io.ReadFull(r.r.R, content); err != nil {
+		return nil, fmt.Errorf("failed to read record content: %w", err)
+	}
+	return &Record{Header: header, Content: content}, nil
+}
+
+// bufioReader returns a buffered reader of data
+func bufioReader(data []byte) *bufio.Reader {
+	return bufio.NewReader(bytes.NewReader(data))
+}
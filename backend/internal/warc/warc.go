@@ -0,0 +1,224 @@
+// Package warc reads and writes WARC 1.1 files, the ISO 28500 format web
+// archives use to store HTTP responses. Records are gzip-compressed one by
+// one, so that archives are valid .warc.gz files.
+package warc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Version is the WARC version written
+const Version = "WARC/1.1"
+
+// Record types
+const (
+	TypeWarcinfo = "warcinfo"
+	TypeResponse = "response"
+	TypeResource = "resource"
+	TypeRequest  = "request"
+	TypeMetadata = "metadata"
+)
+
+// ContentTypeHTTPResponse is the content type of response record blocks
+const ContentTypeHTTPResponse = "application/http; msgtype=response"
+
+// Record is a WARC record: named header fields and a content block
+type Record struct {
+	Header  textproto.MIMEHeader
+	Content []byte
+}
+
+// Type returns the WARC-Type of the record
+func (r *Record) Type() string {
+	return r.Header.Get("WARC-Type")
+}
+
+// TargetURI returns the WARC-Target-URI of the record
+func (r *Record) TargetURI() string {
+	return r.Header.Get("WARC-Target-URI")
+}
+
+// Date returns the WARC-Date of the record, or the zero time if it is invalid
+func (r *Record) Date() time.Time {
+	date, _ := time.Parse(time.RFC3339Nano, r.Header.Get("WARC-Date"))
+	return date
+}
+
+// HTTPResponse parses the content of a response record
+func (r *Record) HTTPResponse() (*http.Response, error) {
+	if r.Type() != TypeResponse {
+		return nil, fmt.Errorf("not a response record: %s", r.Type())
+	}
+	return http.ReadResponse(bufioReader(r.Content), nil)
+}
+
+// NewResponse creates a response record for a response from targetURI,
+// recording status, header and the already decoded body
+func NewResponse(targetURI string, date time.Time, status int, header http.Header, body []byte) *Record {
+	var block bytes.Buffer
+	fmt.Fprintf(&block, "HTTP/1.1 %d %s\r\n", status, http.StatusText(status))
+
+	header = header.Clone()
+	// The body is stored decoded and in one piece
+	header.Del("Content-Encoding")
+	header.Del("Transfer-Encoding")
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	header.Write(&block)
+	block.WriteString("\r\n")
+	block.Write(body)
+
+	record := &Record{
+		Header:  make(textproto.MIMEHeader),
+		Content: block.Bytes(),
+	}
+	record.Header.Set("WARC-Type", TypeResponse)
+	record.Header.Set("WARC-Target-URI", targetURI)
+	record.Header.Set("WARC-Date", date.UTC().Format(time.RFC3339))
+	record.Header.Set("WARC-Payload-Digest", digest(body))
+	record.Header.Set("Content-Type", ContentTypeHTTPResponse)
+	return record
+}
+
+// NewWarcinfo creates a warcinfo record describing the file
+func NewWarcinfo(date time.Time, filename string, fields map[string]string) *Record {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var block bytes.Buffer
+	for _, name := range names {
+		fmt.Fprintf(&block, "%s: %s\r\n", name, fields[name])
+	}
+
+	record := &Record{
+		Header:  make(textproto.MIMEHeader),
+		Content: block.Bytes(),
+	}
+	record.Header.Set("WARC-Type", TypeWarcinfo)
+	record.Header.Set("WARC-Date", date.UTC().Format(time.RFC3339))
+	if filename != "" {
+		record.Header.Set("WARC-Filename", filename)
+	}
+	record.Header.Set("Content-Type", "application/warc-fields")
+	return record
+}
+
+// Writer writes records to a WARC file
+type Writer struct {
+	w io.Writer
+	// warcinfoID is the record ID of the warcinfo record, referenced by later records
+	warcinfoID string
+}
+
+// NewWriter creates a writer writing gzip-compressed records to w
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write writes a record as its own gzip member. WARC-Record-ID,
+// WARC-Block-Digest and Content-Length are set here.
+func (w *Writer) Write(record *Record) error {
+	if record.Header.Get("WARC-Record-ID") == "" {
+		id, err := newRecordID()
+		if err != nil {
+			return err
+		}
+		record.Header.Set("WARC-Record-ID", id)
+	}
+	if record.Type() == TypeWarcinfo {
+		w.warcinfoID = record.Header.Get("WARC-Record-ID")
+	} else if w.warcinfoID != "" && record.Header.Get("WARC-Warcinfo-ID") == "" {
+		record.Header.Set("WARC-Warcinfo-ID", w.warcinfoID)
+	}
+	record.Header.Set("WARC-Block-Digest", digest(record.Content))
+	record.Header.Set("Content-Length", strconv.Itoa(len(record.Content)))
+
+	zw := gzip.NewWriter(w.w)
+	if err := writeRecord(zw, record); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// writeRecord writes the version line, header fields, content and the two
+// line breaks ending a record. WARC-Type comes first, the rest are sorted.
+func writeRecord(w io.Writer, record *Record) error {
+	var buf bytes.Buffer
+	buf.WriteString(Version + "\r\n")
+	buf.WriteString("WARC-Type: " + record.Type() + "\r\n")
+
+	names := make([]string, 0, len(record.Header))
+	for name := range record.Header {
+		if name != textproto.CanonicalMIMEHeaderKey("WARC-Type") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range record.Header[name] {
+			buf.WriteString(fieldName(name) + ": " + value + "\r\n")
+		}
+	}
+	buf.WriteString("\r\n")
+
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	if _, err := w.Write(record.Content); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\r\n\r\n")
+	return err
+}
+
+// fieldName restores the spelling the standard uses for WARC fields, which
+// canonicalization turns into forms like "Warc-Target-Uri"
+func fieldName(canonical string) string {
+	if name, ok := fieldNames[canonical]; ok {
+		return name
+	}
+	return canonical
+}
+
+var fieldNames = func() map[string]string {
+	names := map[string]string{}
+	for _, name := range []string{
+		"WARC-Type", "WARC-Record-ID", "WARC-Date", "WARC-Target-URI",
+		"WARC-Warcinfo-ID", "WARC-Filename", "WARC-Block-Digest",
+		"WARC-Payload-Digest", "WARC-Concurrent-To", "WARC-Refers-To",
+		"WARC-IP-Address", "WARC-Truncated", "WARC-Identified-Payload-Type",
+	} {
+		names[textproto.CanonicalMIMEHeaderKey(name)] = name
+	}
+	return names
+}()
+
+// digest returns the SHA-1 digest of data in the base32 form WARC tools use
+func digest(data []byte) string {
+	sum := sha1.Sum(data)
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum[:])
+}
+
+// newRecordID returns a random urn:uuid record ID
+func newRecordID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate record ID: %w", err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
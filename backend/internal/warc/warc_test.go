@@ -0,0 +1,93 @@
+package warc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteAndRead(t *testing.T) {
+	date := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	header := http.Header{}
+	header.Set("Content-Type", "text/html; charset=utf-8")
+	header.Set("Content-Encoding", "gzip")
+	body := []byte("<html><body>Hello</body></html>")
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	require.NoError(t, w.Write(NewWarcinfo(date, "bookmark-1.warc.gz", map[string]string{"software": "bookmarks-go"})))
+	require.NoError(t, w.Write(NewResponse("https://example.com/", date, http.StatusOK, header, body)))
+
+	r, err := NewReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+
+	info, err := r.Next()
+	require.NoError(t, err)
+	assert.Equal(t, TypeWarcinfo, info.Type())
+	assert.Equal(t, "bookmark-1.warc.gz", info.Header.Get("WARC-Filename"))
+	assert.Equal(t, "software: bookmarks-go\r\n", string(info.Content))
+
+	record, err := r.Next()
+	require.NoError(t, err)
+	assert.Equal(t, TypeResponse, record.Type())
+	assert.Equal(t, "https://example.com/", record.TargetURI())
+	assert.Equal(t, date, record.Date())
+	assert.Equal(t, info.Header.Get("WARC-Record-ID"), record.Header.Get("WARC-Warcinfo-ID"))
+	assert.Equal(t, digest(body), record.Header.Get("WARC-Payload-Digest"))
+	assert.Equal(t, digest(record.Content), record.Header.Get("WARC-Block-Digest"))
+
+	resp, err := record.HTTPResponse()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/html; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.Empty(t, resp.Header.Get("Content-Encoding"))
+	got, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, body, got)
+
+	_, err = r.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestRecordsAreSeparateGzipMembers(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	require.NoError(t, w.Write(NewResponse("https://example.com/a", time.Now(), http.StatusOK, http.Header{}, []byte("a"))))
+	require.NoError(t, w.Write(NewResponse("https://example.com/b", time.Now(), http.StatusOK, http.Header{}, []byte("b"))))
+
+	zr, err := gzip.NewReader(&buf)
+	require.NoError(t, err)
+	zr.Multistream(false)
+	first, err := io.ReadAll(zr)
+	require.NoError(t, err)
+
+	text := string(first)
+	assert.True(t, strings.HasPrefix(text, "WARC/1.1\r\nWARC-Type: response\r\n"))
+	assert.Contains(t, text, "WARC-Target-URI: https://example.com/a\r\n")
+	assert.NotContains(t, text, "https://example.com/b")
+	assert.True(t, strings.HasSuffix(text, "\r\n\r\n"))
+}
+
+func TestReadUncompressed(t *testing.T) {
+	archive := "WARC/1.0\r\nWARC-Type: resource\r\nWARC-Target-URI: https://example.com/a.txt\r\nContent-Length: 5\r\n\r\nhello\r\n\r\n"
+
+	r, err := NewReader(strings.NewReader(archive))
+	require.NoError(t, err)
+	record, err := r.Next()
+	require.NoError(t, err)
+	assert.Equal(t, TypeResource, record.Type())
+	assert.Equal(t, "hello", string(record.Content))
+
+	_, err = record.HTTPResponse()
+	assert.Error(t, err)
+
+	_, err = r.Next()
+	assert.Equal(t, io.EOF, err)
+}
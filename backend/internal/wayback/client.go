@@ -0,0 +1,97 @@
+// Package wayback looks up archived snapshots of pages through a
+// Wayback Machine style availability API
+package wayback
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the Internet Archive, which serves the availability API
+const DefaultBaseURL = "https://archive.org"
+
+// timestampLayout is the 14-digit timestamp format used by the Wayback Machine
+const timestampLayout = "20060102150405"
+
+// ErrNoSnapshot is returned when no usable snapshot of a page exists
+var ErrNoSnapshot = errors.New("no archived snapshot")
+
+// Snapshot is an archived copy of a page
+type Snapshot struct {
+	URL       string
+	Timestamp time.Time
+}
+
+// Client queries the availability API at a base URL
+type Client struct {
+	baseURL string
+	client  *http.Client
+}
+
+// New creates a client for the availability API at baseURL, such as
+// DefaultBaseURL or a local stub
+func New(baseURL string, timeout time.Duration) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// availability is the response of the availability API
+type availability struct {
+	ArchivedSnapshots struct {
+		Closest *struct {
+			Available bool   `json:"available"`
+			URL       string `json:"url"`
+			Timestamp string `json:"timestamp"`
+			Status    string `json:"status"`
+		} `json:"closest"`
+	} `json:"archived_snapshots"`
+}
+
+// Lookup returns the successfully archived snapshot of pageURL closest to at.
+// A zero at asks for the most recent snapshot.
+func (c *Client) Lookup(ctx context.Context, pageURL string, at time.Time) (*Snapshot, error) {
+	query := url.Values{"url": {pageURL}}
+	if !at.IsZero() {
+		query.Set("timestamp", at.UTC().Format(timestampLayout))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/wayback/available?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query availability: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var result availability
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode availability: %w", err)
+	}
+
+	closest := result.ArchivedSnapshots.Closest
+	if closest == nil || !closest.Available || closest.URL == "" || (closest.Status != "" && closest.Status != "200") {
+		return nil, ErrNoSnapshot
+	}
+	timestamp, err := time.Parse(timestampLayout, closest.Timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snapshot timestamp: %w", err)
+	}
+
+	return &Snapshot{URL: closest.URL, Timestamp: timestamp}, nil
+}
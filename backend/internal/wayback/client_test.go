@@ -0,0 +1,57 @@
+package wayback
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookup(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/wayback/available", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Query().Get("url") {
+		case "https://example.com/dead":
+			assert.Equal(t, "20200102030405", r.URL.Query().Get("timestamp"))
+			w.Write([]byte(`{"url": "https://example.com/dead", "archived_snapshots": {"closest": {
+				"status": "200", "available": true, "timestamp": "20191231235959",
+				"url": "http://web.archive.org/web/20191231235959/https://example.com/dead"}}}`))
+		case "https://example.com/notfound":
+			w.Write([]byte(`{"archived_snapshots": {"closest": {
+				"status": "404", "available": true, "timestamp": "20191231235959",
+				"url": "http://web.archive.org/web/20191231235959/https://example.com/notfound"}}}`))
+		default:
+			w.Write([]byte(`{"archived_snapshots": {}}`))
+		}
+	}))
+	defer ts.Close()
+
+	client := New(ts.URL+"/", 5*time.Second)
+
+	snapshot, err := client.Lookup(context.Background(), "https://example.com/dead", time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, "http://web.archive.org/web/20191231235959/https://example.com/dead", snapshot.URL)
+	assert.Equal(t, time.Date(2019, 12, 31, 23, 59, 59, 0, time.UTC), snapshot.Timestamp)
+
+	_, err = client.Lookup(context.Background(), "https://example.com/notfound", time.Time{})
+	assert.Equal(t, ErrNoSnapshot, err)
+
+	_, err = client.Lookup(context.Background(), "https://example.com/never", time.Time{})
+	assert.Equal(t, ErrNoSnapshot, err)
+}
+
+func TestLookupServerError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	_, err := New(ts.URL, 5*time.Second).Lookup(context.Background(), "https://example.com", time.Time{})
+	assert.Error(t, err)
+	assert.NotEqual(t, ErrNoSnapshot, err)
+}
@@ -0,0 +1,16 @@
+-- Add reading statistics to bookmarks
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS word_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS reading_time_minutes INTEGER NOT NULL DEFAULT 0;
+
+-- Create bookmark_contents table for extracted article bodies
+CREATE TABLE IF NOT EXISTS bookmark_contents (
+    bookmark_id INTEGER PRIMARY KEY REFERENCES bookmarks(id) ON DELETE CASCADE,
+    text TEXT NOT NULL,
+    html TEXT NOT NULL,
+    word_count INTEGER NOT NULL DEFAULT 0,
+    reading_time_minutes INTEGER NOT NULL DEFAULT 0,
+    extracted_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create full-text search index over the article body
+CREATE INDEX IF NOT EXISTS idx_bookmark_contents_text ON bookmark_contents USING GIN (to_tsvector('simple', text));
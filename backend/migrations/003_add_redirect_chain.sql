@@ -0,0 +1,6 @@
+-- Record the resolved final URL and the redirect hops leading to it
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS final_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS redirects JSONB;
+
+-- Create index on final_url for duplicate detection across short links
+CREATE INDEX IF NOT EXISTS idx_bookmarks_final_url ON bookmarks(final_url);
@@ -0,0 +1,3 @@
+-- Record the media type and size of bookmarked resources
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS media_type TEXT NOT NULL DEFAULT '';
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS content_length BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+-- Add author and page count, filled from HTML meta tags and PDF document information
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS author TEXT NOT NULL DEFAULT '';
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS page_count INTEGER NOT NULL DEFAULT 0;
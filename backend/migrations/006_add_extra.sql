@@ -0,0 +1,2 @@
+-- Add site-specific metadata filled by the scraper's extractors (GitHub, YouTube, ...)
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS extra JSONB;
@@ -0,0 +1,4 @@
+-- Add HTTP validators of the last fetch, used for conditional requests on refresh
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS etag TEXT NOT NULL DEFAULT '';
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS last_modified TEXT NOT NULL DEFAULT '';
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS content_hash TEXT NOT NULL DEFAULT '';
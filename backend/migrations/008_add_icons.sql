@@ -0,0 +1,2 @@
+-- Add every candidate icon of the page; favicon_url holds the one selected
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS icons JSONB;
@@ -0,0 +1,2 @@
+-- Add the page's preview image (og:image or twitter:image)
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS image_url TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+-- Track whether metadata was fetched: pending while retries are scheduled, ok or failed
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS metadata_status TEXT NOT NULL DEFAULT 'ok';
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS metadata_error TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_bookmarks_metadata_status ON bookmarks(metadata_status) WHERE metadata_status <> 'ok';
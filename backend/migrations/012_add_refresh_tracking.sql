@@ -0,0 +1,9 @@
+-- Track when metadata was last refreshed and which fields the user edited
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS refreshed_at TIMESTAMP WITH TIME ZONE;
+UPDATE bookmarks SET refreshed_at = updated_at WHERE refreshed_at IS NULL;
+ALTER TABLE bookmarks ALTER COLUMN refreshed_at SET DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE bookmarks ALTER COLUMN refreshed_at SET NOT NULL;
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS locked_fields JSONB;
+
+-- Create index for finding stale bookmarks
+CREATE INDEX IF NOT EXISTS idx_bookmarks_refreshed_at ON bookmarks(refreshed_at);
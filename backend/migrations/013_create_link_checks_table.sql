@@ -0,0 +1,27 @@
+-- Track link health of bookmarks
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS health TEXT NOT NULL DEFAULT 'unknown';
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS check_failures INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS last_checked_at TIMESTAMP WITH TIME ZONE;
+
+-- Create index for listing broken bookmarks
+CREATE INDEX IF NOT EXISTS idx_bookmarks_health ON bookmarks(health);
+
+-- Create index for finding bookmarks due for a check
+CREATE INDEX IF NOT EXISTS idx_bookmarks_last_checked_at ON bookmarks(last_checked_at NULLS FIRST);
+
+-- Create link_checks table for the check history of each bookmark
+CREATE TABLE IF NOT EXISTS link_checks (
+    id BIGSERIAL PRIMARY KEY,
+    bookmark_id INTEGER NOT NULL REFERENCES bookmarks(id) ON DELETE CASCADE,
+    checked_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ok BOOLEAN NOT NULL,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    final_url TEXT NOT NULL DEFAULT '',
+    latency_ms BIGINT NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    tls_error TEXT NOT NULL DEFAULT '',
+    inconclusive BOOLEAN NOT NULL DEFAULT false
+);
+
+-- Create index for the check history of a bookmark
+CREATE INDEX IF NOT EXISTS idx_link_checks_bookmark_id ON link_checks(bookmark_id, checked_at DESC);
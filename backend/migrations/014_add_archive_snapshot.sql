@@ -0,0 +1,3 @@
+-- Record the nearest web archive snapshot of dead or unreachable links
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS archive_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP WITH TIME ZONE;
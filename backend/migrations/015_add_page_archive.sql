@@ -0,0 +1,2 @@
+-- Record when the page and its subresources were last archived to a WARC file
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS page_archived_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,26 @@
+-- Let users watch bookmarks for changes to their content
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS watched BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS watch_checked_at TIMESTAMP WITH TIME ZONE;
+
+-- Create index for finding watched bookmarks due for a check
+CREATE INDEX IF NOT EXISTS idx_bookmarks_watch_checked_at ON bookmarks(watch_checked_at NULLS FIRST) WHERE watched;
+
+-- Create page_texts table for the last seen content text of each watched bookmark
+CREATE TABLE IF NOT EXISTS page_texts (
+    bookmark_id INTEGER PRIMARY KEY REFERENCES bookmarks(id) ON DELETE CASCADE,
+    text TEXT NOT NULL,
+    fetched_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Create page_changes table for the detected changes of watched bookmarks
+CREATE TABLE IF NOT EXISTS page_changes (
+    id BIGSERIAL PRIMARY KEY,
+    bookmark_id INTEGER NOT NULL REFERENCES bookmarks(id) ON DELETE CASCADE,
+    detected_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    diff TEXT NOT NULL,
+    added INTEGER NOT NULL DEFAULT 0,
+    removed INTEGER NOT NULL DEFAULT 0
+);
+
+-- Create index for the change history of a bookmark
+CREATE INDEX IF NOT EXISTS idx_page_changes_bookmark_id ON page_changes(bookmark_id, id DESC);
@@ -0,0 +1,10 @@
+-- Record where the values of descriptive fields came from and how far they are trusted
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS provenance JSONB;
+
+-- Edited fields were entered by the user
+UPDATE bookmarks b
+SET provenance = (
+    SELECT jsonb_object_agg(field, '{"source": "user", "confidence": 1}'::jsonb)
+    FROM jsonb_array_elements_text(b.locked_fields) AS field
+)
+WHERE provenance IS NULL AND jsonb_typeof(locked_fields) = 'array' AND jsonb_array_length(locked_fields) > 0;
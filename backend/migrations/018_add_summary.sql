@@ -0,0 +1,3 @@
+-- Store summaries of the extracted content generated by a language model
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS summary TEXT NOT NULL DEFAULT '';
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS summarized_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,16 @@
+-- Let users tag bookmarks
+ALTER TABLE bookmarks ADD COLUMN IF NOT EXISTS tags JSONB;
+
+-- Create index for listing bookmarks by tag
+CREATE INDEX IF NOT EXISTS idx_bookmarks_tags ON bookmarks USING GIN (tags);
+
+-- Create tag_suggestions table for tags proposed by a language model
+CREATE TABLE IF NOT EXISTS tag_suggestions (
+    id BIGSERIAL PRIMARY KEY,
+    bookmark_id INTEGER NOT NULL REFERENCES bookmarks(id) ON DELETE CASCADE,
+    tag TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    decided_at TIMESTAMP WITH TIME ZONE,
+    UNIQUE (bookmark_id, tag)
+);